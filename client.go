@@ -13,6 +13,12 @@ type Client interface {
 	v1.PostServiceClient
 	v1.TagServiceClient
 	v1.TierServiceClient
+	v1.WebhookServiceClient
+	v1.FeedServiceClient
+	v1.SpaceServiceClient
+	v1.PlatformTagServiceClient
+	v1.TokenServiceClient
+	v1.AccountServiceClient
 	io.Closer
 }
 
@@ -23,6 +29,12 @@ type client struct {
 	v1.PostServiceClient
 	v1.TagServiceClient
 	v1.TierServiceClient
+	v1.WebhookServiceClient
+	v1.FeedServiceClient
+	v1.SpaceServiceClient
+	v1.PlatformTagServiceClient
+	v1.TokenServiceClient
+	v1.AccountServiceClient
 }
 
 func NewClient(port string) (Client, error) {
@@ -31,12 +43,18 @@ func NewClient(port string) (Client, error) {
 		return nil, err
 	}
 	return &client{
-		conn:                conn,
-		CourseServiceClient: v1.NewCourseServiceClient(conn),
-		PageServiceClient:   v1.NewPageServiceClient(conn),
-		PostServiceClient:   v1.NewPostServiceClient(conn),
-		TagServiceClient:    v1.NewTagServiceClient(conn),
-		TierServiceClient:   v1.NewTierServiceClient(conn),
+		conn:                     conn,
+		CourseServiceClient:      v1.NewCourseServiceClient(conn),
+		PageServiceClient:        v1.NewPageServiceClient(conn),
+		PostServiceClient:        v1.NewPostServiceClient(conn),
+		TagServiceClient:         v1.NewTagServiceClient(conn),
+		TierServiceClient:        v1.NewTierServiceClient(conn),
+		WebhookServiceClient:     v1.NewWebhookServiceClient(conn),
+		FeedServiceClient:        v1.NewFeedServiceClient(conn),
+		SpaceServiceClient:       v1.NewSpaceServiceClient(conn),
+		PlatformTagServiceClient: v1.NewPlatformTagServiceClient(conn),
+		TokenServiceClient:       v1.NewTokenServiceClient(conn),
+		AccountServiceClient:     v1.NewAccountServiceClient(conn),
 	}, nil
 }
 
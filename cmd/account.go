@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/emrgen/unpost"
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/olekukonko/tablewriter"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var accountCmd = &cobra.Command{
+	Use:   "account",
+	Short: "account commands",
+}
+
+func init() {
+	accountCmd.AddCommand(setupOwner())
+	accountCmd.AddCommand(listAccounts())
+	accountCmd.AddCommand(updateAccountRole())
+	accountCmd.AddCommand(deactivateAccount())
+	accountCmd.AddCommand(reactivateAccount())
+}
+
+func setupOwner() *cobra.Command {
+	var email string
+	var username string
+	var password string
+
+	command := &cobra.Command{
+		Use:   "setup",
+		Short: "Create the owner account on the first run",
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			setup, err := client.CheckOwnerSetup(tokenContext(), &v1.CheckOwnerSetupRequest{})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			if setup.IsSetup {
+				cmd.Println("the owner is already set up")
+				return
+			}
+
+			if email == "" || username == "" || password == "" {
+				logrus.Errorf("missing required flags: --email, --username, --password")
+				return
+			}
+
+			res, err := client.CreateOwner(tokenContext(), &v1.CreateOwnerRequest{
+				Email:    email,
+				Username: username,
+				Password: password,
+			})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			cmd.Println("Owner created:", res.User.Id)
+		},
+	}
+
+	command.Flags().StringVarP(&email, "email", "e", "", "owner email")
+	command.Flags().StringVarP(&username, "username", "u", "", "owner username")
+	command.Flags().StringVarP(&password, "password", "p", "", "owner password")
+
+	return command
+}
+
+func listAccounts() *cobra.Command {
+	var roles []string
+	var all bool
+	var page int32
+	var perPage int32
+
+	command := &cobra.Command{
+		Use:   "list",
+		Short: "List the accounts",
+		Run: func(cmd *cobra.Command, args []string) {
+			request := &v1.ListAccountsRequest{
+				IncludeDeactivated: all,
+				Page:               page,
+				PerPage:            perPage,
+			}
+			for _, value := range roles {
+				role, err := userRoleFlag(value)
+				if err != nil {
+					logrus.Error(err)
+					return
+				}
+				request.Roles = append(request.Roles, role)
+			}
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			res, err := client.ListAccounts(tokenContext(), request)
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"ID", "Name", "Email", "Role", "Deactivated At"})
+			for _, account := range res.Accounts {
+				deactivatedAt := ""
+				if account.DeactivatedAt != nil {
+					deactivatedAt = account.DeactivatedAt.AsTime().Format("2006-01-02 15:04:05")
+				}
+				table.Append([]string{account.Id, account.Name, account.Email, strings.ToLower(account.Role.String()), deactivatedAt})
+			}
+			table.Render()
+		},
+	}
+
+	command.Flags().StringSliceVar(&roles, "role", nil, "list the accounts of the roles, viewer, author, admin or owner")
+	command.Flags().BoolVar(&all, "all", false, "include the deactivated accounts")
+	command.Flags().Int32Var(&page, "page", 0, "page number, starting at 0")
+	command.Flags().Int32Var(&perPage, "per-page", 20, "accounts per page")
+
+	return command
+}
+
+func updateAccountRole() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "role <account-id> <role>",
+		Short: "Change the role of an account",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			role, err := userRoleFlag(args[1])
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			res, err := client.UpdateAccountRole(tokenContext(), &v1.UpdateAccountRoleRequest{Id: args[0], Role: role})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			cmd.Printf("Account %s is now %s\n", res.Account.Id, strings.ToLower(res.Account.Role.String()))
+		},
+	}
+
+	return command
+}
+
+func deactivateAccount() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "deactivate <account-id>",
+		Short: "Stop an account from logging in",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			_, err = client.DeactivateAccount(tokenContext(), &v1.DeactivateAccountRequest{Id: args[0]})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			cmd.Println("Account deactivated:", args[0])
+		},
+	}
+
+	return command
+}
+
+func reactivateAccount() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "reactivate <account-id>",
+		Short: "Let a deactivated account log in again",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			_, err = client.ReactivateAccount(tokenContext(), &v1.ReactivateAccountRequest{Id: args[0]})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			cmd.Println("Account reactivated:", args[0])
+		},
+	}
+
+	return command
+}
+
+func userRoleFlag(value string) (v1.UserRole, error) {
+	switch value {
+	case "viewer":
+		return v1.UserRole_Viewer, nil
+	case "author":
+		return v1.UserRole_Author, nil
+	case "admin":
+		return v1.UserRole_Admin, nil
+	case "owner":
+		return v1.UserRole_Owner, nil
+	default:
+		return 0, fmt.Errorf("invalid role %q, must be one of viewer, author, admin, owner", value)
+	}
+}
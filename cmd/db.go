@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"context"
+	"os"
+	"strconv"
+
 	"github.com/emrgen/unpost/internal/config"
-	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/migrate"
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
@@ -18,15 +23,152 @@ func init() {
 func Migrate() *cobra.Command {
 	command := &cobra.Command{
 		Use:   "migrate",
-		Short: "Migrate the database",
-		Run: func(cmd *cobra.Command, args []string) {
-			db := config.GetDb(config.LoadConfig())
-			err := model.Migrate(db)
+		Short: "Migrate the database, same as migrate up",
+		// the failures are reported by the exit status, the usage would hide them
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return migrateUp(cmd, 0)
+		},
+	}
+
+	command.AddCommand(migrateUpCommand())
+	command.AddCommand(migrateDownCommand())
+	command.AddCommand(migrateStatusCommand())
+	command.AddCommand(migrateCreateCommand())
+
+	return command
+}
+
+func migrateUpCommand() *cobra.Command {
+	var steps int
+
+	command := &cobra.Command{
+		Use:          "up",
+		Short:        "Apply the pending migrations",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return migrateUp(cmd, steps)
+		},
+	}
+
+	command.Flags().IntVarP(&steps, "steps", "n", 0, "number of migrations to apply, all when 0")
+
+	return command
+}
+
+func migrateUp(cmd *cobra.Command, steps int) error {
+	migrator, err := newMigrator()
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(context.Background(), steps)
+	for _, migration := range applied {
+		cmd.Printf("applied %06d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		cmd.Println("database is up to date")
+	}
+
+	return nil
+}
+
+func migrateDownCommand() *cobra.Command {
+	var steps int
+
+	command := &cobra.Command{
+		Use:          "down",
+		Short:        "Revert the latest migrations",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			migrator, err := newMigrator()
+			if err != nil {
+				return err
+			}
+
+			reverted, err := migrator.Down(context.Background(), steps)
+			for _, migration := range reverted {
+				cmd.Printf("reverted %06d_%s\n", migration.Version, migration.Name)
+			}
 			if err != nil {
-				panic(err)
+				return err
 			}
+			if len(reverted) == 0 {
+				cmd.Println("no migration to revert")
+			}
+
+			return nil
 		},
 	}
 
+	command.Flags().IntVarP(&steps, "steps", "n", 1, "number of migrations to revert, all when 0")
+
 	return command
 }
+
+func migrateStatusCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:          "status",
+		Short:        "List the applied and the pending migrations",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			migrator, err := newMigrator()
+			if err != nil {
+				return err
+			}
+
+			statuses, err := migrator.Status(context.Background())
+			if err != nil {
+				return err
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Version", "Name", "Status", "Applied At"})
+			for _, status := range statuses {
+				state, appliedAt := "pending", ""
+				if status.AppliedAt != nil {
+					state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				if status.Unknown {
+					state = "unknown"
+				}
+				table.Append([]string{strconv.FormatInt(status.Version, 10), status.Name, state, appliedAt})
+			}
+			table.Render()
+
+			return nil
+		},
+	}
+
+	return command
+}
+
+func migrateCreateCommand() *cobra.Command {
+	var dir string
+
+	command := &cobra.Command{
+		Use:          "create <name>",
+		Short:        "Create the up and down scripts of a new migration for every dialect",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := migrate.Create(dir, args[0])
+			for _, file := range files {
+				cmd.Println("created", file)
+			}
+
+			return err
+		},
+	}
+
+	command.Flags().StringVarP(&dir, "dir", "d", "internal/migrate/migrations", "migrations directory of the source tree")
+
+	return command
+}
+
+func newMigrator() (*migrate.Migrator, error) {
+	return migrate.New(config.GetDb(config.LoadConfig()))
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emrgen/unpost"
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/importer"
+	"github.com/google/uuid"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func importBlog(format, short string) *cobra.Command {
+	var spaceID string
+	var siteURL string
+	var linkFormat string
+	var conflict string
+	var dryRun bool
+
+	command := &cobra.Command{
+		Use:   format + " <file>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			space, err := uuid.Parse(spaceID)
+			if err != nil && !dryRun {
+				cmd.Println("a valid space id is required")
+				return
+			}
+			policy, ok := v1.ImportConflictPolicy_value[strings.ToUpper(conflict)]
+			if !ok || v1.ImportConflictPolicy(policy) == v1.ImportConflictPolicy_OVERWRITE {
+				cmd.Printf("unknown conflict policy %q, use skip or rename\n", conflict)
+				return
+			}
+
+			parser, err := importer.NewParser(format)
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+			defer file.Close()
+
+			blog, err := parser.Parse(file)
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+
+			opts := importer.Options{
+				SpaceID:    space,
+				SiteURL:    siteURL,
+				LinkFormat: linkFormat,
+			}
+			var client unpost.Client
+			if !dryRun {
+				client, err = unpost.NewClient("8030")
+				if err != nil {
+					cmd.Println(err)
+					return
+				}
+				defer client.Close()
+
+				// the authors are matched to the accounts by email
+				opts.Authors, err = accountEmails(client)
+				if err != nil {
+					cmd.Println(err)
+					return
+				}
+			}
+
+			plan, err := importer.Map(blog, opts)
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+
+			printImportSummary(cmd, &plan.Summary, !dryRun)
+			if dryRun {
+				cmd.Println("dry run, nothing was imported")
+				return
+			}
+
+			var failed, skipped int
+			for _, post := range plan.Posts {
+				content, err := importer.Document(post).Markdown()
+				var res *v1.ImportPostResponse
+				if err == nil {
+					res, err = client.ImportPost(tokenContext(), &v1.ImportPostRequest{
+						SpaceId:        spaceID,
+						Content:        string(content),
+						ConflictPolicy: v1.ImportConflictPolicy(policy),
+					})
+				}
+				if err != nil {
+					cmd.Printf("%s: %v\n", post.Slug, err)
+					failed++
+					continue
+				}
+				if res.GetSkipped() {
+					skipped++
+				}
+			}
+
+			cmd.Printf("imported %d of %d posts, %d already existed\n", len(plan.Posts)-failed-skipped, len(plan.Posts), skipped)
+		},
+	}
+
+	command.Flags().StringVarP(&spaceID, "space", "s", "", "space id to import into")
+	command.Flags().StringVar(&siteURL, "site-url", "", "address of the old blog, overrides the one found in the export")
+	command.Flags().StringVar(&linkFormat, "link-format", importer.DefaultLinkFormat, "new link of the posts, {slug} is replaced by the post slug")
+	command.Flags().StringVar(&conflict, "on-conflict", "skip", "policy for the posts whose slug exists in the space: skip or rename")
+	command.Flags().BoolVar(&dryRun, "dry-run", false, "print the summary without importing")
+
+	return command
+}
+
+// accountEmails maps the lowercase emails of the accounts to their ids
+func accountEmails(client unpost.Client) (map[string]string, error) {
+	const perPage = 100
+
+	emails := make(map[string]string)
+	for page := int32(0); ; page++ {
+		res, err := client.ListAccounts(tokenContext(), &v1.ListAccountsRequest{
+			IncludeDeactivated: true,
+			Page:               page,
+			PerPage:            perPage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range res.GetAccounts() {
+			emails[strings.ToLower(account.GetEmail())] = account.GetId()
+		}
+		if len(res.GetAccounts()) < perPage {
+			return emails, nil
+		}
+	}
+}
+
+// printImportSummary prints what the import creates, the matched authors are known once the accounts are listed
+func printImportSummary(cmd *cobra.Command, summary *importer.Summary, matched bool) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Item", "Count"})
+	table.Append([]string{"Posts", strconv.Itoa(summary.Posts)})
+	table.Append([]string{"Pages", strconv.Itoa(summary.Pages)})
+	table.Append([]string{"Published", strconv.Itoa(summary.Published)})
+	table.Append([]string{"Drafts", strconv.Itoa(summary.Drafts)})
+	table.Append([]string{"Tags", strconv.Itoa(len(summary.Tags))})
+	table.Append([]string{"Authors", strconv.Itoa(len(summary.Authors))})
+	if matched {
+		table.Append([]string{"Entries of an account", strconv.Itoa(summary.Matched)})
+	}
+	table.Append([]string{"Rewritten links", strconv.Itoa(summary.RewrittenLinks)})
+
+	skipped := make([]string, 0, len(summary.Skipped))
+	for kind := range summary.Skipped {
+		skipped = append(skipped, kind)
+	}
+	sort.Strings(skipped)
+	for _, kind := range skipped {
+		table.Append([]string{"Skipped " + kind, strconv.Itoa(summary.Skipped[kind])})
+	}
+	table.Render()
+
+	if len(summary.Authors) > 0 {
+		// the posts of the authors without an account are owned by the importer, the authors are listed to be invited
+		authors := make([]string, 0, len(summary.Authors))
+		for author := range summary.Authors {
+			authors = append(authors, author)
+		}
+		sort.Strings(authors)
+		for _, author := range authors {
+			cmd.Printf("author %s: %d entries\n", author, summary.Authors[author])
+		}
+	}
+}
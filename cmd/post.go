@@ -7,7 +7,9 @@ import (
 	"github.com/olekukonko/tablewriter"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -25,6 +27,11 @@ func init() {
 	postCmd.AddCommand(addPostTag())
 	postCmd.AddCommand(removePostTag())
 	postCmd.AddCommand(updatePostStatus())
+	postCmd.AddCommand(importPosts())
+	postCmd.AddCommand(exportPost())
+	postCmd.AddCommand(bulkPosts())
+	postCmd.AddCommand(pendingReviews())
+	postCmd.AddCommand(previewLinks())
 }
 
 func postCreate() *cobra.Command {
@@ -131,16 +138,15 @@ func postList() *cobra.Command {
 			defer client.Close()
 
 			req := &v1.ListPostRequest{}
+			if spaceID != "" {
+				req.SpaceId = &spaceID
+			}
 
 			if postStatus != "" {
-				var status v1.PostStatus
-				switch postStatus {
-				case "draft":
-					status = v1.PostStatus_DRAFT
-				case "published":
-					status = v1.PostStatus_PUBLISHED
-				case "archived":
-					status = v1.PostStatus_ARCHIVED
+				status, err := postStatusFlag(postStatus)
+				if err != nil {
+					logrus.Error(err)
+					return
 				}
 				req.Status = &status
 			}
@@ -367,9 +373,14 @@ func removePostTag() *cobra.Command {
 func updatePostStatus() *cobra.Command {
 	var postID string
 	var status string
+	var notes string
 	command := &cobra.Command{
 		Use:   "status",
-		Short: "Publish a post",
+		Short: "Move a post through the review workflow",
+		Long: `Move a post through the review workflow.
+
+The authors submit their drafts with in_review and publish them once an admin approved them,
+the admins approve a post or reject it back to draft with notes for the author.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if postID == "" {
 				logrus.Errorf("missing required flag: --post-id")
@@ -388,21 +399,16 @@ func updatePostStatus() *cobra.Command {
 			}
 			defer client.Close()
 
-			var postStatus v1.PostStatus
-			switch status {
-			case "draft":
-				postStatus = v1.PostStatus_DRAFT
-			case "published":
-				postStatus = v1.PostStatus_PUBLISHED
-			case "archived":
-				postStatus = v1.PostStatus_ARCHIVED
-			default:
-				logrus.Errorf("invalid status, must be one of draft, published, archived")
+			postStatus, err := postStatusFlag(status)
+			if err != nil {
+				logrus.Error(err)
+				return
 			}
 
 			_, err = client.UpdatePostStatus(tokenContext(), &v1.UpdatePostStatusRequest{
 				PostId: postID,
 				Status: postStatus,
+				Notes:  notes,
 			})
 			if err != nil {
 				logrus.Error(err)
@@ -414,7 +420,186 @@ func updatePostStatus() *cobra.Command {
 	}
 
 	command.Flags().StringVarP(&postID, "post-id", "p", "", "post id")
-	command.Flags().StringVarP(&status, "status", "s", "", "status of the post, one of draft, published, archived")
+	command.Flags().StringVarP(&status, "status", "s", "", "status of the post, one of draft, in_review, approved, published, archived")
+	command.Flags().StringVarP(&notes, "notes", "n", "", "notes of the reviewer for the author")
+
+	return command
+}
+
+func pendingReviews() *cobra.Command {
+	var spaceID string
+
+	command := &cobra.Command{
+		Use:   "reviews",
+		Short: "List the posts waiting for a review",
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			req := &v1.ListPendingReviewsRequest{}
+			if spaceID != "" {
+				req.SpaceId = &spaceID
+			}
+			res, err := client.ListPendingReviews(tokenContext(), req)
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Post ID", "Title", "Requested By", "Submitted At"})
+			for _, review := range res.Reviews {
+				table.Append([]string{review.PostId, review.GetPost().GetTitle(), review.RequestedBy, review.CreatedAt.AsTime().Format("2006-01-02 15:04:05")})
+			}
+			table.Render()
+		},
+	}
+
+	command.Flags().StringVarP(&spaceID, "space-id", "s", "", "space id")
+
+	return command
+}
+
+func importPosts() *cobra.Command {
+	var spaceID string
+
+	command := &cobra.Command{
+		Use:   "import <dir>",
+		Short: "Import the markdown files of a directory as posts",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if spaceID == "" {
+				logrus.Errorf("missing required flag: --space-id")
+				return
+			}
+
+			var files []string
+			err := filepath.WalkDir(args[0], func(path string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !entry.IsDir() && strings.EqualFold(filepath.Ext(path), ".md") {
+					files = append(files, path)
+				}
+				return nil
+			})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			if len(files) == 0 {
+				cmd.Println("no markdown files found")
+				return
+			}
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"File", "ID", "Slug ID", "Title", "Status"})
+			var createdTags []string
+			var failed int
+			for _, file := range files {
+				content, err := os.ReadFile(file)
+				if err != nil {
+					logrus.Errorf("%s: %v", file, err)
+					failed++
+					continue
+				}
+
+				res, err := client.ImportPost(tokenContext(), &v1.ImportPostRequest{
+					SpaceId: spaceID,
+					Content: string(content),
+				})
+				if err != nil {
+					logrus.Errorf("%s: %v", file, err)
+					failed++
+					continue
+				}
+
+				table.Append([]string{file, res.Post.Id, res.Post.SlugId, res.Post.Title, res.Post.Status.String()})
+				for _, tag := range res.GetCreatedTags() {
+					createdTags = append(createdTags, tag.GetName())
+				}
+			}
+			table.Render()
+
+			if len(createdTags) > 0 {
+				cmd.Printf("Created tags: %s\n", strings.Join(createdTags, ", "))
+			}
+			cmd.Printf("Imported %d of %d files\n", len(files)-failed, len(files))
+		},
+	}
+
+	command.Flags().StringVarP(&spaceID, "space-id", "s", "", "space id")
+
+	return command
+}
+
+func exportPost() *cobra.Command {
+	var postID string
+	var format string
+	var output string
+
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "Export a post as markdown or html",
+		Run: func(cmd *cobra.Command, args []string) {
+			if postID == "" {
+				logrus.Errorf("missing required flag: --post-id")
+				return
+			}
+
+			var postFormat v1.PostFormat
+			switch format {
+			case "markdown", "md":
+				postFormat = v1.PostFormat_MARKDOWN
+			case "html":
+				postFormat = v1.PostFormat_HTML
+			default:
+				logrus.Errorf("invalid format, must be one of markdown, html")
+				return
+			}
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			res, err := client.ExportPost(tokenContext(), &v1.ExportPostRequest{
+				PostId: postID,
+				Format: postFormat,
+			})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			if output == "" {
+				fmt.Print(res.GetContent())
+				return
+			}
+			if err := os.WriteFile(output, []byte(res.GetContent()), 0o644); err != nil {
+				logrus.Error(err)
+				return
+			}
+			cmd.Printf("Post exported to %s\n", output)
+		},
+	}
+
+	command.Flags().StringVarP(&postID, "post-id", "p", "", "post id")
+	command.Flags().StringVarP(&format, "format", "f", "markdown", "export format: markdown or html")
+	command.Flags().StringVarP(&output, "output", "o", "", "output file, defaults to stdout")
 
 	return command
 }
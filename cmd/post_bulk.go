@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/emrgen/unpost"
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// bulkBatchSize is the number of posts sent in one batch rpc, the server accepts at most 500
+const bulkBatchSize = 500
+
+func bulkPosts() *cobra.Command {
+	var filter string
+	var status string
+	var addTags []string
+	var removeTags []string
+	var tierID string
+	var authorID string
+	var remove bool
+	var atomic bool
+	var dryRun bool
+
+	command := &cobra.Command{
+		Use:   "bulk [post-id...|-]",
+		Short: "Update or delete many posts at once",
+		Long: `Update or delete many posts at once.
+
+The posts are given as arguments, read from stdin with "-", or selected with a filter
+expression of space separated terms: space=<id> status=<status> tag=<id> author=<id>.
+
+  unstak post list ... | unstak post bulk - --status published
+  unstak post bulk --filter "space=<id> status=draft" --add-tag <id> --dry-run`,
+		Run: func(cmd *cobra.Command, args []string) {
+			update := &v1.BatchUpdatePostsRequest{
+				AddTagIds:    addTags,
+				RemoveTagIds: removeTags,
+				Atomic:       atomic,
+			}
+			if cmd.Flags().Changed("status") {
+				postStatus, err := postStatusFlag(status)
+				if err != nil {
+					cmd.Println(err)
+					return
+				}
+				update.Status = &postStatus
+			}
+			if cmd.Flags().Changed("tier") {
+				update.TierId = &tierID
+			}
+			if cmd.Flags().Changed("author") {
+				update.AuthorId = &authorID
+			}
+			changes := describeBulkUpdate(update)
+			if remove && len(changes) > 0 {
+				cmd.Println("--delete cannot be combined with the update flags")
+				return
+			}
+			if !remove && len(changes) == 0 {
+				cmd.Println("nothing to do, use --delete or the update flags")
+				return
+			}
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+			defer client.Close()
+
+			ctx := tokenContext()
+			postIDs, err := bulkPostIDs(ctx, client, args, filter, cmd.InOrStdin())
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+			if len(postIDs) == 0 {
+				cmd.Println("no posts selected")
+				return
+			}
+
+			action := "delete"
+			if !remove {
+				action = strings.Join(changes, ", ")
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			if dryRun {
+				table.SetHeader([]string{"ID", "Title", "Status", "Planned"})
+			} else {
+				table.SetHeader([]string{"ID", "Title", "Status", "Result"})
+			}
+
+			var failed int
+			for start := 0; start < len(postIDs); start += bulkBatchSize {
+				chunk := postIDs[start:min(start+bulkBatchSize, len(postIDs))]
+
+				var results []*v1.BatchPostResult
+				switch {
+				case dryRun:
+					res, err := client.BatchGetPosts(ctx, &v1.BatchGetPostsRequest{PostIds: chunk})
+					if err != nil {
+						cmd.Println(err)
+						return
+					}
+					results = res.Results
+				case remove:
+					res, err := client.BatchDeletePosts(ctx, &v1.BatchDeletePostsRequest{PostIds: chunk, Atomic: atomic})
+					if err != nil {
+						cmd.Println(err)
+						return
+					}
+					results = res.Results
+				default:
+					update.PostIds = chunk
+					res, err := client.BatchUpdatePosts(ctx, update)
+					if err != nil {
+						cmd.Println(err)
+						return
+					}
+					results = res.Results
+				}
+
+				for _, result := range results {
+					outcome := "ok"
+					if dryRun {
+						outcome = action
+					}
+					if result.Error != "" {
+						outcome = result.Error
+						failed++
+					}
+					table.Append([]string{result.PostId, result.GetPost().GetTitle(), bulkPostStatus(result.GetPost()), outcome})
+				}
+			}
+			table.Render()
+
+			switch {
+			case dryRun:
+				cmd.Printf("dry run: %d posts would be changed (%s), %d not found\n", len(postIDs)-failed, action, failed)
+			case failed > 0:
+				cmd.Printf("%d of %d posts failed\n", failed, len(postIDs))
+			default:
+				cmd.Printf("%d posts changed\n", len(postIDs))
+			}
+		},
+	}
+
+	command.Flags().StringVarP(&filter, "filter", "f", "", `select the posts with a filter expression, like "space=<id> status=draft tag=<id>"`)
+	command.Flags().StringVar(&status, "status", "", "set the status, one of draft, in_review, approved, published, archived")
+	command.Flags().StringSliceVar(&addTags, "add-tag", nil, "add a tag by id")
+	command.Flags().StringSliceVar(&removeTags, "remove-tag", nil, "remove a tag by id")
+	command.Flags().StringVar(&tierID, "tier", "", "gate the posts behind a tier, empty makes them public")
+	command.Flags().StringVar(&authorID, "author", "", "set the author by account id")
+	command.Flags().BoolVar(&remove, "delete", false, "delete the posts")
+	command.Flags().BoolVar(&atomic, "atomic", false, "roll back every post when one fails")
+	command.Flags().BoolVar(&dryRun, "dry-run", false, "preview the selected posts without changing them")
+
+	return command
+}
+
+// bulkPostIDs returns the ids given as arguments, read from stdin or selected by the filter expression
+func bulkPostIDs(ctx context.Context, client unpost.Client, args []string, filter string, stdin io.Reader) ([]string, error) {
+	switch {
+	case filter != "" && len(args) > 0:
+		return nil, fmt.Errorf("the post ids and --filter cannot be combined")
+	case len(args) == 1 && args[0] == "-":
+		var ids []string
+		scanner := bufio.NewScanner(stdin)
+		for scanner.Scan() {
+			ids = append(ids, strings.Fields(scanner.Text())...)
+		}
+		return ids, scanner.Err()
+	case len(args) > 0:
+		return args, nil
+	case filter == "":
+		return nil, fmt.Errorf("the posts are given as arguments, with - for stdin, or with --filter")
+	}
+
+	request, err := parsePostFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	const perPage = 100
+	request.PerPage = perPage
+	var ids []string
+	for page := int32(0); ; page++ {
+		request.Page = page
+		res, err := client.ListPost(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range res.Posts {
+			ids = append(ids, post.Id)
+		}
+		if len(res.Posts) < perPage {
+			return ids, nil
+		}
+	}
+}
+
+// parsePostFilter parses a filter expression of space separated key=value terms into a ListPost request
+func parsePostFilter(filter string) (*v1.ListPostRequest, error) {
+	request := &v1.ListPostRequest{}
+	for _, term := range strings.Fields(filter) {
+		key, value, ok := strings.Cut(term, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid filter term %q, expected key=value", term)
+		}
+
+		switch key {
+		case "space":
+			request.SpaceId = &value
+		case "status":
+			postStatus, err := postStatusFlag(value)
+			if err != nil {
+				return nil, err
+			}
+			request.Status = &postStatus
+		case "tag":
+			request.Tags = append(request.Tags, &v1.Tag{Id: value})
+		case "author":
+			request.Authors = append(request.Authors, &v1.Account{Id: value})
+		default:
+			return nil, fmt.Errorf("unknown filter key %q, expected one of space, status, tag, author", key)
+		}
+	}
+
+	return request, nil
+}
+
+func postStatusFlag(value string) (v1.PostStatus, error) {
+	switch value {
+	case "draft":
+		return v1.PostStatus_DRAFT, nil
+	case "in_review":
+		return v1.PostStatus_IN_REVIEW, nil
+	case "approved":
+		return v1.PostStatus_APPROVED, nil
+	case "published":
+		return v1.PostStatus_PUBLISHED, nil
+	case "archived":
+		return v1.PostStatus_ARCHIVED, nil
+	default:
+		return 0, fmt.Errorf("invalid status %q, must be one of draft, in_review, approved, published, archived", value)
+	}
+}
+
+// describeBulkUpdate lists the changes of the update for the preview
+func describeBulkUpdate(update *v1.BatchUpdatePostsRequest) []string {
+	var changes []string
+	if update.Status != nil {
+		changes = append(changes, "status "+strings.ToLower(update.GetStatus().String()))
+	}
+	for _, tagID := range update.GetAddTagIds() {
+		changes = append(changes, "add tag "+tagID)
+	}
+	for _, tagID := range update.GetRemoveTagIds() {
+		changes = append(changes, "remove tag "+tagID)
+	}
+	if update.TierId != nil {
+		if update.GetTierId() == "" {
+			changes = append(changes, "make public")
+		} else {
+			changes = append(changes, "tier "+update.GetTierId())
+		}
+	}
+	if update.AuthorId != nil {
+		changes = append(changes, "author "+update.GetAuthorId())
+	}
+
+	return changes
+}
+
+func bulkPostStatus(post *v1.Post) string {
+	if post == nil {
+		return ""
+	}
+
+	return strings.ToLower(post.Status.String())
+}
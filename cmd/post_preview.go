@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/emrgen/unpost"
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/olekukonko/tablewriter"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func previewLinks() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "preview",
+		Short: "Share unpublished posts with preview links",
+	}
+
+	command.AddCommand(createPreviewLink())
+	command.AddCommand(listPreviewLinks())
+	command.AddCommand(revokePreviewLink())
+	command.AddCommand(listPreviewAccesses())
+
+	return command
+}
+
+func createPreviewLink() *cobra.Command {
+	var postID string
+	var ttl time.Duration
+
+	command := &cobra.Command{
+		Use:   "create",
+		Short: "Create a preview link of a post",
+		Run: func(cmd *cobra.Command, args []string) {
+			if postID == "" {
+				logrus.Errorf("missing required flag: --post-id")
+				return
+			}
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			res, err := client.CreatePreviewLink(tokenContext(), &v1.CreatePreviewLinkRequest{
+				PostId:     postID,
+				TtlSeconds: int64(ttl.Seconds()),
+			})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			cmd.Println("Preview link created:", res.Link.Id)
+			cmd.Println("Expires at:", res.Link.ExpiresAt.AsTime().Format("2006-01-02 15:04:05"))
+			cmd.Printf("GET /v1/posts/%s?preview_token=%s\n", res.Link.PostId, res.Link.Token)
+		},
+	}
+
+	command.Flags().StringVarP(&postID, "post-id", "p", "", "post id")
+	command.Flags().DurationVar(&ttl, "ttl", 0, "lifetime of the link, 7 days by default and 30 days at most")
+
+	return command
+}
+
+func listPreviewLinks() *cobra.Command {
+	var postID string
+
+	command := &cobra.Command{
+		Use:   "list",
+		Short: "List the preview links of a post",
+		Run: func(cmd *cobra.Command, args []string) {
+			if postID == "" {
+				logrus.Errorf("missing required flag: --post-id")
+				return
+			}
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			res, err := client.ListPreviewLinks(tokenContext(), &v1.ListPreviewLinksRequest{PostId: postID})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"ID", "Created By", "Expires At", "Revoked At"})
+			for _, link := range res.Links {
+				revokedAt := ""
+				if link.RevokedAt != nil {
+					revokedAt = link.RevokedAt.AsTime().Format("2006-01-02 15:04:05")
+				}
+				table.Append([]string{link.Id, link.CreatedById, link.ExpiresAt.AsTime().Format("2006-01-02 15:04:05"), revokedAt})
+			}
+			table.Render()
+		},
+	}
+
+	command.Flags().StringVarP(&postID, "post-id", "p", "", "post id")
+
+	return command
+}
+
+func revokePreviewLink() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "revoke <link-id>",
+		Short: "Revoke a preview link",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			_, err = client.RevokePreviewLink(tokenContext(), &v1.RevokePreviewLinkRequest{Id: args[0]})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			cmd.Println("Preview link revoked:", args[0])
+		},
+	}
+
+	return command
+}
+
+func listPreviewAccesses() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "accesses <link-id>",
+		Short: "List the reads through a preview link",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			res, err := client.ListPreviewAccesses(tokenContext(), &v1.ListPreviewAccessesRequest{Id: args[0]})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Accessed At", "User ID", "Remote Address", "User Agent"})
+			for _, access := range res.Accesses {
+				table.Append([]string{access.AccessedAt.AsTime().Format("2006-01-02 15:04:05"), access.UserId, access.RemoteAddr, access.UserAgent})
+			}
+			table.Render()
+		},
+	}
+
+	return command
+}
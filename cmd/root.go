@@ -25,4 +25,6 @@ func init() {
 	rootCmd.AddCommand(tierCmd)
 	rootCmd.AddCommand(postCmd)
 	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(tokenCmd)
+	rootCmd.AddCommand(accountCmd)
 }
@@ -24,8 +24,14 @@ var serveCmd = &cobra.Command{
 			logrus.Errorf("error getting http port: %v", err)
 		}
 
-		logrus.Infof("grpc port: %s, http port: %s", grpcPort, httpPort)
-		err = server.Start(grpcPort, httpPort)
+		//get admin port
+		adminPort, err := cmd.Flags().GetString("ap")
+		if err != nil {
+			logrus.Errorf("error getting admin port: %v", err)
+		}
+
+		logrus.Infof("grpc port: %s, http port: %s, admin port: %s", grpcPort, httpPort, adminPort)
+		err = server.Start(grpcPort, httpPort, adminPort)
 		if err != nil {
 			logrus.Errorf("error starting service: %v", err)
 			return
@@ -36,8 +42,9 @@ var serveCmd = &cobra.Command{
 // init function to add flags to serveCmd
 func init() {
 	rootCmd.AddCommand(serveCmd)
-	var grpcPort, httpPort string
+	var grpcPort, httpPort, adminPort string
 
 	serveCmd.Flags().StringVar(&grpcPort, "gp", "8030", "Port to run grpc server on")
 	serveCmd.Flags().StringVar(&httpPort, "hp", "8031", "Port to run http server on")
+	serveCmd.Flags().StringVar(&adminPort, "ap", "8032", "Port to run admin server with the metrics on")
 }
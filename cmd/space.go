@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/emrgen/unpost"
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// importChunkSize is the size of the archive chunks sent to the server
+const importChunkSize = 64 * 1024
+
+func init() {
+	importCmd := importSpace()
+	importCmd.AddCommand(importBlog("wordpress", "Import the posts and pages of a WordPress WXR export"))
+	importCmd.AddCommand(importBlog("ghost", "Import the posts and pages of a Ghost json export"))
+
+	rootCmd.AddCommand(exportSpace())
+	rootCmd.AddCommand(importCmd)
+}
+
+func exportSpace() *cobra.Command {
+	var spaceID string
+	var output string
+
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "Export the content of a space into a tar.gz archive",
+		Run: func(cmd *cobra.Command, args []string) {
+			if spaceID == "" {
+				cmd.Println("space id is required")
+				return
+			}
+			if output == "" {
+				output = spaceID + ".tar.gz"
+			}
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+			defer client.Close()
+
+			stream, err := client.ExportSpace(tokenContext(), &v1.ExportSpaceRequest{SpaceId: spaceID})
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+
+			file, err := os.Create(output)
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+			defer file.Close()
+
+			var size int
+			for {
+				chunk, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					cmd.Println(err)
+					// a partial archive cannot be imported
+					_ = os.Remove(output)
+					return
+				}
+
+				n, err := file.Write(chunk.GetData())
+				if err != nil {
+					cmd.Println(err)
+					return
+				}
+				size += n
+			}
+
+			cmd.Printf("exported space %s to %s (%d bytes)\n", spaceID, output, size)
+		},
+	}
+
+	command.Flags().StringVarP(&spaceID, "space", "s", "", "space id")
+	command.Flags().StringVarP(&output, "output", "o", "", "archive file, defaults to <space id>.tar.gz")
+
+	return command
+}
+
+func importSpace() *cobra.Command {
+	var spaceID string
+	var conflict string
+
+	command := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Import a space archive created by export",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if spaceID == "" {
+				cmd.Println("space id is required")
+				return
+			}
+
+			policy, ok := v1.ImportConflictPolicy_value[strings.ToUpper(conflict)]
+			if !ok {
+				cmd.Printf("unknown conflict policy %q, use skip, overwrite or rename\n", conflict)
+				return
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+			defer file.Close()
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+			defer client.Close()
+
+			stream, err := client.ImportSpace(tokenContext())
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+
+			err = stream.Send(&v1.ImportSpaceRequest{
+				Options: &v1.ImportSpaceOptions{
+					SpaceId:        spaceID,
+					ConflictPolicy: v1.ImportConflictPolicy(policy),
+				},
+			})
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+
+			buf := make([]byte, importChunkSize)
+			for {
+				n, err := file.Read(buf)
+				if n > 0 {
+					if err := stream.Send(&v1.ImportSpaceRequest{Data: buf[:n]}); err != nil {
+						// the server closed the stream, its error is returned by CloseAndRecv
+						break
+					}
+				}
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					cmd.Println(err)
+					return
+				}
+			}
+
+			res, err := stream.CloseAndRecv()
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Section", "Created", "Skipped", "Overwritten", "Renamed"})
+			for _, section := range res.GetSections() {
+				table.Append([]string{
+					section.GetSection(),
+					strconv.Itoa(int(section.GetCreated())),
+					strconv.Itoa(int(section.GetSkipped())),
+					strconv.Itoa(int(section.GetOverwritten())),
+					strconv.Itoa(int(section.GetRenamed())),
+				})
+			}
+			table.Render()
+
+			for _, warning := range res.GetWarnings() {
+				cmd.Println("warning:", warning)
+			}
+		},
+	}
+
+	command.Flags().StringVarP(&spaceID, "space", "s", "", "space id to import into")
+	command.Flags().StringVarP(&conflict, "conflict", "c", "skip", "conflict policy: skip, overwrite or rename")
+
+	return command
+}
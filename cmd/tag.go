@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/emrgen/unpost"
 	v1 "github.com/emrgen/unpost/apis/v1"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
-	"os"
 )
 
 var tagCmd = &cobra.Command{
@@ -16,11 +21,15 @@ var tagCmd = &cobra.Command{
 func init() {
 	tagCmd.AddCommand(tagCreate())
 	tagCmd.AddCommand(tagList())
+	tagCmd.AddCommand(tagMerge())
+	tagCmd.AddCommand(tagTree())
 }
 
 func tagCreate() *cobra.Command {
 	var tagName string
 	var spaceID string
+	var parentID string
+	var color string
 
 	command := &cobra.Command{
 		Use:   "create",
@@ -43,8 +52,10 @@ func tagCreate() *cobra.Command {
 			}
 
 			res, err := client.CreateTag(tokenContext(), &v1.CreateTagRequest{
-				SpaceId: spaceID,
-				Name:    tagName,
+				SpaceId:  spaceID,
+				Name:     tagName,
+				ParentId: parentID,
+				Color:    color,
 			})
 			if err != nil {
 				cmd.Println(err)
@@ -60,12 +71,15 @@ func tagCreate() *cobra.Command {
 
 	command.Flags().StringVarP(&spaceID, "space", "s", "", "space id")
 	command.Flags().StringVarP(&tagName, "name", "n", "", "tag name")
+	command.Flags().StringVarP(&parentID, "parent", "p", "", "parent tag id")
+	command.Flags().StringVar(&color, "color", "", "tag color, like #1e90ff")
 
 	return command
 }
 
 func tagList() *cobra.Command {
 	var spaceID string
+	var prefix string
 
 	command := &cobra.Command{
 		Use:   "list",
@@ -84,6 +98,7 @@ func tagList() *cobra.Command {
 
 			res, err := client.ListTag(tokenContext(), &v1.ListTagRequest{
 				SpaceId: spaceID,
+				Prefix:  prefix,
 			})
 			if err != nil {
 				cmd.Println(err)
@@ -91,16 +106,124 @@ func tagList() *cobra.Command {
 			}
 
 			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"ID", "Name"})
+			table.SetHeader([]string{"ID", "Name", "Parent", "Posts"})
 
 			for _, tag := range res.Tags {
-				table.Append([]string{tag.Id, tag.Name})
+				table.Append([]string{tag.Id, tag.Name, tag.ParentId, strconv.FormatInt(tag.PostCount, 10)})
 			}
 
 			table.Render()
 		},
 	}
 
+	command.Flags().StringVarP(&spaceID, "space", "s", "", "space id")
+	command.Flags().StringVar(&prefix, "prefix", "", "list the tags starting with the prefix")
+
+	return command
+}
+
+func tagMerge() *cobra.Command {
+	var targetID string
+
+	command := &cobra.Command{
+		Use:   "merge <source-tag-id>...",
+		Short: "Merge tags into a target tag",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if targetID == "" {
+				cmd.Println("target tag id is required")
+				return
+			}
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+			defer client.Close()
+
+			res, err := client.MergeTags(tokenContext(), &v1.MergeTagsRequest{
+				TargetId:  targetID,
+				SourceIds: args,
+			})
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+
+			cmd.Printf("merged %d tags into %s (%s)\n", len(args), res.Tag.Name, res.Tag.Id)
+		},
+	}
+
+	command.Flags().StringVarP(&targetID, "into", "i", "", "target tag id")
+
+	return command
+}
+
+func tagTree() *cobra.Command {
+	var spaceID string
+
+	command := &cobra.Command{
+		Use:   "tree",
+		Short: "Print the tag hierarchy of a space",
+		Run: func(cmd *cobra.Command, args []string) {
+			if spaceID == "" {
+				cmd.Println("space id is required")
+				return
+			}
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				cmd.Println(err)
+				return
+			}
+			defer client.Close()
+
+			const perPage = 100
+			var tags []*v1.Tag
+			for page := int32(0); ; page++ {
+				res, err := client.ListTag(tokenContext(), &v1.ListTagRequest{
+					SpaceId: spaceID,
+					Page:    page,
+					PerPage: perPage,
+				})
+				if err != nil {
+					cmd.Println(err)
+					return
+				}
+				tags = append(tags, res.Tags...)
+				if len(res.Tags) < perPage {
+					break
+				}
+			}
+
+			known := make(map[string]bool, len(tags))
+			for _, tag := range tags {
+				known[tag.Id] = true
+			}
+			children := make(map[string][]*v1.Tag)
+			for _, tag := range tags {
+				parentID := tag.ParentId
+				if !known[parentID] {
+					parentID = ""
+				}
+				children[parentID] = append(children[parentID], tag)
+			}
+			for _, siblings := range children {
+				sort.Slice(siblings, func(i, j int) bool { return siblings[i].Name < siblings[j].Name })
+			}
+
+			var print func(parentID string, depth int)
+			print = func(parentID string, depth int) {
+				for _, tag := range children[parentID] {
+					fmt.Printf("%s%s (%d)\n", strings.Repeat("  ", depth), tag.Name, tag.PostCount)
+					print(tag.Id, depth+1)
+				}
+			}
+			print("", 0)
+		},
+	}
+
 	command.Flags().StringVarP(&spaceID, "space", "s", "", "space id")
 
 	return command
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/emrgen/unpost"
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/olekukonko/tablewriter"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "api token commands",
+}
+
+func init() {
+	tokenCmd.AddCommand(createApiToken())
+	tokenCmd.AddCommand(listApiTokens())
+	tokenCmd.AddCommand(revokeApiToken())
+}
+
+func createApiToken() *cobra.Command {
+	var spaceID string
+	var name string
+	var scopes []string
+	var ttl time.Duration
+
+	command := &cobra.Command{
+		Use:   "create",
+		Short: "Create a scoped api token of a space",
+		Run: func(cmd *cobra.Command, args []string) {
+			if spaceID == "" {
+				logrus.Errorf("missing required flag: --space-id")
+				return
+			}
+			if name == "" {
+				logrus.Errorf("missing required flag: --name")
+				return
+			}
+			if len(scopes) == 0 {
+				logrus.Errorf("missing required flag: --scope")
+				return
+			}
+
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			res, err := client.CreateApiToken(tokenContext(), &v1.CreateApiTokenRequest{
+				SpaceId:    spaceID,
+				Name:       name,
+				Scopes:     scopes,
+				TtlSeconds: int64(ttl.Seconds()),
+			})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			cmd.Println("Api token created:", res.Token.Id)
+			cmd.Println("Scopes:", strings.Join(res.Token.Scopes, ", "))
+			cmd.Println("Expires at:", formatTokenTime(res.Token.ExpiresAt))
+			cmd.Println("Secret, it is not shown again:", res.Secret)
+		},
+	}
+
+	command.Flags().StringVarP(&spaceID, "space-id", "s", "", "space id")
+	command.Flags().StringVarP(&name, "name", "n", "", "token name")
+	command.Flags().StringSliceVar(&scopes, "scope", nil, "scope of the token, like read:posts, write:posts or admin:tiers")
+	command.Flags().DurationVar(&ttl, "ttl", 0, "lifetime of the token, the token never expires by default")
+
+	return command
+}
+
+func listApiTokens() *cobra.Command {
+	var spaceID string
+
+	command := &cobra.Command{
+		Use:   "list",
+		Short: "List the api tokens",
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			request := &v1.ListApiTokensRequest{}
+			if spaceID != "" {
+				request.SpaceId = &spaceID
+			}
+			res, err := client.ListApiTokens(tokenContext(), request)
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"ID", "Name", "Space ID", "Scopes", "Expires At", "Last Used At", "Revoked At"})
+			for _, token := range res.Tokens {
+				table.Append([]string{
+					token.Id,
+					token.Name,
+					token.SpaceId,
+					strings.Join(token.Scopes, ","),
+					formatTokenTime(token.ExpiresAt),
+					formatTokenTime(token.LastUsedAt),
+					formatTokenTime(token.RevokedAt),
+				})
+			}
+			table.Render()
+		},
+	}
+
+	command.Flags().StringVarP(&spaceID, "space-id", "s", "", "list the tokens of the space")
+
+	return command
+}
+
+func revokeApiToken() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "revoke <token-id>",
+		Short: "Revoke an api token",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := unpost.NewClient("8030")
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+			defer client.Close()
+
+			_, err = client.RevokeApiToken(tokenContext(), &v1.RevokeApiTokenRequest{Id: args[0]})
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			cmd.Println("Api token revoked:", args[0])
+		},
+	}
+
+	return command
+}
+
+func formatTokenTime(t *timestamppb.Timestamp) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.AsTime().Format("2006-01-02 15:04:05")
+}
@@ -16,7 +16,12 @@ func main() {
 		httpPort = "4021"
 	}
 
-	err := server.Start(grpcPort, httpPort)
+	adminPort := os.Getenv("ADMIN_PORT")
+	if adminPort == "" {
+		adminPort = "4022"
+	}
+
+	err := server.Start(grpcPort, httpPort, adminPort)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,133 @@
+package apitoken
+
+import (
+	"slices"
+	"strings"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/model"
+)
+
+// The scopes of the api tokens, the admin scopes are only granted by the admins
+const (
+	ScopeReadPosts   = "read:posts"
+	ScopeWritePosts  = "write:posts"
+	ScopeReadTags    = "read:tags"
+	ScopeWriteTags   = "write:tags"
+	ScopeReadTiers   = "read:tiers"
+	ScopeAdminTiers  = "admin:tiers"
+	ScopeAdminSpaces = "admin:spaces"
+)
+
+// Scopes are the known scopes
+var Scopes = []string{
+	ScopeReadPosts,
+	ScopeWritePosts,
+	ScopeReadTags,
+	ScopeWriteTags,
+	ScopeReadTiers,
+	ScopeAdminTiers,
+	ScopeAdminSpaces,
+}
+
+// methodScopes maps the methods open to the api tokens to the scope they require.
+// The other methods, the accounts, the tokens and the webhooks of the platform among them, need a login.
+var methodScopes = map[string]string{
+	v1.PostService_GetPost_FullMethodName:          ScopeReadPosts,
+	v1.PostService_GetPostBySlag_FullMethodName:    ScopeReadPosts,
+	v1.PostService_ListPost_FullMethodName:         ScopeReadPosts,
+	v1.PostService_ExportPost_FullMethodName:       ScopeReadPosts,
+	v1.PostService_RenderPost_FullMethodName:       ScopeReadPosts,
+	v1.PostService_ListRelatedPosts_FullMethodName: ScopeReadPosts,
+	v1.PostService_BatchGetPosts_FullMethodName:    ScopeReadPosts,
+	v1.FeedService_WatchContent_FullMethodName:     ScopeReadPosts,
+
+	v1.PostService_CreatePost_FullMethodName:          ScopeWritePosts,
+	v1.PostService_UpdatePost_FullMethodName:          ScopeWritePosts,
+	v1.PostService_DeletePost_FullMethodName:          ScopeWritePosts,
+	v1.PostService_AddPostTag_FullMethodName:          ScopeWritePosts,
+	v1.PostService_RemovePostTag_FullMethodName:       ScopeWritePosts,
+	v1.PostService_UpdatePostStatus_FullMethodName:    ScopeWritePosts,
+	v1.PostService_ImportPost_FullMethodName:          ScopeWritePosts,
+	v1.PostService_BatchUpdatePosts_FullMethodName:    ScopeWritePosts,
+	v1.PostService_BatchDeletePosts_FullMethodName:    ScopeWritePosts,
+	v1.PostService_CreatePreviewLink_FullMethodName:   ScopeWritePosts,
+	v1.PostService_ListPreviewLinks_FullMethodName:    ScopeWritePosts,
+	v1.PostService_RevokePreviewLink_FullMethodName:   ScopeWritePosts,
+	v1.PostService_ListPreviewAccesses_FullMethodName: ScopeWritePosts,
+
+	v1.TagService_GetTag_FullMethodName:                      ScopeReadTags,
+	v1.TagService_ListTag_FullMethodName:                     ScopeReadTags,
+	v1.PlatformTagService_GetPlatformTag_FullMethodName:      ScopeReadTags,
+	v1.PlatformTagService_ListPlatformTags_FullMethodName:    ScopeReadTags,
+	v1.PlatformTagService_SuggestPlatformTags_FullMethodName: ScopeReadTags,
+
+	v1.TagService_CreateTag_FullMethodName:                  ScopeWriteTags,
+	v1.TagService_UpdateTag_FullMethodName:                  ScopeWriteTags,
+	v1.TagService_DeleteTag_FullMethodName:                  ScopeWriteTags,
+	v1.TagService_MergeTags_FullMethodName:                  ScopeWriteTags,
+	v1.PlatformTagService_MapTag_FullMethodName:             ScopeWriteTags,
+	v1.PlatformTagService_ProposePlatformTag_FullMethodName: ScopeWriteTags,
+
+	v1.TierService_GetTier_FullMethodName:                ScopeReadTiers,
+	v1.TierService_ListTiers_FullMethodName:              ScopeReadTiers,
+	v1.TierMemberService_GetTierMember_FullMethodName:    ScopeReadTiers,
+	v1.TierMemberService_ListTierMember_FullMethodName:   ScopeReadTiers,
+	v1.TierService_CreateTier_FullMethodName:             ScopeAdminTiers,
+	v1.TierService_UpdateTier_FullMethodName:             ScopeAdminTiers,
+	v1.TierService_DeleteTier_FullMethodName:             ScopeAdminTiers,
+	v1.TierMemberService_CreateTierMember_FullMethodName: ScopeAdminTiers,
+	v1.TierMemberService_UpdateTierMember_FullMethodName: ScopeAdminTiers,
+	v1.TierMemberService_DeleteTierMember_FullMethodName: ScopeAdminTiers,
+
+	v1.SpaceService_ExportSpace_FullMethodName: ScopeAdminSpaces,
+	v1.SpaceService_ImportSpace_FullMethodName: ScopeAdminSpaces,
+}
+
+// MethodScope returns the scope required by the method, false when the method is closed to the api tokens
+func MethodScope(method string) (string, bool) {
+	scope, ok := methodScopes[method]
+	return scope, ok
+}
+
+// ValidScope returns true if the scope is known
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// roleRanks orders the roles from the viewer up
+var roleRanks = map[model.UserRole]int{
+	model.UserRoleViewer: 0,
+	model.UserRoleAuthor: 1,
+	model.UserRoleAdmin:  2,
+	model.UserRoleOwner:  3,
+}
+
+// ScopeRole returns the highest role the scopes allow, the admin scopes allow the admin role and the write scopes the author role
+func ScopeRole(scopes []string) model.UserRole {
+	role := model.UserRoleViewer
+	for _, scope := range scopes {
+		switch {
+		case AdminScope(scope):
+			return model.UserRoleAdmin
+		case strings.HasPrefix(scope, "write:"):
+			role = model.UserRoleAuthor
+		}
+	}
+
+	return role
+}
+
+// CapRole returns the lower of the role and the limit
+func CapRole(role, limit model.UserRole) model.UserRole {
+	if roleRanks[role] > roleRanks[limit] {
+		return limit
+	}
+
+	return role
+}
+
+// AdminScope returns true if the scope is only granted by the admins
+func AdminScope(scope string) bool {
+	return strings.HasPrefix(scope, "admin:")
+}
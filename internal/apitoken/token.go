@@ -0,0 +1,132 @@
+// Package apitoken issues and verifies the scoped api tokens of the spaces.
+// A token is unstak_<id>_<secret>, the id finds the token and only the hash of the secret is stored.
+package apitoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/x"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Prefix tells the api tokens apart from the jwt tokens
+	Prefix = "unstak_"
+	// lastUsedResolution bounds the writes of the last use of a busy token
+	lastUsedResolution = time.Minute
+)
+
+// ErrInvalidToken is returned for a malformed, unknown, revoked or expired token
+var ErrInvalidToken = errors.New("invalid api token")
+
+// IsApiToken returns true if the bearer token is an api token
+func IsApiToken(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
+
+// Generate returns a new token for the id and the hash of its secret
+func Generate(id uuid.UUID) (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+
+	return Prefix + hex.EncodeToString(id[:]) + "_" + encoded, x.HashSecret(encoded), nil
+}
+
+// Parse returns the id and the secret of a token
+func Parse(token string) (uuid.UUID, string, error) {
+	rest, ok := strings.CutPrefix(token, Prefix)
+	if !ok {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+	rawID, secret, ok := strings.Cut(rest, "_")
+	if !ok || secret == "" {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+
+	return id, secret, nil
+}
+
+// Store keeps the tokens and the accounts of their creators
+type Store interface {
+	store.ApiTokenStore
+	GetUser(ctx context.Context, id uuid.UUID) (*model.User, error)
+}
+
+// Authenticator resolves the api tokens presented by the callers
+type Authenticator struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewAuthenticator creates a new Authenticator
+func NewAuthenticator(store Store) *Authenticator {
+	return &Authenticator{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Authenticate returns the active token matching the bearer token and the role it acts with, and records its use.
+// The token acts with the current role of its creator capped by its scopes, a deactivated creator disables it.
+func (a *Authenticator) Authenticate(ctx context.Context, bearer string) (*model.ApiToken, model.UserRole, error) {
+	id, secret, err := Parse(bearer)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := a.store.GetApiToken(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, "", ErrInvalidToken
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := a.now()
+	if subtle.ConstantTimeCompare([]byte(x.HashSecret(secret)), []byte(token.SecretHash)) != 1 || !token.Active(now) {
+		return nil, "", ErrInvalidToken
+	}
+
+	creatorID, err := uuid.Parse(token.CreatedByID)
+	if err != nil {
+		return nil, "", ErrInvalidToken
+	}
+	creator, err := a.store.GetUser(ctx, creatorID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, "", ErrInvalidToken
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if !creator.Active() {
+		return nil, "", ErrInvalidToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		// a failed write only delays the last use, the call goes on
+		if err := a.store.TouchApiToken(ctx, id, now); err != nil {
+			logrus.Errorf("error recording the use of api token %s: %v", id, err)
+		} else {
+			token.LastUsedAt = &now
+		}
+	}
+
+	return token, CapRole(creator.Role, ScopeRole(token.ScopeList())), nil
+}
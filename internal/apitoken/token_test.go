@@ -0,0 +1,168 @@
+package apitoken
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store/storetest"
+	"github.com/emrgen/unpost/internal/x"
+	"github.com/google/uuid"
+)
+
+func TestGenerateParse(t *testing.T) {
+	id := uuid.New()
+	token, hash, err := Generate(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsApiToken(token) {
+		t.Fatalf("expected %q to be an api token", token)
+	}
+
+	parsedID, secret, err := Parse(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsedID != id || x.HashSecret(secret) != hash {
+		t.Errorf("the parsed token does not match the generated one")
+	}
+
+	for _, malformed := range []string{"", "unstak_", "unstak_nothex_secret", "unstak_" + id.String(), "bearer"} {
+		if _, _, err := Parse(malformed); err != ErrInvalidToken {
+			t.Errorf("%q: expected an invalid token, got %v", malformed, err)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+	authenticator := NewAuthenticator(s)
+	newCreator := func(role model.UserRole) *model.User {
+		user := &model.User{ID: uuid.NewString(), Username: "jane", Email: uuid.NewString() + "@example.com", Role: role}
+		if err := s.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	creator := newCreator(model.UserRoleAuthor)
+
+	newToken := func(mutate func(token *model.ApiToken)) string {
+		id := uuid.New()
+		secret, hash, err := Generate(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token := &model.ApiToken{
+			ID:          id.String(),
+			SpaceID:     uuid.NewString(),
+			Name:        "ci",
+			SecretHash:  hash,
+			Scopes:      ScopeReadPosts,
+			CreatedByID: creator.ID,
+		}
+		mutate(token)
+		if err := s.CreateApiToken(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+		return secret
+	}
+
+	active := newToken(func(*model.ApiToken) {})
+	token, role, err := authenticator.Authenticate(ctx, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !token.HasScope(ScopeReadPosts) || token.HasScope(ScopeWritePosts) {
+		t.Errorf("unexpected scopes %q", token.Scopes)
+	}
+	// the read scopes cap the author to a viewer
+	if role != model.UserRoleViewer {
+		t.Errorf("expected the token to act as a viewer, got %s", role)
+	}
+	stored, err := s.GetApiToken(ctx, uuid.MustParse(token.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("expected the use of the token to be recorded")
+	}
+
+	past := time.Now().Add(-time.Minute)
+	id, _, _ := Parse(active)
+	for name, bearer := range map[string]string{
+		"revoked":         newToken(func(token *model.ApiToken) { token.RevokedAt = &past }),
+		"unknown creator": newToken(func(token *model.ApiToken) { token.CreatedByID = uuid.NewString() }),
+		"deactivated creator": newToken(func(token *model.ApiToken) {
+			deactivated := newCreator(model.UserRoleAdmin)
+			deactivated.DeactivatedAt = &past
+			if err := s.UpdateUser(ctx, deactivated); err != nil {
+				t.Fatal(err)
+			}
+			token.CreatedByID = deactivated.ID
+		}),
+		"expired":      newToken(func(token *model.ApiToken) { token.ExpiresAt = &past }),
+		"wrong secret": Prefix + id.String() + "_guess",
+		"unknown":      Prefix + uuid.NewString() + "_secret",
+	} {
+		if _, _, err := authenticator.Authenticate(ctx, bearer); err != ErrInvalidToken {
+			t.Errorf("%s: expected an invalid token, got %v", name, err)
+		}
+	}
+}
+
+func TestTokenRole(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+	authenticator := NewAuthenticator(s)
+	admin := &model.User{ID: uuid.NewString(), Username: "jane", Email: "jane@example.com", Role: model.UserRoleAdmin}
+	if err := s.CreateUser(ctx, admin); err != nil {
+		t.Fatal(err)
+	}
+
+	id := uuid.New()
+	secret, hash, err := Generate(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := &model.ApiToken{ID: id.String(), SpaceID: uuid.NewString(), Name: "ci", SecretHash: hash, Scopes: ScopeWritePosts + "," + ScopeAdminTiers, CreatedByID: admin.ID}
+	if err := s.CreateApiToken(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		role   model.UserRole
+		scopes string
+		want   model.UserRole
+	}{
+		{"admin with an admin scope", model.UserRoleAdmin, ScopeWritePosts + "," + ScopeAdminTiers, model.UserRoleAdmin},
+		{"owner with an admin scope", model.UserRoleOwner, ScopeAdminSpaces, model.UserRoleAdmin},
+		{"admin with a write scope", model.UserRoleAdmin, ScopeWritePosts, model.UserRoleAuthor},
+		// the token follows the demotion of its creator
+		{"demoted admin", model.UserRoleViewer, ScopeWritePosts + "," + ScopeAdminTiers, model.UserRoleViewer},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			admin.Role = test.role
+			if err := s.UpdateUser(ctx, admin); err != nil {
+				t.Fatal(err)
+			}
+			token.Scopes = test.scopes
+			if err := s.UpdateApiToken(ctx, token); err != nil {
+				t.Fatal(err)
+			}
+
+			_, role, err := authenticator.Authenticate(ctx, secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if role != test.want {
+				t.Errorf("expected %s, got %s", test.want, role)
+			}
+		})
+	}
+}
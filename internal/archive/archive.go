@@ -0,0 +1,144 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+)
+
+// FormatVersion is the version of the archive layout, the importer rejects the archives of a newer version
+const FormatVersion = 1
+
+const (
+	manifestName = "manifest.json"
+	// defaultBatchSize is the number of the records of a json lines entry
+	defaultBatchSize = 500
+)
+
+// The sections of an archive in the order they are written. The records of a section only reference
+// the records of the earlier sections, so the importer remaps the ids in a single pass.
+const (
+	SectionTags        = "tags"
+	SectionTiers       = "tiers"
+	SectionTierMembers = "tier_members"
+	SectionCourses     = "courses"
+	SectionPages       = "pages"
+	SectionPosts       = "posts"
+	SectionFiles       = "files"
+)
+
+// Sections lists the sections in archive order
+var Sections = []string{SectionTags, SectionTiers, SectionTierMembers, SectionCourses, SectionPages, SectionPosts, SectionFiles}
+
+// fileRefPattern matches the links of the files service in the content
+var fileRefPattern = regexp.MustCompile(`/v1/files/([0-9a-fA-F-]{36})`)
+
+// FileStore reads and writes the files referenced by the content, e.g. the images of a post.
+// Without a file store the archives carry no files and the importer reports the skipped ones.
+type FileStore interface {
+	// Open returns the content and the size of a file.
+	Open(ctx context.Context, id string) (io.ReadCloser, int64, error)
+	// Exists checks whether the file is stored.
+	Exists(ctx context.Context, id string) (bool, error)
+	// Put stores a file.
+	Put(ctx context.Context, id string, r io.Reader, size int64) error
+}
+
+// Manifest is the first entry of an archive
+type Manifest struct {
+	Version    int       `json:"version"`
+	SpaceID    string    `json:"space_id"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// Tag is the archived form of a tag
+type Tag struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Tier is the archived form of a tier
+type Tier struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	CreatedByID    string    `json:"created_by_id"`
+	Free           bool      `json:"free"`
+	MonthlyCost    float64   `json:"monthly_cost"`
+	YearlyCost     float64   `json:"yearly_cost"`
+	HalfYearlyCost float64   `json:"half_yearly_cost"`
+	QuarterlyCost  float64   `json:"quarterly_cost"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TierMember is the archived form of a tier member, the user ids are kept as they are
+type TierMember struct {
+	ID          string    `json:"id"`
+	TierID      string    `json:"tier_id"`
+	UserID      string    `json:"user_id"`
+	CreatedByID string    `json:"created_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Course is the archived form of a course
+type Course struct {
+	ID          string           `json:"id"`
+	DocumentID  string           `json:"document_id"`
+	CreatedByID string           `json:"created_by_id"`
+	Status      model.PostStatus `json:"status"`
+	Reaction    string           `json:"reaction"`
+	TagIDs      []string         `json:"tag_ids,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// Page is the archived form of a page
+type Page struct {
+	ID          string           `json:"id"`
+	CourseID    string           `json:"course_id"`
+	Content     string           `json:"content"`
+	CreatedByID string           `json:"created_by_id"`
+	Status      model.PostStatus `json:"status"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// Post is the archived form of a post, the author ids are kept as they are and the tier is remapped
+type Post struct {
+	ID          string           `json:"id"`
+	AuthorID    string           `json:"author_id,omitempty"`
+	TierID      string           `json:"tier_id,omitempty"`
+	Slug        string           `json:"slug"`
+	SlugID      string           `json:"slug_id"`
+	Title       string           `json:"title"`
+	Summary     string           `json:"summary"`
+	Excerpt     string           `json:"excerpt"`
+	Content     string           `json:"content"`
+	Status      model.PostStatus `json:"status"`
+	Version     int64            `json:"version"`
+	TagIDs      []string         `json:"tag_ids,omitempty"`
+	PublishedAt *time.Time       `json:"published_at,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+func tagIDs(tags []*model.Tag) []string {
+	ids := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		ids = append(ids, tag.ID)
+	}
+
+	return ids
+}
+
+// fileRefs returns the ids of the files linked from the content
+func fileRefs(content string) []string {
+	var ids []string
+	for _, match := range fileRefPattern.FindAllStringSubmatch(content, -1) {
+		ids = append(ids, match[1])
+	}
+
+	return ids
+}
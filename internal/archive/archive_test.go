@@ -0,0 +1,198 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/store/storetest"
+	"github.com/google/uuid"
+)
+
+type memoryFiles map[string][]byte
+
+func (m memoryFiles) Open(ctx context.Context, id string) (io.ReadCloser, int64, error) {
+	data, ok := m[id]
+	if !ok {
+		return nil, 0, store.ErrNotFound
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+func (m memoryFiles) Exists(ctx context.Context, id string) (bool, error) {
+	_, ok := m[id]
+	return ok, nil
+}
+
+func (m memoryFiles) Put(ctx context.Context, id string, r io.Reader, size int64) error {
+	data, err := io.ReadAll(r)
+	m[id] = data
+	return err
+}
+
+// seedSpace creates a space with a tag, a tier with a member, a course with a page and a post
+func seedSpace(t *testing.T, s *store.GormStore, spaceID uuid.UUID, fileID string) {
+	ctx := context.Background()
+	tag := &model.Tag{ID: uuid.NewString(), SpaceID: spaceID.String(), Name: "go"}
+	tier := &model.Tier{ID: uuid.NewString(), SpaceID: spaceID.String(), Name: "pro", CreatedByID: uuid.NewString(), MonthlyCost: 5}
+	member := &model.TierMember{ID: uuid.NewString(), TierID: tier.ID, UserID: uuid.NewString(), CreatedByID: tier.CreatedByID}
+	course := &model.Course{ID: uuid.NewString(), SpaceID: spaceID.String(), DocumentID: uuid.NewString(), CreatedByID: tier.CreatedByID, Status: model.PostStatusDraft}
+	page := &model.Page{ID: uuid.NewString(), SpaceID: spaceID.String(), CourseID: course.ID, Content: "page", CreatedByID: tier.CreatedByID}
+	post := &model.Post{
+		ID:       uuid.NewString(),
+		SpaceID:  spaceID.String(),
+		Slug:     "hello",
+		SlugID:   "hello-slug",
+		Title:    "Hello",
+		Content:  "![cover](/v1/files/" + fileID + ")",
+		Status:   model.PostStatusPublished,
+		Version:  3,
+		AuthorID: tier.CreatedByID,
+		TierID:   &tier.ID,
+	}
+
+	for _, create := range []func() error{
+		func() error { return s.CreateTag(ctx, tag) },
+		func() error { return s.CreateTier(ctx, tier) },
+		func() error { return s.AddTierMember(ctx, member) },
+		func() error { return s.CreateCourse(ctx, course) },
+		func() error { return s.UpdateCourseTags(ctx, uuid.MustParse(course.ID), []*model.Tag{tag}) },
+		func() error { return s.CreatePage(ctx, page) },
+		func() error { return s.CreatePost(ctx, post) },
+		func() error { return s.UpdatePostTags(ctx, uuid.MustParse(post.ID), []*model.Tag{tag}) },
+	} {
+		if err := create(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func export(t *testing.T, s *store.GormStore, files FileStore, spaceID uuid.UUID) []byte {
+	var buf bytes.Buffer
+	result, err := NewExporter(s, files).Export(context.Background(), spaceID, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, section := range []string{SectionTags, SectionTiers, SectionTierMembers, SectionCourses, SectionPages, SectionPosts} {
+		if result.Counts[section] != 1 {
+			t.Errorf("exported %d %s, want 1", result.Counts[section], section)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	source := storetest.NewStore(t)
+	sourceSpace := uuid.New()
+	fileID := uuid.NewString()
+	sourceFiles := memoryFiles{fileID: []byte("image")}
+	seedSpace(t, source, sourceSpace, fileID)
+
+	data := export(t, source, sourceFiles, sourceSpace)
+
+	target := storetest.NewStore(t)
+	targetSpace := uuid.New()
+	targetFiles := memoryFiles{}
+	result, err := NewImporter(target, targetFiles, ImportOptions{SpaceID: targetSpace}).Import(ctx, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, section := range Sections {
+		if result.Sections[section] == nil || result.Sections[section].Created != 1 {
+			t.Errorf("%s: %+v, want 1 created", section, result.Sections[section])
+		}
+	}
+	if string(targetFiles[fileID]) != "image" {
+		t.Error("referenced file is not imported")
+	}
+
+	var posts []*model.Post
+	err = target.ScanSpacePosts(ctx, targetSpace, 10, func(batch []*model.Post) error {
+		posts = append(posts, batch...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("imported %d posts, want 1", len(posts))
+	}
+	if posts[0].Title != "Hello" || posts[0].SlugID != "hello-slug" || len(posts[0].Tags) != 1 {
+		t.Errorf("unexpected post %+v", posts[0])
+	}
+
+	tag, err := target.GetTagByName(ctx, targetSpace, "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts[0].Tags[0].ID != tag.ID {
+		t.Errorf("post tag %s is not remapped to %s", posts[0].Tags[0].ID, tag.ID)
+	}
+
+	tier, err := target.GetTierByName(ctx, "pro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts[0].TierID == nil || *posts[0].TierID != tier.ID {
+		t.Errorf("post tier %v is not remapped to %s", posts[0].TierID, tier.ID)
+	}
+	if posts[0].AuthorID == "" {
+		t.Error("post author is not imported")
+	}
+}
+
+func TestImportConflicts(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+	spaceID := uuid.New()
+	seedSpace(t, s, spaceID, uuid.NewString())
+	data := export(t, s, nil, spaceID)
+
+	targetSpace := uuid.New()
+	if _, err := NewImporter(s, nil, ImportOptions{SpaceID: targetSpace}).Import(ctx, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		policy ConflictPolicy
+		check  func(result *SectionResult) bool
+	}{
+		{ConflictSkip, func(result *SectionResult) bool { return result.Skipped == 1 }},
+		{ConflictOverwrite, func(result *SectionResult) bool { return result.Overwritten == 1 }},
+		{ConflictRename, func(result *SectionResult) bool { return result.Renamed == 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			result, err := NewImporter(s, nil, ImportOptions{SpaceID: targetSpace, Conflict: tt.policy}).Import(ctx, bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, section := range []string{SectionTags, SectionTiers, SectionTierMembers, SectionCourses, SectionPages, SectionPosts} {
+				if !tt.check(result.Sections[section]) {
+					t.Errorf("%s: unexpected result %+v", section, result.Sections[section])
+				}
+			}
+		})
+	}
+
+	if _, err := s.GetTagByName(ctx, targetSpace, "go (2)"); err != nil {
+		t.Errorf("renamed tag is missing: %v", err)
+	}
+	if _, err := s.GetTierByName(ctx, "pro (2)"); err != nil {
+		t.Errorf("renamed tier is missing: %v", err)
+	}
+}
+
+func TestImportRejectsInvalidArchive(t *testing.T) {
+	_, err := NewImporter(storetest.NewStore(t), nil, ImportOptions{SpaceID: uuid.New()}).Import(context.Background(), bytes.NewReader([]byte("not an archive")))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,286 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportResult reports the number of the exported records by section
+type ExportResult struct {
+	Counts map[string]int
+	// SkippedFiles are the referenced files left out of the archive, either missing or without a file store
+	SkippedFiles []string
+}
+
+// Exporter writes the content of a space into an archive.
+// The records are read and written in batches, so the size of a space does not bound the memory.
+type Exporter struct {
+	store     store.SpaceStore
+	files     FileStore
+	batchSize int
+}
+
+// NewExporter creates a new Exporter, a nil file store leaves the referenced files out of the archive
+func NewExporter(store store.SpaceStore, files FileStore) *Exporter {
+	return &Exporter{
+		store:     store,
+		files:     files,
+		batchSize: defaultBatchSize,
+	}
+}
+
+// Export writes the tar.gz archive of the space to w
+func (e *Exporter) Export(ctx context.Context, spaceID uuid.UUID, w io.Writer) (*ExportResult, error) {
+	gz := gzip.NewWriter(w)
+	aw := &writer{
+		tw:      tar.NewWriter(gz),
+		entries: make(map[string]int),
+		result:  &ExportResult{Counts: make(map[string]int)},
+	}
+
+	manifest := Manifest{Version: FormatVersion, SpaceID: spaceID.String(), ExportedAt: time.Now().UTC()}
+	if err := aw.writeJSON(manifestName, manifest); err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]struct{})
+	addRefs := func(content string) {
+		for _, id := range fileRefs(content) {
+			refs[id] = struct{}{}
+		}
+	}
+
+	err := e.store.ScanSpaceTags(ctx, spaceID, e.batchSize, func(tags []*model.Tag) error {
+		return writeBatch(aw, SectionTags, tags, func(tag *model.Tag) Tag {
+			return Tag{ID: tag.ID, Name: tag.Name}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.store.ScanSpaceTiers(ctx, spaceID, e.batchSize, func(tiers []*model.Tier) error {
+		return writeBatch(aw, SectionTiers, tiers, func(tier *model.Tier) Tier {
+			return Tier{
+				ID:             tier.ID,
+				Name:           tier.Name,
+				CreatedByID:    tier.CreatedByID,
+				Free:           tier.Free,
+				MonthlyCost:    tier.MonthlyCost,
+				YearlyCost:     tier.YearlyCost,
+				HalfYearlyCost: tier.HalfYearlyCost,
+				QuarterlyCost:  tier.QuarterlyCost,
+				CreatedAt:      tier.CreatedAt,
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.store.ScanSpaceTierMembers(ctx, spaceID, e.batchSize, func(members []*model.TierMember) error {
+		return writeBatch(aw, SectionTierMembers, members, func(member *model.TierMember) TierMember {
+			return TierMember{
+				ID:          member.ID,
+				TierID:      member.TierID,
+				UserID:      member.UserID,
+				CreatedByID: member.CreatedByID,
+				CreatedAt:   member.CreatedAt,
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.store.ScanSpaceCourses(ctx, spaceID, e.batchSize, func(courses []*model.Course) error {
+		return writeBatch(aw, SectionCourses, courses, func(course *model.Course) Course {
+			return Course{
+				ID:          course.ID,
+				DocumentID:  course.DocumentID,
+				CreatedByID: course.CreatedByID,
+				Status:      course.Status,
+				Reaction:    course.Reaction,
+				TagIDs:      tagIDs(course.Tags),
+				CreatedAt:   course.CreatedAt,
+				UpdatedAt:   course.UpdatedAt,
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.store.ScanSpacePages(ctx, spaceID, e.batchSize, func(pages []*model.Page) error {
+		return writeBatch(aw, SectionPages, pages, func(page *model.Page) Page {
+			addRefs(page.Content)
+			return Page{
+				ID:          page.ID,
+				CourseID:    page.CourseID,
+				Content:     page.Content,
+				CreatedByID: page.CreatedByID,
+				Status:      page.Status,
+				CreatedAt:   page.CreatedAt,
+				UpdatedAt:   page.UpdatedAt,
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.store.ScanSpacePosts(ctx, spaceID, e.batchSize, func(posts []*model.Post) error {
+		return writeBatch(aw, SectionPosts, posts, func(post *model.Post) Post {
+			addRefs(post.Content)
+			var tierID string
+			if post.TierID != nil {
+				tierID = *post.TierID
+			}
+			return Post{
+				ID:          post.ID,
+				AuthorID:    post.AuthorID,
+				TierID:      tierID,
+				Slug:        post.Slug,
+				SlugID:      post.SlugID,
+				Title:       post.Title,
+				Summary:     post.Summary,
+				Excerpt:     post.Excerpt,
+				Content:     post.Content,
+				Status:      post.Status,
+				Version:     post.Version,
+				TagIDs:      tagIDs(post.Tags),
+				PublishedAt: post.PublishedAt,
+				CreatedAt:   post.CreatedAt,
+				UpdatedAt:   post.UpdatedAt,
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.writeFiles(ctx, aw, refs); err != nil {
+		return nil, err
+	}
+
+	if err := aw.tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return aw.result, nil
+}
+
+func (e *Exporter) writeFiles(ctx context.Context, aw *writer, refs map[string]struct{}) error {
+	ids := make([]string, 0, len(refs))
+	for id := range refs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if e.files == nil {
+			aw.result.SkippedFiles = append(aw.result.SkippedFiles, id)
+			continue
+		}
+
+		err := func() error {
+			file, size, err := e.files.Open(ctx, id)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			return aw.writeEntry(SectionFiles+"/"+id, file, size)
+		}()
+		if err != nil {
+			// a missing file must not fail the export of the content, the tar stream is broken only when
+			// the entry was partially written
+			if aw.broken {
+				return err
+			}
+			logrus.Warnf("skipping file %s of the export: %v", id, err)
+			aw.result.SkippedFiles = append(aw.result.SkippedFiles, id)
+			continue
+		}
+		aw.result.Counts[SectionFiles]++
+	}
+
+	return nil
+}
+
+// writer writes the entries of an archive
+type writer struct {
+	tw      *tar.Writer
+	entries map[string]int
+	result  *ExportResult
+	// broken is set when an entry failed after its header was written
+	broken bool
+}
+
+// writeBatch writes the records as a json lines entry of the section
+func writeBatch[M any, R any](w *writer, section string, models []*M, convert func(*M) R) error {
+	if len(models) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, m := range models {
+		if err := encoder.Encode(convert(m)); err != nil {
+			return err
+		}
+	}
+
+	w.entries[section]++
+	name := fmt.Sprintf("%s/%06d.jsonl", section, w.entries[section])
+	if err := w.writeEntry(name, &buf, int64(buf.Len())); err != nil {
+		return err
+	}
+	w.result.Counts[section] += len(models)
+
+	return nil
+}
+
+func (w *writer) writeJSON(name string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return w.writeEntry(name, bytes.NewReader(data), int64(len(data)))
+}
+
+func (w *writer) writeEntry(name string, r io.Reader, size int64) error {
+	err := w.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		w.broken = true
+		return err
+	}
+
+	if _, err := io.CopyN(w.tw, r, size); err != nil {
+		w.broken = true
+		return err
+	}
+
+	return nil
+}
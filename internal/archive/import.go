@@ -0,0 +1,638 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/x"
+	"github.com/google/uuid"
+)
+
+// ConflictPolicy decides what happens to an imported record that already exists in the space
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing record, the references in the archive point to it
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing record with the imported one
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictRename imports a copy of the record with a new id and a unique name
+	ConflictRename ConflictPolicy = "rename"
+)
+
+// maxRenameAttempts bounds the search for a free name
+const maxRenameAttempts = 100
+
+var ErrInvalidArchive = errors.New("invalid archive")
+
+// ParseConflictPolicy parses the name of a conflict policy
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(strings.ToLower(name)); policy {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q, use skip, overwrite or rename", name)
+	}
+}
+
+// ImportOptions configures an import
+type ImportOptions struct {
+	// SpaceID is the space the content is imported into
+	SpaceID  uuid.UUID
+	Conflict ConflictPolicy
+}
+
+// SectionResult counts the imported records of a section
+type SectionResult struct {
+	Created     int
+	Skipped     int
+	Overwritten int
+	Renamed     int
+}
+
+// ImportResult reports the imported records by section
+type ImportResult struct {
+	Sections map[string]*SectionResult
+	Warnings []string
+}
+
+// Importer reads an archive into a space.
+//
+// The ids of the archive are remapped into the target space: the id of a record is derived from the
+// target space and the archived id, so importing the same archive twice finds the records of the first
+// import and applies the conflict policy to them, while importing it into another space creates new ones.
+// The tags and the tiers also conflict by name. The import does not publish events to the webhooks.
+type Importer struct {
+	store store.UnstakStore
+	files FileStore
+	opts  ImportOptions
+	// ids maps the archived ids of the referenced records to their ids in the target space
+	ids    map[string]string
+	result *ImportResult
+}
+
+// NewImporter creates a new Importer, a nil file store skips the files of the archive
+func NewImporter(store store.UnstakStore, files FileStore, opts ImportOptions) *Importer {
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictSkip
+	}
+
+	return &Importer{
+		store: store,
+		files: files,
+		opts:  opts,
+		ids:   make(map[string]string),
+		result: &ImportResult{
+			Sections: make(map[string]*SectionResult),
+		},
+	}
+}
+
+// Import reads the tar.gz archive from r, each json lines entry is imported in a transaction
+func (i *Importer) Import(ctx context.Context, r io.Reader) (*ImportResult, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	manifestRead := false
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return i.result, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if !manifestRead {
+			if err := readManifest(header, tr); err != nil {
+				return i.result, err
+			}
+			manifestRead = true
+			continue
+		}
+
+		section, name, _ := strings.Cut(header.Name, "/")
+		switch section {
+		case SectionTags:
+			err = importBatch(ctx, i, tr, i.importTag)
+		case SectionTiers:
+			err = importBatch(ctx, i, tr, i.importTier)
+		case SectionTierMembers:
+			err = importBatch(ctx, i, tr, i.importTierMember)
+		case SectionCourses:
+			err = importBatch(ctx, i, tr, i.importCourse)
+		case SectionPages:
+			err = importBatch(ctx, i, tr, i.importPage)
+		case SectionPosts:
+			err = importBatch(ctx, i, tr, i.importPost)
+		case SectionFiles:
+			err = i.importFile(ctx, name, tr, header.Size)
+		default:
+			i.warn("unknown archive entry %s", header.Name)
+		}
+		if err != nil {
+			return i.result, fmt.Errorf("%s: %w", header.Name, err)
+		}
+	}
+
+	if !manifestRead {
+		return i.result, fmt.Errorf("%w: the archive is empty", ErrInvalidArchive)
+	}
+
+	return i.result, nil
+}
+
+func readManifest(header *tar.Header, r io.Reader) error {
+	if header.Name != manifestName {
+		return fmt.Errorf("%w: %s is not the first entry", ErrInvalidArchive, manifestName)
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if manifest.Version < 1 || manifest.Version > FormatVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidArchive, manifest.Version)
+	}
+
+	return nil
+}
+
+// importBatch decodes the json lines entry and imports its records in a transaction
+func importBatch[R any](ctx context.Context, i *Importer, r io.Reader, fn func(ctx context.Context, tx store.UnstakStore, record *R) error) error {
+	var records []*R
+	scanner := bufio.NewScanner(r)
+	// a record holds the whole content of a post
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		record := new(R)
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// the id mapping of a failed batch is rolled back with it
+	ids := make(map[string]string, len(i.ids))
+	for k, v := range i.ids {
+		ids[k] = v
+	}
+
+	err := i.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		for _, record := range records {
+			if err := fn(ctx, tx, record); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		i.ids = ids
+	}
+
+	return err
+}
+
+func (i *Importer) section(name string) *SectionResult {
+	result, ok := i.result.Sections[name]
+	if !ok {
+		result = &SectionResult{}
+		i.result.Sections[name] = result
+	}
+
+	return result
+}
+
+func (i *Importer) warn(format string, args ...any) {
+	i.result.Warnings = append(i.result.Warnings, fmt.Sprintf(format, args...))
+}
+
+// targetID derives the id of an archived record in the target space
+func (i *Importer) targetID(archivedID string) uuid.UUID {
+	return uuid.NewSHA1(i.opts.SpaceID, []byte(archivedID))
+}
+
+// resolve returns the id of an imported record in the target space
+func (i *Importer) resolve(archivedID string) (uuid.UUID, bool) {
+	id, ok := i.ids[archivedID]
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	return uuid.MustParse(id), true
+}
+
+func (i *Importer) resolveTags(section string, archivedIDs []string) []*model.Tag {
+	tags := make([]*model.Tag, 0, len(archivedIDs))
+	for _, archivedID := range archivedIDs {
+		id, ok := i.resolve(archivedID)
+		if !ok {
+			i.warn("%s: tag %s is not in the archive", section, archivedID)
+			continue
+		}
+		tags = append(tags, &model.Tag{ID: id.String()})
+	}
+
+	return tags
+}
+
+// exists reports whether a lookup found the record, the not found error of the store is not an error
+func exists[T any](_ *T, err error) (bool, error) {
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// rename returns the first of name (2), name (3)... that is not taken
+func rename(name string, taken func(name string) (bool, error)) (string, error) {
+	for n := 2; n < maxRenameAttempts; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		ok, err := taken(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("no free name for %q", name)
+}
+
+func (i *Importer) importTag(ctx context.Context, tx store.UnstakStore, record *Tag) error {
+	result := i.section(SectionTags)
+	id := i.targetID(record.ID)
+
+	existing, err := tx.GetTag(ctx, id)
+	ok, err := exists(existing, err)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		existing, err = tx.GetTagByName(ctx, i.opts.SpaceID, record.Name)
+		if ok, err = exists(existing, err); err != nil {
+			return err
+		}
+	}
+
+	tag := &model.Tag{ID: id.String(), SpaceID: i.opts.SpaceID.String(), Name: record.Name}
+	if ok {
+		switch i.opts.Conflict {
+		case ConflictSkip:
+			i.ids[record.ID] = existing.ID
+			result.Skipped++
+			return nil
+		case ConflictOverwrite:
+			// the name is the only field of a tag, the existing tag is reused as it is
+			i.ids[record.ID] = existing.ID
+			result.Overwritten++
+			return nil
+		case ConflictRename:
+			tag.ID = uuid.New().String()
+			tag.Name, err = rename(record.Name, func(name string) (bool, error) {
+				return exists(tx.GetTagByName(ctx, i.opts.SpaceID, name))
+			})
+			if err != nil {
+				return err
+			}
+			result.Renamed++
+		}
+	} else {
+		result.Created++
+	}
+
+	if err := tx.CreateTag(ctx, tag); err != nil {
+		return err
+	}
+	i.ids[record.ID] = tag.ID
+
+	return nil
+}
+
+func (i *Importer) importTier(ctx context.Context, tx store.UnstakStore, record *Tier) error {
+	result := i.section(SectionTiers)
+	id := i.targetID(record.ID)
+
+	existing, err := tx.GetTier(ctx, id)
+	ok, err := exists(existing, err)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		existing, err = tx.GetTierByName(ctx, record.Name)
+		if ok, err = exists(existing, err); err != nil {
+			return err
+		}
+	}
+
+	tier := &model.Tier{
+		ID:             id.String(),
+		SpaceID:        i.opts.SpaceID.String(),
+		Name:           record.Name,
+		CreatedByID:    record.CreatedByID,
+		Free:           record.Free,
+		MonthlyCost:    record.MonthlyCost,
+		YearlyCost:     record.YearlyCost,
+		HalfYearlyCost: record.HalfYearlyCost,
+		QuarterlyCost:  record.QuarterlyCost,
+	}
+	if ok {
+		switch i.opts.Conflict {
+		case ConflictSkip:
+			i.ids[record.ID] = existing.ID
+			result.Skipped++
+			return nil
+		case ConflictOverwrite:
+			tier.ID = existing.ID
+			tier.SpaceID = existing.SpaceID
+			tier.CreatedAt = existing.CreatedAt
+			if err := tx.UpdateTier(ctx, tier); err != nil {
+				return err
+			}
+			i.ids[record.ID] = tier.ID
+			result.Overwritten++
+			return nil
+		case ConflictRename:
+			tier.ID = uuid.New().String()
+			tier.Name, err = rename(record.Name, func(name string) (bool, error) {
+				return exists(tx.GetTierByName(ctx, name))
+			})
+			if err != nil {
+				return err
+			}
+			result.Renamed++
+		}
+	} else {
+		result.Created++
+	}
+
+	if err := tx.CreateTier(ctx, tier); err != nil {
+		return err
+	}
+	i.ids[record.ID] = tier.ID
+
+	return nil
+}
+
+func (i *Importer) importTierMember(ctx context.Context, tx store.UnstakStore, record *TierMember) error {
+	result := i.section(SectionTierMembers)
+	tierID, ok := i.resolve(record.TierID)
+	if !ok {
+		i.warn("%s: tier %s of member %s is not in the archive", SectionTierMembers, record.TierID, record.ID)
+		result.Skipped++
+		return nil
+	}
+
+	member := &model.TierMember{
+		ID:          i.targetID(record.ID).String(),
+		TierID:      tierID.String(),
+		UserID:      record.UserID,
+		CreatedByID: record.CreatedByID,
+	}
+
+	existing, err := tx.GetTierMember(ctx, uuid.MustParse(member.ID))
+	if ok, err = exists(existing, err); err != nil {
+		return err
+	}
+	if ok {
+		switch i.opts.Conflict {
+		case ConflictSkip:
+			result.Skipped++
+			return nil
+		case ConflictOverwrite:
+			existing.TierID = member.TierID
+			existing.UserID = member.UserID
+			existing.Tier = nil
+			if err := tx.UpdateTierMember(ctx, existing); err != nil {
+				return err
+			}
+			result.Overwritten++
+			return nil
+		case ConflictRename:
+			member.ID = uuid.New().String()
+			result.Renamed++
+		}
+	} else {
+		result.Created++
+	}
+
+	return tx.AddTierMember(ctx, member)
+}
+
+func (i *Importer) importCourse(ctx context.Context, tx store.UnstakStore, record *Course) error {
+	result := i.section(SectionCourses)
+	course := &model.Course{
+		ID:          i.targetID(record.ID).String(),
+		DocumentID:  record.DocumentID,
+		CreatedByID: record.CreatedByID,
+		SpaceID:     i.opts.SpaceID.String(),
+		Status:      record.Status,
+		Reaction:    record.Reaction,
+	}
+
+	existing, err := tx.GetCourse(ctx, uuid.MustParse(course.ID))
+	ok, err := exists(existing, err)
+	if err != nil {
+		return err
+	}
+	if ok {
+		switch i.opts.Conflict {
+		case ConflictSkip:
+			i.ids[record.ID] = existing.ID
+			result.Skipped++
+			return nil
+		case ConflictOverwrite:
+			course.CreatedAt = existing.CreatedAt
+			if err := tx.UpdateCourse(ctx, course); err != nil {
+				return err
+			}
+			result.Overwritten++
+		case ConflictRename:
+			course.ID = uuid.New().String()
+			if err := tx.CreateCourse(ctx, course); err != nil {
+				return err
+			}
+			result.Renamed++
+		}
+	} else {
+		if err := tx.CreateCourse(ctx, course); err != nil {
+			return err
+		}
+		result.Created++
+	}
+	i.ids[record.ID] = course.ID
+
+	return tx.UpdateCourseTags(ctx, uuid.MustParse(course.ID), i.resolveTags(SectionCourses, record.TagIDs))
+}
+
+func (i *Importer) importPage(ctx context.Context, tx store.UnstakStore, record *Page) error {
+	result := i.section(SectionPages)
+	courseID, ok := i.resolve(record.CourseID)
+	if !ok {
+		i.warn("%s: course %s of page %s is not in the archive", SectionPages, record.CourseID, record.ID)
+		result.Skipped++
+		return nil
+	}
+
+	page := &model.Page{
+		ID:          i.targetID(record.ID).String(),
+		Content:     record.Content,
+		CourseID:    courseID.String(),
+		SpaceID:     i.opts.SpaceID.String(),
+		CreatedByID: record.CreatedByID,
+		Status:      record.Status,
+	}
+
+	existing, err := tx.GetPage(ctx, uuid.MustParse(page.ID))
+	if ok, err = exists(existing, err); err != nil {
+		return err
+	}
+	if ok {
+		switch i.opts.Conflict {
+		case ConflictSkip:
+			result.Skipped++
+			return nil
+		case ConflictOverwrite:
+			page.CreatedAt = existing.CreatedAt
+			result.Overwritten++
+			return tx.UpdatePage(ctx, page)
+		case ConflictRename:
+			page.ID = uuid.New().String()
+			result.Renamed++
+		}
+	} else {
+		result.Created++
+	}
+
+	return tx.CreatePage(ctx, page)
+}
+
+func (i *Importer) importPost(ctx context.Context, tx store.UnstakStore, record *Post) error {
+	result := i.section(SectionPosts)
+	post := &model.Post{
+		ID:          i.targetID(record.ID).String(),
+		SpaceID:     i.opts.SpaceID.String(),
+		Slug:        record.Slug,
+		SlugID:      record.SlugID,
+		Title:       record.Title,
+		Summary:     record.Summary,
+		Excerpt:     record.Excerpt,
+		Content:     record.Content,
+		Status:      record.Status,
+		Version:     record.Version,
+		PublishedAt: record.PublishedAt,
+		AuthorID:    record.AuthorID,
+	}
+	if record.TierID != "" {
+		// a post without its tier would be open to every reader
+		tierID, ok := i.resolve(record.TierID)
+		if !ok {
+			i.warn("%s: tier %s of post %s is not in the archive", SectionPosts, record.TierID, record.ID)
+			result.Skipped++
+			return nil
+		}
+		tier := tierID.String()
+		post.TierID = &tier
+	}
+
+	existing, err := tx.GetPost(ctx, uuid.MustParse(post.ID))
+	ok, err := exists(existing, err)
+	if err != nil {
+		return err
+	}
+	if ok {
+		switch i.opts.Conflict {
+		case ConflictSkip:
+			result.Skipped++
+			return nil
+		case ConflictOverwrite:
+			post.SlugID = existing.SlugID
+			post.CreatedAt = existing.CreatedAt
+			// the clients editing the post see a new version
+			post.Version = existing.Version + 1
+			if err := tx.UpdatePost(ctx, post); err != nil {
+				return err
+			}
+			result.Overwritten++
+
+			return tx.UpdatePostTags(ctx, uuid.MustParse(post.ID), i.resolveTags(SectionPosts, record.TagIDs))
+		case ConflictRename:
+			post.ID = uuid.New().String()
+			post.Slug = record.Slug + "-copy"
+			result.Renamed++
+		}
+	} else {
+		result.Created++
+	}
+
+	// the slug ids are unique across the spaces, the archived one is kept when it is free
+	taken, err := exists(tx.GetPostBySlugID(ctx, post.SlugID))
+	if err != nil {
+		return err
+	}
+	if taken || post.SlugID == "" {
+		post.SlugID = x.RandomString(12)
+	}
+
+	if err := tx.CreatePost(ctx, post); err != nil {
+		return err
+	}
+
+	return tx.UpdatePostTags(ctx, uuid.MustParse(post.ID), i.resolveTags(SectionPosts, record.TagIDs))
+}
+
+func (i *Importer) importFile(ctx context.Context, id string, r io.Reader, size int64) error {
+	result := i.section(SectionFiles)
+	if i.files == nil {
+		i.warn("%s: %s is skipped, no file store is configured", SectionFiles, id)
+		result.Skipped++
+		return nil
+	}
+
+	exists, err := i.files.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists && i.opts.Conflict != ConflictOverwrite {
+		// a file is immutable content addressed by its id, a renamed copy would be identical
+		result.Skipped++
+		return nil
+	}
+
+	if err := i.files.Put(ctx, id, r, size); err != nil {
+		return err
+	}
+	if exists {
+		result.Overwritten++
+	} else {
+		result.Created++
+	}
+
+	return nil
+}
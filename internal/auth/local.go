@@ -0,0 +1,615 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/x"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// issuer tells the tokens of the local provider apart in the logs, the audience matches the supabase tokens
+const (
+	issuer   = "unpost"
+	audience = "authenticated"
+)
+
+// the lifetimes of the one-time tokens sent to the users
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 24 * time.Hour
+)
+
+// dummyHash is compared when the account does not exist so that a login takes as long for unknown emails
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("unpost dummy password"), bcrypt.DefaultCost)
+
+// LocalStore keeps the accounts, the sessions and the linked identities of the local provider
+type LocalStore interface {
+	store.UserStore
+	store.AuthSessionStore
+	store.VerificationTokenStore
+	store.UserIdentityStore
+}
+
+// LocalProvider keeps the accounts in the users table with bcrypt hashed passwords and signs its own jwt tokens.
+// The access tokens are short lived, logging out revokes the refresh token of the session.
+// The password reset and the email verification tokens are delivered by the notifier.
+type LocalProvider struct {
+	store      LocalStore
+	notifier   Notifier
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+var (
+	_ Provider       = (*LocalProvider)(nil)
+	_ IdentityLinker = (*LocalProvider)(nil)
+)
+
+// NewLocalProvider creates a new LocalProvider, the access tokens are signed with the jwt secret
+func NewLocalProvider(store LocalStore, notifier Notifier, jwtSecret string, accessTTL, refreshTTL time.Duration) *LocalProvider {
+	return &LocalProvider{
+		store:      store,
+		notifier:   notifier,
+		secret:     []byte(jwtSecret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		now:        time.Now,
+	}
+}
+
+func (p *LocalProvider) Signup(ctx context.Context, email, password, name string) (*User, error) {
+	email = normalizeEmail(email)
+	if _, err := p.store.GetUserByEmail(ctx, email); err == nil {
+		return nil, ErrUserExists
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	user := &model.User{
+		ID:           uuid.NewString(),
+		Username:     name,
+		Email:        email,
+		Role:         model.UserRoleViewer,
+		PasswordHash: string(hash),
+	}
+	if err := p.store.CreateUser(ctx, user); err != nil {
+		// a concurrent signup with the same email hits the unique index
+		if errors.Is(err, store.ErrAlreadyExists) {
+			return nil, ErrUserExists
+		}
+		return nil, err
+	}
+
+	signedUp := userFromModel(user)
+	token, err := p.newVerificationToken(ctx, user, model.VerificationEmail, emailVerificationTTL)
+	if err != nil {
+		return nil, err
+	}
+	// the account is created, a failed delivery does not undo the signup
+	if err := p.notifier.EmailVerification(ctx, signedUp, token); err != nil {
+		logrus.Errorf("error sending the email verification to %s: %v", user.Email, err)
+	}
+
+	return signedUp, nil
+}
+
+func (p *LocalProvider) Login(ctx context.Context, email, password string) (*Session, error) {
+	user, err := p.store.GetUserByEmail(ctx, normalizeEmail(email))
+	if errors.Is(err, store.ErrNotFound) {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the accounts synced from another provider have no password
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if !user.Active() {
+		return nil, ErrUserDeactivated
+	}
+
+	return p.startSession(ctx, user)
+}
+
+// LoginWithIdentity logs the user of an identity provider in. A new identity is linked to the account with
+// the same email when the identity provider verified the email, otherwise an account without a password is created.
+func (p *LocalProvider) LoginWithIdentity(ctx context.Context, identity *Identity) (*Session, error) {
+	user, err := p.identityUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active() {
+		return nil, ErrUserDeactivated
+	}
+
+	return p.startSession(ctx, user)
+}
+
+// identityUser returns the account linked to the identity, the identity is linked on its first login
+func (p *LocalProvider) identityUser(ctx context.Context, identity *Identity) (*model.User, error) {
+	linked, err := p.store.GetUserIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return p.store.GetUser(ctx, uuid.MustParse(linked.UserID))
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	email := normalizeEmail(identity.Email)
+	if email == "" {
+		return nil, ErrIdentityEmailMissing
+	}
+
+	now := p.now()
+	user, err := p.store.GetUserByEmail(ctx, email)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		name := identity.Name
+		if name == "" {
+			name, _, _ = strings.Cut(email, "@")
+		}
+		user = &model.User{
+			ID:       uuid.NewString(),
+			Username: name,
+			Email:    email,
+			Role:     model.UserRoleViewer,
+		}
+		if identity.EmailVerified {
+			user.EmailVerifiedAt = &now
+		}
+		if err := p.store.CreateUser(ctx, user); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case !identity.EmailVerified:
+		// anyone can claim an unverified email at some identity provider
+		return nil, ErrIdentityNotLinked
+	case user.EmailVerifiedAt == nil:
+		// nobody proved to own the email of the account before, the password may have been set by someone else
+		user.EmailVerifiedAt = &now
+		user.PasswordHash = ""
+		if err := p.store.UpdateUser(ctx, user); err != nil {
+			return nil, err
+		}
+		if err := p.store.RevokeAuthSessions(ctx, uuid.MustParse(user.ID), now); err != nil {
+			return nil, err
+		}
+	}
+
+	err = p.store.CreateUserIdentity(ctx, &model.UserIdentity{
+		ID:       uuid.NewString(),
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// startSession creates a session for the user and issues its tokens
+func (p *LocalProvider) startSession(ctx context.Context, user *model.User) (*Session, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	now := p.now()
+	session := &model.AuthSession{
+		ID:               uuid.NewString(),
+		UserID:           user.ID,
+		RefreshTokenHash: x.HashSecret(secret),
+		ExpiresAt:        now.Add(p.refreshTTL),
+	}
+	if err := p.store.CreateAuthSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return p.issue(user, session, secret)
+}
+
+func (p *LocalProvider) Logout(ctx context.Context, accessToken string) error {
+	claims, err := p.parse(accessToken)
+	if err != nil {
+		return err
+	}
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+
+	session, err := p.store.GetAuthSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+	now := p.now()
+	session.RevokedAt = &now
+
+	return p.store.UpdateAuthSession(ctx, session)
+}
+
+// Refresh rotates the refresh token of the session, the previous refresh token stops working
+func (p *LocalProvider) Refresh(ctx context.Context, refreshToken string) (*Session, error) {
+	sessionID, secret, ok := splitToken(refreshToken)
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := p.store.GetAuthSession(ctx, sessionID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(session.RefreshTokenHash), []byte(x.HashSecret(secret))) != 1 {
+		return nil, ErrInvalidRefreshToken
+	}
+	now := p.now()
+	if !session.Active(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// the role may have changed since the login, the user is read again
+	user, err := p.store.GetUser(ctx, uuid.MustParse(session.UserID))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	next, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	// a concurrent refresh with the same token rotated the session first
+	err = p.store.RotateAuthSession(ctx, sessionID, session.RefreshTokenHash, x.HashSecret(next), now.Add(p.refreshTTL))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	session.RefreshTokenHash = x.HashSecret(next)
+	session.ExpiresAt = now.Add(p.refreshTTL)
+
+	return p.issue(user, session, next)
+}
+
+func (p *LocalProvider) GetUser(ctx context.Context, userID uuid.UUID) (*User, error) {
+	user, err := p.store.GetUser(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return userFromModel(user), nil
+}
+
+func (p *LocalProvider) SetRole(ctx context.Context, userID uuid.UUID, role model.UserRole) error {
+	user, err := p.store.GetUser(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	user.Role = role
+
+	return p.store.UpdateUser(ctx, user)
+}
+
+func (p *LocalProvider) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	return p.store.DeleteUser(ctx, userID)
+}
+
+// SetActive deactivates or reactivates the account, the deactivation ends the sessions of the account.
+// The access tokens already issued stay valid until they expire.
+func (p *LocalProvider) SetActive(ctx context.Context, userID uuid.UUID, active bool) error {
+	user, err := p.store.GetUser(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if user.Active() == active {
+		return nil
+	}
+
+	now := p.now()
+	user.DeactivatedAt = nil
+	if !active {
+		user.DeactivatedAt = &now
+	}
+	if err := p.store.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	if active {
+		return nil
+	}
+
+	return p.store.RevokeAuthSessions(ctx, userID, now)
+}
+
+func (p *LocalProvider) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := p.store.GetUserByEmail(ctx, normalizeEmail(email))
+	// the callers learn nothing about the accounts
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.Active() {
+		return nil
+	}
+
+	token, err := p.newVerificationToken(ctx, user, model.VerificationPasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return p.notifier.PasswordReset(ctx, userFromModel(user), token)
+}
+
+// ConfirmPasswordReset sets the password and ends the sessions of the account, the email is proven on the way
+func (p *LocalProvider) ConfirmPasswordReset(ctx context.Context, email, token, password string) error {
+	user, verification, err := p.useVerificationToken(ctx, email, token, model.VerificationPasswordReset)
+	if err != nil {
+		return err
+	}
+	if !user.Active() {
+		return ErrInvalidToken
+	}
+
+	// the token is spent first, a failed reset is requested again
+	if err := p.store.UpdateVerificationToken(ctx, verification); err != nil {
+		return err
+	}
+
+	return p.setPassword(ctx, user, password)
+}
+
+func (p *LocalProvider) VerifyEmail(ctx context.Context, email, token string) error {
+	user, verification, err := p.useVerificationToken(ctx, email, token, model.VerificationEmail)
+	if err != nil {
+		return err
+	}
+
+	if err := p.store.UpdateVerificationToken(ctx, verification); err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	user.EmailVerifiedAt = verification.UsedAt
+
+	return p.store.UpdateUser(ctx, user)
+}
+
+// ChangePassword replaces the password and ends the sessions of the user, the user logs in again
+func (p *LocalProvider) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string) error {
+	user, err := p.store.GetUser(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)) != nil {
+		return ErrInvalidCredentials
+	}
+
+	return p.setPassword(ctx, user, newPassword)
+}
+
+// setPassword stores the hash of the password and ends the sessions, the refresh tokens of the old password stop working
+func (p *LocalProvider) setPassword(ctx context.Context, user *model.User, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := p.now()
+	user.PasswordHash = string(hash)
+	// the reset token reached the inbox of the user
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &now
+	}
+	if err := p.store.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	return p.store.RevokeAuthSessions(ctx, uuid.MustParse(user.ID), now)
+}
+
+// newVerificationToken stores a one-time token for the user and returns it
+func (p *LocalProvider) newVerificationToken(ctx context.Context, user *model.User, purpose model.VerificationPurpose, ttl time.Duration) (string, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return "", err
+	}
+
+	verification := &model.VerificationToken{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: x.HashSecret(secret),
+		ExpiresAt: p.now().Add(ttl),
+	}
+	if err := p.store.CreateVerificationToken(ctx, verification); err != nil {
+		return "", err
+	}
+
+	return verification.ID + "." + secret, nil
+}
+
+// useVerificationToken checks the one-time token and marks it used, the caller saves the token.
+// The email is optional, when given it must be the email of the account.
+func (p *LocalProvider) useVerificationToken(ctx context.Context, email, token string, purpose model.VerificationPurpose) (*model.User, *model.VerificationToken, error) {
+	id, secret, ok := splitToken(token)
+	if !ok {
+		return nil, nil, ErrInvalidToken
+	}
+
+	verification, err := p.store.GetVerificationToken(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(verification.TokenHash), []byte(x.HashSecret(secret))) != 1 {
+		return nil, nil, ErrInvalidToken
+	}
+	now := p.now()
+	if verification.Purpose != purpose || !verification.Usable(now) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	user, err := p.store.GetUser(ctx, uuid.MustParse(verification.UserID))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if email != "" && normalizeEmail(email) != user.Email {
+		return nil, nil, ErrInvalidToken
+	}
+	verification.UsedAt = &now
+
+	return user, verification, nil
+}
+
+// claims are the claims of the access tokens, the role sits in the app metadata like in the supabase tokens
+type claims struct {
+	jwt.RegisteredClaims
+	Email       string         `json:"email"`
+	AppMetadata map[string]any `json:"app_metadata"`
+	SessionID   string         `json:"session_id"`
+}
+
+// issue signs an access token for the user and returns the session with the refresh token
+func (p *LocalProvider) issue(user *model.User, session *model.AuthSession, secret string) (*Session, error) {
+	now := p.now()
+	expiresAt := now.Add(p.accessTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   user.ID,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Email:       user.Email,
+		AppMetadata: map[string]any{"role": string(user.Role)},
+		SessionID:   session.ID,
+	})
+	accessToken, err := token.SignedString(p.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		AccessToken:  accessToken,
+		RefreshToken: session.ID + "." + secret,
+		TokenType:    "bearer",
+		ExpiresIn:    int(p.accessTTL.Seconds()),
+		ExpiresAt:    expiresAt.Unix(),
+		User:         userFromModel(user),
+	}, nil
+}
+
+// parse verifies an access token of the provider
+func (p *LocalProvider) parse(accessToken string) (*claims, error) {
+	parsed := &claims{}
+	_, err := jwt.ParseWithClaims(accessToken, parsed, func(token *jwt.Token) (any, error) {
+		return p.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithTimeFunc(p.now),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+func userFromModel(user *model.User) *User {
+	return &User{
+		ID:            user.ID,
+		Email:         user.Email,
+		Name:          user.Username,
+		Role:          user.Role,
+		Deactivated:   !user.Active(),
+		EmailVerified: user.EmailVerifiedAt != nil,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// splitToken returns the id and the secret of a refresh or a verification token, the tokens are <id>.<secret>
+func splitToken(token string) (uuid.UUID, string, bool) {
+	rawID, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return uuid.Nil, "", false
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return uuid.Nil, "", false
+	}
+
+	return id, secret, true
+}
@@ -0,0 +1,325 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store/storetest"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// recordingNotifier keeps the last token sent of each kind
+type recordingNotifier struct {
+	reset        string
+	verification string
+}
+
+func (n *recordingNotifier) PasswordReset(ctx context.Context, user *User, token string) error {
+	n.reset = token
+	return nil
+}
+
+func (n *recordingNotifier) EmailVerification(ctx context.Context, user *User, token string) error {
+	n.verification = token
+	return nil
+}
+
+func newTestProvider(t *testing.T) *LocalProvider {
+	return NewLocalProvider(storetest.NewStore(t), LogNotifier{}, "secret", 15*time.Minute, time.Hour)
+}
+
+func TestLocalSignupLogin(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestProvider(t)
+
+	user, err := provider.Signup(ctx, "Jane@Example.com", "password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "jane@example.com" || user.Name != "jane" || user.Role != model.UserRoleViewer {
+		t.Errorf("unexpected user %+v", user)
+	}
+	if _, err := provider.Signup(ctx, "jane@example.com", "password", "jane"); !errors.Is(err, ErrUserExists) {
+		t.Errorf("expected the account to exist, got %v", err)
+	}
+
+	for _, credentials := range [][2]string{{"jane@example.com", "wrong"}, {"john@example.com", "password"}} {
+		if _, err := provider.Login(ctx, credentials[0], credentials[1]); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: expected invalid credentials, got %v", credentials[0], err)
+		}
+	}
+
+	session, err := provider.Login(ctx, "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the interceptor reads the user and the role of the access token
+	token, err := jwt.Parse(session.AccessToken, func(token *jwt.Token) (any, error) {
+		return []byte("secret"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	if claims["sub"] != user.ID {
+		t.Errorf("expected the subject %s, got %v", user.ID, claims["sub"])
+	}
+	if role := claims["app_metadata"].(map[string]any)["role"]; role != string(model.UserRoleViewer) {
+		t.Errorf("expected the viewer role, got %v", role)
+	}
+}
+
+func TestLocalRefreshLogout(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestProvider(t)
+
+	user, err := provider.Signup(ctx, "jane@example.com", "password", "jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := provider.Login(ctx, "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the refreshed session carries the new role
+	if err := provider.SetRole(ctx, uuid.MustParse(user.ID), model.UserRoleAuthor); err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := provider.Refresh(ctx, session.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.User.Role != model.UserRoleAuthor {
+		t.Errorf("expected the author role, got %s", refreshed.User.Role)
+	}
+
+	// the refresh tokens are rotated
+	if _, err := provider.Refresh(ctx, session.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected the previous refresh token to fail, got %v", err)
+	}
+
+	if err := provider.Logout(ctx, refreshed.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Refresh(ctx, refreshed.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected the refresh token of the ended session to fail, got %v", err)
+	}
+
+	provider.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	expired, err := provider.Login(ctx, "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.now = func() time.Time { return time.Now().Add(4 * time.Hour) }
+	if _, err := provider.Refresh(ctx, expired.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected the expired refresh token to fail, got %v", err)
+	}
+
+	for _, malformed := range []string{"", "token", uuid.NewString() + ".secret", "nothex.secret"} {
+		if _, err := provider.Refresh(ctx, malformed); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("%q: expected an invalid refresh token, got %v", malformed, err)
+		}
+	}
+}
+
+// racingStore holds the session reads until every refresh read the session, the refreshes then rotate it concurrently
+type racingStore struct {
+	LocalStore
+	reads sync.WaitGroup
+}
+
+func (s *racingStore) GetAuthSession(ctx context.Context, id uuid.UUID) (*model.AuthSession, error) {
+	session, err := s.LocalStore.GetAuthSession(ctx, id)
+	s.reads.Done()
+	s.reads.Wait()
+	return session, err
+}
+
+func TestLocalConcurrentRefresh(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestProvider(t)
+
+	if _, err := provider.Signup(ctx, "jane@example.com", "password", "jane"); err != nil {
+		t.Fatal(err)
+	}
+	session, err := provider.Login(ctx, "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the refreshes racing with the same token rotate the session a single time
+	const refreshes = 8
+	racing := &racingStore{LocalStore: provider.store}
+	racing.reads.Add(refreshes)
+	provider.store = racing
+	errs := make(chan error, refreshes)
+	var wg sync.WaitGroup
+	for range refreshes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := provider.Refresh(ctx, session.RefreshToken)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrInvalidRefreshToken):
+			t.Errorf("expected an invalid refresh token, got %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("expected a single refresh to succeed, got %d", succeeded)
+	}
+}
+
+func TestLocalSetActive(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestProvider(t)
+
+	user, err := provider.Signup(ctx, "jane@example.com", "password", "jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := provider.Login(ctx, "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID := uuid.MustParse(user.ID)
+	if err := provider.SetActive(ctx, userID, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Login(ctx, "jane@example.com", "password"); !errors.Is(err, ErrUserDeactivated) {
+		t.Errorf("expected the deactivated account to be refused, got %v", err)
+	}
+	if _, err := provider.Login(ctx, "jane@example.com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected invalid credentials before the deactivation is told, got %v", err)
+	}
+	// the deactivation ends the sessions
+	if _, err := provider.Refresh(ctx, session.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected the session to be ended, got %v", err)
+	}
+	deactivated, err := provider.GetUser(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deactivated.Deactivated {
+		t.Error("expected the account to be deactivated")
+	}
+
+	if err := provider.SetActive(ctx, userID, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Login(ctx, "jane@example.com", "password"); err != nil {
+		t.Errorf("expected the reactivated account to log in, got %v", err)
+	}
+}
+
+func TestLocalPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestProvider(t)
+	notifier := &recordingNotifier{}
+	provider.notifier = notifier
+
+	if _, err := provider.Signup(ctx, "jane@example.com", "password", "jane"); err != nil {
+		t.Fatal(err)
+	}
+	session, err := provider.Login(ctx, "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an unknown email is not told apart
+	if err := provider.RequestPasswordReset(ctx, "john@example.com"); err != nil || notifier.reset != "" {
+		t.Fatalf("expected nothing to be sent, got %q %v", notifier.reset, err)
+	}
+	if err := provider.RequestPasswordReset(ctx, "Jane@example.com"); err != nil || notifier.reset == "" {
+		t.Fatalf("expected a reset token, got %v", err)
+	}
+
+	if err := provider.ConfirmPasswordReset(ctx, "john@example.com", notifier.reset, "new password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected the token of another email to fail, got %v", err)
+	}
+	if err := provider.ConfirmPasswordReset(ctx, "", notifier.verification, "new password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected the verification token to fail, got %v", err)
+	}
+	if err := provider.ConfirmPasswordReset(ctx, "", notifier.reset, "new password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.ConfirmPasswordReset(ctx, "", notifier.reset, "other password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected the used token to fail, got %v", err)
+	}
+
+	if _, err := provider.Refresh(ctx, session.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected the reset to end the sessions, got %v", err)
+	}
+	if _, err := provider.Login(ctx, "jane@example.com", "password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected the old password to fail, got %v", err)
+	}
+	if _, err := provider.Login(ctx, "jane@example.com", "new password"); err != nil {
+		t.Errorf("expected the new password to work, got %v", err)
+	}
+
+	provider.now = func() time.Time { return time.Now().Add(2 * passwordResetTTL) }
+	if err := provider.RequestPasswordReset(ctx, "jane@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	provider.now = func() time.Time { return time.Now().Add(4 * passwordResetTTL) }
+	if err := provider.ConfirmPasswordReset(ctx, "", notifier.reset, "other password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected the expired token to fail, got %v", err)
+	}
+}
+
+func TestLocalVerifyEmailChangePassword(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestProvider(t)
+	notifier := &recordingNotifier{}
+	provider.notifier = notifier
+
+	user, err := provider.Signup(ctx, "jane@example.com", "password", "jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.EmailVerified || notifier.verification == "" {
+		t.Fatalf("expected an unverified email and a verification token, got %+v", user)
+	}
+
+	if err := provider.VerifyEmail(ctx, "", "malformed"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected the malformed token to fail, got %v", err)
+	}
+	if err := provider.VerifyEmail(ctx, "jane@example.com", notifier.verification); err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.MustParse(user.ID)
+	verified, err := provider.GetUser(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verified.EmailVerified {
+		t.Error("expected the email to be verified")
+	}
+
+	if err := provider.ChangePassword(ctx, userID, "wrong", "new password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected the wrong current password to fail, got %v", err)
+	}
+	if err := provider.ChangePassword(ctx, userID, "password", "new password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Login(ctx, "jane@example.com", "new password"); err != nil {
+		t.Errorf("expected the new password to work, got %v", err)
+	}
+}
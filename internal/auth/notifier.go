@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Notifier delivers the one-time tokens of the local provider to the users, usually by email
+type Notifier interface {
+	// PasswordReset sends the password reset token to the user
+	PasswordReset(ctx context.Context, user *User, token string) error
+	// EmailVerification sends the email verification token to the user
+	EmailVerification(ctx context.Context, user *User, token string) error
+}
+
+// LogNotifier logs the tokens instead of sending them, it stands in for a mailer during development
+type LogNotifier struct{}
+
+var _ Notifier = LogNotifier{}
+
+func (LogNotifier) PasswordReset(ctx context.Context, user *User, token string) error {
+	logrus.Infof("password reset token for %s: %s", user.Email, token)
+	return nil
+}
+
+func (LogNotifier) EmailVerification(ctx context.Context, user *User, token string) error {
+	logrus.Infof("email verification token for %s: %s", user.Email, token)
+	return nil
+}
@@ -0,0 +1,391 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emrgen/unpost/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keysRefreshInterval limits how often the signing keys are fetched again for an unknown key id
+const keysRefreshInterval = time.Minute
+
+// maxResponseSize limits the responses read from the identity providers
+const maxResponseSize = 1 << 20
+
+// idTokenMethods are the signing methods accepted for the id tokens
+var idTokenMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// discovery is the part of the provider metadata the login uses
+type discovery struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	JwksURI                       string   `json:"jwks_uri"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// OIDCProvider signs the users in with an external OpenID Connect provider through the authorization code flow
+// with pkce. The endpoints and the signing keys are discovered from the issuer on first use.
+type OIDCProvider struct {
+	config config.OIDCProviderConfig
+	client http.Client
+	now    func() time.Time
+
+	mu            sync.Mutex
+	discovery     *discovery
+	keys          map[string]any
+	keysFetchedAt time.Time
+}
+
+// NewOIDCProvider creates a new OIDCProvider, nothing is fetched until the first login
+func NewOIDCProvider(cfg config.OIDCProviderConfig, httpClient http.Client) *OIDCProvider {
+	return &OIDCProvider{
+		config: cfg,
+		client: httpClient,
+		now:    time.Now,
+	}
+}
+
+// Name returns the name of the provider in the api
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// AuthorizationURL returns the page of the provider the user signs in at, it carries the S256 challenge of the verifier
+func (p *OIDCProvider) AuthorizationURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange redeems the authorization code and returns the identity of the id token.
+// A code or an id token rejected on the way returns ErrInvalidAuthorization.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", p.config.ClientID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// the public clients have no secret, they are identified by the client id and the pkce verifier
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&token); err != nil && res.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("decoding the token response of %s: %w", p.config.Name, err)
+	}
+	switch {
+	case res.StatusCode == http.StatusBadRequest || res.StatusCode == http.StatusUnauthorized:
+		return nil, fmt.Errorf("%w: %s %s", ErrInvalidAuthorization, token.Error, token.ErrorDescription)
+	case res.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("token endpoint of %s returned %s", p.config.Name, res.Status)
+	case token.IDToken == "":
+		return nil, fmt.Errorf("%w: no id token in the response", ErrInvalidAuthorization)
+	}
+
+	return p.verify(ctx, metadata, token.IDToken, nonce)
+}
+
+// idTokenClaims are the claims of the id tokens the login reads
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string    `json:"nonce"`
+	AuthorizedParty   string    `json:"azp"`
+	Email             string    `json:"email"`
+	EmailVerified     claimBool `json:"email_verified"`
+	Name              string    `json:"name"`
+	PreferredUsername string    `json:"preferred_username"`
+}
+
+// verify checks the signature, the issuer, the audience, the expiry and the nonce of the id token
+func (p *OIDCProvider) verify(ctx context.Context, metadata *discovery, idToken, nonce string) (*Identity, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, metadata, kid)
+	},
+		jwt.WithValidMethods(idTokenMethods),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(p.now),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAuthorization, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidAuthorization)
+	}
+	// a token issued to several clients names the client it was requested by
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("%w: token issued to another client", ErrInvalidAuthorization)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject in the id token", ErrInvalidAuthorization)
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+
+	return &Identity{
+		Provider:      p.config.Name,
+		Subject:       claims.Subject,
+		Email:         normalizeEmail(claims.Email),
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          name,
+	}, nil
+}
+
+// discover fetches the metadata of the provider once, a failed fetch is tried again on the next login
+func (p *OIDCProvider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(p.config.Issuer, "/")
+	metadata := &discovery{}
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", metadata); err != nil {
+		return nil, err
+	}
+	// the issuer of the metadata is the issuer of the id tokens, it must be the configured one
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("issuer of %s is %s, expected %s", p.config.Name, metadata.Issuer, p.config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JwksURI == "" {
+		return nil, fmt.Errorf("metadata of %s misses an endpoint", p.config.Name)
+	}
+	if len(metadata.CodeChallengeMethodsSupported) > 0 && !slices.Contains(metadata.CodeChallengeMethodsSupported, "S256") {
+		return nil, fmt.Errorf("%s does not support the S256 code challenge", p.config.Name)
+	}
+	p.discovery = metadata
+
+	return metadata, nil
+}
+
+// key returns the signing key of the id token, the keys are fetched again when the provider rotated them
+func (p *OIDCProvider) key(ctx context.Context, metadata *discovery, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if p.now().Sub(p.keysFetchedAt) < keysRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, metadata.JwksURI, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// the unsupported key types are skipped, the tokens signed with them fail
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys = keys
+	p.keysFetchedAt = p.now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds the key by id, a token without a key id is checked with the only key of the provider
+func (p *OIDCProvider) lookupKey(kid string) (any, bool) {
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, res.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(v)
+}
+
+// jsonWebKey is a public key of the key set of the provider, only the rsa and the ec keys are read
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// claimBool reads the boolean claims some providers send as strings
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = claimBool(v)
+	case string:
+		*b = claimBool(v == "true")
+	default:
+		*b = false
+	}
+
+	return nil
+}
+
+// codeChallenge returns the S256 pkce challenge of the code verifier
+func codeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,332 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emrgen/unpost/internal/config"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	testClientID     = "unpost"
+	testClientSecret = "client secret"
+	testRedirectURL  = "https://unpost.test/callback"
+)
+
+// mockOIDCServer is an identity provider for the tests, it signs the id tokens of the identity with its own key
+type mockOIDCServer struct {
+	*httptest.Server
+	t   *testing.T
+	key *rsa.PrivateKey
+
+	mu sync.Mutex
+	// the identity signed into the next id tokens
+	subject       string
+	email         string
+	emailVerified bool
+	// audience overrides the audience of the id tokens when set
+	audience string
+	// codes are the issued authorization codes with the pkce challenge and the nonce of their login
+	codes map[string][2]string
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &mockOIDCServer{t: t, key: key, codes: map[string][2]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", server.discovery)
+	mux.HandleFunc("GET /authorize", server.authorize)
+	mux.HandleFunc("POST /token", server.token)
+	mux.HandleFunc("GET /jwks", server.jwks)
+	server.Server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// signIn sets the identity the next logins sign in as
+func (s *mockOIDCServer) signIn(subject, email string, emailVerified bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subject, s.email, s.emailVerified = subject, email, emailVerified
+}
+
+func (s *mockOIDCServer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                           s.URL,
+		"authorization_endpoint":           s.URL + "/authorize",
+		"token_endpoint":                   s.URL + "/token",
+		"jwks_uri":                         s.URL + "/jwks",
+		"code_challenge_methods_supported": []string{"S256"},
+	})
+}
+
+// authorize signs the user in at once and redirects back with a code
+func (s *mockOIDCServer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != testClientID || query.Get("redirect_uri") != testRedirectURL ||
+		query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code := uuid.NewString()
+	s.mu.Lock()
+	s.codes[code] = [2]string{query.Get("code_challenge"), query.Get("nonce")}
+	s.mu.Unlock()
+
+	redirect, _ := url.Parse(testRedirectURL)
+	redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *mockOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, _ := r.BasicAuth()
+	if clientID != url.QueryEscape(testClientID) || secret != url.QueryEscape(testClientSecret) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.FormValue("grant_type") != "authorization_code" || r.FormValue("redirect_uri") != testRedirectURL {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login, ok := s.codes[r.FormValue("code")]
+	delete(s.codes, r.FormValue("code"))
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != login[0] {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	audience := testClientID
+	if s.audience != "" {
+		audience = s.audience
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            s.subject,
+		"aud":            audience,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          login[1],
+		"email":          s.email,
+		"email_verified": s.emailVerified,
+	})
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		s.t.Error(err)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (s *mockOIDCServer) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newTestSocialLogin(t *testing.T, server *mockOIDCServer) (*SocialLogin, *LocalProvider) {
+	provider := newTestProvider(t)
+	oidc := NewOIDCProvider(config.OIDCProviderConfig{
+		Name:         "mock",
+		Issuer:       server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email"},
+	}, *server.Client())
+
+	return NewSocialLogin(provider.store.(*store.GormStore), provider, []*OIDCProvider{oidc}), provider
+}
+
+// authorize follows the authorization url like the browser and returns the code and the state of the redirect
+func authorize(t *testing.T, server *mockOIDCServer, authorizationURL string) (string, string) {
+	client := *server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	res, err := client.Get(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	location, err := res.Location()
+	if err != nil {
+		t.Fatalf("expected a redirect, got %s", res.Status)
+	}
+
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func socialLogin(t *testing.T, server *mockOIDCServer, social *SocialLogin) (*Session, error) {
+	ctx := context.Background()
+	authorizationURL, state, err := social.Start(ctx, "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, redirectState := authorize(t, server, authorizationURL)
+	if redirectState != state {
+		t.Fatalf("expected the state to come back, got %q", redirectState)
+	}
+
+	return social.Complete(ctx, "mock", state, code)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	ctx := context.Background()
+	server := newMockOIDCServer(t)
+	social, _ := newTestSocialLogin(t, server)
+	server.signIn("subject-1", "Jane@example.com", true)
+
+	session, err := socialLogin(t, server, social)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := session.User
+	if user.Email != "jane@example.com" || user.Name != "jane" || user.Role != model.UserRoleViewer || !user.EmailVerified {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	// the identity is linked, a changed email at the provider logs in the same account
+	server.signIn("subject-1", "jane@example.org", true)
+	session, err = socialLogin(t, server, social)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.User.ID != user.ID {
+		t.Errorf("expected the linked account %s, got %s", user.ID, session.User.ID)
+	}
+
+	if _, _, err := social.Start(ctx, "other"); !errors.Is(err, ErrUnknownOIDCProvider) {
+		t.Errorf("expected the provider to be unknown, got %v", err)
+	}
+}
+
+func TestOIDCLinkByEmail(t *testing.T) {
+	ctx := context.Background()
+	server := newMockOIDCServer(t)
+	social, provider := newTestSocialLogin(t, server)
+
+	user, err := provider.Signup(ctx, "jane@example.com", "password", "jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := provider.Login(ctx, "jane@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// anyone can claim an email the provider did not verify
+	server.signIn("subject-1", "jane@example.com", false)
+	if _, err := socialLogin(t, server, social); !errors.Is(err, ErrIdentityNotLinked) {
+		t.Errorf("expected the unverified email not to be linked, got %v", err)
+	}
+
+	server.signIn("subject-2", "jane@example.com", true)
+	session, err := socialLogin(t, server, social)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.User.ID != user.ID || !session.User.EmailVerified {
+		t.Errorf("expected the account %s to be linked and verified, got %+v", user.ID, session.User)
+	}
+
+	// the email of the account was never proven, the password and the sessions set before are dropped
+	if _, err := provider.Login(ctx, "jane@example.com", "password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected the password to be dropped, got %v", err)
+	}
+	if _, err := provider.Refresh(ctx, local.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected the session to end, got %v", err)
+	}
+
+	if err := provider.SetActive(ctx, uuid.MustParse(user.ID), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := socialLogin(t, server, social); !errors.Is(err, ErrUserDeactivated) {
+		t.Errorf("expected the deactivated account to be refused, got %v", err)
+	}
+}
+
+func TestOIDCRejected(t *testing.T) {
+	ctx := context.Background()
+	server := newMockOIDCServer(t)
+	social, _ := newTestSocialLogin(t, server)
+	server.signIn("subject-1", "jane@example.com", true)
+
+	authorizationURL, state, err := social.Start(ctx, "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := authorize(t, server, authorizationURL)
+	if _, err := social.Complete(ctx, "mock", state, "unknown"); !errors.Is(err, ErrInvalidAuthorization) {
+		t.Errorf("expected the unknown code to be rejected, got %v", err)
+	}
+	// the state is spent by the failed login
+	if _, err := social.Complete(ctx, "mock", state, code); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("expected the used state to be rejected, got %v", err)
+	}
+
+	authorizationURL, state, err = social.Start(ctx, "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ = authorize(t, server, authorizationURL)
+	social.now = func() time.Time { return time.Now().Add(2 * oidcLoginTTL) }
+	if _, err := social.Complete(ctx, "mock", state, code); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("expected the expired state to be rejected, got %v", err)
+	}
+	social.now = time.Now
+
+	server.mu.Lock()
+	server.audience = "another client"
+	server.mu.Unlock()
+	if _, err := socialLogin(t, server, social); !errors.Is(err, ErrInvalidAuthorization) {
+		t.Errorf("expected the id token of another client to be rejected, got %v", err)
+	}
+}
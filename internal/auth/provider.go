@@ -0,0 +1,108 @@
+// Package auth abstracts the identity provider of the accounts.
+// The supabase provider delegates to a supabase project, the local provider keeps the accounts
+// in the database and issues its own jwt tokens so that it runs without an external service.
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the email or the password is wrong
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrUserExists is returned when an account with the email already exists
+	ErrUserExists = errors.New("account already exists")
+	// ErrUserDeactivated is returned when a deactivated account logs in
+	ErrUserDeactivated = errors.New("account is deactivated")
+	// ErrUserNotFound is returned when the account does not exist
+	ErrUserNotFound = errors.New("account not found")
+	// ErrInvalidRefreshToken is returned for a malformed, unknown, revoked or expired refresh token
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrInvalidToken is returned for a malformed, unknown, used or expired password reset or verification token
+	ErrInvalidToken = errors.New("invalid or expired token")
+	// ErrUnknownOIDCProvider is returned for a social login with a provider that is not configured
+	ErrUnknownOIDCProvider = errors.New("unknown identity provider")
+	// ErrInvalidOIDCState is returned for a malformed, unknown, completed or expired social login state
+	ErrInvalidOIDCState = errors.New("invalid or expired login state")
+	// ErrInvalidAuthorization is returned when the authorization code or the id token of the identity provider is rejected
+	ErrInvalidAuthorization = errors.New("authorization rejected")
+	// ErrIdentityEmailMissing is returned when the identity provider does not share the email of the user
+	ErrIdentityEmailMissing = errors.New("identity provider returned no email")
+	// ErrIdentityNotLinked is returned when an account has the email of an identity the identity provider did not verify
+	ErrIdentityNotLinked = errors.New("account with the email exists, the identity provider did not verify the email")
+)
+
+// Provider manages the accounts and the sessions of the users
+type Provider interface {
+	// Signup creates an account with a password
+	Signup(ctx context.Context, email, password, name string) (*User, error)
+	// Login exchanges the email and the password for a session
+	Login(ctx context.Context, email, password string) (*Session, error)
+	// Logout ends the session of the access token
+	Logout(ctx context.Context, accessToken string) error
+	// Refresh exchanges a refresh token for a new session
+	Refresh(ctx context.Context, refreshToken string) (*Session, error)
+	// GetUser returns the account of the user
+	GetUser(ctx context.Context, userID uuid.UUID) (*User, error)
+	// SetRole stores the role of the user, the role is carried by the access tokens issued afterwards
+	SetRole(ctx context.Context, userID uuid.UUID, role model.UserRole) error
+	// SetActive deactivates or reactivates the account, a deactivated account cannot log in
+	SetActive(ctx context.Context, userID uuid.UUID, active bool) error
+	// DeleteUser removes the account, it undoes a signup the platform could not complete
+	DeleteUser(ctx context.Context, userID uuid.UUID) error
+	// RequestPasswordReset sends a password reset token to the email, an unknown email is not reported
+	RequestPasswordReset(ctx context.Context, email string) error
+	// ConfirmPasswordReset sets the password of the account the reset token was sent to
+	ConfirmPasswordReset(ctx context.Context, email, token, password string) error
+	// VerifyEmail confirms the email the verification token was sent to
+	VerifyEmail(ctx context.Context, email, token string) error
+	// ChangePassword replaces the password of the user once the current password is confirmed
+	ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string) error
+}
+
+// IdentityLinker logs the users of the external identity providers in, the identities are linked to the accounts
+type IdentityLinker interface {
+	// LoginWithIdentity starts a session for the account of the identity, the account is created or linked by email
+	LoginWithIdentity(ctx context.Context, identity *Identity) (*Session, error)
+}
+
+// Identity is the account of a user at an external identity provider
+type Identity struct {
+	// Provider is the name of the identity provider in the config
+	Provider string
+	// Subject is the id of the account at the identity provider
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// User is an account of the auth provider, the role is empty until one is assigned
+type User struct {
+	ID    string
+	Email string
+	Name  string
+	Role  model.UserRole
+	// Deactivated is true while the account cannot log in
+	Deactivated   bool
+	EmailVerified bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Session holds the tokens of a login
+type Session struct {
+	AccessToken  string
+	RefreshToken string
+	TokenType    string
+	// ExpiresIn is the lifetime of the access token in seconds
+	ExpiresIn int
+	// ExpiresAt is the unix time at which the access token expires
+	ExpiresAt int64
+	User      *User
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/x"
+	"github.com/google/uuid"
+)
+
+// oidcLoginTTL is how long the user has to sign in at the identity provider
+const oidcLoginTTL = 10 * time.Minute
+
+// SocialLogin signs the users in with the external identity providers. A login starts with a redirect to the
+// provider and completes when the client hands the code and the state of the callback back.
+// The state and the pkce verifier are kept in the database so that any server completes the login.
+type SocialLogin struct {
+	store     store.OIDCLoginStore
+	linker    IdentityLinker
+	providers map[string]*OIDCProvider
+	now       func() time.Time
+}
+
+// NewSocialLogin creates a new SocialLogin, the linker starts the sessions of the identities
+func NewSocialLogin(store store.OIDCLoginStore, linker IdentityLinker, providers []*OIDCProvider) *SocialLogin {
+	byName := make(map[string]*OIDCProvider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &SocialLogin{
+		store:     store,
+		linker:    linker,
+		providers: byName,
+		now:       time.Now,
+	}
+}
+
+// Start begins a login with the provider, it returns the page the user signs in at and the state it redirects back with
+func (s *SocialLogin) Start(ctx context.Context, providerName string) (authorizationURL string, state string, err error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownOIDCProvider
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := newSecret()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := newSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	login := &model.OIDCLogin{
+		ID:           uuid.NewString(),
+		Provider:     providerName,
+		StateHash:    x.HashSecret(secret),
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		ExpiresAt:    s.now().Add(oidcLoginTTL),
+	}
+	state = login.ID + "." + secret
+
+	authorizationURL, err = provider.AuthorizationURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.store.CreateOIDCLogin(ctx, login); err != nil {
+		return "", "", err
+	}
+
+	return authorizationURL, state, nil
+}
+
+// Complete redeems the code of the callback and starts a session for the user, the state is used once
+func (s *SocialLogin) Complete(ctx context.Context, providerName, state, code string) (*Session, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	id, secret, ok := splitToken(state)
+	if !ok {
+		return nil, ErrInvalidOIDCState
+	}
+	login, err := s.store.GetOIDCLogin(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(login.StateHash), []byte(x.HashSecret(secret))) != 1 {
+		return nil, ErrInvalidOIDCState
+	}
+	now := s.now()
+	if login.Provider != providerName || !login.Usable(now) {
+		return nil, ErrInvalidOIDCState
+	}
+
+	// the login is spent before the code is redeemed, a failed login starts over
+	login.UsedAt = &now
+	if err := s.store.UpdateOIDCLogin(ctx, login); err != nil {
+		return nil, err
+	}
+
+	identity, err := provider.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.linker.LoginWithIdentity(ctx, identity)
+}
@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/google/uuid"
+	supabase "github.com/supabase-community/auth-go"
+	"github.com/supabase-community/auth-go/types"
+)
+
+// SupabaseProvider delegates the accounts and the sessions to a supabase project.
+// The role of a user is kept in the app metadata, the admin calls use the service key.
+type SupabaseProvider struct {
+	client supabase.Client
+	admin  supabase.Client
+}
+
+var _ Provider = (*SupabaseProvider)(nil)
+
+// NewSupabaseProvider creates a new SupabaseProvider for the project
+func NewSupabaseProvider(projectRef, apiKey string, httpClient http.Client) *SupabaseProvider {
+	client := supabase.New(projectRef, apiKey).WithClient(httpClient)
+
+	return &SupabaseProvider{
+		client: client,
+		admin:  client.WithToken(apiKey),
+	}
+}
+
+func (p *SupabaseProvider) Signup(ctx context.Context, email, password, name string) (*User, error) {
+	signup, err := p.client.Signup(types.SignupRequest{
+		Email:    email,
+		Password: password,
+		Data:     map[string]any{"name": name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return userFromSupabase(&signup.User), nil
+}
+
+func (p *SupabaseProvider) Login(ctx context.Context, email, password string) (*Session, error) {
+	token, err := p.client.Token(types.TokenRequest{
+		GrantType: "password",
+		Email:     email,
+		Password:  password,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sessionFromSupabase(&token.Session), nil
+}
+
+func (p *SupabaseProvider) Logout(ctx context.Context, accessToken string) error {
+	return p.client.WithToken(accessToken).Logout()
+}
+
+func (p *SupabaseProvider) Refresh(ctx context.Context, refreshToken string) (*Session, error) {
+	token, err := p.client.Token(types.TokenRequest{
+		GrantType:    "refresh_token",
+		RefreshToken: refreshToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sessionFromSupabase(&token.Session), nil
+}
+
+func (p *SupabaseProvider) GetUser(ctx context.Context, userID uuid.UUID) (*User, error) {
+	user, err := p.admin.AdminGetUser(types.AdminGetUserRequest{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	return userFromSupabase(&user.User), nil
+}
+
+func (p *SupabaseProvider) SetRole(ctx context.Context, userID uuid.UUID, role model.UserRole) error {
+	_, err := p.admin.AdminUpdateUser(types.AdminUpdateUserRequest{
+		UserID: userID,
+		AppMetadata: map[string]any{
+			"role": string(role),
+		},
+	})
+
+	return err
+}
+
+func (p *SupabaseProvider) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	return p.admin.AdminDeleteUser(types.AdminDeleteUserRequest{UserID: userID})
+}
+
+// banDuration keeps a deactivated account banned, supabase has no deactivation of its own
+const banDuration = 100 * 365 * 24 * time.Hour
+
+func (p *SupabaseProvider) SetActive(ctx context.Context, userID uuid.UUID, active bool) error {
+	ban := types.BanDurationNone()
+	if !active {
+		ban = types.BanDurationTime(banDuration)
+	}
+	_, err := p.admin.AdminUpdateUser(types.AdminUpdateUserRequest{
+		UserID:      userID,
+		BanDuration: &ban,
+	})
+
+	return err
+}
+
+// RequestPasswordReset lets supabase send the recovery email
+func (p *SupabaseProvider) RequestPasswordReset(ctx context.Context, email string) error {
+	return p.client.Recover(types.RecoverRequest{Email: email})
+}
+
+// ConfirmPasswordReset exchanges the recovery token for a session of the user and sets the password with it,
+// supabase needs the email along with the token
+func (p *SupabaseProvider) ConfirmPasswordReset(ctx context.Context, email, token, password string) error {
+	if email == "" {
+		return ErrInvalidToken
+	}
+	verified, err := p.client.VerifyForUser(types.VerifyForUserRequest{
+		Type:  types.VerificationTypeRecovery,
+		Token: token,
+		Email: email,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	_, err = p.client.WithToken(verified.AccessToken).UpdateUser(types.UpdateUserRequest{Password: &password})
+	return err
+}
+
+func (p *SupabaseProvider) VerifyEmail(ctx context.Context, email, token string) error {
+	if email == "" {
+		return ErrInvalidToken
+	}
+	_, err := p.client.VerifyForUser(types.VerifyForUserRequest{
+		Type:  types.VerificationTypeSignup,
+		Token: token,
+		Email: email,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return nil
+}
+
+// ChangePassword confirms the current password with a login, supabase does not ask for it
+func (p *SupabaseProvider) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string) error {
+	user, err := p.admin.AdminGetUser(types.AdminGetUserRequest{UserID: userID})
+	if err != nil {
+		return err
+	}
+	if _, err := p.client.Token(types.TokenRequest{
+		GrantType: "password",
+		Email:     user.Email,
+		Password:  currentPassword,
+	}); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	_, err = p.admin.AdminUpdateUser(types.AdminUpdateUserRequest{
+		UserID:   userID,
+		Password: newPassword,
+	})
+	return err
+}
+
+func sessionFromSupabase(session *types.Session) *Session {
+	return &Session{
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		TokenType:    session.TokenType,
+		ExpiresIn:    session.ExpiresIn,
+		ExpiresAt:    session.ExpiresAt,
+		User:         userFromSupabase(&session.User),
+	}
+}
+
+func userFromSupabase(user *types.User) *User {
+	converted := &User{
+		ID:        user.ID.String(),
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		// the deactivated accounts are banned
+		Deactivated:   user.BannedUntil != nil && user.BannedUntil.After(time.Now()),
+		EmailVerified: user.EmailConfirmedAt != nil,
+	}
+	if role, ok := user.AppMetadata["role"].(string); ok {
+		converted.Role = model.UserRole(role)
+	}
+	if name, ok := user.UserMetadata["name"].(string); ok {
+		converted.Name = name
+	}
+
+	return converted
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// InvalidationBus broadcasts the cache keys to evict to every server replica.
+// The store publishes the keys of the changed objects and every replica evicts them from its caches.
+type InvalidationBus interface {
+	// Publish broadcasts the keys to all the subscribers
+	Publish(ctx context.Context, keys ...string) error
+	// Subscribe registers the handler for the published keys, the returned func removes the handler.
+	// An empty key list asks the handler to drop every entry, e.g. when notifications might have been missed.
+	Subscribe(handler func(keys []string)) func()
+	// Close stops the bus
+	Close() error
+}
+
+// Evictor is implemented by the caches that can be subscribed to an InvalidationBus
+type Evictor interface {
+	Delete(keys ...string)
+	Purge()
+}
+
+// Subscribe evicts the published keys from the cache
+func Subscribe(bus InvalidationBus, cache Evictor) func() {
+	return bus.Subscribe(func(keys []string) {
+		if len(keys) == 0 {
+			cache.Purge()
+			return
+		}
+		cache.Delete(keys...)
+	})
+}
+
+// PostKey returns the cache key of a post
+func PostKey(id string) string {
+	return "post:" + id
+}
+
+// PostSlugKey returns the cache key of a post by slug id
+func PostSlugKey(slugID string) string {
+	return "post:slug:" + slugID
+}
+
+// RenderedPostKey returns the cache key of the rendered content of a post version.
+// A new version gets a new key, so the rendered posts need no invalidation.
+func RenderedPostKey(id string, version int64) string {
+	return "post:rendered:" + id + ":" + strconv.FormatInt(version, 10)
+}
+
+// subscribers keeps the handlers registered on a bus
+type subscribers struct {
+	mu       sync.RWMutex
+	next     int
+	handlers map[int]func(keys []string)
+}
+
+func (s *subscribers) add(handler func(keys []string)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.handlers == nil {
+		s.handlers = make(map[int]func(keys []string))
+	}
+	id := s.next
+	s.next++
+	s.handlers[id] = handler
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.handlers, id)
+	}
+}
+
+func (s *subscribers) notify(keys []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, handler := range s.handlers {
+		handler(keys)
+	}
+}
+
+var _ InvalidationBus = (*LocalBus)(nil)
+
+// LocalBus is an in-process InvalidationBus, it is used when a single replica is running (e.g. with sqlite)
+type LocalBus struct {
+	subs subscribers
+}
+
+// NewLocalBus creates a new LocalBus
+func NewLocalBus() *LocalBus {
+	return &LocalBus{}
+}
+
+// Publish notifies the subscribers synchronously
+func (b *LocalBus) Publish(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	b.subs.notify(keys)
+	return nil
+}
+
+func (b *LocalBus) Subscribe(handler func(keys []string)) func() {
+	return b.subs.add(handler)
+}
+
+func (b *LocalBus) Close() error {
+	return nil
+}
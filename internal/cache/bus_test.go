@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLocalBusEvictsSubscribedCaches(t *testing.T) {
+	bus := NewLocalBus()
+	c := NewObjectCache[object](10, 0)
+	unsubscribe := Subscribe(bus, c)
+
+	c.Set(PostKey("a"), &object{name: "a"})
+	c.Set(PostKey("b"), &object{name: "b"})
+
+	if err := bus.Publish(context.Background(), PostKey("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(PostKey("a")); ok {
+		t.Error("expected a to be evicted")
+	}
+	if _, ok := c.Get(PostKey("b")); !ok {
+		t.Error("expected b to be cached")
+	}
+
+	unsubscribe()
+	if err := bus.Publish(context.Background(), PostKey("b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(PostKey("b")); !ok {
+		t.Error("expected b to be cached after unsubscribe")
+	}
+}
@@ -1,26 +1,278 @@
 package cache
 
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
 // ObjectCache is a cache for generic object
 // It is used to store published documents in memory to avoid fetching them from the database
 // When a document is published, it is stored in the cache and when a document is unpublished, it is removed from the cache
 // A document in a cache has a TTL to avoid storing it indefinitely
+//
+// The cache is bounded by size, the least recently used entry is evicted when the cache is full.
+// Concurrent loads of the same missing key are collapsed into a single call to the loader.
+// A nil *ObjectCache is valid and behaves as a disabled cache.
 type ObjectCache[T any] struct {
-	cache map[string]*T
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	items map[string]*list.Element
+	order *list.List // front is the most recently used entry
+	group singleflight.Group
+	now   func() time.Time
+	// generation is bumped by every eviction, the loads started before it do not store their result
+	generation uint64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type entry[T any] struct {
+	key       string
+	object    *T
+	expiresAt time.Time
+}
+
+// loadTimeout bounds a shared load, the load does not stop when the caller that started it gives up
+const loadTimeout = 30 * time.Second
+
+// LoadFunc loads an object missing from the cache.
+// The returned bool reports whether the object may be stored in the cache.
+type LoadFunc[T any] func(ctx context.Context) (*T, bool, error)
+
+// Stats is a snapshot of the cache counters
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+// HitRatio returns the ratio of hits to total lookups
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(total)
 }
 
-// NewObjectCache creates a new ObjectCache
-func NewObjectCache[T any]() *ObjectCache[T] {
+// NewObjectCache creates a new ObjectCache holding at most size objects, each for at most ttl
+// A zero ttl keeps the objects until they are evicted or deleted
+func NewObjectCache[T any](size int, ttl time.Duration) *ObjectCache[T] {
+	if size <= 0 {
+		size = 1
+	}
+
 	return &ObjectCache[T]{
-		cache: make(map[string]*T),
+		size:  size,
+		ttl:   ttl,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+		now:   time.Now,
 	}
 }
 
 // GetDocument returns a document from the cache
 func (c *ObjectCache[T]) GetDocument(key string) *T {
-	return c.cache[key]
+	object, _ := c.Get(key)
+	return object
 }
 
 // SetDocument sets a document in the cache
 func (c *ObjectCache[T]) SetDocument(key string, object *T) {
-	c.cache[key] = object
+	c.Set(key, object)
+}
+
+// Get returns the object stored for the key if it is present and not expired
+func (c *ObjectCache[T]) Get(key string) (*T, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	e := el.Value.(*entry[T])
+	if c.expired(e) {
+		c.removeElement(el)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	c.hits.Add(1)
+
+	return e.object, true
+}
+
+// Set stores the object for the key, evicting the least recently used entry if the cache is full
+func (c *ObjectCache[T]) Set(key string, object *T) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, object)
+}
+
+// set stores the object, the caller holds the lock
+func (c *ObjectCache[T]) set(key string, object *T) {
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.now().Add(c.ttl)
+	}
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[T])
+		e.object = object
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry[T]{key: key, object: object, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		c.removeElement(c.order.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// Delete removes the keys from the cache
+func (c *ObjectCache[T]) Delete(keys ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.removeElement(el)
+		}
+	}
+}
+
+// Purge removes every entry from the cache
+func (c *ObjectCache[T]) Purge() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// GetOrLoad returns the cached object for the key or loads it with the loader.
+// Concurrent calls for the same key share a single loader call, a call after an eviction starts a new one.
+// The shared load runs apart from the callers, a caller whose context ends stops waiting for it.
+func (c *ObjectCache[T]) GetOrLoad(ctx context.Context, key string, load LoadFunc[T]) (*T, error) {
+	if c == nil {
+		object, _, err := load(ctx)
+		return object, err
+	}
+
+	if object, ok := c.Get(key); ok {
+		return object, nil
+	}
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	flight := strconv.FormatUint(generation, 10) + "/" + key
+	results := c.group.DoChan(flight, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		object, keep, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			c.setUnlessEvicted(key, object, generation)
+		}
+
+		return object, nil
+	})
+
+	select {
+	case res := <-results:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*T), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// setUnlessEvicted stores a loaded object unless an eviction happened since the load started,
+// the object may be older than the change that caused the eviction
+func (c *ObjectCache[T]) setUnlessEvicted(key string, object *T, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return
+	}
+	c.set(key, object)
+}
+
+// Len returns the number of entries in the cache, including the expired ones not yet removed
+func (c *ObjectCache[T]) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// Stats returns a snapshot of the cache counters
+func (c *ObjectCache[T]) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      c.Len(),
+	}
+}
+
+func (c *ObjectCache[T]) expired(e *entry[T]) bool {
+	return !e.expiresAt.IsZero() && !c.now().Before(e.expiresAt)
+}
+
+func (c *ObjectCache[T]) removeElement(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*entry[T]).key)
 }
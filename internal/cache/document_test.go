@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type object struct {
+	name string
+}
+
+func TestObjectCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewObjectCache[object](2, 0)
+	c.Set("a", &object{name: "a"})
+	c.Set("b", &object{name: "b"})
+
+	// touch a so that b becomes the least recently used entry
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+
+	c.Set("c", &object{name: "c"})
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("expected a to be cached")
+	}
+	if got := c.Stats().Evictions; got != 1 {
+		t.Errorf("evictions = %d, want 1", got)
+	}
+}
+
+func TestObjectCacheExpiresEntries(t *testing.T) {
+	now := time.Now()
+	c := NewObjectCache[object](10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", &object{name: "a"})
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to be expired")
+	}
+	if c.Len() != 0 {
+		t.Errorf("len = %d, want 0", c.Len())
+	}
+}
+
+func TestObjectCacheGetOrLoadCollapsesConcurrentLoads(t *testing.T) {
+	c := NewObjectCache[object](10, time.Minute)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (*object, bool, error) {
+		calls.Add(1)
+		<-release
+		return &object{name: "a"}, true, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetOrLoad(context.Background(), "a", load); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("loader calls = %d, want 1", got)
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("expected a to be cached")
+	}
+}
+
+func TestObjectCacheGetOrLoadAfterEviction(t *testing.T) {
+	c := NewObjectCache[object](10, time.Minute)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.GetOrLoad(context.Background(), "a", func(ctx context.Context) (*object, bool, error) {
+			close(started)
+			<-release
+			return &object{name: "stale"}, true, nil
+		})
+	}()
+
+	<-started
+	c.Delete("a")
+
+	// the load started before the eviction is not shared with the later callers
+	obj, err := c.GetOrLoad(context.Background(), "a", func(ctx context.Context) (*object, bool, error) {
+		return &object{name: "fresh"}, true, nil
+	})
+	if err != nil || obj.name != "fresh" {
+		t.Fatalf("GetOrLoad = %v, %v, want fresh", obj, err)
+	}
+
+	close(release)
+	<-done
+	if obj, _ := c.Get("a"); obj == nil || obj.name != "fresh" {
+		t.Errorf("cached %v, want fresh", obj)
+	}
+
+	started, release, done = make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.GetOrLoad(context.Background(), "b", func(ctx context.Context) (*object, bool, error) {
+			close(started)
+			<-release
+			return &object{name: "stale"}, true, nil
+		})
+	}()
+	<-started
+	c.Delete("b")
+	close(release)
+	<-done
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected the load started before the eviction not to be cached")
+	}
+}
+
+func TestObjectCacheGetOrLoadCallerCancel(t *testing.T) {
+	c := NewObjectCache[object](10, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	first := make(chan error)
+	go func() {
+		_, err := c.GetOrLoad(ctx, "a", func(ctx context.Context) (*object, bool, error) {
+			close(started)
+			<-release
+			// the load is not cancelled with the caller that started it
+			if err := ctx.Err(); err != nil {
+				return nil, false, err
+			}
+			return &object{name: "a"}, true, nil
+		})
+		first <- err
+	}()
+
+	<-started
+	second := make(chan *object)
+	go func() {
+		obj, err := c.GetOrLoad(context.Background(), "a", func(ctx context.Context) (*object, bool, error) {
+			t.Error("expected the load to be shared")
+			return nil, false, nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		second <- obj
+	}()
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+	close(release)
+	if obj := <-second; obj == nil || obj.name != "a" {
+		t.Errorf("obj = %v, want a", obj)
+	}
+}
+
+func TestObjectCacheGetOrLoadSkipsUncacheable(t *testing.T) {
+	c := NewObjectCache[object](10, time.Minute)
+
+	obj, err := c.GetOrLoad(context.Background(), "a", func(ctx context.Context) (*object, bool, error) {
+		return &object{name: "a"}, false, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.name != "a" {
+		t.Errorf("name = %q, want a", obj.name)
+	}
+	if c.Len() != 0 {
+		t.Errorf("len = %d, want 0", c.Len())
+	}
+
+	loadErr := errors.New("load failed")
+	if _, err := c.GetOrLoad(context.Background(), "b", func(ctx context.Context) (*object, bool, error) { return nil, false, loadErr }); !errors.Is(err, loadErr) {
+		t.Errorf("err = %v, want %v", err, loadErr)
+	}
+}
+
+func TestNilObjectCacheIsDisabled(t *testing.T) {
+	var c *ObjectCache[object]
+
+	c.Set("a", &object{name: "a"})
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected nil cache to miss")
+	}
+
+	obj, err := c.GetOrLoad(context.Background(), "a", func(ctx context.Context) (*object, bool, error) {
+		return &object{name: "a"}, true, nil
+	})
+	if err != nil || obj == nil {
+		t.Fatalf("GetOrLoad = %v, %v", obj, err)
+	}
+	c.Delete("a")
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// InvalidationChannel is the postgres channel used to broadcast the invalidated keys
+	InvalidationChannel = "unstak_cache_invalidation"
+
+	// postgres limits a notification payload to 8000 bytes, the keys are sent in batches to stay below it
+	maxKeysPerNotification = 64
+	maxListenBackoff       = 30 * time.Second
+)
+
+var _ InvalidationBus = (*PostgresBus)(nil)
+
+// PostgresBus is an InvalidationBus backed by postgres LISTEN/NOTIFY.
+// Every replica listens on the same channel, so a key published by one replica is evicted by all of them.
+type PostgresBus struct {
+	db         *sql.DB
+	connString string
+	subs       subscribers
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+type invalidationPayload struct {
+	Keys []string `json:"keys"`
+}
+
+// NewPostgresBus creates a new PostgresBus, db is used to publish and connString to open the listening connection
+func NewPostgresBus(db *sql.DB, connString string) *PostgresBus {
+	return &PostgresBus{
+		db:         db,
+		connString: connString,
+	}
+}
+
+// Publish sends the keys with pg_notify
+func (b *PostgresBus) Publish(ctx context.Context, keys ...string) error {
+	for len(keys) > 0 {
+		n := min(len(keys), maxKeysPerNotification)
+		payload, err := json.Marshal(invalidationPayload{Keys: keys[:n]})
+		if err != nil {
+			return err
+		}
+
+		if _, err := b.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", InvalidationChannel, string(payload)); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+
+	return nil
+}
+
+func (b *PostgresBus) Subscribe(handler func(keys []string)) func() {
+	return b.subs.add(handler)
+}
+
+// Start listens for the notifications until the bus is closed.
+// The listening connection is reopened with a backoff when it is lost.
+func (b *PostgresBus) Start(ctx context.Context) {
+	ctx, b.cancel = context.WithCancel(ctx)
+	b.done = make(chan struct{})
+
+	go func() {
+		defer close(b.done)
+
+		backoff := time.Second
+		for {
+			err := b.listen(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			logrus.Errorf("cache invalidation listener stopped: %v, retrying in %v", err, backoff)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxListenBackoff)
+		}
+	}()
+}
+
+func (b *PostgresBus) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, b.connString)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+InvalidationChannel); err != nil {
+		return err
+	}
+
+	// a notification might have been missed while the connection was down, the subscribers drop everything
+	b.subs.notify(nil)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var payload invalidationPayload
+		if err := json.Unmarshal([]byte(notification.Payload), &payload); err != nil {
+			logrus.Errorf("invalid cache invalidation payload: %v", err)
+			continue
+		}
+
+		if len(payload.Keys) > 0 {
+			b.subs.notify(payload.Keys)
+		}
+	}
+}
+
+// Close stops the listener and waits for it to exit
+func (b *PostgresBus) Close() error {
+	if b.cancel == nil {
+		return nil
+	}
+
+	b.cancel()
+	<-b.done
+
+	return nil
+}
@@ -3,6 +3,10 @@ package config
 import (
 	"github.com/google/uuid"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // config package is used to load the configuration from the environment variables
@@ -37,11 +41,73 @@ type SupabaseConfig struct {
 	JwtSecret  string `json:"jwt"`
 }
 
+// AuthConfig selects the auth provider of the accounts, one of supabase or local.
+// The local provider keeps the accounts in the database and signs its own tokens, it needs no external service.
+type AuthConfig struct {
+	Provider string `json:"provider"`
+	// JwtSecret verifies the access tokens, the local provider signs them with it
+	JwtSecret       string        `json:"jwt_secret"`
+	AccessTokenTTL  time.Duration `json:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `json:"refresh_token_ttl"`
+	// RateLimit is the number of calls a client makes to each unauthenticated method per minute, 0 disables the limit
+	RateLimit int `json:"rate_limit"`
+	// OIDCProviders are the external identity providers the users sign in with, only the local provider links them
+	OIDCProviders []OIDCProviderConfig `json:"oidc_providers"`
+}
+
+// OIDCProviderConfig configures a social login, the endpoints of the provider are discovered from the issuer
+type OIDCProviderConfig struct {
+	// Name identifies the provider in the api, like google
+	Name         string `json:"name"`
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// RedirectURL is the page of the client that receives the code and completes the login
+	RedirectURL string   `json:"redirect_url"`
+	Scopes      []string `json:"scopes"`
+}
+
+const (
+	AuthProviderSupabase = "supabase"
+	AuthProviderLocal    = "local"
+)
+
+type CacheConfig struct {
+	Enabled bool          `json:"enabled"`
+	Size    int           `json:"size"`
+	TTL     time.Duration `json:"ttl"`
+}
+
+// TracingConfig selects the exporter of the OpenTelemetry traces.
+// The exporter is one of none, stdout, file or otlp, file and stdout work without a collector.
+type TracingConfig struct {
+	Exporter    string  `json:"exporter"`
+	Endpoint    string  `json:"endpoint"`
+	FilePath    string  `json:"file_path"`
+	SampleRatio float64 `json:"sample_ratio"`
+}
+
+// ServerConfig configures the lifecycle of the servers
+type ServerConfig struct {
+	// ShutdownTimeout is how long the in-flight requests are drained before the servers are stopped
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+}
+
+// DocumentConfig locates the document service, an empty address skips its readiness check
+type DocumentConfig struct {
+	Address string `json:"address"`
+}
+
 type Config struct {
 	Environment       string `json:"environment"`
 	DbConfig          DbConfig
 	ObjectStoreConfig ObjectStoreConfig
 	SupabaseConfig    SupabaseConfig
+	AuthConfig        AuthConfig
+	CacheConfig       CacheConfig
+	TracingConfig     TracingConfig
+	ServerConfig      ServerConfig
+	DocumentConfig    DocumentConfig
 	AdminUserID       uuid.UUID
 }
 
@@ -85,25 +151,111 @@ func LoadConfig() *Config {
 		panic("DB_CONNECTION_STRING is not set")
 	}
 
-	// load object supabase config
-	SupabaseProjectRef := os.Getenv("SUPABASE_PROJECT_REF")
-	if SupabaseProjectRef == "" {
-		panic("SUPABASE_PROJECT_REF is not set")
+	// load auth config
+	AuthProvider := os.Getenv("AUTH_PROVIDER")
+	if AuthProvider == "" {
+		AuthProvider = AuthProviderSupabase
 	}
 
-	SupabaseApiKey := os.Getenv("SUPABASE_API_KEY")
-	if SupabaseApiKey == "" {
-		panic("SUPABASE_API_KEY is not set")
+	var supabaseConfig SupabaseConfig
+	var AuthJwtSecret string
+	switch AuthProvider {
+	case AuthProviderSupabase:
+		supabaseConfig = loadSupabaseConfig()
+		AuthJwtSecret = supabaseConfig.JwtSecret
+	case AuthProviderLocal:
+		AuthJwtSecret = os.Getenv("AUTH_JWT_SECRET")
+		if AuthJwtSecret == "" {
+			panic("AUTH_JWT_SECRET is not set")
+		}
+	default:
+		panic("AUTH_PROVIDER must be one of supabase, local")
 	}
 
-	SupabaseJwtSecret := os.Getenv("SUPABASE_JWT_SECRET")
-	if SupabaseJwtSecret == "" {
-		panic("SUPABASE_JWT_SECRET is not set")
+	var err error
+	AccessTokenTTL := 15 * time.Minute
+	if ttl := os.Getenv("AUTH_ACCESS_TOKEN_TTL"); ttl != "" {
+		AccessTokenTTL, err = time.ParseDuration(ttl)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	RefreshTokenTTL := 30 * 24 * time.Hour
+	if ttl := os.Getenv("AUTH_REFRESH_TOKEN_TTL"); ttl != "" {
+		RefreshTokenTTL, err = time.ParseDuration(ttl)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	AuthRateLimit := 10
+	if limit := os.Getenv("AUTH_RATE_LIMIT"); limit != "" {
+		AuthRateLimit, err = strconv.Atoi(limit)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	OIDCProviders := loadOIDCProviders()
+	if len(OIDCProviders) > 0 && AuthProvider != AuthProviderLocal {
+		panic("OIDC_PROVIDERS requires the local auth provider")
 	}
 
-	AdminUserID, err := uuid.Parse(os.Getenv("ADMIN_USER_ID"))
-	if err != nil {
-		panic(err)
+	// the admin user of the deployments created before the owner setup becomes the owner, it is optional
+	var AdminUserID uuid.UUID
+	if id := os.Getenv("ADMIN_USER_ID"); id != "" {
+		AdminUserID, err = uuid.Parse(id)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// load cache config
+	CacheEnabled := os.Getenv("CACHE_ENABLED") == "true"
+
+	CacheSize := 1000
+	if size := os.Getenv("CACHE_SIZE"); size != "" {
+		CacheSize, err = strconv.Atoi(size)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	CacheTTL := 5 * time.Minute
+	if ttl := os.Getenv("CACHE_TTL"); ttl != "" {
+		CacheTTL, err = time.ParseDuration(ttl)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// load tracing config
+	TracingExporter := os.Getenv("TRACING_EXPORTER")
+	if TracingExporter == "" {
+		TracingExporter = "none"
+	}
+
+	TracingFilePath := os.Getenv("TRACING_FILE_PATH")
+	if TracingFilePath == "" {
+		TracingFilePath = ".tmp/traces.jsonl"
+	}
+
+	TracingSampleRatio := 1.0
+	if ratio := os.Getenv("TRACING_SAMPLE_RATIO"); ratio != "" {
+		TracingSampleRatio, err = strconv.ParseFloat(ratio, 64)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// load server config
+	ShutdownTimeout := 30 * time.Second
+	if timeout := os.Getenv("SHUTDOWN_TIMEOUT"); timeout != "" {
+		ShutdownTimeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			panic(err)
+		}
 	}
 
 	AppConfig = &Config{
@@ -112,13 +264,100 @@ func LoadConfig() *Config {
 			Type:             DbType,
 			ConnectionString: DbConnString,
 		},
-		SupabaseConfig: SupabaseConfig{
-			ProjectRef: SupabaseProjectRef,
-			ApiKey:     SupabaseApiKey,
-			JwtSecret:  SupabaseJwtSecret,
+		SupabaseConfig: supabaseConfig,
+		AuthConfig: AuthConfig{
+			Provider:        AuthProvider,
+			JwtSecret:       AuthJwtSecret,
+			AccessTokenTTL:  AccessTokenTTL,
+			RefreshTokenTTL: RefreshTokenTTL,
+			RateLimit:       AuthRateLimit,
+			OIDCProviders:   OIDCProviders,
+		},
+		CacheConfig: CacheConfig{
+			Enabled: CacheEnabled,
+			Size:    CacheSize,
+			TTL:     CacheTTL,
+		},
+		TracingConfig: TracingConfig{
+			Exporter:    TracingExporter,
+			Endpoint:    os.Getenv("TRACING_OTLP_ENDPOINT"),
+			FilePath:    TracingFilePath,
+			SampleRatio: TracingSampleRatio,
+		},
+		ServerConfig: ServerConfig{
+			ShutdownTimeout: ShutdownTimeout,
+		},
+		DocumentConfig: DocumentConfig{
+			Address: os.Getenv("DOCUMENT_SERVICE_ADDRESS"),
 		},
 		AdminUserID: AdminUserID,
 	}
 
 	return AppConfig
 }
+
+// loadSupabaseConfig loads the supabase config, it is only required by the supabase auth provider
+func loadSupabaseConfig() SupabaseConfig {
+	SupabaseProjectRef := os.Getenv("SUPABASE_PROJECT_REF")
+	if SupabaseProjectRef == "" {
+		panic("SUPABASE_PROJECT_REF is not set")
+	}
+
+	SupabaseApiKey := os.Getenv("SUPABASE_API_KEY")
+	if SupabaseApiKey == "" {
+		panic("SUPABASE_API_KEY is not set")
+	}
+
+	SupabaseJwtSecret := os.Getenv("SUPABASE_JWT_SECRET")
+	if SupabaseJwtSecret == "" {
+		panic("SUPABASE_JWT_SECRET is not set")
+	}
+
+	return SupabaseConfig{
+		ProjectRef: SupabaseProjectRef,
+		ApiKey:     SupabaseApiKey,
+		JwtSecret:  SupabaseJwtSecret,
+	}
+}
+
+// loadOIDCProviders loads the providers listed in OIDC_PROVIDERS, each one is configured by the variables
+// prefixed with OIDC_<NAME>_, like OIDC_GOOGLE_ISSUER
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if provider.Issuer == "" {
+			panic(prefix + "ISSUER is not set")
+		}
+		if provider.ClientID == "" {
+			panic(prefix + "CLIENT_ID is not set")
+		}
+		if provider.RedirectURL == "" {
+			panic(prefix + "REDIRECT_URL is not set")
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+		// the id token is only issued for the openid scope
+		if !slices.Contains(provider.Scopes, "openid") {
+			provider.Scopes = append([]string{"openid"}, provider.Scopes...)
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
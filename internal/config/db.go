@@ -13,14 +13,14 @@ func GetDb(config *Config) *gorm.DB {
 	var err error
 
 	if config.DbConfig.Type == "postgres" {
-		rdb, err = gorm.Open(postgres.Open(config.DbConfig.ConnectionString), &gorm.Config{})
+		rdb, err = gorm.Open(postgres.Open(config.DbConfig.ConnectionString), &gorm.Config{TranslateError: true})
 	} else {
 		filePath := os.Getenv("SQLITE_FILE_PATH")
 		if filePath == "" {
 			filePath = ".tmp/db/_document.db"
 		}
 
-		rdb, err = gorm.Open(sqlite.Open(filePath), &gorm.Config{})
+		rdb, err = gorm.Open(sqlite.Open(filePath), &gorm.Config{TranslateError: true})
 	}
 
 	if err != nil {
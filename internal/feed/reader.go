@@ -0,0 +1,113 @@
+package feed
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+)
+
+const (
+	defaultBatchSize = 100
+	// defaultGapTimeout is how long the holes in the sequence of a read batch are waited for before they are skipped.
+	// A hole is either a transaction that is not committed yet or a rolled back one.
+	defaultGapTimeout = 10 * time.Second
+)
+
+var ErrInvalidResumeToken = errors.New("invalid resume token")
+
+// Reader reads the outbox events in sequence order without skipping the events committed late.
+//
+// The sequence is allocated when an event is inserted, so a transaction that commits after a later one
+// leaves a temporary hole in the sequence. The reader stops before a hole until it is filled or
+// the gap timeout elapses, which makes resuming from the returned cursor gap free.
+type Reader struct {
+	store      store.OutboxStore
+	batchSize  int
+	gapTimeout time.Duration
+	now        func() time.Time
+	// gaps remembers when a missing sequence was first seen
+	gaps map[uint64]time.Time
+}
+
+// NewReader creates a new Reader
+func NewReader(store store.OutboxStore) *Reader {
+	return &Reader{
+		store:      store,
+		batchSize:  defaultBatchSize,
+		gapTimeout: defaultGapTimeout,
+		now:        time.Now,
+		gaps:       make(map[uint64]time.Time),
+	}
+}
+
+// BatchSize returns the maximum number of events returned by Next
+func (r *Reader) BatchSize() int {
+	return r.batchSize
+}
+
+// Next returns the events after the cursor and the cursor to resume from
+func (r *Reader) Next(ctx context.Context, cursor uint64) ([]*model.OutboxEvent, uint64, error) {
+	events, err := r.store.ListEventsAfter(ctx, cursor, r.batchSize)
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	// every hole of the batch is seen at once, so that the holes share one timeout instead of
+	// waiting for them one after the other
+	now := r.now()
+	last := cursor
+	for _, event := range events {
+		for expected := last + 1; expected < event.Seq; expected++ {
+			if _, ok := r.gaps[expected]; !ok {
+				r.gaps[expected] = now
+			}
+		}
+		last = event.Seq
+	}
+
+	ready := make([]*model.OutboxEvent, 0, len(events))
+	for _, event := range events {
+		for expected := cursor + 1; expected < event.Seq; expected++ {
+			// wait for the missing event, it might still be committed
+			if now.Sub(r.gaps[expected]) < r.gapTimeout {
+				return ready, cursor, nil
+			}
+			delete(r.gaps, expected)
+		}
+
+		delete(r.gaps, event.Seq)
+		ready = append(ready, event)
+		cursor = event.Seq
+	}
+
+	return ready, cursor, nil
+}
+
+// EncodeToken encodes the cursor into an opaque resume token
+func EncodeToken(cursor uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("seq:" + strconv.FormatUint(cursor, 10)))
+}
+
+// DecodeToken decodes a resume token created by EncodeToken
+func DecodeToken(token string) (uint64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidResumeToken
+	}
+
+	if len(raw) < 4 || string(raw[:4]) != "seq:" {
+		return 0, ErrInvalidResumeToken
+	}
+
+	cursor, err := strconv.ParseUint(string(raw[4:]), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidResumeToken
+	}
+
+	return cursor, nil
+}
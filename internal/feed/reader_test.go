@@ -0,0 +1,153 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+)
+
+type fakeOutbox struct {
+	events []*model.OutboxEvent
+}
+
+func (f *fakeOutbox) CreateOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakeOutbox) ListUndispatchedEvents(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	return nil, nil
+}
+
+func (f *fakeOutbox) MarkEventsDispatched(ctx context.Context, ids []string, at time.Time) error {
+	return nil
+}
+
+func (f *fakeOutbox) ListEventsAfter(ctx context.Context, afterSeq uint64, limit int) ([]*model.OutboxEvent, error) {
+	events := make([]*model.OutboxEvent, 0)
+	for _, event := range f.events {
+		if event.Seq > afterSeq && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (f *fakeOutbox) LatestEventSeq(ctx context.Context) (uint64, error) {
+	if len(f.events) == 0 {
+		return 0, nil
+	}
+
+	return f.events[len(f.events)-1].Seq, nil
+}
+
+func seqs(events []*model.OutboxEvent) []uint64 {
+	res := make([]uint64, 0, len(events))
+	for _, event := range events {
+		res = append(res, event.Seq)
+	}
+
+	return res
+}
+
+func TestReaderWaitsForLateCommits(t *testing.T) {
+	ctx := context.Background()
+	outbox := &fakeOutbox{}
+	// event 2 is not committed yet
+	outbox.events = []*model.OutboxEvent{{Seq: 1}, {Seq: 3}}
+
+	now := time.Now()
+	r := NewReader(outbox)
+	r.now = func() time.Time { return now }
+
+	events, cursor, err := r.Next(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := seqs(events); len(got) != 1 || got[0] != 1 || cursor != 1 {
+		t.Fatalf("events = %v, cursor = %d, want [1], 1", got, cursor)
+	}
+
+	// event 2 commits within the gap timeout
+	outbox.events = []*model.OutboxEvent{{Seq: 1}, {Seq: 2}, {Seq: 3}}
+	events, cursor, err = r.Next(ctx, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := seqs(events); len(got) != 2 || got[0] != 2 || got[1] != 3 || cursor != 3 {
+		t.Fatalf("events = %v, cursor = %d, want [2 3], 3", got, cursor)
+	}
+}
+
+func TestReaderSkipsRolledBackSequences(t *testing.T) {
+	ctx := context.Background()
+	outbox := &fakeOutbox{events: []*model.OutboxEvent{{Seq: 1}, {Seq: 3}}}
+
+	now := time.Now()
+	r := NewReader(outbox)
+	r.now = func() time.Time { return now }
+
+	_, cursor, err := r.Next(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != 1 {
+		t.Fatalf("cursor = %d, want 1", cursor)
+	}
+
+	now = now.Add(r.gapTimeout)
+	events, cursor, err := r.Next(ctx, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := seqs(events); len(got) != 1 || got[0] != 3 || cursor != 3 {
+		t.Fatalf("events = %v, cursor = %d, want [3], 3", got, cursor)
+	}
+}
+
+func TestReaderWaitsOnceForTheGapsOfABatch(t *testing.T) {
+	ctx := context.Background()
+	// events 2 and 4 were rolled back
+	outbox := &fakeOutbox{events: []*model.OutboxEvent{{Seq: 1}, {Seq: 3}, {Seq: 5}}}
+
+	now := time.Now()
+	r := NewReader(outbox)
+	r.now = func() time.Time { return now }
+
+	events, cursor, err := r.Next(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := seqs(events); len(got) != 1 || got[0] != 1 || cursor != 1 {
+		t.Fatalf("events = %v, cursor = %d, want [1], 1", got, cursor)
+	}
+
+	now = now.Add(r.gapTimeout)
+	events, cursor, err = r.Next(ctx, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := seqs(events); len(got) != 2 || got[0] != 3 || got[1] != 5 || cursor != 5 {
+		t.Fatalf("events = %v, cursor = %d, want [3 5], 5", got, cursor)
+	}
+	if len(r.gaps) != 0 {
+		t.Errorf("expected the skipped gaps to be forgotten, got %v", r.gaps)
+	}
+}
+
+func TestResumeToken(t *testing.T) {
+	cursor, err := DecodeToken(EncodeToken(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != 42 {
+		t.Errorf("cursor = %d, want 42", cursor)
+	}
+
+	if _, err := DecodeToken("not-a-token"); err != ErrInvalidResumeToken {
+		t.Errorf("err = %v, want %v", err, ErrInvalidResumeToken)
+	}
+}
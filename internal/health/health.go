@@ -0,0 +1,180 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusShutdown    = "shutting down"
+)
+
+// CheckFunc checks a dependency of the service, a non nil error makes the service not ready
+type CheckFunc func(ctx context.Context) error
+
+// Report is the result of the readiness checks
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Ready returns true when all the checks passed
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Checker runs the readiness checks of the service dependencies.
+// Once Shutdown is called the service reports not ready, so that the load balancers stop sending
+// new requests while the in-flight ones are drained.
+type Checker struct {
+	timeout      time.Duration
+	mu           sync.RWMutex
+	checks       map[string]CheckFunc
+	shuttingDown atomic.Bool
+}
+
+// NewChecker creates a new Checker, each check is cancelled after the timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]CheckFunc),
+	}
+}
+
+// Add registers a named check
+func (c *Checker) Add(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+}
+
+// Check runs the checks concurrently and reports the failed ones
+func (c *Checker) Check(ctx context.Context) Report {
+	if c.shuttingDown.Load() {
+		return Report{Status: StatusShutdown}
+	}
+
+	c.mu.RLock()
+	checks := make(map[string]CheckFunc, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	report := Report{Status: StatusOK, Checks: make(map[string]string, len(checks))}
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check CheckFunc) {
+			defer wg.Done()
+
+			result := StatusOK
+			if err := check(ctx); err != nil {
+				result = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result != StatusOK {
+				report.Status = StatusUnavailable
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// Shutdown marks the service as not ready
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// LivenessHandler reports that the process is up, it does not check the dependencies
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: StatusOK})
+	})
+}
+
+// ReadinessHandler reports whether the dependencies of the service are available
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context())
+
+		code := http.StatusOK
+		if !report.Ready() {
+			code = http.StatusServiceUnavailable
+		}
+		writeReport(w, code, report)
+	})
+}
+
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logrus.Errorf("error writing health report: %v", err)
+	}
+}
+
+// Watch runs the checks every interval and mirrors the result into the grpc health server until ctx is done
+func (c *Checker) Watch(ctx context.Context, server *health.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+		if report := c.Check(ctx); !report.Ready() {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			logrus.Warnf("service is not ready: %v", report.Checks)
+		}
+		server.SetServingStatus("", status)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GRPCCheck checks a downstream grpc service with the standard health service
+func GRPCCheck(conn grpc.ClientConnInterface) CheckFunc {
+	client := healthpb.NewHealthClient(conn)
+
+	return func(ctx context.Context) error {
+		res, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			return err
+		}
+		if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("service is %s", res.GetStatus())
+		}
+
+		return nil
+	}
+}
+
+// IsHealthMethod returns true for the methods of the grpc health service, they need no authentication
+func IsHealthMethod(fullMethod string) bool {
+	return fullMethod == healthpb.Health_Check_FullMethodName || fullMethod == healthpb.Health_Watch_FullMethodName
+}
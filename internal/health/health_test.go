@@ -0,0 +1,54 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	checker.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	checker.Add("document", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report := checker.Check(context.Background())
+	if report.Ready() {
+		t.Fatal("expected the service to be not ready")
+	}
+	if report.Checks["database"] != StatusOK || report.Checks["document"] != "connection refused" {
+		t.Errorf("unexpected checks: %v", report.Checks)
+	}
+
+	rec = httptest.NewRecorder()
+	checker.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	checker.Shutdown()
+
+	if report := checker.Check(context.Background()); report.Status != StatusShutdown {
+		t.Errorf("expected %q, got %q", StatusShutdown, report.Status)
+	}
+
+	// the process is still alive while it drains
+	rec := httptest.NewRecorder()
+	checker.LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected %d, got %d", http.StatusOK, rec.Code)
+	}
+}
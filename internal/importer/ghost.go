@@ -0,0 +1,169 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+)
+
+// Ghost reads the json export of a Ghost blog, Settings > Labs > Export in the admin
+type Ghost struct{}
+
+var _ Parser = Ghost{}
+
+// ghostExport is the export file, the newer versions wrap it in a db array
+type ghostExport struct {
+	DB   []ghostDB  `json:"db"`
+	Data *ghostData `json:"data"`
+}
+
+type ghostDB struct {
+	Data ghostData `json:"data"`
+}
+
+type ghostData struct {
+	Posts        []ghostPost    `json:"posts"`
+	Tags         []ghostTag     `json:"tags"`
+	PostsTags    []ghostPostTag `json:"posts_tags"`
+	Users        []ghostUser    `json:"users"`
+	PostsAuthors []ghostAuthor  `json:"posts_authors"`
+	Settings     []ghostSetting `json:"settings"`
+}
+
+type ghostPost struct {
+	ID            string     `json:"id"`
+	Title         string     `json:"title"`
+	Slug          string     `json:"slug"`
+	HTML          string     `json:"html"`
+	CustomExcerpt string     `json:"custom_excerpt"`
+	Status        string     `json:"status"`
+	Type          string     `json:"type"`
+	Page          any        `json:"page"`
+	PublishedAt   *time.Time `json:"published_at"`
+	AuthorID      string     `json:"author_id"`
+}
+
+type ghostTag struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type ghostPostTag struct {
+	PostID string `json:"post_id"`
+	TagID  string `json:"tag_id"`
+}
+
+type ghostUser struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type ghostAuthor struct {
+	PostID   string `json:"post_id"`
+	AuthorID string `json:"author_id"`
+}
+
+type ghostSetting struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+// Parse reads the posts and the pages of the export, the internal #tags are skipped
+func (Ghost) Parse(r io.Reader) (*Blog, error) {
+	var export ghostExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("invalid ghost export: %w", err)
+	}
+
+	data := export.Data
+	if len(export.DB) > 0 {
+		data = &export.DB[0].Data
+	}
+	if data == nil {
+		return nil, errors.New("invalid ghost export: no data")
+	}
+
+	blog := &Blog{Skipped: make(map[string]int)}
+	for _, setting := range data.Settings {
+		if site, ok := setting.Value.(string); ok && setting.Key == "url" {
+			blog.URL = site
+		}
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range data.Tags {
+		// the tags starting with # are internal, they are used for the theme only
+		if strings.HasPrefix(tag.Name, "#") {
+			blog.Skipped["internal tag"]++
+			continue
+		}
+		tags[tag.ID] = tag.Name
+	}
+	postTags := make(map[string][]string)
+	for _, postTag := range data.PostsTags {
+		name, ok := tags[postTag.TagID]
+		if !ok {
+			continue
+		}
+		postTags[postTag.PostID] = appendUnique(postTags[postTag.PostID], name)
+	}
+
+	users := make(map[string]string)
+	emails := make(map[string]string)
+	for _, user := range data.Users {
+		users[user.ID] = user.Name
+		emails[user.ID] = strings.TrimSpace(user.Email)
+	}
+	postAuthors := make(map[string][]string)
+	// the first author of a post is its primary author
+	primaryAuthors := make(map[string]string)
+	for _, author := range data.PostsAuthors {
+		if name, ok := users[author.AuthorID]; ok {
+			postAuthors[author.PostID] = appendUnique(postAuthors[author.PostID], name)
+			if _, ok := primaryAuthors[author.PostID]; !ok {
+				primaryAuthors[author.PostID] = author.AuthorID
+			}
+		}
+	}
+
+	for _, post := range data.Posts {
+		entry := &Entry{
+			SourceID:    post.ID,
+			Kind:        KindPost,
+			Title:       strings.TrimSpace(post.Title),
+			Slug:        post.Slug,
+			HTML:        post.HTML,
+			Excerpt:     strings.TrimSpace(post.CustomExcerpt),
+			Status:      model.PostStatusDraft,
+			Tags:        postTags[post.ID],
+			Authors:     postAuthors[post.ID],
+			AuthorEmail: emails[primaryAuthors[post.ID]],
+			Links:       []string{ghostURL + "/" + post.Slug + "/"},
+		}
+		// before ghost 4 the pages were flagged, the page field is a bool or 0 and 1 depending on the version
+		if post.Type == "page" || post.Page == true || post.Page == float64(1) {
+			entry.Kind = KindPage
+		}
+		if post.Status == "published" {
+			entry.Status = model.PostStatusPublished
+			entry.PublishedAt = post.PublishedAt
+		}
+		if len(entry.Authors) == 0 {
+			// the older exports have a single author on the post
+			if name, ok := users[post.AuthorID]; ok {
+				entry.Authors = []string{name}
+				entry.AuthorEmail = emails[post.AuthorID]
+			}
+		}
+
+		blog.Entries = append(blog.Entries, entry)
+	}
+
+	return blog, nil
+}
@@ -0,0 +1,300 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/render"
+	"github.com/google/uuid"
+)
+
+// DefaultLinkFormat is the link of an imported post, {slug} is replaced by the slug of the post
+const DefaultLinkFormat = "/posts/{slug}"
+
+// Kind tells the posts from the pages of a blog
+type Kind string
+
+const (
+	KindPost Kind = "post"
+	KindPage Kind = "page"
+)
+
+// Entry is a post or a page read from a blog export
+type Entry struct {
+	SourceID    string
+	Kind        Kind
+	Title       string
+	Slug        string
+	HTML        string
+	Excerpt     string
+	Status      model.PostStatus
+	PublishedAt *time.Time
+	Tags        []string
+	Authors     []string
+	// AuthorEmail is the email of the first author, it matches the author to an account
+	AuthorEmail string
+	// Links are the urls the entry was reachable at on the old blog
+	Links []string
+}
+
+// Blog is the content of a blog export
+type Blog struct {
+	// URL is the address of the old blog, the absolute links to it are rewritten
+	URL     string
+	Entries []*Entry
+	// Skipped counts the items of the export left out by their type
+	Skipped map[string]int
+}
+
+// Parser reads a blog export
+type Parser interface {
+	Parse(r io.Reader) (*Blog, error)
+}
+
+// NewParser returns the parser of the export format, wordpress or ghost
+func NewParser(format string) (Parser, error) {
+	switch strings.ToLower(format) {
+	case "wordpress", "wxr":
+		return WordPress{}, nil
+	case "ghost":
+		return Ghost{}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// Options configures the mapping of a blog into a space
+type Options struct {
+	SpaceID uuid.UUID
+	// SiteURL overrides the address of the old blog found in the export
+	SiteURL string
+	// LinkFormat is the new link of the posts, DefaultLinkFormat when empty
+	LinkFormat string
+	// Authors maps the lowercase emails to the ids of the accounts, the importer owns the posts of the other authors
+	Authors map[string]string
+}
+
+// Summary reports what an import creates, it is all a dry run prints
+type Summary struct {
+	Posts     int
+	Pages     int
+	Published int
+	Drafts    int
+	Tags      []string
+	// Authors counts the entries by author
+	Authors map[string]int
+	// Matched counts the entries whose author has an account
+	Matched        int
+	RewrittenLinks int
+	Skipped        map[string]int
+}
+
+// Plan is the mapped content of a blog
+type Plan struct {
+	Posts   []*model.Post
+	Summary Summary
+}
+
+// Map turns the entries of the blog into posts and tags of the space.
+// The slugs are deduplicated and the links between the entries are rewritten to the new slugs.
+func Map(blog *Blog, opts Options) (*Plan, error) {
+	siteURL := blog.URL
+	if opts.SiteURL != "" {
+		siteURL = opts.SiteURL
+	}
+	site, err := url.Parse(siteURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid site url %q: %w", siteURL, err)
+	}
+	linkFormat := opts.LinkFormat
+	if linkFormat == "" {
+		linkFormat = DefaultLinkFormat
+	}
+
+	plan := &Plan{
+		Summary: Summary{
+			Authors: make(map[string]int),
+			Skipped: make(map[string]int),
+		},
+	}
+	for kind, count := range blog.Skipped {
+		plan.Summary.Skipped[kind] = count
+	}
+
+	// the slugs are assigned first, so the links to the later entries can be rewritten
+	slugs := make(map[string]bool)
+	links := make(map[string]string)
+	entrySlugs := make([]string, len(blog.Entries))
+	for i, entry := range blog.Entries {
+		slug := render.Slugify(entry.Slug)
+		if slug == "" {
+			slug = render.Slugify(entry.Title)
+		}
+		if slug == "" {
+			slug = "untitled"
+		}
+		slug = uniqueSlug(slugs, slug)
+		entrySlugs[i] = slug
+
+		link := strings.ReplaceAll(linkFormat, "{slug}", slug)
+		for _, old := range entry.Links {
+			key, ok := linkKey(old, site)
+			// the first entry keeps a link shared by several entries
+			if _, taken := links[key]; ok && !taken {
+				links[key] = link
+			}
+		}
+	}
+
+	tags := make(map[string]*model.Tag)
+	for i, entry := range blog.Entries {
+		content, rewritten := rewriteLinks(entry.HTML, site, links)
+		plan.Summary.RewrittenLinks += rewritten
+
+		post := &model.Post{
+			ID:          uuid.NewString(),
+			SpaceID:     opts.SpaceID.String(),
+			Slug:        entrySlugs[i],
+			Title:       entry.Title,
+			Summary:     entry.Excerpt,
+			Content:     content,
+			Status:      entry.Status,
+			PublishedAt: entry.PublishedAt,
+			AuthorID:    opts.Authors[strings.ToLower(entry.AuthorEmail)],
+		}
+		if post.AuthorID != "" {
+			plan.Summary.Matched++
+		}
+		for _, name := range entry.Tags {
+			tag, ok := tags[name]
+			if !ok {
+				tag = &model.Tag{ID: uuid.NewString(), SpaceID: opts.SpaceID.String(), Name: name}
+				tags[name] = tag
+				plan.Summary.Tags = append(plan.Summary.Tags, name)
+			}
+			post.Tags = append(post.Tags, tag)
+		}
+		plan.Posts = append(plan.Posts, post)
+
+		if entry.Kind == KindPage {
+			plan.Summary.Pages++
+		} else {
+			plan.Summary.Posts++
+		}
+		if entry.Status == model.PostStatusPublished {
+			plan.Summary.Published++
+		} else {
+			plan.Summary.Drafts++
+		}
+		for _, author := range entry.Authors {
+			plan.Summary.Authors[author]++
+		}
+	}
+	sort.Strings(plan.Summary.Tags)
+
+	return plan, nil
+}
+
+// Document returns the post as a markdown document with front matter, the html content is kept as is
+func Document(post *model.Post) *render.Document {
+	doc := &render.Document{
+		FrontMatter: render.FrontMatter{
+			Title:   post.Title,
+			Slug:    post.Slug,
+			Summary: post.Summary,
+			Status:  string(post.Status),
+			Date:    post.PublishedAt,
+			Author:  post.AuthorID,
+		},
+		Body: post.Content,
+	}
+	for _, tag := range post.Tags {
+		doc.FrontMatter.Tags = append(doc.FrontMatter.Tags, tag.Name)
+	}
+
+	return doc
+}
+
+func uniqueSlug(taken map[string]bool, slug string) string {
+	candidate := slug
+	for n := 2; taken[candidate]; n++ {
+		candidate = slug + "-" + strconv.Itoa(n)
+	}
+	taken[candidate] = true
+
+	return candidate
+}
+
+// ghostURL is the placeholder of the site address in the ghost exports
+const ghostURL = "__GHOST_URL__"
+
+// linkKey normalizes a link to the old blog, the links to other sites are not keys
+func linkKey(raw string, site *url.URL) (string, bool) {
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), ghostURL)
+	u, err := url.Parse(raw)
+	if err != nil || u.Opaque != "" {
+		return "", false
+	}
+	if u.Host != "" && !sameHost(u.Host, site.Host) {
+		return "", false
+	}
+	if u.Host == "" && u.Scheme != "" {
+		return "", false
+	}
+	if u.Host == "" && !strings.HasPrefix(u.Path, "/") && u.RawQuery == "" {
+		// relative links cannot be resolved without the page they are on
+		return "", false
+	}
+
+	path := strings.TrimPrefix(u.Path, strings.TrimSuffix(site.Path, "/"))
+	key := strings.TrimSuffix(path, "/")
+	if query := u.Query(); query.Get("p") != "" || query.Get("page_id") != "" {
+		key += "?" + u.RawQuery
+	}
+	if key == "" {
+		return "", false
+	}
+
+	return key, true
+}
+
+func sameHost(a, b string) bool {
+	return strings.EqualFold(strings.TrimPrefix(a, "www."), strings.TrimPrefix(b, "www."))
+}
+
+var hrefPattern = regexp.MustCompile(`(href\s*=\s*)("[^"]*"|'[^']*')`)
+
+// rewriteLinks replaces the links to the imported entries, the fragments are kept
+func rewriteLinks(content string, site *url.URL, links map[string]string) (string, int) {
+	var rewritten int
+	content = hrefPattern.ReplaceAllStringFunc(content, func(attr string) string {
+		match := hrefPattern.FindStringSubmatch(attr)
+		quote := match[2][:1]
+		href := match[2][1 : len(match[2])-1]
+
+		target, fragment, _ := strings.Cut(href, "#")
+		key, ok := linkKey(target, site)
+		if !ok {
+			return attr
+		}
+		link, ok := links[key]
+		if !ok {
+			return attr
+		}
+		if fragment != "" {
+			link += "#" + fragment
+		}
+		rewritten++
+
+		return match[1] + quote + link + quote
+	})
+
+	return content, rewritten
+}
@@ -0,0 +1,164 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/google/uuid"
+)
+
+const wxrXML = `<?xml version="1.0" encoding="UTF-8" ?>
+<rss version="2.0"
+	xmlns:excerpt="http://wordpress.org/export/1.2/excerpt/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:atom="http://www.w3.org/2005/Atom"
+	xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<title>Blog</title>
+	<atom:link href="https://blog.example.com/feed/" rel="self" type="application/rss+xml" />
+	<link>https://blog.example.com</link>
+	<wp:author><wp:author_login>admin</wp:author_login><wp:author_display_name><![CDATA[Jane Doe]]></wp:author_display_name><wp:author_email>Jane@Example.com</wp:author_email></wp:author>
+	<item>
+		<title>Hello World</title>
+		<link>https://blog.example.com/2020/01/hello-world/</link>
+		<guid isPermaLink="false">https://blog.example.com/?p=1</guid>
+		<dc:creator><![CDATA[admin]]></dc:creator>
+		<content:encoded><![CDATA[<p>Read <a href="https://www.blog.example.com/about/#team">about us</a> and <a href="https://other.example.com/about/">elsewhere</a>.</p>]]></content:encoded>
+		<excerpt:encoded><![CDATA[The first post]]></excerpt:encoded>
+		<wp:post_id>1</wp:post_id>
+		<wp:post_date><![CDATA[2020-01-02 12:00:00]]></wp:post_date>
+		<wp:post_date_gmt><![CDATA[2020-01-02 10:00:00]]></wp:post_date_gmt>
+		<wp:post_name><![CDATA[hello-world]]></wp:post_name>
+		<wp:status><![CDATA[publish]]></wp:status>
+		<wp:post_type><![CDATA[post]]></wp:post_type>
+		<category domain="category" nicename="uncategorized"><![CDATA[Uncategorized]]></category>
+		<category domain="category" nicename="news"><![CDATA[News]]></category>
+		<category domain="post_tag" nicename="go"><![CDATA[Go]]></category>
+	</item>
+	<item>
+		<title>About</title>
+		<link>https://blog.example.com/about/</link>
+		<content:encoded><![CDATA[<a href="/?p=1">first post</a>]]></content:encoded>
+		<wp:post_id>2</wp:post_id>
+		<wp:post_date_gmt><![CDATA[0000-00-00 00:00:00]]></wp:post_date_gmt>
+		<wp:post_name><![CDATA[about]]></wp:post_name>
+		<wp:status><![CDATA[draft]]></wp:status>
+		<wp:post_type><![CDATA[page]]></wp:post_type>
+	</item>
+	<item>
+		<title>logo.png</title>
+		<wp:post_type><![CDATA[attachment]]></wp:post_type>
+	</item>
+</channel>
+</rss>`
+
+func TestWordPress(t *testing.T) {
+	blog, err := WordPress{}.Parse(strings.NewReader(wxrXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blog.URL != "https://blog.example.com" {
+		t.Errorf("unexpected site url %q", blog.URL)
+	}
+	if len(blog.Entries) != 2 || blog.Skipped["attachment"] != 1 {
+		t.Fatalf("unexpected entries %d and skipped %v", len(blog.Entries), blog.Skipped)
+	}
+
+	post := blog.Entries[0]
+	if post.Kind != KindPost || post.Status != model.PostStatusPublished || post.Excerpt != "The first post" {
+		t.Errorf("unexpected post %+v", post)
+	}
+	if strings.Join(post.Tags, ",") != "News,Go" || strings.Join(post.Authors, ",") != "Jane Doe" {
+		t.Errorf("unexpected tags %v or authors %v", post.Tags, post.Authors)
+	}
+	if post.PublishedAt == nil || !post.PublishedAt.Equal(time.Date(2020, 1, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected publish date %v", post.PublishedAt)
+	}
+
+	page := blog.Entries[1]
+	if page.Kind != KindPage || page.Status != model.PostStatusDraft || page.PublishedAt != nil {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	authorID := uuid.NewString()
+	plan, err := Map(blog, Options{SpaceID: uuid.New(), Authors: map[string]string{"jane@example.com": authorID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the page has no author, it is owned by the importer
+	if plan.Posts[0].AuthorID != authorID || plan.Posts[1].AuthorID != "" || plan.Summary.Matched != 1 {
+		t.Errorf("unexpected authors %q, %q", plan.Posts[0].AuthorID, plan.Posts[1].AuthorID)
+	}
+	if !strings.Contains(plan.Posts[0].Content, `href="/posts/about#team"`) || !strings.Contains(plan.Posts[0].Content, `href="https://other.example.com/about/"`) {
+		t.Errorf("unexpected post content %s", plan.Posts[0].Content)
+	}
+	if plan.Posts[1].Content != `<a href="/posts/hello-world">first post</a>` {
+		t.Errorf("unexpected page content %s", plan.Posts[1].Content)
+	}
+	if plan.Summary.RewrittenLinks != 2 || plan.Summary.Posts != 1 || plan.Summary.Pages != 1 || len(plan.Summary.Tags) != 2 {
+		t.Errorf("unexpected summary %+v", plan.Summary)
+	}
+}
+
+const ghostJSON = `{
+	"db": [{
+		"meta": {"version": "5.0.0"},
+		"data": {
+			"posts": [
+				{"id": "p1", "title": "Welcome", "slug": "welcome", "html": "<a href=\"__GHOST_URL__/welcome/\">self</a>", "status": "published", "type": "post", "published_at": "2021-05-01T08:00:00.000Z"},
+				{"id": "p2", "title": "Welcome", "slug": "welcome", "html": "", "status": "draft", "type": "page"}
+			],
+			"tags": [{"id": "t1", "name": "Getting Started"}, {"id": "t2", "name": "#hidden"}],
+			"posts_tags": [{"post_id": "p1", "tag_id": "t1"}, {"post_id": "p1", "tag_id": "t2"}],
+			"users": [{"id": "u1", "name": "Ghost Writer", "email": "writer@example.com"}],
+			"posts_authors": [{"post_id": "p1", "author_id": "u1"}]
+		}
+	}]
+}`
+
+func TestGhost(t *testing.T) {
+	blog, err := Ghost{}.Parse(strings.NewReader(ghostJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blog.Entries) != 2 || blog.Skipped["internal tag"] != 1 {
+		t.Fatalf("unexpected entries %d and skipped %v", len(blog.Entries), blog.Skipped)
+	}
+
+	post := blog.Entries[0]
+	if strings.Join(post.Tags, ",") != "Getting Started" || strings.Join(post.Authors, ",") != "Ghost Writer" || post.AuthorEmail != "writer@example.com" || post.PublishedAt == nil {
+		t.Errorf("unexpected post %+v", post)
+	}
+	if blog.Entries[1].Kind != KindPage {
+		t.Errorf("unexpected kind %s", blog.Entries[1].Kind)
+	}
+
+	authorID := uuid.NewString()
+	plan, err := Map(blog, Options{SpaceID: uuid.New(), LinkFormat: "/blog/{slug}", Authors: map[string]string{"writer@example.com": authorID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Posts[0].Slug != "welcome" || plan.Posts[1].Slug != "welcome-2" {
+		t.Errorf("slugs are not deduplicated: %s, %s", plan.Posts[0].Slug, plan.Posts[1].Slug)
+	}
+	if plan.Posts[0].Content != `<a href="/blog/welcome">self</a>` {
+		t.Errorf("unexpected content %s", plan.Posts[0].Content)
+	}
+
+	doc, err := Document(plan.Posts[0]).Markdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(doc), "- Getting Started") || !strings.Contains(string(doc), "author: "+authorID) {
+		t.Errorf("tags or author are missing from the front matter: %s", doc)
+	}
+}
+
+func TestNewParser(t *testing.T) {
+	if _, err := NewParser("blogger"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+)
+
+// WordPress reads the WXR export of a WordPress blog, Tools > Export in the admin
+type WordPress struct{}
+
+var _ Parser = WordPress{}
+
+type wxr struct {
+	Channel struct {
+		// the atom:link of the feed matches too, so the links are collected
+		Links       []string    `xml:"link"`
+		BaseBlogURL string      `xml:"base_blog_url"`
+		Authors     []wxrAuthor `xml:"author"`
+		Items       []wxrItem   `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrAuthor struct {
+	Login       string `xml:"author_login"`
+	DisplayName string `xml:"author_display_name"`
+	Email       string `xml:"author_email"`
+}
+
+type wxrItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        string        `xml:"guid"`
+	Creator     string        `xml:"creator"`
+	Encoded     []wxrEncoded  `xml:"encoded"`
+	PostID      string        `xml:"post_id"`
+	PostDate    string        `xml:"post_date"`
+	PostDateGMT string        `xml:"post_date_gmt"`
+	PostName    string        `xml:"post_name"`
+	Status      string        `xml:"status"`
+	PostType    string        `xml:"post_type"`
+	Categories  []wxrCategory `xml:"category"`
+}
+
+// wxrEncoded is either the content or the excerpt, they differ by namespace only
+type wxrEncoded struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+type wxrCategory struct {
+	Domain   string `xml:"domain,attr"`
+	Nicename string `xml:"nicename,attr"`
+	Name     string `xml:",chardata"`
+}
+
+// wxrDateLayout is the layout of the wp:post_date elements
+const wxrDateLayout = "2006-01-02 15:04:05"
+
+// Parse reads the posts and the pages of the export, the attachments, menus and revisions are skipped
+func (WordPress) Parse(r io.Reader) (*Blog, error) {
+	var doc wxr
+	decoder := xml.NewDecoder(r)
+	// the exports are utf-8, a few old ones declare other charsets they do not use
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid wordpress export: %w", err)
+	}
+
+	authors := make(map[string]string)
+	emails := make(map[string]string)
+	for _, author := range doc.Channel.Authors {
+		if author.DisplayName != "" {
+			authors[author.Login] = author.DisplayName
+		}
+		emails[author.Login] = strings.TrimSpace(author.Email)
+	}
+
+	blog := &Blog{URL: doc.Channel.BaseBlogURL, Skipped: make(map[string]int)}
+	for _, link := range doc.Channel.Links {
+		if blog.URL == "" {
+			blog.URL = strings.TrimSpace(link)
+		}
+	}
+
+	for _, item := range doc.Channel.Items {
+		var kind Kind
+		switch item.PostType {
+		case "post", "":
+			kind = KindPost
+		case "page":
+			kind = KindPage
+		default:
+			blog.Skipped[item.PostType]++
+			continue
+		}
+		if item.Status == "trash" || item.Status == "auto-draft" || item.Status == "inherit" {
+			blog.Skipped[item.Status]++
+			continue
+		}
+
+		entry := &Entry{
+			SourceID: item.PostID,
+			Kind:     kind,
+			Title:    strings.TrimSpace(item.Title),
+			Slug:     item.PostName,
+			Status:   model.PostStatusDraft,
+			Links:    []string{item.Link, item.GUID},
+		}
+		if item.Status == "publish" {
+			entry.Status = model.PostStatusPublished
+		}
+		if item.PostID != "" {
+			// the default permalinks work even when pretty permalinks are enabled
+			if kind == KindPage {
+				entry.Links = append(entry.Links, "/?page_id="+item.PostID)
+			} else {
+				entry.Links = append(entry.Links, "/?p="+item.PostID)
+			}
+		}
+		for _, encoded := range item.Encoded {
+			if strings.Contains(encoded.XMLName.Space, "excerpt") {
+				entry.Excerpt = strings.TrimSpace(encoded.Value)
+			} else {
+				entry.HTML = encoded.Value
+			}
+		}
+		if date := wxrDate(item.PostDateGMT, item.PostDate); date != nil && entry.Status == model.PostStatusPublished {
+			entry.PublishedAt = date
+		}
+		if item.Creator != "" {
+			name, ok := authors[item.Creator]
+			if !ok {
+				name = item.Creator
+			}
+			entry.Authors = []string{name}
+			entry.AuthorEmail = emails[item.Creator]
+		}
+		for _, category := range item.Categories {
+			name := strings.TrimSpace(category.Name)
+			// every post without a category is in the default one, it carries no meaning
+			if name == "" || category.Nicename == "uncategorized" {
+				continue
+			}
+			if category.Domain == "category" || category.Domain == "post_tag" {
+				entry.Tags = appendUnique(entry.Tags, name)
+			}
+		}
+
+		blog.Entries = append(blog.Entries, entry)
+	}
+
+	return blog, nil
+}
+
+// wxrDate parses the gmt date, the local date is used when the gmt date is not set
+func wxrDate(gmt, local string) *time.Time {
+	if date, err := time.Parse(wxrDateLayout, gmt); err == nil && date.Year() > 1 {
+		return &date
+	}
+	if date, err := time.Parse(wxrDateLayout, local); err == nil && date.Year() > 1 {
+		return &date
+	}
+
+	return nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+
+	return append(values, value)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/emrgen/unpost/internal/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterDB exposes the connection pool stats of the database
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterCache exposes the stats of a cache, stats is read on every scrape
+func RegisterCache(name string, stats func() cache.Stats) error {
+	return Registry.Register(newCacheCollector(name, stats))
+}
+
+// cacheCollector reads the cache counters on scrape instead of mirroring every hit into a prometheus counter
+type cacheCollector struct {
+	stats     func() cache.Stats
+	hits      *prometheus.Desc
+	misses    *prometheus.Desc
+	evictions *prometheus.Desc
+	size      *prometheus.Desc
+	hitRatio  *prometheus.Desc
+}
+
+func newCacheCollector(name string, stats func() cache.Stats) *cacheCollector {
+	labels := prometheus.Labels{"cache": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", metric), help, nil, labels)
+	}
+
+	return &cacheCollector{
+		stats:     stats,
+		hits:      desc("hits_total", "Number of the cache lookups that found the object."),
+		misses:    desc("misses_total", "Number of the cache lookups that missed the object."),
+		evictions: desc("evictions_total", "Number of the objects evicted to make room for new ones."),
+		size:      desc("size", "Number of the objects in the cache."),
+		hitRatio:  desc("hit_ratio", "Ratio of the cache hits to all lookups."),
+	}
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.evictions
+	ch <- c.size
+	ch <- c.hitRatio
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+	ch <- prometheus.MustNewConstMetric(c.hitRatio, prometheus.GaugeValue, stats.HitRatio())
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor counts the gRPC calls and observes their latency
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeRPC(info.FullMethod, start, err)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor counts the streaming gRPC calls and observes how long the streams were open
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeRPC(info.FullMethod, start, err)
+
+		return err
+	}
+}
+
+func observeRPC(method string, start time.Time, err error) {
+	grpcRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	grpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+// GatewayMiddleware counts the gateway requests by the route pattern, the raw path is not used as a label
+// to keep the number of the series bounded
+func GatewayMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next(recorder, r, pathParams)
+
+		route := "unknown"
+		if pattern, ok := runtime.HTTPPattern(r.Context()); ok {
+			route = pattern.String()
+		}
+
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(recorder.status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// statusRecorder remembers the status code written by the gateway
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush keeps the server-sent event streams working through the recorder
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "unstak"
+
+// Registry holds the collectors of the service, it is served by Handler
+var Registry = prometheus.NewRegistry()
+
+var (
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Number of the gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+
+	grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the gRPC calls by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of the gateway http requests by route and status code.",
+	}, []string{"method", "route", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the gateway http requests by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	jobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "runs_total",
+		Help:      "Number of the background job runs by job and result.",
+	}, []string{"job", "result"})
+
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "run_duration_seconds",
+		Help:      "Duration of the background job runs.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"job"})
+
+	outboxEvents = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "events_dispatched_total",
+		Help:      "Number of the outbox events fanned out to the webhooks.",
+	})
+
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "webhook_deliveries_total",
+		Help:      "Number of the webhook delivery attempts by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		grpcRequests,
+		grpcDuration,
+		httpRequests,
+		httpDuration,
+		jobRuns,
+		jobDuration,
+		outboxEvents,
+		webhookDeliveries,
+	)
+}
+
+// Handler serves the collected metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveJob records a run of a background job started at start, a non nil err counts as a failed run
+func ObserveJob(job string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+
+	jobRuns.WithLabelValues(job, result).Inc()
+	jobDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+}
+
+// ObserveOutboxEvents records the number of the outbox events fanned out to the webhooks
+func ObserveOutboxEvents(count int) {
+	outboxEvents.Add(float64(count))
+}
+
+// ObserveWebhookDelivery records a webhook delivery attempt, the result is delivered, retry or failed
+func ObserveWebhookDelivery(result string) {
+	webhookDeliveries.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emrgen/unpost/internal/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveJob(t *testing.T) {
+	ObserveJob("test_job", time.Now(), nil)
+	ObserveJob("test_job", time.Now(), nil)
+	ObserveJob("test_job", time.Now(), errors.New("failed"))
+
+	expected := `
+# HELP unstak_job_runs_total Number of the background job runs by job and result.
+# TYPE unstak_job_runs_total counter
+unstak_job_runs_total{job="test_job",result="error"} 1
+unstak_job_runs_total{job="test_job",result="ok"} 2
+`
+	if err := testutil.GatherAndCompare(Registry, strings.NewReader(expected), "unstak_job_runs_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCacheCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newCacheCollector("post", func() cache.Stats {
+		return cache.Stats{Hits: 3, Misses: 1, Evictions: 2, Size: 5}
+	}))
+
+	expected := `
+# HELP unstak_cache_hit_ratio Ratio of the cache hits to all lookups.
+# TYPE unstak_cache_hit_ratio gauge
+unstak_cache_hit_ratio{cache="post"} 0.75
+# HELP unstak_cache_hits_total Number of the cache lookups that found the object.
+# TYPE unstak_cache_hits_total counter
+unstak_cache_hits_total{cache="post"} 3
+# HELP unstak_cache_size Number of the objects in the cache.
+# TYPE unstak_cache_size gauge
+unstak_cache_size{cache="post"} 5
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "unstak_cache_hit_ratio", "unstak_cache_hits_total", "unstak_cache_size"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,384 @@
+package migrate
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// migrationsFS holds the migrations of every dialect, migrations/<dialect>/<version>_<name>.<up|down>.sql
+//
+//go:embed migrations
+var migrationsFS embed.FS
+
+// lockKey is the postgres advisory lock held while a migration runs
+const lockKey = 7_311_424_522
+
+var (
+	// ErrSchemaBehind is returned when the database misses some of the migrations of the binary
+	ErrSchemaBehind = errors.New("database schema is behind")
+	// ErrUnknownMigration is returned when the database has a migration that is not part of the binary
+	ErrUnknownMigration = errors.New("unknown migration")
+
+	fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+)
+
+// Migration is a versioned change of the schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a migration with the time it was applied, AppliedAt is nil for the pending migrations
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Unknown is true for the migrations applied by a newer binary
+	Unknown bool
+}
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"not null"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Load returns the embedded migrations of the dialect ordered by version
+func Load(dialect string) ([]Migration, error) {
+	return load(migrationsFS, path.Join("migrations", dialect))
+}
+
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %s: %w", path.Base(dir), err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrator applies the migrations of the database dialect.
+// Each migration runs in its own transaction together with its schema_migrations row, the transaction
+// holds a lock so that the replicas starting at the same time do not apply a migration twice.
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+}
+
+// New creates a migrator for the dialect of the database
+func New(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies up to steps pending migrations, all of them when steps is not positive
+func (m *Migrator) Up(ctx context.Context, steps int) ([]Migration, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for steps <= 0 || len(done) < steps {
+		var next *Migration
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := m.lock(tx); err != nil {
+				return err
+			}
+
+			// the applied migrations are read under the lock, another replica may have just applied one
+			applied, err := m.applied(tx)
+			if err != nil {
+				return err
+			}
+			for i := range m.migrations {
+				if _, ok := applied[m.migrations[i].Version]; !ok {
+					next = &m.migrations[i]
+					break
+				}
+			}
+			if next == nil {
+				return nil
+			}
+
+			logrus.Infof("applying migration %d_%s", next.Version, next.Name)
+			if err := tx.Exec(next.Up).Error; err != nil {
+				return fmt.Errorf("migration %d_%s: %w", next.Version, next.Name, err)
+			}
+
+			return tx.Create(&schemaMigration{Version: next.Version, Name: next.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, err
+		}
+		if next == nil {
+			break
+		}
+		done = append(done, *next)
+	}
+
+	return done, nil
+}
+
+// Down reverts up to steps of the latest applied migrations, all of them when steps is not positive
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for steps <= 0 || len(done) < steps {
+		var last *Migration
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := m.lock(tx); err != nil {
+				return err
+			}
+
+			var row schemaMigration
+			err := tx.Order("version DESC").Limit(1).Find(&row).Error
+			if err != nil || row.Version == 0 {
+				return err
+			}
+
+			last = m.find(row.Version)
+			if last == nil {
+				return fmt.Errorf("%w: %d_%s is applied, revert it with the binary that added it", ErrUnknownMigration, row.Version, row.Name)
+			}
+			if strings.TrimSpace(last.Down) == "" {
+				return fmt.Errorf("migration %d_%s has no down script", last.Version, last.Name)
+			}
+
+			logrus.Infof("reverting migration %d_%s", last.Version, last.Name)
+			if err := tx.Exec(last.Down).Error; err != nil {
+				return fmt.Errorf("migration %d_%s: %w", last.Version, last.Name, err)
+			}
+
+			return tx.Delete(&schemaMigration{}, row.Version).Error
+		})
+		if err != nil {
+			return done, err
+		}
+		if last == nil {
+			break
+		}
+		done = append(done, *last)
+	}
+
+	return done, nil
+}
+
+// Status lists the known and the applied migrations ordered by version
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		statuses = append(statuses, MigrationStatus{Version: row.Version, Name: row.Name, AppliedAt: &row.AppliedAt, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// Check returns ErrSchemaBehind when some of the migrations are not applied.
+// The migrations applied by a newer binary are allowed, so that the replicas can be rolled back.
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, fmt.Sprintf("%d_%s", status.Version, status.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w, pending migrations %s: run `unstak db migrate up`", ErrSchemaBehind, strings.Join(pending, ", "))
+	}
+
+	return nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	return m.db.WithContext(ctx).Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error
+}
+
+// lock serializes the migrators until the end of the transaction
+func (m *Migrator) lock(tx *gorm.DB) error {
+	if m.dialect == "postgres" {
+		return tx.Exec("SELECT pg_advisory_xact_lock(?)", lockKey).Error
+	}
+
+	// sqlite takes the write lock of the database file on the first write of the transaction,
+	// the other migrators wait for it in the busy timeout
+	return tx.Exec("DELETE FROM schema_migrations WHERE version < 0").Error
+}
+
+func (m *Migrator) applied(tx *gorm.DB) (map[int64]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := tx.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+// Create adds the empty up and down scripts of a new migration for every dialect found in dir,
+// the version follows the latest migration of all the dialects
+func Create(dir, name string) ([]string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name is required")
+	}
+
+	dialects, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var version int64
+	var dirs []string
+	for _, dialect := range dialects {
+		if !dialect.IsDir() {
+			continue
+		}
+		dirs = append(dirs, filepath.Join(dir, dialect.Name()))
+
+		migrations, err := load(os.DirFS(dir), dialect.Name())
+		if err != nil {
+			return nil, err
+		}
+		if n := len(migrations); n > 0 && migrations[n-1].Version > version {
+			version = migrations[n-1].Version
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no dialect directories in %s", dir)
+	}
+	version++
+
+	var files []string
+	for _, dialectDir := range dirs {
+		for _, direction := range []string{"up", "down"} {
+			file := filepath.Join(dialectDir, fmt.Sprintf("%06d_%s.%s.sql", version, name, direction))
+			content := fmt.Sprintf("-- %s migration %06d_%s for %s\n", direction, version, name, filepath.Base(dialectDir))
+			if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+				return files, err
+			}
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
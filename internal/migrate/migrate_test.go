@@ -0,0 +1,145 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emrgen/unpost/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "unpost.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+	migrator, err := New(openDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.Check(ctx); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("expected ErrSchemaBehind, got %v", err)
+	}
+
+	applied, err := migrator.Up(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Errorf("expected %d applied migrations, got %d", len(migrator.migrations), len(applied))
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second run has nothing to apply
+	applied, err = migrator.Up(ctx, 0)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("expected no migration, got %v, %v", applied, err)
+	}
+
+	for _, table := range []any{&model.Post{}, &model.Reaction{}, &model.PlatformTag{}, &model.NewsLetter{}, &model.User{}} {
+		if !migrator.db.Migrator().HasTable(table) {
+			t.Errorf("table of %T is missing", table)
+		}
+	}
+
+	reverted, err := migrator.Down(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(migrator.migrations) {
+		t.Errorf("expected %d reverted migrations, got %d", len(migrator.migrations), len(reverted))
+	}
+	if migrator.db.Migrator().HasTable(&model.Post{}) {
+		t.Error("posts table is not dropped")
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			t.Errorf("migration %d is still applied", status.Version)
+		}
+	}
+}
+
+func TestBaselineAutoMigrated(t *testing.T) {
+	db := openDB(t)
+	if err := db.AutoMigrate(&model.Post{}, &model.Tag{}, &model.Webhook{}); err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the baseline matches the databases created by AutoMigrate, the later migrations alter that schema
+	if _, err := migrator.Up(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDialectsInSync(t *testing.T) {
+	postgres, err := Load("postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := Load("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("postgres has %d migrations, sqlite has %d", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+			t.Errorf("migration %d_%s has no sqlite counterpart", postgres[i].Version, postgres[i].Name)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	for _, dialect := range []string{"postgres", "sqlite"} {
+		if err := os.MkdirAll(filepath.Join(dir, dialect), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, dialect, "000003_init.up.sql"), []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := Create(dir, "Add post Slug index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("expected 4 files, got %v", files)
+	}
+	if filepath.Base(files[0]) != "000004_add_post_slug_index.up.sql" {
+		t.Errorf("unexpected file name %s", files[0])
+	}
+
+	migrations, err := load(os.DirFS(dir), "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[1].Version != 4 {
+		t.Errorf("unexpected migrations %v", migrations)
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE "posts" DROP COLUMN IF EXISTS "published_at";
@@ -0,0 +1,3 @@
+-- the publish date of the posts, set when a post is published or imported with a date
+
+ALTER TABLE "posts" ADD COLUMN IF NOT EXISTS "published_at" timestamptz;
@@ -0,0 +1,12 @@
+-- the curation of the platform tags and the mapping of the space tags onto them,
+-- the existing platform tags were created by the admins and stay approved
+
+ALTER TABLE "platform_tags" ADD COLUMN IF NOT EXISTS "description" text;
+ALTER TABLE "platform_tags" ADD COLUMN IF NOT EXISTS "status" text NOT NULL DEFAULT 'approved';
+ALTER TABLE "platform_tags" ADD COLUMN IF NOT EXISTS "proposed_by_id" text;
+ALTER TABLE "platform_tags" ADD COLUMN IF NOT EXISTS "reviewed_by_id" text;
+ALTER TABLE "platform_tags" ADD COLUMN IF NOT EXISTS "reviewed_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_platform_tags_status" ON "platform_tags"("status");
+
+ALTER TABLE "tags" ADD COLUMN IF NOT EXISTS "platform_tag_id" text REFERENCES "platform_tags"("id") ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS "idx_tags_platform_tag_id" ON "tags"("platform_tag_id");
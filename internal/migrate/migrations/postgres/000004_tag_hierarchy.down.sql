@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "idx_tags_parent_id";
+ALTER TABLE "tags" DROP COLUMN IF EXISTS "color";
+ALTER TABLE "tags" DROP COLUMN IF EXISTS "description";
+ALTER TABLE "tags" DROP COLUMN IF EXISTS "parent_id";
@@ -0,0 +1,6 @@
+-- the nesting of the tags, their descriptions and colors
+
+ALTER TABLE "tags" ADD COLUMN IF NOT EXISTS "parent_id" text REFERENCES "tags"("id") ON DELETE SET NULL;
+ALTER TABLE "tags" ADD COLUMN IF NOT EXISTS "description" text;
+ALTER TABLE "tags" ADD COLUMN IF NOT EXISTS "color" text;
+CREATE INDEX IF NOT EXISTS "idx_tags_parent_id" ON "tags"("parent_id");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "preview_accesses";
+DROP TABLE IF EXISTS "preview_links";
@@ -0,0 +1,7 @@
+-- the shareable preview links of the unpublished posts and the audit of their reads
+
+CREATE TABLE IF NOT EXISTS "preview_links" ("id" text,"post_id" text NOT NULL,"space_id" text NOT NULL,"created_by_id" text NOT NULL,"expires_at" timestamptz NOT NULL,"revoked_at" timestamptz,"revoked_by_id" text,"created_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_preview_links_post_id" ON "preview_links"("post_id");
+
+CREATE TABLE IF NOT EXISTS "preview_accesses" ("id" text,"preview_link_id" text NOT NULL,"post_id" text NOT NULL,"user_id" text,"remote_addr" text,"user_agent" text,"accessed_at" timestamptz NOT NULL,PRIMARY KEY ("id"),CONSTRAINT "fk_preview_accesses_preview_link" FOREIGN KEY ("preview_link_id") REFERENCES "preview_links"("id") ON DELETE CASCADE);
+CREATE INDEX IF NOT EXISTS "idx_preview_accesses_preview_link_id" ON "preview_accesses"("preview_link_id");
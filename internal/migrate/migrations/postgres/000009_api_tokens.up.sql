@@ -0,0 +1,5 @@
+-- the scoped api tokens of the spaces, only the hashes of the secrets are stored
+
+CREATE TABLE IF NOT EXISTS "api_tokens" ("id" text,"space_id" text NOT NULL,"name" text NOT NULL,"secret_hash" text NOT NULL,"scopes" text NOT NULL,"role" text NOT NULL,"created_by_id" text NOT NULL,"expires_at" timestamptz,"last_used_at" timestamptz,"revoked_at" timestamptz,"created_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_api_tokens_space_id" ON "api_tokens"("space_id");
+CREATE INDEX IF NOT EXISTS "idx_api_tokens_created_by_id" ON "api_tokens"("created_by_id");
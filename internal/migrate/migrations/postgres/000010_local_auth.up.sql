@@ -0,0 +1,6 @@
+-- the accounts and the sessions of the local auth provider
+
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "password_hash" text NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users"("email");
+CREATE TABLE IF NOT EXISTS "auth_sessions" ("id" text,"user_id" text NOT NULL,"refresh_token_hash" text NOT NULL,"expires_at" timestamptz,"revoked_at" timestamptz,"created_at" timestamptz,"updated_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_auth_sessions_user_id" ON "auth_sessions"("user_id");
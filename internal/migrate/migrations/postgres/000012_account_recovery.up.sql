@@ -0,0 +1,5 @@
+-- the verified emails and the one-time tokens of the local auth provider
+
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "email_verified_at" timestamptz;
+CREATE TABLE IF NOT EXISTS "verification_tokens" ("id" text,"user_id" text NOT NULL,"purpose" text NOT NULL,"token_hash" text NOT NULL,"expires_at" timestamptz,"used_at" timestamptz,"created_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_verification_tokens_user_id" ON "verification_tokens"("user_id");
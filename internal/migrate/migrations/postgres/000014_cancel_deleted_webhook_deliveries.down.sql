@@ -0,0 +1,2 @@
+-- the cancelled deliveries of the deleted webhooks stay cancelled
+SELECT 1;
@@ -0,0 +1,3 @@
+-- the pending deliveries of the webhooks deleted before the deletion cancelled them
+
+UPDATE "webhook_deliveries" SET "status" = 'cancelled' WHERE "status" = 'pending' AND "webhook_id" IN (SELECT "id" FROM "webhooks" WHERE "deleted_at" IS NOT NULL);
@@ -0,0 +1,3 @@
+-- the api tokens act with the current role of their creator
+
+ALTER TABLE "api_tokens" DROP COLUMN "role";
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS `webhook_deliveries`;
+DROP TABLE IF EXISTS `webhooks`;
+DROP TABLE IF EXISTS `outbox_events`;
+DROP TABLE IF EXISTS `news_letters`;
+DROP TABLE IF EXISTS `reactions`;
+DROP TABLE IF EXISTS `tier_members`;
+DROP TABLE IF EXISTS `tiers`;
+DROP TABLE IF EXISTS `pages`;
+DROP TABLE IF EXISTS `course_tags`;
+DROP TABLE IF EXISTS `course_platform_tags`;
+DROP TABLE IF EXISTS `course_authors`;
+DROP TABLE IF EXISTS `courses`;
+DROP TABLE IF EXISTS `platform_tags`;
+DROP TABLE IF EXISTS `users`;
+DROP TABLE IF EXISTS `post_tags`;
+DROP TABLE IF EXISTS `tags`;
+DROP TABLE IF EXISTS `posts`;
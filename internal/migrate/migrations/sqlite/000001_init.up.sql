@@ -0,0 +1,54 @@
+-- the baseline schema, IF NOT EXISTS keeps it compatible with the databases created by gorm AutoMigrate
+
+CREATE TABLE IF NOT EXISTS `posts` (`id` text,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`space_id` text,`slug` text,`slug_id` text NOT NULL,`title` text,`summary` text,`excerpt` text,`content` text NOT NULL,`status` text NOT NULL DEFAULT 'draft',`version` integer,PRIMARY KEY (`id`),CONSTRAINT `uni_posts_slug_id` UNIQUE (`slug_id`));
+CREATE INDEX IF NOT EXISTS `idx_posts_space_id` ON `posts`(`space_id`);
+CREATE INDEX IF NOT EXISTS `idx_posts_deleted_at` ON `posts`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `tags` (`id` text,`space_id` text NOT NULL,`name` text NOT NULL,PRIMARY KEY (`id`));
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_space_name` ON `tags`(`space_id`,`name`);
+
+CREATE TABLE IF NOT EXISTS `post_tags` (`post_id` text,`tag_id` text,PRIMARY KEY (`post_id`,`tag_id`),CONSTRAINT `fk_post_tags_tag` FOREIGN KEY (`tag_id`) REFERENCES `tags`(`id`) ON DELETE CASCADE ON UPDATE CASCADE,CONSTRAINT `fk_post_tags_post` FOREIGN KEY (`post_id`) REFERENCES `posts`(`id`) ON DELETE CASCADE ON UPDATE CASCADE);
+
+CREATE TABLE IF NOT EXISTS `users` (`id` text NOT NULL,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`username` text NOT NULL,`email` text NOT NULL,`role` text NOT NULL DEFAULT 'viewer',PRIMARY KEY (`id`));
+CREATE INDEX IF NOT EXISTS `idx_users_deleted_at` ON `users`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `platform_tags` (`id` text,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`name` text NOT NULL,PRIMARY KEY (`id`),CONSTRAINT `uni_platform_tags_name` UNIQUE (`name`));
+CREATE INDEX IF NOT EXISTS `idx_platform_tags_deleted_at` ON `platform_tags`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `courses` (`id` text,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`document_id` text NOT NULL,`created_by_id` text NOT NULL,`space_id` text NOT NULL,`status` text NOT NULL DEFAULT 'draft',`reaction` text NOT NULL,PRIMARY KEY (`id`));
+CREATE INDEX IF NOT EXISTS `idx_courses_deleted_at` ON `courses`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `course_authors` (`course_id` text,`user_id` text NOT NULL,PRIMARY KEY (`course_id`,`user_id`),CONSTRAINT `fk_course_authors_course` FOREIGN KEY (`course_id`) REFERENCES `courses`(`id`) ON DELETE CASCADE ON UPDATE CASCADE,CONSTRAINT `fk_course_authors_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE ON UPDATE CASCADE);
+CREATE TABLE IF NOT EXISTS `course_platform_tags` (`course_id` text,`platform_tag_id` text,PRIMARY KEY (`course_id`,`platform_tag_id`),CONSTRAINT `fk_course_platform_tags_course` FOREIGN KEY (`course_id`) REFERENCES `courses`(`id`) ON DELETE CASCADE ON UPDATE CASCADE,CONSTRAINT `fk_course_platform_tags_platform_tag` FOREIGN KEY (`platform_tag_id`) REFERENCES `platform_tags`(`id`) ON DELETE CASCADE ON UPDATE CASCADE);
+CREATE TABLE IF NOT EXISTS `course_tags` (`course_id` text,`tag_id` text,PRIMARY KEY (`course_id`,`tag_id`),CONSTRAINT `fk_course_tags_course` FOREIGN KEY (`course_id`) REFERENCES `courses`(`id`) ON DELETE CASCADE ON UPDATE CASCADE,CONSTRAINT `fk_course_tags_tag` FOREIGN KEY (`tag_id`) REFERENCES `tags`(`id`) ON DELETE CASCADE ON UPDATE CASCADE);
+
+CREATE TABLE IF NOT EXISTS `pages` (`id` text,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`content` text NOT NULL,`course_id` text NOT NULL,`space_id` text NOT NULL,`created_by_id` text NOT NULL,`status` text,PRIMARY KEY (`id`),CONSTRAINT `fk_pages_course` FOREIGN KEY (`course_id`) REFERENCES `courses`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_pages_deleted_at` ON `pages`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `tiers` (`id` text,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`space_id` text NOT NULL,`name` text NOT NULL,`created_by_id` text NOT NULL,`free` numeric NOT NULL DEFAULT false,`monthly_cost` real,`yearly_cost` real,`half_yearly_cost` real,`quarterly_cost` real,PRIMARY KEY (`id`));
+CREATE INDEX IF NOT EXISTS `idx_tiers_deleted_at` ON `tiers`(`deleted_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_space_project` ON `tiers`(`name`);
+
+CREATE TABLE IF NOT EXISTS `tier_members` (`id` text NOT NULL,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`tier_id` text NOT NULL,`user_id` text NOT NULL,`created_by_id` text NOT NULL,`update_by_id` text,PRIMARY KEY (`id`),CONSTRAINT `fk_tier_members_tier` FOREIGN KEY (`tier_id`) REFERENCES `tiers`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_tier_members_deleted_at` ON `tier_members`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `reactions` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`post_id` text NOT NULL,`user_id` text NOT NULL,`name` text NOT NULL,`state` numeric NOT NULL,CONSTRAINT `fk_reactions_post` FOREIGN KEY (`post_id`) REFERENCES `posts`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_reactions_deleted_at` ON `reactions`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `news_letters` (`id` text,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`user_id` text NOT NULL,`active` numeric NOT NULL,`emails` text NOT NULL,PRIMARY KEY (`id`));
+CREATE INDEX IF NOT EXISTS `idx_news_letters_deleted_at` ON `news_letters`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `outbox_events` (`seq` integer PRIMARY KEY AUTOINCREMENT,`id` text NOT NULL,`type` text NOT NULL,`aggregate_id` text NOT NULL,`space_id` text,`payload` text NOT NULL,`created_at` datetime,`dispatched_at` datetime);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_dispatched_at` ON `outbox_events`(`dispatched_at`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_space_id` ON `outbox_events`(`space_id`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_aggregate_id` ON `outbox_events`(`aggregate_id`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_type` ON `outbox_events`(`type`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_outbox_events_id` ON `outbox_events`(`id`);
+
+CREATE TABLE IF NOT EXISTS `webhooks` (`id` text,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`url` text NOT NULL,`secret` text NOT NULL,`event_types` text,`active` numeric NOT NULL DEFAULT true,`created_by_id` text,PRIMARY KEY (`id`));
+CREATE INDEX IF NOT EXISTS `idx_webhooks_deleted_at` ON `webhooks`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (`id` text,`webhook_id` text NOT NULL,`event_id` text NOT NULL,`status` text NOT NULL DEFAULT 'pending',`attempts` integer NOT NULL DEFAULT 0,`next_attempt_at` datetime,`response_code` integer,`last_error` text,`created_at` datetime,`updated_at` datetime,PRIMARY KEY (`id`),CONSTRAINT `fk_webhook_deliveries_webhook` FOREIGN KEY (`webhook_id`) REFERENCES `webhooks`(`id`) ON DELETE CASCADE,CONSTRAINT `fk_webhook_deliveries_event` FOREIGN KEY (`event_id`) REFERENCES `outbox_events`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_next_attempt_at` ON `webhook_deliveries`(`next_attempt_at`);
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_status` ON `webhook_deliveries`(`status`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_webhook_event` ON `webhook_deliveries`(`webhook_id`,`event_id`);
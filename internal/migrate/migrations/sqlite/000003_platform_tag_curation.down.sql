@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS `idx_tags_platform_tag_id`;
+ALTER TABLE `tags` DROP COLUMN `platform_tag_id`;
+
+DROP INDEX IF EXISTS `idx_platform_tags_status`;
+ALTER TABLE `platform_tags` DROP COLUMN `reviewed_at`;
+ALTER TABLE `platform_tags` DROP COLUMN `reviewed_by_id`;
+ALTER TABLE `platform_tags` DROP COLUMN `proposed_by_id`;
+ALTER TABLE `platform_tags` DROP COLUMN `status`;
+ALTER TABLE `platform_tags` DROP COLUMN `description`;
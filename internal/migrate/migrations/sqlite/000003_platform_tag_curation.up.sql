@@ -0,0 +1,13 @@
+-- the curation of the platform tags and the mapping of the space tags onto them,
+-- the existing platform tags were created by the admins and stay approved.
+-- the mapping has no foreign key here, sqlite cannot drop a column that has one.
+
+ALTER TABLE `platform_tags` ADD COLUMN `description` text;
+ALTER TABLE `platform_tags` ADD COLUMN `status` text NOT NULL DEFAULT 'approved';
+ALTER TABLE `platform_tags` ADD COLUMN `proposed_by_id` text;
+ALTER TABLE `platform_tags` ADD COLUMN `reviewed_by_id` text;
+ALTER TABLE `platform_tags` ADD COLUMN `reviewed_at` datetime;
+CREATE INDEX IF NOT EXISTS `idx_platform_tags_status` ON `platform_tags`(`status`);
+
+ALTER TABLE `tags` ADD COLUMN `platform_tag_id` text;
+CREATE INDEX IF NOT EXISTS `idx_tags_platform_tag_id` ON `tags`(`platform_tag_id`);
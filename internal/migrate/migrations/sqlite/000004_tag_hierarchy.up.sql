@@ -0,0 +1,7 @@
+-- the nesting of the tags, their descriptions and colors.
+-- the parent has no foreign key here, sqlite cannot drop a column that has one.
+
+ALTER TABLE `tags` ADD COLUMN `parent_id` text;
+ALTER TABLE `tags` ADD COLUMN `description` text;
+ALTER TABLE `tags` ADD COLUMN `color` text;
+CREATE INDEX IF NOT EXISTS `idx_tags_parent_id` ON `tags`(`parent_id`);
@@ -0,0 +1,4 @@
+-- the related posts, precomputed by the relations job
+
+CREATE TABLE IF NOT EXISTS `post_relations` (`post_id` text,`related_post_id` text,`space_id` text NOT NULL,`score` real NOT NULL,`computed_at` datetime,PRIMARY KEY (`post_id`,`related_post_id`));
+CREATE INDEX IF NOT EXISTS `idx_post_relations_space_id` ON `post_relations`(`space_id`);
@@ -0,0 +1,6 @@
+-- the author of a post and the tier gating it
+
+ALTER TABLE `posts` ADD COLUMN `author_id` text;
+ALTER TABLE `posts` ADD COLUMN `tier_id` text;
+CREATE INDEX IF NOT EXISTS `idx_posts_author_id` ON `posts`(`author_id`);
+CREATE INDEX IF NOT EXISTS `idx_posts_tier_id` ON `posts`(`tier_id`);
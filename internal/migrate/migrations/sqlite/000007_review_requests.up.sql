@@ -0,0 +1,6 @@
+-- the submissions of the posts to the editors and their decisions
+
+CREATE TABLE IF NOT EXISTS `review_requests` (`id` text,`post_id` text NOT NULL,`space_id` text NOT NULL,`requested_by` text NOT NULL,`status` text NOT NULL DEFAULT 'pending',`reviewer_id` text,`notes` text,`created_at` datetime,`decided_at` datetime,PRIMARY KEY (`id`));
+CREATE INDEX IF NOT EXISTS `idx_review_requests_post_id` ON `review_requests`(`post_id`);
+CREATE INDEX IF NOT EXISTS `idx_review_requests_space_id` ON `review_requests`(`space_id`);
+CREATE INDEX IF NOT EXISTS `idx_review_requests_status` ON `review_requests`(`status`);
@@ -0,0 +1,4 @@
+-- the deactivation of the accounts, the accounts are listed by role
+
+ALTER TABLE `users` ADD COLUMN `deactivated_at` datetime;
+CREATE INDEX IF NOT EXISTS `idx_users_role` ON `users`(`role`);
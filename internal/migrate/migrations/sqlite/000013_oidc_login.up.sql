@@ -0,0 +1,6 @@
+-- the identities of the external OIDC providers linked to the users and the pending social logins
+
+CREATE TABLE IF NOT EXISTS `user_identities` (`id` text,`user_id` text NOT NULL,`provider` text NOT NULL,`subject` text NOT NULL,`email` text NOT NULL,`created_at` datetime,`updated_at` datetime,PRIMARY KEY (`id`));
+CREATE INDEX IF NOT EXISTS `idx_user_identities_user_id` ON `user_identities`(`user_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_user_identities_provider_subject` ON `user_identities`(`provider`,`subject`);
+CREATE TABLE IF NOT EXISTS `oidc_logins` (`id` text,`provider` text NOT NULL,`state_hash` text NOT NULL,`code_verifier` text NOT NULL,`nonce` text NOT NULL,`expires_at` datetime,`used_at` datetime,`created_at` datetime,PRIMARY KEY (`id`));
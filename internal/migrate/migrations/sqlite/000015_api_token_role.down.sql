@@ -0,0 +1,3 @@
+-- the restored tokens act as viewers until they are created again
+
+ALTER TABLE `api_tokens` ADD COLUMN `role` text NOT NULL DEFAULT 'viewer';
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// ApiToken gives a program access to the resources of a space without a login.
+// The secret is only shown once, the token keeps its hash. It acts with the current role of its creator, its scopes limit the calls and the role.
+type ApiToken struct {
+	ID          string `gorm:"primaryKey;uuid"`
+	SpaceID     string `gorm:"not null;index"`
+	Name        string `gorm:"not null"`
+	SecretHash  string `gorm:"not null"`
+	Scopes      string `gorm:"not null"` // comma separated scopes, like read:posts
+	CreatedByID string `gorm:"not null;index"`
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+	CreatedAt   time.Time
+}
+
+// ScopeList returns the scopes of the token
+func (t *ApiToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return nil
+	}
+
+	return strings.Split(t.Scopes, ",")
+}
+
+// HasScope returns true if the token holds the scope
+func (t *ApiToken) HasScope(scope string) bool {
+	return slices.Contains(t.ScopeList(), scope)
+}
+
+// Active returns true if the token is neither revoked nor expired
+func (t *ApiToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventPostCreated   EventType = "post.created"
+	EventPostUpdated   EventType = "post.updated"
+	EventPostPublished EventType = "post.published"
+	EventPostDeleted   EventType = "post.deleted"
+	// EventReviewRequested notifies the reviewers of a post submitted for review
+	EventReviewRequested   EventType = "review.requested"
+	EventReviewApproved    EventType = "review.approved"
+	EventReviewRejected    EventType = "review.rejected"
+	EventCourseCreated     EventType = "course.created"
+	EventCourseUpdated     EventType = "course.updated"
+	EventCourseDeleted     EventType = "course.deleted"
+	EventPageCreated       EventType = "page.created"
+	EventPageUpdated       EventType = "page.updated"
+	EventPageDeleted       EventType = "page.deleted"
+	EventTierCreated       EventType = "tier.created"
+	EventTierDeleted       EventType = "tier.deleted"
+	EventTierMemberCreated EventType = "tier_member.created"
+	EventTierMemberDeleted EventType = "tier_member.deleted"
+	// EventWebhookTest is only sent to a single webhook to verify the endpoint, it is never stored in the outbox
+	EventWebhookTest EventType = "webhook.test"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the change it describes
+// The dispatcher fans the events out to the webhooks, Seq gives the total order of the events
+type OutboxEvent struct {
+	Seq          uint64    `gorm:"primaryKey;autoIncrement"`
+	ID           string    `gorm:"uuid;not null;uniqueIndex"`
+	Type         EventType `gorm:"not null;index"`
+	AggregateID  string    `gorm:"not null;index"`
+	SpaceID      string    `gorm:"index"`
+	Payload      string    `gorm:"not null"` // json encoded event data
+	CreatedAt    time.Time
+	DispatchedAt *time.Time `gorm:"index"`
+}
+
+// NewOutboxEvent creates a new outbox event with the json encoded data
+func NewOutboxEvent(eventType EventType, aggregateID, spaceID string, data any) (*OutboxEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxEvent{
+		ID:          uuid.New().String(),
+		Type:        eventType,
+		AggregateID: aggregateID,
+		SpaceID:     spaceID,
+		Payload:     string(payload),
+	}, nil
+}
@@ -1,11 +1,31 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PlatformTagStatus is the curation state of a platform tag
+type PlatformTagStatus string
+
+const (
+	// PlatformTagProposed tags wait for an admin review, they are not suggested
+	PlatformTagProposed PlatformTagStatus = "proposed"
+	PlatformTagApproved PlatformTagStatus = "approved"
+	PlatformTagRejected PlatformTagStatus = "rejected"
+)
 
 // PlatformTag is a model for platform tags
 // A platform tag is a tag that is used to categorize and create suggestions in the platform
+// The space tags are mapped onto the approved platform tags, the tagged posts teach the suggestions.
 type PlatformTag struct {
 	gorm.Model
-	ID   string `gorm:"primaryKey;uuid"`
-	Name string `gorm:"not null;unique"`
+	ID           string `gorm:"primaryKey;uuid"`
+	Name         string `gorm:"not null;unique"`
+	Description  string
+	Status       PlatformTagStatus `gorm:"not null;default:approved;index"`
+	ProposedByID string
+	ReviewedByID string
+	ReviewedAt   *time.Time
 }
@@ -1,13 +1,19 @@
 package model
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type PostStatus string
 
 const (
-	PostStatusDraft     PostStatus = "draft"
+	PostStatusDraft PostStatus = "draft"
+	// PostStatusInReview posts wait for an editor to approve or reject them
+	PostStatusInReview PostStatus = "in_review"
+	// PostStatusApproved posts can be published by their author
+	PostStatusApproved  PostStatus = "approved"
 	PostStatusPublished PostStatus = "published"
 	PostStatusArchived  PostStatus = "archived"
 )
@@ -15,6 +21,7 @@ const (
 type Post struct {
 	gorm.Model
 	ID      string `gorm:"primaryKey;uuid"`
+	SpaceID string `gorm:"uuid;index"`
 	Slug    string
 	SlugID  string `gorm:"not null;unique"`
 	Title   string
@@ -25,6 +32,11 @@ type Post struct {
 	Status  PostStatus `gorm:"not null;default:draft"`
 	Tags    []*Tag     `gorm:"many2many:post_tags;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	Version int64
+	// PublishedAt is set when the post is first published
+	PublishedAt *time.Time
+	AuthorID    string `gorm:"index"`
+	// TierID gates the post behind a tier, nil for a public post
+	TierID *string `gorm:"index"`
 }
 
 // PostReaction is a map of reaction names to their counts
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// PostRelation ranks a published post of the same space as related to a post.
+// The relations are recomputed in the background, a post keeps its best ones.
+type PostRelation struct {
+	PostID        string  `gorm:"primaryKey"`
+	RelatedPostID string  `gorm:"primaryKey"`
+	SpaceID       string  `gorm:"not null;index"`
+	Score         float64 `gorm:"not null"`
+	ComputedAt    time.Time
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// PreviewLink shares an unpublished post with the readers without an account until it expires or is revoked
+type PreviewLink struct {
+	ID          string    `gorm:"primaryKey;uuid"`
+	PostID      string    `gorm:"not null;index"`
+	SpaceID     string    `gorm:"not null"`
+	CreatedByID string    `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	RevokedAt   *time.Time
+	RevokedByID string
+	CreatedAt   time.Time
+}
+
+// Active returns true if the link still opens the post
+func (l *PreviewLink) Active(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
+
+// PreviewAccess records a read of a post through a preview link
+type PreviewAccess struct {
+	ID            string `gorm:"primaryKey;uuid"`
+	PreviewLinkID string `gorm:"not null;index"`
+	PostID        string `gorm:"not null"`
+	// UserID is empty for the readers without an account
+	UserID     string
+	RemoteAddr string
+	UserAgent  string
+	AccessedAt time.Time `gorm:"not null"`
+}
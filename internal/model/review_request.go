@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// ReviewRequestStatus is the outcome of a review request
+type ReviewRequestStatus string
+
+const (
+	ReviewRequestPending  ReviewRequestStatus = "pending"
+	ReviewRequestApproved ReviewRequestStatus = "approved"
+	ReviewRequestRejected ReviewRequestStatus = "rejected"
+	// ReviewRequestWithdrawn requests were pulled back to draft by the author before a decision
+	ReviewRequestWithdrawn ReviewRequestStatus = "withdrawn"
+)
+
+// ReviewRequest is the submission of a post to the editors, a post has at most one pending request.
+// The decided requests are kept as the review history of the post.
+type ReviewRequest struct {
+	ID          string              `gorm:"primaryKey;uuid"`
+	PostID      string              `gorm:"not null;index"`
+	SpaceID     string              `gorm:"not null;index"`
+	RequestedBy string              `gorm:"not null"`
+	Status      ReviewRequestStatus `gorm:"not null;default:pending;index"`
+	ReviewerID  string
+	// Notes are left by the reviewer, they tell the author what to change on a rejection
+	Notes     string
+	CreatedAt time.Time
+	DecidedAt *time.Time
+}
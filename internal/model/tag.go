@@ -4,4 +4,11 @@ type Tag struct {
 	ID      string `gorm:"primaryKey;uuid"`
 	SpaceID string `gorm:"not null;uniqueIndex:idx_space_name"`
 	Name    string `gorm:"not null;uniqueIndex:idx_space_name"`
+	// ParentID nests the tag under another tag of the space, nil for a root tag
+	ParentID    *string `gorm:"index"`
+	Description string
+	// Color is a hex color like #1e90ff
+	Color string
+	// PlatformTagID maps the space tag onto a platform tag
+	PlatformTagID *string `gorm:"index"`
 }
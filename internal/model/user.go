@@ -1,6 +1,10 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type UserRole string
 
@@ -8,13 +12,101 @@ const (
 	UserRoleViewer UserRole = "viewer"
 	UserRoleAuthor          = "author"
 	UserRoleAdmin           = "admin"
-	UserRoleOwner           = "owner" // first user who logs-in becomes the owner
+	UserRoleOwner           = "owner" // the first user, created by the owner setup
 )
 
 type User struct {
 	gorm.Model
 	ID       string   `gorm:"not null"`
 	Username string   `gorm:"not null"`
-	Email    string   `gorm:"not null"`
-	Role     UserRole `gorm:"not null;default:'viewer'"` // first user to sign up is the owner
+	Email    string   `gorm:"not null;uniqueIndex"`
+	Role     UserRole `gorm:"not null;default:'viewer';index"` // the owner is created by the owner setup
+	// PasswordHash is the bcrypt hash of the password, only the local auth provider keeps the passwords
+	PasswordHash string `gorm:"not null;default:''"`
+	// DeactivatedAt is set while the account is deactivated, a deactivated account cannot log in
+	DeactivatedAt *time.Time
+	// EmailVerifiedAt is set once the user proved to own the email
+	EmailVerifiedAt *time.Time
+}
+
+// Active returns true if the account is not deactivated
+func (u *User) Active() bool {
+	return u.DeactivatedAt == nil
+}
+
+// AuthSession is a login of the local auth provider, its refresh token is exchanged for new access tokens
+// until it expires or the user logs out. Only the hash of the refresh token is stored.
+type AuthSession struct {
+	ID               string `gorm:"primaryKey;uuid"`
+	UserID           string `gorm:"not null;index"`
+	RefreshTokenHash string `gorm:"not null"`
+	ExpiresAt        time.Time
+	RevokedAt        *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// Active returns true if the session is neither revoked nor expired
+func (s *AuthSession) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+type VerificationPurpose string
+
+const (
+	VerificationPasswordReset VerificationPurpose = "password_reset"
+	VerificationEmail         VerificationPurpose = "email_verification"
+)
+
+// VerificationToken is a one-time token of the local auth provider sent to the email of a user,
+// it resets the password or verifies the email. Only the hash of the token is stored.
+type VerificationToken struct {
+	ID        string              `gorm:"primaryKey;uuid"`
+	UserID    string              `gorm:"not null;index"`
+	Purpose   VerificationPurpose `gorm:"not null"`
+	TokenHash string              `gorm:"not null"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// Usable returns true if the token is neither used nor expired
+func (t *VerificationToken) Usable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// UserIdentity links a user to the account of an external OIDC identity provider,
+// the subject is the id of the account at the provider.
+type UserIdentity struct {
+	ID       string `gorm:"primaryKey;uuid"`
+	UserID   string `gorm:"not null;index"`
+	Provider string `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject  string `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	// Email is the email given by the provider at the first login
+	Email     string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// OIDCLogin is a pending social login, it keeps the pkce code verifier and the nonce between the redirect
+// to the identity provider and the callback. Only the hash of the state is stored.
+type OIDCLogin struct {
+	ID           string `gorm:"primaryKey;uuid"`
+	Provider     string `gorm:"not null"`
+	StateHash    string `gorm:"not null"`
+	CodeVerifier string `gorm:"not null"`
+	Nonce        string `gorm:"not null"`
+	ExpiresAt    time.Time
+	UsedAt       *time.Time
+	CreatedAt    time.Time
+}
+
+// TableName keeps the acronym in one piece, the default name is o_id_c_logins
+func (OIDCLogin) TableName() string {
+	return "oidc_logins"
+}
+
+// Usable returns true if the login is neither completed nor expired
+func (l *OIDCLogin) Usable(now time.Time) bool {
+	return l.UsedAt == nil && now.Before(l.ExpiresAt)
 }
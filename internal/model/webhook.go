@@ -0,0 +1,64 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook is an endpoint registered by an integrator to receive the domain events
+type Webhook struct {
+	gorm.Model
+	ID          string `gorm:"primaryKey;uuid"`
+	URL         string `gorm:"not null"`
+	Secret      string `gorm:"not null"` // used to sign the deliveries with HMAC-SHA256
+	EventTypes  string // comma separated event types, empty means all the events
+	Active      bool   `gorm:"not null;default:true"`
+	CreatedByID string `gorm:"uuid"`
+}
+
+// Subscribed returns true if the webhook receives the event type
+func (w *Webhook) Subscribed(eventType EventType) bool {
+	if !w.Active {
+		return false
+	}
+	if w.EventTypes == "" {
+		return true
+	}
+
+	for _, t := range strings.Split(w.EventTypes, ",") {
+		if EventType(strings.TrimSpace(t)) == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryFailed deliveries exhausted their retries, they form the dead-letter queue
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+	// WebhookDeliveryCancelled deliveries were pending when their webhook was deleted
+	WebhookDeliveryCancelled WebhookDeliveryStatus = "cancelled"
+)
+
+// WebhookDelivery tracks the delivery of an outbox event to a webhook
+type WebhookDelivery struct {
+	ID            string                `gorm:"primaryKey;uuid"`
+	WebhookID     string                `gorm:"not null;uniqueIndex:idx_webhook_event"`
+	EventID       string                `gorm:"not null;uniqueIndex:idx_webhook_event"`
+	Status        WebhookDeliveryStatus `gorm:"not null;default:pending;index"`
+	Attempts      int                   `gorm:"not null;default:0"`
+	NextAttemptAt time.Time             `gorm:"index"`
+	ResponseCode  int
+	LastError     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Webhook       *Webhook     `gorm:"foreignKey:WebhookID;references:ID;constraint:OnDelete:CASCADE"`
+	Event         *OutboxEvent `gorm:"foreignKey:EventID;references:ID"`
+}
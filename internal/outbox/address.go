@@ -0,0 +1,72 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned for a webhook that points into the private network of the server
+var ErrPrivateAddress = errors.New("webhook url must resolve to a public address")
+
+// ValidateURL checks that the webhook url is http or https and that its host resolves to public addresses only
+func ValidateURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return errors.New("webhook url must be http or https")
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return errors.New("webhook url has no host")
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return ErrPrivateAddress
+		}
+	}
+
+	return nil
+}
+
+// publicIP returns false for the loopback, private, link-local, multicast and unspecified addresses
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// publicClient returns a client that only connects to public addresses, the check runs on every connection
+// so that a host resolving to a private address after the validation or a redirect is refused too
+func publicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// a proxy would connect on behalf of the server
+	transport.Proxy = nil
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
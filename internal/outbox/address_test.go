@@ -0,0 +1,45 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestValidateURL(t *testing.T) {
+	ctx := context.Background()
+	for _, rawURL := range []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"https://10.0.0.1/hook",
+		"https://192.168.1.5/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		if err := ValidateURL(ctx, rawURL); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("%s: expected the private address to be refused, got %v", rawURL, err)
+		}
+	}
+
+	if err := ValidateURL(ctx, "ftp://93.184.216.34/hook"); err == nil {
+		t.Error("expected the ftp url to be refused")
+	}
+	if err := ValidateURL(ctx, "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("expected the public address to be allowed, got %v", err)
+	}
+}
+
+func TestPublicClientRefusesPrivateAddresses(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	_, err = publicClient(defaultSendTimeout).Get("http://" + listener.Addr().String())
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("expected the loopback connection to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/emrgen/unpost/internal/metrics"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = 2 * time.Second
+	// MaxAttempts is the number of delivery attempts before a delivery is moved to the dead-letter queue
+	MaxAttempts = 8
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+	// deliveryLease keeps a claimed delivery away from the other replicas while it is being sent
+	deliveryLease = time.Minute
+)
+
+// Dispatcher fans the outbox events out to the subscribed webhooks and delivers them.
+// Failed deliveries are retried with an exponential backoff until MaxAttempts is reached.
+type Dispatcher struct {
+	store        store.UnstakStore
+	sender       *Sender
+	pollInterval time.Duration
+	batchSize    int
+	now          func() time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher creates a new Dispatcher
+func NewDispatcher(store store.UnstakStore, sender *Sender) *Dispatcher {
+	return &Dispatcher{
+		store:        store,
+		sender:       sender,
+		pollInterval: defaultPollInterval,
+		batchSize:    defaultBatchSize,
+		now:          time.Now,
+	}
+}
+
+// Start polls the outbox in the background until Stop is called
+func (d *Dispatcher) Start(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		ticker := time.NewTicker(d.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			start := time.Now()
+			err := d.RunOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				logrus.Errorf("outbox dispatcher error: %v", err)
+			}
+			metrics.ObserveJob("outbox_dispatcher", start, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the dispatcher and waits for the in-flight deliveries
+func (d *Dispatcher) Stop() {
+	if d.cancel == nil {
+		return
+	}
+
+	d.cancel()
+	d.wg.Wait()
+}
+
+// RunOnce fans out the pending events and sends the due deliveries
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	if err := d.fanOut(ctx); err != nil {
+		return err
+	}
+
+	return d.deliver(ctx)
+}
+
+// fanOut creates a delivery for every webhook subscribed to the undispatched events
+func (d *Dispatcher) fanOut(ctx context.Context) error {
+	var dispatched int
+	err := d.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		events, err := tx.ListUndispatchedEvents(ctx, d.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		webhooks, err := tx.ListActiveWebhooks(ctx)
+		if err != nil {
+			return err
+		}
+
+		now := d.now()
+		deliveries := make([]*model.WebhookDelivery, 0)
+		ids := make([]string, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+			for _, webhook := range webhooks {
+				if !webhook.Subscribed(event.Type) {
+					continue
+				}
+
+				deliveries = append(deliveries, &model.WebhookDelivery{
+					ID:            uuid.New().String(),
+					WebhookID:     webhook.ID,
+					EventID:       event.ID,
+					Status:        model.WebhookDeliveryPending,
+					NextAttemptAt: now,
+				})
+			}
+		}
+
+		if err := tx.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+			return err
+		}
+
+		dispatched = len(ids)
+
+		return tx.MarkEventsDispatched(ctx, ids, now)
+	})
+	if err != nil {
+		return err
+	}
+	metrics.ObserveOutboxEvents(dispatched)
+
+	return nil
+}
+
+// deliver claims the due deliveries and sends them
+func (d *Dispatcher) deliver(ctx context.Context) error {
+	var deliveries []*model.WebhookDelivery
+	err := d.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		var err error
+		deliveries, err = tx.ListDueWebhookDeliveries(ctx, d.now(), d.batchSize)
+		if err != nil {
+			return err
+		}
+
+		// claim the deliveries so that the other replicas skip them while they are sent
+		leaseUntil := d.now().Add(deliveryLease)
+		for _, delivery := range deliveries {
+			delivery.NextAttemptAt = leaseUntil
+			if err := tx.UpdateWebhookDelivery(ctx, delivery); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		d.attempt(ctx, delivery)
+		if err := d.store.UpdateWebhookDelivery(ctx, delivery); err != nil {
+			logrus.Errorf("error updating webhook delivery %s: %v", delivery.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// attempt sends the delivery once and schedules the next attempt on failure
+func (d *Dispatcher) attempt(ctx context.Context, delivery *model.WebhookDelivery) {
+	// the webhook was deleted after the delivery was claimed
+	if delivery.Webhook == nil {
+		delivery.Status = model.WebhookDeliveryCancelled
+		return
+	}
+
+	delivery.Attempts++
+
+	code, err := d.sender.Send(ctx, delivery.Webhook, delivery.Event, delivery.ID)
+	delivery.ResponseCode = code
+	if err == nil {
+		delivery.Status = model.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		metrics.ObserveWebhookDelivery("delivered")
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= MaxAttempts {
+		delivery.Status = model.WebhookDeliveryFailed
+		logrus.Warnf("webhook delivery %s moved to the dead-letter queue after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+		metrics.ObserveWebhookDelivery("failed")
+		return
+	}
+
+	metrics.ObserveWebhookDelivery("retry")
+
+	delivery.NextAttemptAt = d.now().Add(Backoff(delivery.Attempts))
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts
+func Backoff(attempts int) time.Duration {
+	backoff := baseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return backoff
+}
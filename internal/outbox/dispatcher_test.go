@@ -0,0 +1,181 @@
+package outbox
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/store/storetest"
+	"github.com/google/uuid"
+)
+
+func createEvent(t *testing.T, s store.UnstakStore) *model.OutboxEvent {
+	event, err := model.NewOutboxEvent(model.EventPostPublished, uuid.NewString(), "", map[string]string{"title": "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateOutboxEvent(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	return event
+}
+
+func TestDispatcherDeliversSignedEvents(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify("secret", r.Header.Get(SignatureHeader), body, time.Minute, time.Now()) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(EventHeader) != string(model.EventPostPublished) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received.Add(1)
+	}))
+	defer server.Close()
+
+	if err := s.CreateWebhook(ctx, &model.Webhook{ID: uuid.NewString(), URL: server.URL, Secret: "secret", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	// not subscribed to the published events
+	if err := s.CreateWebhook(ctx, &model.Webhook{ID: uuid.NewString(), URL: server.URL, Secret: "secret", Active: true, EventTypes: string(model.EventPostDeleted)}); err != nil {
+		t.Fatal(err)
+	}
+	createEvent(t, s)
+
+	d := NewDispatcher(s, NewSender(server.Client()))
+	if err := d.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := received.Load(); got != 1 {
+		t.Errorf("received = %d, want 1", got)
+	}
+
+	status := model.WebhookDeliveryDelivered
+	deliveries, err := s.ListWebhookDeliveries(ctx, &store.WebhookDeliveryFilter{Status: &status}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Errorf("delivered = %d, want 1", len(deliveries))
+	}
+
+	// the event is dispatched only once
+	if err := d.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := received.Load(); got != 1 {
+		t.Errorf("received = %d after second run, want 1", got)
+	}
+}
+
+func TestDispatcherMovesExhaustedDeliveriesToDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := s.CreateWebhook(ctx, &model.Webhook{ID: uuid.NewString(), URL: server.URL, Secret: "secret", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	createEvent(t, s)
+
+	now := time.Now()
+	d := NewDispatcher(s, NewSender(server.Client()))
+	d.now = func() time.Time { return now }
+
+	for i := 0; i < MaxAttempts; i++ {
+		if err := d.RunOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(maxBackoff)
+	}
+
+	failed := model.WebhookDeliveryFailed
+	deliveries, err := s.ListWebhookDeliveries(ctx, &store.WebhookDeliveryFilter{Status: &failed}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("failed = %d, want 1", len(deliveries))
+	}
+	if deliveries[0].Attempts != MaxAttempts {
+		t.Errorf("attempts = %d, want %d", deliveries[0].Attempts, MaxAttempts)
+	}
+	if deliveries[0].ResponseCode != http.StatusInternalServerError {
+		t.Errorf("response code = %d, want 500", deliveries[0].ResponseCode)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if got := Backoff(1); got != baseBackoff {
+		t.Errorf("Backoff(1) = %v, want %v", got, baseBackoff)
+	}
+	if got := Backoff(3); got != 4*baseBackoff {
+		t.Errorf("Backoff(3) = %v, want %v", got, 4*baseBackoff)
+	}
+	if got := Backoff(100); got != maxBackoff {
+		t.Errorf("Backoff(100) = %v, want %v", got, maxBackoff)
+	}
+}
+
+func TestDispatcherSkipsDeliveriesOfDeletedWebhooks(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	webhookID := uuid.New()
+	if err := s.CreateWebhook(ctx, &model.Webhook{ID: webhookID.String(), URL: server.URL, Secret: "secret", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	createEvent(t, s)
+
+	now := time.Now()
+	d := NewDispatcher(s, NewSender(server.Client()))
+	d.now = func() time.Time { return now }
+	if err := d.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteWebhook(ctx, webhookID); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(maxBackoff)
+	if err := d.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := received.Load(); got != 1 {
+		t.Errorf("received = %d, want 1", got)
+	}
+
+	cancelled := model.WebhookDeliveryCancelled
+	deliveries, err := s.ListWebhookDeliveries(ctx, &store.WebhookDeliveryFilter{Status: &cancelled}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Attempts != 1 {
+		t.Errorf("expected the pending delivery to be cancelled, got %+v", deliveries)
+	}
+}
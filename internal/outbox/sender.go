@@ -0,0 +1,135 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emrgen/unpost/internal/model"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of the delivery as "t=<unix time>,v1=<hex digest>"
+	// The digest is computed over "<unix time>.<body>" with the webhook secret
+	SignatureHeader = "X-Unstak-Signature"
+	EventHeader     = "X-Unstak-Event"
+	DeliveryHeader  = "X-Unstak-Delivery"
+
+	defaultSendTimeout = 10 * time.Second
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrEventNotFound   = errors.New("event not found")
+)
+
+// Envelope is the body posted to the webhooks
+type Envelope struct {
+	ID        string          `json:"id"`
+	Type      model.EventType `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Sender posts the signed events to the webhooks
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewSender creates a new Sender, a nil client uses a client with a default timeout that only reaches public addresses
+func NewSender(client *http.Client) *Sender {
+	if client == nil {
+		client = publicClient(defaultSendTimeout)
+	}
+
+	return &Sender{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Send posts the event to the webhook and returns the response status code.
+// Any non 2xx response is an error.
+func (s *Sender) Send(ctx context.Context, webhook *model.Webhook, event *model.OutboxEvent, deliveryID string) (int, error) {
+	if webhook == nil {
+		return 0, ErrWebhookNotFound
+	}
+	if event == nil {
+		return 0, ErrEventNotFound
+	}
+
+	body, err := json.Marshal(Envelope{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, s.now(), body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// Sign returns the signature header value of the body
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + digest(secret, timestamp, body)
+}
+
+// Verify checks the signature header value of the body, it is meant for the webhook receivers
+func Verify(secret, signature string, body []byte, tolerance time.Duration, now time.Time) bool {
+	timestamp, sig, found := strings.Cut(strings.TrimPrefix(signature, "t="), ",v1=")
+	if !found {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if tolerance > 0 && now.Sub(time.Unix(unix, 0)).Abs() > tolerance {
+		return false
+	}
+
+	return hmac.Equal([]byte(sig), []byte(digest(secret, timestamp, body)))
+}
+
+func digest(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
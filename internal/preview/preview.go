@@ -0,0 +1,78 @@
+// Package preview signs and verifies the tokens of the shareable preview links.
+// A preview token opens a single unpublished post to the callers without an account until it expires,
+// the tokens are signed with a key derived from the jwt secret so they never pass as a login token.
+package preview
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// audience tells the preview tokens apart from the other tokens signed by the server
+const audience = "preview"
+
+// ErrInvalidToken is returned for a malformed, tampered or expired preview token
+var ErrInvalidToken = errors.New("invalid preview token")
+
+// Claims are the claims of a preview token
+type Claims struct {
+	// LinkID identifies the preview link, a revoked link rejects its tokens
+	LinkID    string
+	PostID    string
+	ExpiresAt time.Time
+}
+
+// Signer signs and verifies the preview tokens
+type Signer struct {
+	key []byte
+}
+
+// NewSigner creates a new Signer with a key derived from the secret
+func NewSigner(secret string) *Signer {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("unpost preview links"))
+
+	return &Signer{
+		key: mac.Sum(nil),
+	}
+}
+
+// Sign returns the token of the claims
+func (s *Signer) Sign(claims Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ID:        claims.LinkID,
+		Subject:   claims.PostID,
+		Audience:  jwt.ClaimStrings{audience},
+		ExpiresAt: jwt.NewNumericDate(claims.ExpiresAt),
+	})
+
+	return token.SignedString(s.key)
+}
+
+// Verify returns the claims of a valid token
+func (s *Signer) Verify(token string) (*Claims, error) {
+	var registered jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &registered, func(*jwt.Token) (any, error) {
+		return s.key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if registered.ID == "" || registered.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return &Claims{
+		LinkID:    registered.ID,
+		PostID:    registered.Subject,
+		ExpiresAt: registered.ExpiresAt.Time,
+	}, nil
+}
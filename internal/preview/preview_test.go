@@ -0,0 +1,67 @@
+package preview
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestSignVerify(t *testing.T) {
+	signer := NewSigner("secret")
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	token, err := signer.Sign(Claims{LinkID: "link", PostID: "post", ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := signer.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.LinkID != "link" || claims.PostID != "post" || !claims.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	signer := NewSigner("secret")
+
+	expired, err := signer.Sign(Claims{LinkID: "link", PostID: "post", ExpiresAt: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSigner("other").Sign(Claims{LinkID: "link", PostID: "post", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a login token signed with the jwt secret itself is not a preview token
+	login, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "post",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{
+		"expired":   expired,
+		"other key": other,
+		"login":     login,
+		"malformed": "not-a-token",
+	} {
+		if _, err := signer.Verify(token); err != ErrInvalidToken {
+			t.Errorf("%s: expected an invalid token, got %v", name, err)
+		}
+	}
+
+	// the preview tokens do not pass as login tokens either
+	valid, err := signer.Sign(Claims{LinkID: "link", PostID: "post", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(valid, func(*jwt.Token) (any, error) { return []byte("secret"), nil }); err == nil {
+		t.Error("expected the preview token to fail with the jwt secret")
+	}
+}
@@ -0,0 +1,211 @@
+package related
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/emrgen/unpost/internal/metrics"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/suggest"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultInterval = 30 * time.Minute
+	// maxCandidates caps the latest posts of a space compared with each other
+	maxCandidates = 1000
+	// MaxRelations is the number of related posts kept for a post
+	MaxRelations = 10
+
+	tagWeight      = 0.4
+	reactionWeight = 0.2
+	textWeight     = 0.4
+)
+
+// Job recomputes the related posts of every space in the background
+type Job struct {
+	store    store.UnstakStore
+	interval time.Duration
+	now      func() time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewJob creates a new Job
+func NewJob(store store.UnstakStore) *Job {
+	return &Job{
+		store:    store,
+		interval: defaultInterval,
+		now:      time.Now,
+	}
+}
+
+// Start recomputes the relations in the background until Stop is called
+func (j *Job) Start(ctx context.Context) {
+	ctx, j.cancel = context.WithCancel(ctx)
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			start := time.Now()
+			err := j.RunOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				logrus.Errorf("related posts job error: %v", err)
+			}
+			metrics.ObserveJob("related_posts", start, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the job and waits for the running computation
+func (j *Job) Stop() {
+	if j.cancel == nil {
+		return
+	}
+
+	j.cancel()
+	j.wg.Wait()
+}
+
+// RunOnce recomputes the relations of every space, a failed space does not stop the others
+func (j *Job) RunOnce(ctx context.Context) error {
+	spaceIDs, err := j.store.ListRelationSpaceIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, spaceID := range spaceIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		id, err := uuid.Parse(spaceID)
+		if err != nil {
+			logrus.Warnf("related posts skipped the invalid space id %q: %v", spaceID, err)
+			continue
+		}
+
+		if err := j.RunSpace(ctx, id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RunSpace recomputes the relations of the posts of a space
+func (j *Job) RunSpace(ctx context.Context, spaceID uuid.UUID) error {
+	posts, err := j.store.ListRelationCandidates(ctx, spaceID, maxCandidates)
+	if err != nil {
+		return err
+	}
+
+	postIDs := make([]string, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	reactors, err := j.store.ListPostReactors(ctx, postIDs)
+	if err != nil {
+		return err
+	}
+
+	relations := Compute(posts, reactors, MaxRelations)
+	now := j.now()
+	for _, relation := range relations {
+		relation.SpaceID = spaceID.String()
+		relation.ComputedAt = now
+	}
+
+	return j.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		return tx.ReplacePostRelations(ctx, spaceID, relations)
+	})
+}
+
+// Compute ranks the posts related to each post by the shared tags, the users reacting to both posts
+// and the similarity of their texts, at most limit relations are kept for a post.
+func Compute(posts []*model.Post, reactors map[string][]string, limit int) []*model.PostRelation {
+	corpus := suggest.NewCorpus()
+	tags := make(map[string]map[string]bool, len(posts))
+	users := make(map[string]map[string]bool, len(posts))
+	for _, post := range posts {
+		corpus.Add(post.ID, suggest.Terms(post.Title, post.Content))
+
+		tags[post.ID] = make(map[string]bool, len(post.Tags))
+		for _, tag := range post.Tags {
+			tags[post.ID][tag.ID] = true
+		}
+		users[post.ID] = make(map[string]bool, len(reactors[post.ID]))
+		for _, userID := range reactors[post.ID] {
+			users[post.ID][userID] = true
+		}
+	}
+
+	var relations []*model.PostRelation
+	for _, post := range posts {
+		text := make(map[string]float64)
+		for _, similar := range corpus.Similar(post.ID, 0) {
+			text[similar.ID] = similar.Score
+		}
+
+		var ranked []*model.PostRelation
+		for _, other := range posts {
+			if other.ID == post.ID {
+				continue
+			}
+
+			score := tagWeight*jaccard(tags[post.ID], tags[other.ID]) +
+				reactionWeight*jaccard(users[post.ID], users[other.ID]) +
+				textWeight*text[other.ID]
+			if score > 0 {
+				ranked = append(ranked, &model.PostRelation{PostID: post.ID, RelatedPostID: other.ID, Score: score})
+			}
+		}
+
+		sort.Slice(ranked, func(i, j int) bool {
+			if ranked[i].Score != ranked[j].Score {
+				return ranked[i].Score > ranked[j].Score
+			}
+			return ranked[i].RelatedPostID < ranked[j].RelatedPostID
+		})
+		if len(ranked) > limit {
+			ranked = ranked[:limit]
+		}
+		relations = append(relations, ranked...)
+	}
+
+	return relations
+}
+
+// jaccard returns the share of the union of the sets found in both sets
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	var shared int
+	for key := range a {
+		if b[key] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
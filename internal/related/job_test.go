@@ -0,0 +1,100 @@
+package related
+
+import (
+	"context"
+	"testing"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store/storetest"
+	"github.com/google/uuid"
+)
+
+func TestCompute(t *testing.T) {
+	golang := &model.Tag{ID: "go"}
+	posts := []*model.Post{
+		{ID: "channels", Title: "Go channels", Content: "goroutines talk over channels", Tags: []*model.Tag{golang}},
+		{ID: "goroutines", Title: "Goroutines", Content: "goroutines are cheap", Tags: []*model.Tag{golang}},
+		{ID: "postgres", Title: "Postgres", Content: "indexes"},
+		{ID: "mysql", Title: "MySQL", Content: "replication"},
+	}
+	reactors := map[string][]string{"postgres": {"alice"}, "mysql": {"alice", "bob"}}
+
+	relations := Compute(posts, reactors, 1)
+
+	related := make(map[string]string)
+	for _, relation := range relations {
+		if _, ok := related[relation.PostID]; ok {
+			t.Errorf("more than the limit of relations for %s", relation.PostID)
+		}
+		related[relation.PostID] = relation.RelatedPostID
+	}
+	if related["channels"] != "goroutines" || related["goroutines"] != "channels" {
+		t.Errorf("expected the go posts to be related, got %v", related)
+	}
+	// the posts share only their readers
+	if related["postgres"] != "mysql" || related["mysql"] != "postgres" {
+		t.Errorf("expected the database posts to be related, got %v", related)
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+	spaceID := uuid.New()
+
+	tag := &model.Tag{ID: uuid.NewString(), SpaceID: spaceID.String(), Name: "go"}
+	if err := s.CreateTag(ctx, tag); err != nil {
+		t.Fatal(err)
+	}
+	newPost := func(title string, status model.PostStatus, tags ...*model.Tag) *model.Post {
+		post := &model.Post{
+			ID:      uuid.NewString(),
+			SpaceID: spaceID.String(),
+			SlugID:  uuid.NewString(),
+			Title:   title,
+			Content: title,
+			Status:  status,
+			Tags:    tags,
+		}
+		if err := s.CreatePost(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+		return post
+	}
+	first := newPost("Go channels", model.PostStatusPublished, tag)
+	second := newPost("Go generics", model.PostStatusPublished, tag)
+	newPost("Go drafts", model.PostStatusDraft, tag)
+
+	// the posts created before the spaces carry no or a malformed space id, they are skipped
+	for _, invalid := range []string{"", "legacy"} {
+		post := &model.Post{ID: uuid.NewString(), SpaceID: invalid, SlugID: uuid.NewString(), Title: "Go", Status: model.PostStatusPublished}
+		if err := s.CreatePost(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := NewJob(s).RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	posts, err := s.ListRelatedPosts(ctx, uuid.MustParse(first.ID), MaxRelations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != second.ID {
+		t.Fatalf("expected only the published post to be related, got %d posts", len(posts))
+	}
+
+	// an unpublished post drops out of the relations before the next run
+	second.Status = model.PostStatusDraft
+	if err := s.UpdatePost(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	posts, err = s.ListRelatedPosts(ctx, uuid.MustParse(first.ID), MaxRelations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 0 {
+		t.Errorf("expected no related post, got %d", len(posts))
+	}
+}
@@ -0,0 +1,49 @@
+package render
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// the classes carry the code languages, the highlighted tokens and the heading anchors
+	p.AllowAttrs("class").Matching(bluemonday.SpaceSeparatedTokens).OnElements("a", "code", "pre", "span", "div")
+	p.AllowAttrs("loading").Matching(regexp.MustCompile(`^(lazy|eager)$`)).OnElements("img")
+	p.RequireNoFollowOnLinks(false)
+
+	return p
+}
+
+// HTML renders markdown into sanitized html
+func HTML(src string) (string, error) {
+	rendered, err := Render(src)
+	if err != nil {
+		return "", err
+	}
+
+	return rendered.HTML, nil
+}
+
+// Sanitize removes the scripts, event handlers and the other unsafe markup from html
+func Sanitize(src string) string {
+	return policy.Sanitize(src)
+}
+
+var imgTag = regexp.MustCompile(`<img\b[^>]*>`)
+
+// lazyImages defers the loading of the images below the fold, the explicit loading attributes are kept.
+// It runs on the sanitized html, where the tags and the attributes are normalized.
+func lazyImages(src string) string {
+	return imgTag.ReplaceAllStringFunc(src, func(tag string) string {
+		if strings.Contains(tag, " loading=") {
+			return tag
+		}
+
+		return `<img loading="lazy"` + strings.TrimPrefix(tag, "<img")
+	})
+}
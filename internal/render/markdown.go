@@ -0,0 +1,99 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterDelimiter opens and closes the yaml front matter of a markdown document
+const frontMatterDelimiter = "---"
+
+// ErrInvalidFrontMatter is returned when the front matter of a document cannot be parsed
+var ErrInvalidFrontMatter = errors.New("invalid front matter")
+
+// FrontMatter is the metadata of a post written at the top of a markdown document
+type FrontMatter struct {
+	Title   string   `yaml:"title"`
+	Slug    string   `yaml:"slug,omitempty"`
+	Tags    []string `yaml:"tags,omitempty"`
+	Summary string   `yaml:"summary,omitempty"`
+	Status  string   `yaml:"status,omitempty"`
+	// Date is the publish date of the post
+	Date *time.Time `yaml:"date,omitempty"`
+	// Author is the id of the account of the author, the importer is the author when empty
+	Author string `yaml:"author,omitempty"`
+}
+
+// Document is a markdown document with its front matter
+type Document struct {
+	FrontMatter FrontMatter
+	Body        string
+}
+
+// ParseMarkdown splits the yaml front matter from the markdown body.
+// A document without front matter is returned with an empty FrontMatter.
+func ParseMarkdown(src []byte) (*Document, error) {
+	text := strings.ReplaceAll(strings.TrimPrefix(string(src), "\ufeff"), "\r\n", "\n")
+
+	lines := strings.SplitAfter(text, "\n")
+	if strings.TrimSuffix(lines[0], "\n") != frontMatterDelimiter {
+		return &Document{Body: text}, nil
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSuffix(lines[i], "\n") != frontMatterDelimiter {
+			continue
+		}
+
+		doc := &Document{Body: strings.TrimLeft(strings.Join(lines[i+1:], ""), "\n")}
+		if err := yaml.Unmarshal([]byte(strings.Join(lines[1:i], "")), &doc.FrontMatter); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFrontMatter, err)
+		}
+
+		return doc, nil
+	}
+
+	return nil, fmt.Errorf("%w: missing closing %s", ErrInvalidFrontMatter, frontMatterDelimiter)
+}
+
+// Markdown writes the document back with its front matter
+func (d *Document) Markdown() ([]byte, error) {
+	header, err := yaml.Marshal(d.FrontMatter)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterDelimiter + "\n")
+	buf.Write(header)
+	buf.WriteString(frontMatterDelimiter + "\n\n")
+	buf.WriteString(d.Body)
+	if !strings.HasSuffix(d.Body, "\n") {
+		buf.WriteString("\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slugify turns a title into a url slug, the accents are dropped and the other symbols become dashes
+func Slugify(title string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(strings.ToLower(title)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return strings.Trim(nonSlugChars.ReplaceAllString(b.String(), "-"), "-")
+}
@@ -0,0 +1,128 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Heading is an entry of the table of contents
+type Heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// Rendered is the sanitized html of a content with its table of contents
+type Rendered struct {
+	HTML string
+	TOC  []Heading
+}
+
+var (
+	tocKey = parser.NewContextKey()
+
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			extension.Footnote,
+			// the tokens get classes, the inline styles would not pass the sanitizer
+			highlighting.NewHighlighting(highlighting.WithFormatOptions(html.WithClasses(true))),
+		),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+			parser.WithASTTransformers(util.Prioritized(headingTransformer{}, 100)),
+		),
+		// the raw html of the writers is kept and cleaned by the sanitizer
+		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+	)
+)
+
+// Render converts markdown into sanitized html. The headings get ids and anchor links,
+// the code blocks are highlighted and the images are lazy-loaded.
+func Render(src string) (*Rendered, error) {
+	ctx := parser.NewContext()
+
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf, parser.WithContext(ctx)); err != nil {
+		return nil, err
+	}
+
+	rendered := &Rendered{HTML: lazyImages(Sanitize(buf.String()))}
+	if toc, ok := ctx.Get(tocKey).([]Heading); ok {
+		rendered.TOC = toc
+	}
+
+	return rendered, nil
+}
+
+// headingTransformer collects the table of contents and appends an anchor link to the headings
+type headingTransformer struct{}
+
+var _ parser.ASTTransformer = headingTransformer{}
+
+func (headingTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var toc []Heading
+	source := reader.Source()
+
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := node.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+
+		id, ok := heading.AttributeString("id")
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+		idValue, ok := id.([]byte)
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+
+		toc = append(toc, Heading{
+			Level: heading.Level,
+			ID:    string(idValue),
+			Text:  strings.TrimSpace(nodeText(heading, source)),
+		})
+
+		anchor := ast.NewLink()
+		anchor.Destination = append([]byte("#"), idValue...)
+		anchor.SetAttributeString("class", []byte("anchor"))
+		anchor.AppendChild(anchor, ast.NewString([]byte("#")))
+		heading.AppendChild(heading, anchor)
+
+		return ast.WalkSkipChildren, nil
+	})
+
+	pc.Set(tocKey, toc)
+}
+
+// nodeText returns the plain text of the inline children of a node
+func nodeText(node ast.Node, source []byte) string {
+	var b strings.Builder
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		default:
+			b.WriteString(nodeText(child, source))
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	src := "---\r\ntitle: Hello World\r\ntags: [go, grpc]\r\nstatus: published\r\ndate: 2024-03-01T10:00:00Z\r\n---\r\n\r\n# Hello\r\n"
+
+	doc, err := ParseMarkdown([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.FrontMatter.Title != "Hello World" || doc.FrontMatter.Status != "published" {
+		t.Errorf("unexpected front matter %+v", doc.FrontMatter)
+	}
+	if strings.Join(doc.FrontMatter.Tags, ",") != "go,grpc" {
+		t.Errorf("unexpected tags %v", doc.FrontMatter.Tags)
+	}
+	if doc.FrontMatter.Date == nil || !doc.FrontMatter.Date.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date %v", doc.FrontMatter.Date)
+	}
+	if doc.Body != "# Hello\n" {
+		t.Errorf("unexpected body %q", doc.Body)
+	}
+
+	out, err := doc.Markdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseMarkdown(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.FrontMatter.Title != doc.FrontMatter.Title || again.Body != doc.Body {
+		t.Errorf("markdown does not round trip: %q", out)
+	}
+}
+
+func TestParseMarkdownWithoutFrontMatter(t *testing.T) {
+	doc, err := ParseMarkdown([]byte("just text\n---\nmore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.FrontMatter.Title != "" || doc.Body != "just text\n---\nmore" {
+		t.Errorf("unexpected document %+v", doc)
+	}
+
+	if _, err := ParseMarkdown([]byte("---\ntitle: open\n")); err == nil {
+		t.Error("expected an error for an unclosed front matter")
+	}
+}
+
+func TestHTMLIsSanitized(t *testing.T) {
+	out, err := HTML("# Title\n\n<script>alert(1)</script>\n\n[link](javascript:alert(1)) <img src=x onerror=alert(1)>\n\n```go\nfmt.Println()\n```\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, unsafe := range []string{"<script", "javascript:", "onerror"} {
+		if strings.Contains(out, unsafe) {
+			t.Errorf("html contains %s: %s", unsafe, out)
+		}
+	}
+	for _, want := range []string{"<h1", `class="chroma"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("html is missing %s: %s", want, out)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Hello, World!":    "hello-world",
+		"  Café au lait  ": "cafe-au-lait",
+		"gRPC & Go 1.23":   "grpc-go-1-23",
+		"---":              "",
+	}
+	for title, want := range tests {
+		if got := Slugify(title); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	rendered, err := Render("# Getting Started\n\nIntro ![diagram](/v1/files/d.png)\n\n## Install `unstak`\n\n<img src=\"a.png\" loading=\"eager\">\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Heading{
+		{Level: 1, ID: "getting-started", Text: "Getting Started"},
+		{Level: 2, ID: "install-unstak", Text: "Install unstak"},
+	}
+	if len(rendered.TOC) != len(want) {
+		t.Fatalf("unexpected table of contents %+v", rendered.TOC)
+	}
+	for i := range want {
+		if rendered.TOC[i] != want[i] {
+			t.Errorf("toc entry %d is %+v, want %+v", i, rendered.TOC[i], want[i])
+		}
+	}
+
+	for _, want := range []string{
+		`<h1 id="getting-started">`,
+		`<a href="#getting-started" class="anchor">#</a>`,
+		`<img loading="lazy" src="/v1/files/d.png"`,
+		`loading="eager"`,
+	} {
+		if !strings.Contains(rendered.HTML, want) {
+			t.Errorf("html is missing %s: %s", want, rendered.HTML)
+		}
+	}
+}
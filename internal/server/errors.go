@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// ErrorInterceptor is a server interceptor that translates the errors returned by the services into gRPC status errors.
+func ErrorInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, statusError(info.FullMethod, err)
+		}
+
+		return resp, nil
+	}
+}
+
+// ErrorStreamInterceptor is a server interceptor that translates the errors returned by the streaming services into gRPC status errors.
+func ErrorStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return statusError(info.FullMethod, err)
+		}
+
+		return nil
+	}
+}
+
+// statusError maps the store errors to the gRPC codes, the status errors are returned as is.
+// The unexpected errors are logged and reported as Internal without leaking the details to the client.
+func statusError(method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var validationErr *store.ValidationError
+	var notFoundErr *store.NotFoundError
+
+	switch {
+	case errors.As(err, &validationErr):
+		badRequest := &errdetails.BadRequest{}
+		for _, violation := range validationErr.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+		return withDetails(status.New(codes.InvalidArgument, err.Error()), badRequest)
+	case errors.As(err, &notFoundErr):
+		return withDetails(status.New(codes.NotFound, err.Error()), &errdetails.ResourceInfo{
+			ResourceType: notFoundErr.Resource,
+			ResourceName: notFoundErr.ID,
+			Description:  err.Error(),
+		})
+	case errors.Is(err, store.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, store.ErrAlreadyExists):
+		return status.Error(codes.AlreadyExists, store.ErrAlreadyExists.Error())
+	case errors.Is(err, store.ErrForeignKeyViolation):
+		return status.Error(codes.FailedPrecondition, store.ErrForeignKeyViolation.Error())
+	case errors.Is(err, store.ErrConflict):
+		return status.Error(codes.Aborted, store.ErrConflict.Error())
+	case errors.Is(err, store.ErrInvalidArgument):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	logrus.Errorf("internal error: %v: %v", method, err)
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// withDetails attaches the details to the status, the status is returned without the details if they fail to encode
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) error {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		logrus.Errorf("error attaching status details: %v", err)
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
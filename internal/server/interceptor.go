@@ -4,11 +4,18 @@ import (
 	"context"
 	"errors"
 	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/apitoken"
+	"github.com/emrgen/unpost/internal/health"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/preview"
 	"github.com/emrgen/unpost/internal/x"
 	"github.com/golang-jwt/jwt/v5"
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"strings"
 	"time"
 )
@@ -18,19 +25,116 @@ var (
 	NoAuthHeaderError = errors.New("no auth header found")
 )
 
-// VerifyTokenInterceptor is a server interceptor that verifies the jwt token for each RPC call.
-func VerifyTokenInterceptor(jwtSecret string) grpc.UnaryServerInterceptor {
+// publicMethods are called without a token, they create the accounts and the sessions and recover the accounts.
+// The server rate limits them per client.
+var publicMethods = map[string]bool{
+	v1.AccountService_CreateAccount_FullMethodName:        true,
+	v1.AccountService_LoginUsingPassword_FullMethodName:   true,
+	v1.AccountService_CheckOwnerSetup_FullMethodName:      true,
+	v1.AccountService_CreateOwner_FullMethodName:          true,
+	v1.AccountService_RefreshToken_FullMethodName:         true,
+	v1.AccountService_RequestPasswordReset_FullMethodName: true,
+	v1.AccountService_ConfirmPasswordReset_FullMethodName: true,
+	v1.AccountService_VerifyEmail_FullMethodName:          true,
+	v1.AccountService_StartOAuthLogin_FullMethodName:      true,
+	v1.AccountService_CompleteOAuthLogin_FullMethodName:   true,
+}
+
+// VerifyTokenInterceptor is a server interceptor that verifies the jwt token or the api token for each RPC call.
+// A GetPost call with a valid preview token is let through without a token, a nil apiTokens rejects the api tokens.
+func VerifyTokenInterceptor(jwtSecret string, apiTokens *apitoken.Authenticator) grpc.UnaryServerInterceptor {
+	previews := preview.NewSigner(jwtSecret)
+
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
-		switch info.FullMethod {
-		case v1.AccountService_CreateAccount_FullMethodName, v1.AccountService_LoginUsingPassword_FullMethodName:
+		if health.IsHealthMethod(info.FullMethod) || publicMethods[info.FullMethod] {
 			return handler(ctx, req)
+		}
+
+		switch info.FullMethod {
+		case v1.PostService_GetPost_FullMethodName:
+			if request, ok := req.(*v1.GetPostRequest); ok && request.GetPreviewToken() != "" {
+				return previewInterceptor(ctx, jwtSecret, apiTokens, previews, request, info, handler)
+			}
+			return tokenInterceptor(ctx, jwtSecret, apiTokens, req, info, handler)
 		default:
-			return tokenInterceptor(ctx, jwtSecret, req, info, handler)
+			return tokenInterceptor(ctx, jwtSecret, apiTokens, req, info, handler)
+		}
+	}
+}
+
+// previewInterceptor lets a read through a preview token in, the service checks that the link is not revoked.
+// The callers with an account are authenticated so that the audit of the link records them.
+func previewInterceptor(ctx context.Context, jwtSecret string, apiTokens *apitoken.Authenticator, previews *preview.Signer, request *v1.GetPostRequest, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if _, err := previews.Verify(request.GetPreviewToken()); err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if _, err := TokenFromHeader(ctx, "Bearer"); err == nil {
+		if authCtx, err := authenticate(ctx, jwtSecret, apiTokens, info.FullMethod); err == nil {
+			ctx = authCtx
+		}
+	}
+
+	return handler(ctx, request)
+}
+
+func tokenInterceptor(ctx context.Context, jwtSecret string, apiTokens *apitoken.Authenticator, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	ctx, err = authenticate(ctx, jwtSecret, apiTokens, info.FullMethod)
+	if err != nil {
+		return nil, authError(err)
+	}
+	if err := checkTokenSpace(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// VerifyTokenStreamInterceptor is a server interceptor that verifies the jwt token or the api token for each streaming RPC call.
+func VerifyTokenStreamInterceptor(jwtSecret string, apiTokens *apitoken.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if health.IsHealthMethod(info.FullMethod) {
+			return handler(srv, ss)
 		}
+
+		ctx, err := authenticate(ss.Context(), jwtSecret, apiTokens, info.FullMethod)
+		if err != nil {
+			return authError(err)
+		}
+
+		wrapped := grpcmiddleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+
+		return handler(srv, &tokenSpaceStream{WrappedServerStream: wrapped})
+	}
+}
+
+// tokenSpaceStream checks the space of the messages received from the callers using an api token
+type tokenSpaceStream struct {
+	*grpcmiddleware.WrappedServerStream
+}
+
+func (s *tokenSpaceStream) RecvMsg(m any) error {
+	if err := s.WrappedServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return checkTokenSpace(s.Context(), m)
+}
+
+// authError keeps the status of the errors that have one, the other errors fail the authentication
+func authError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
 	}
+
+	return status.Error(codes.Unauthenticated, err.Error())
 }
 
-func tokenInterceptor(ctx context.Context, jwtSecret string, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+type apiTokenKey struct{}
+
+// authenticate verifies the jwt token or the api token of the request and returns the context with the user id and the token
+func authenticate(ctx context.Context, jwtSecret string, apiTokens *apitoken.Authenticator, method string) (context.Context, error) {
 	jwtToken, err := TokenFromHeader(ctx, "Bearer")
 	if err != nil {
 		logrus.Errorf("interceptor error getting token from header: %v", err)
@@ -39,7 +143,10 @@ func tokenInterceptor(ctx context.Context, jwtSecret string, req any, info *grpc
 	if len(jwtToken) == 0 {
 		return nil, errors.New("token is empty")
 	}
-	
+	if apitoken.IsApiToken(jwtToken) {
+		return authenticateApiToken(ctx, apiTokens, jwtToken, method)
+	}
+
 	key := []byte(jwtSecret)
 	token, err := jwt.Parse(jwtToken, func(token *jwt.Token) (interface{}, error) {
 		return key, nil
@@ -61,8 +168,74 @@ func tokenInterceptor(ctx context.Context, jwtSecret string, req any, info *grpc
 
 	ctx = x.ContextWithUserID(ctx, userID)
 	ctx = x.ContextWithToken(ctx, jwtToken)
+	// the role is set by the auth provider in the app metadata of the user
+	if metadata, ok := claims["app_metadata"].(map[string]any); ok {
+		if role, ok := metadata["role"].(string); ok {
+			ctx = x.ContextWithRole(ctx, role)
+		}
+	}
+	setAccessLogUser(ctx, userID)
 
-	return handler(ctx, req)
+	return ctx, nil
+}
+
+// authenticateApiToken verifies the api token and its scope for the method, the call acts as the creator of the token
+// with their current role capped by the scopes
+func authenticateApiToken(ctx context.Context, apiTokens *apitoken.Authenticator, bearer, method string) (context.Context, error) {
+	if apiTokens == nil {
+		return nil, apitoken.ErrInvalidToken
+	}
+
+	token, role, err := apiTokens.Authenticate(ctx, bearer)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, ok := apitoken.MethodScope(method)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "method is not open to the api tokens")
+	}
+	if !token.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "api token lacks the %s scope", scope)
+	}
+
+	ctx = x.ContextWithUserID(ctx, token.CreatedByID)
+	ctx = x.ContextWithToken(ctx, bearer)
+	ctx = x.ContextWithRole(ctx, string(role))
+	ctx = x.ContextWithTokenSpace(ctx, token.SpaceID)
+	ctx = context.WithValue(ctx, apiTokenKey{}, token)
+	setAccessLogUser(ctx, token.CreatedByID)
+
+	return ctx, nil
+}
+
+// checkTokenSpace fails when a caller using an api token names another space than the one of the token.
+// The messages carrying a space id must name the space of the token, an empty space would reach every space.
+// The calls naming a resource by id are checked by the services against the space of the loaded resource.
+func checkTokenSpace(ctx context.Context, msg any) error {
+	token, ok := ctx.Value(apiTokenKey{}).(*model.ApiToken)
+	if !ok {
+		return nil
+	}
+
+	var spaceID string
+	switch msg := msg.(type) {
+	case interface{ GetSpaceId() string }:
+		spaceID = msg.GetSpaceId()
+	case *v1.ImportSpaceRequest:
+		if msg.GetOptions() == nil {
+			return nil
+		}
+		spaceID = msg.GetOptions().GetSpaceId()
+	default:
+		return nil
+	}
+
+	if spaceID != token.SpaceID {
+		return status.Error(codes.PermissionDenied, "api token is limited to space "+token.SpaceID)
+	}
+
+	return nil
 }
 
 func TokenFromHeader(ctx context.Context, expectedScheme string) (string, error) {
@@ -92,16 +265,6 @@ func TokenFromHeader(ctx context.Context, expectedScheme string) (string, error)
 	return token, nil
 }
 
-func UnaryGrpcRequestTimeInterceptor() grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		start := time.Now()
-		resp, err := handler(ctx, req)
-		reqTime := time.Since(start)
-		logrus.Infof("request time: %v: %v", info.FullMethod, reqTime)
-		return resp, err
-	}
-}
-
 func UnaryRequestTimeInterceptor() grpc.UnaryClientInterceptor {
 	return func(
 		ctx context.Context,
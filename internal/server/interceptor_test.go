@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/apitoken"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/preview"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/x"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestVerifyTokenInterceptorPreview(t *testing.T) {
+	interceptor := VerifyTokenInterceptor("secret", nil)
+	info := &grpc.UnaryServerInfo{FullMethod: v1.PostService_GetPost_FullMethodName}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+
+	token, err := preview.NewSigner("secret").Sign(preview.Claims{LinkID: "link", PostID: "post", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := interceptor(context.Background(), &v1.GetPostRequest{Id: "post", PreviewToken: token}, info, handler)
+	if err != nil || resp != "ok" {
+		t.Fatalf("expected the preview to pass without a jwt token, got %v", err)
+	}
+
+	for name, req := range map[string]*v1.GetPostRequest{
+		"invalid preview token": {Id: "post", PreviewToken: "invalid"},
+		"no preview token":      {Id: "post"},
+	} {
+		_, err := interceptor(context.Background(), req, info, handler)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s: expected unauthenticated, got %v", name, err)
+		}
+	}
+
+	// the preview token opens only GetPost
+	other := &grpc.UnaryServerInfo{FullMethod: v1.PostService_ListPost_FullMethodName}
+	if _, err := interceptor(context.Background(), &v1.GetPostRequest{PreviewToken: token}, other, handler); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected unauthenticated, got %v", err)
+	}
+}
+
+func TestVerifyTokenInterceptorApiToken(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewGormStore(db, nil)
+	spaceID := uuid.NewString()
+	creator := &model.User{ID: uuid.NewString(), Username: "jane", Email: uuid.NewString() + "@example.com", Role: model.UserRoleAdmin}
+	if err := s.CreateUser(ctx, creator); err != nil {
+		t.Fatal(err)
+	}
+
+	id := uuid.New()
+	secret, hash, err := apitoken.Generate(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.CreateApiToken(ctx, &model.ApiToken{
+		ID:          id.String(),
+		SpaceID:     spaceID,
+		Name:        "ci",
+		SecretHash:  hash,
+		Scopes:      apitoken.ScopeReadTags,
+		CreatedByID: creator.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interceptor := VerifyTokenInterceptor("secret", apitoken.NewAuthenticator(s))
+	handler := func(ctx context.Context, req any) (any, error) {
+		role, _ := x.RoleFromContext(ctx)
+		tokenSpace, _ := x.TokenSpaceFromContext(ctx)
+		return [2]string{role, tokenSpace}, nil
+	}
+	call := func(method string, req any) (any, error) {
+		ctx := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+secret))
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+
+	// the services check the resources named by id against the space of the token
+	caller, err := call(v1.TagService_ListTag_FullMethodName, &v1.ListTagRequest{SpaceId: spaceID})
+	// the read scopes cap the admin to a viewer
+	if err != nil || caller != [2]string{string(model.UserRoleViewer), spaceID} {
+		t.Fatalf("expected the call to act with the capped role of the creator in the space of the token, got %v %v", caller, err)
+	}
+
+	for name, c := range map[string]struct {
+		method string
+		req    any
+	}{
+		"missing scope": {v1.TagService_CreateTag_FullMethodName, &v1.CreateTagRequest{SpaceId: spaceID}},
+		"closed method": {v1.TokenService_CreateApiToken_FullMethodName, &v1.CreateApiTokenRequest{SpaceId: spaceID}},
+		"another space": {v1.TagService_ListTag_FullMethodName, &v1.ListTagRequest{SpaceId: uuid.NewString()}},
+		"every space":   {v1.TagService_ListTag_FullMethodName, &v1.ListTagRequest{}},
+		"webhooks":      {v1.WebhookService_ListWebhooks_FullMethodName, &v1.ListWebhooksRequest{}},
+	} {
+		if _, err := call(c.method, c.req); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: expected permission denied, got %v", name, err)
+		}
+	}
+}
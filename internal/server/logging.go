@@ -0,0 +1,307 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/emrgen/unpost/internal/health"
+	"github.com/emrgen/unpost/internal/x"
+	"github.com/google/uuid"
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpcrecovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// requestIDHeader is the metadata key of the request id, the gateway forwards it as the X-Request-Id header
+	requestIDHeader = "x-request-id"
+	redacted        = "[REDACTED]"
+)
+
+// sensitiveFields are the request fields that are never written to the logs
+var sensitiveFields = map[string]bool{
+	"password":         true,
+	"new_password":     true,
+	"old_password":     true,
+	"current_password": true,
+	"token":            true,
+	"access_token":     true,
+	"refresh_token":    true,
+	"secret":           true,
+	"api_key":          true,
+	"client_secret":    true,
+	"code_verifier":    true,
+	"code":             true,
+	"state":            true,
+	"authorization":    true,
+	"preview_token":    true,
+}
+
+// accessLogger writes one json line per RPC call
+var accessLogger = &logrus.Logger{
+	Out:       os.Stdout,
+	Formatter: &logrus.JSONFormatter{},
+	Hooks:     make(logrus.LevelHooks),
+	Level:     logrus.InfoLevel,
+}
+
+// accessLogEntry collects the fields known only to the inner interceptors, e.g. the authenticated user
+type accessLogEntry struct {
+	userID  string
+	spaceID string
+}
+
+type accessLogKey struct{}
+
+// setAccessLogUser records the authenticated user of the call in the access log
+func setAccessLogUser(ctx context.Context, userID string) {
+	if entry, ok := ctx.Value(accessLogKey{}).(*accessLogEntry); ok {
+		entry.userID = userID
+	}
+}
+
+// RequestIDInterceptor is a server interceptor that accepts the x-request-id of the caller or generates a new one,
+// the request id is added to the context and sent back in the response header.
+func RequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, requestID := requestIDFromIncoming(ctx)
+		if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, requestID)); err != nil {
+			logrus.Errorf("error setting request id header: %v", err)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// RequestIDStreamInterceptor is a server interceptor that adds the request id to the streaming calls.
+func RequestIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, requestID := requestIDFromIncoming(ss.Context())
+		if err := ss.SetHeader(metadata.Pairs(requestIDHeader, requestID)); err != nil {
+			logrus.Errorf("error setting request id header: %v", err)
+		}
+
+		wrapped := grpcmiddleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+
+		return handler(srv, wrapped)
+	}
+}
+
+func requestIDFromIncoming(ctx context.Context) (context.Context, string) {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+
+	// the caller controls the id, keep it short enough to be safe in the logs
+	if requestID == "" || len(requestID) > 128 {
+		requestID = uuid.New().String()
+	}
+
+	return x.ContextWithRequestID(ctx, requestID), requestID
+}
+
+// AccessLogInterceptor is a server interceptor that writes a structured access log line for each RPC call.
+// The request is logged at debug level with the sensitive fields redacted, the health probes are not logged.
+func AccessLogInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if health.IsHealthMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		entry := &accessLogEntry{spaceID: spaceIDOf(req)}
+		ctx = context.WithValue(ctx, accessLogKey{}, entry)
+
+		resp, err := handler(ctx, req)
+
+		fields := accessLogFields(ctx, info.FullMethod, entry, err, time.Since(start))
+		if accessLogger.IsLevelEnabled(logrus.DebugLevel) {
+			fields["request"] = redactRequest(req)
+		}
+		writeAccessLog(fields, err)
+
+		return resp, err
+	}
+}
+
+// AccessLogStreamInterceptor is a server interceptor that writes a structured access log line when a stream ends.
+func AccessLogStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if health.IsHealthMethod(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		start := time.Now()
+		entry := &accessLogEntry{}
+
+		wrapped := &accessLogStream{
+			WrappedServerStream: grpcmiddleware.WrapServerStream(ss),
+			entry:               entry,
+		}
+		wrapped.WrappedContext = context.WithValue(ss.Context(), accessLogKey{}, entry)
+
+		err := handler(srv, wrapped)
+
+		writeAccessLog(accessLogFields(wrapped.Context(), info.FullMethod, entry, err, time.Since(start)), err)
+
+		return err
+	}
+}
+
+// accessLogStream picks the space id from the first received message
+type accessLogStream struct {
+	*grpcmiddleware.WrappedServerStream
+	entry *accessLogEntry
+}
+
+func (s *accessLogStream) RecvMsg(m any) error {
+	if err := s.WrappedServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if s.entry.spaceID == "" {
+		s.entry.spaceID = spaceIDOf(m)
+	}
+
+	return nil
+}
+
+func accessLogFields(ctx context.Context, method string, entry *accessLogEntry, err error, latency time.Duration) logrus.Fields {
+	fields := logrus.Fields{
+		"method":     method,
+		"code":       status.Code(err).String(),
+		"latency_ms": float64(latency.Microseconds()) / 1000,
+	}
+
+	if requestID, ok := x.RequestIDFromContext(ctx); ok {
+		fields["request_id"] = requestID
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields["trace_id"] = spanContext.TraceID().String()
+	}
+	if entry.userID != "" {
+		fields["user_id"] = entry.userID
+	}
+	if entry.spaceID != "" {
+		fields["space_id"] = entry.spaceID
+	}
+	if err != nil {
+		fields["error"] = status.Convert(err).Message()
+	}
+
+	return fields
+}
+
+func writeAccessLog(fields logrus.Fields, err error) {
+	logger := accessLogger.WithFields(fields)
+	switch status.Code(err) {
+	case codes.OK:
+		logger.Info("rpc")
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		logger.Error("rpc")
+	default:
+		logger.Warn("rpc")
+	}
+}
+
+// spaceIDOf returns the space id of the requests scoped to a space
+func spaceIDOf(req any) string {
+	if scoped, ok := req.(interface{ GetSpaceId() string }); ok {
+		return scoped.GetSpaceId()
+	}
+
+	return ""
+}
+
+// redactRequest converts the request into a json object with the sensitive fields replaced
+func redactRequest(req any) any {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+
+	return redact(value)
+}
+
+func redact(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if sensitiveFields[strings.ToLower(key)] {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redact(field)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+
+	return value
+}
+
+// RecoveryInterceptor is a server interceptor that turns a panic in a handler into an Internal error.
+func RecoveryInterceptor() grpc.UnaryServerInterceptor {
+	return grpcrecovery.UnaryServerInterceptor(grpcrecovery.WithRecoveryHandlerContext(recoverPanic))
+}
+
+// RecoveryStreamInterceptor is a server interceptor that turns a panic in a streaming handler into an Internal error.
+func RecoveryStreamInterceptor() grpc.StreamServerInterceptor {
+	return grpcrecovery.StreamServerInterceptor(grpcrecovery.WithRecoveryHandlerContext(recoverPanic))
+}
+
+func recoverPanic(ctx context.Context, p any) error {
+	requestID, _ := x.RequestIDFromContext(ctx)
+	logrus.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"panic":      p,
+	}).Errorf("recovered from panic: %s", debug.Stack())
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// gatewayIncomingHeaderMatcher forwards the request id header of the http request to the grpc server
+func gatewayIncomingHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, requestIDHeader) {
+		return requestIDHeader, true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// gatewayOutgoingHeaderMatcher returns the request id as the X-Request-Id header of the http response
+func gatewayOutgoingHeaderMatcher(key string) (string, bool) {
+	if key == requestIDHeader {
+		return "X-Request-Id", true
+	}
+
+	return runtime.MetadataHeaderPrefix + key, true
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/emrgen/unpost/internal/x"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRedactRemovesSensitiveFields(t *testing.T) {
+	value := redact(map[string]any{
+		"email":    "owner@example.com",
+		"password": "hunter2",
+		"nested": []any{
+			map[string]any{"refresh_token": "abc", "title": "hello"},
+		},
+	}).(map[string]any)
+
+	if value["password"] != redacted {
+		t.Errorf("password = %v, want %v", value["password"], redacted)
+	}
+	if value["email"] != "owner@example.com" {
+		t.Errorf("email = %v, want it unchanged", value["email"])
+	}
+
+	nested := value["nested"].([]any)[0].(map[string]any)
+	if nested["refresh_token"] != redacted {
+		t.Errorf("refresh_token = %v, want %v", nested["refresh_token"], redacted)
+	}
+	if nested["title"] != "hello" {
+		t.Errorf("title = %v, want it unchanged", nested["title"])
+	}
+}
+
+func TestRequestIDFromIncoming(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDHeader, "req-1"))
+	ctx, requestID := requestIDFromIncoming(ctx)
+	if requestID != "req-1" {
+		t.Errorf("request id = %q, want req-1", requestID)
+	}
+	if got, _ := x.RequestIDFromContext(ctx); got != "req-1" {
+		t.Errorf("context request id = %q, want req-1", got)
+	}
+
+	_, requestID = requestIDFromIncoming(context.Background())
+	if requestID == "" {
+		t.Error("request id is not generated when missing")
+	}
+}
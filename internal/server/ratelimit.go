@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RateLimiter counts the calls of each key in fixed windows
+type RateLimiter struct {
+	limit   int
+	window  time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+	pruned  time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter creates a new RateLimiter allowing limit calls per key in each window
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow counts a call of the key, it returns false and the time until the next window once the key is over the limit
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// the ended windows are dropped once per window so that the clients gone do not pile up
+	if now.Sub(l.pruned) >= l.window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+		l.pruned = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+	w.count++
+	if w.count > l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+
+	return true, 0
+}
+
+// RateLimitInterceptor limits the calls of the methods per client and method, the other methods are not limited.
+// It guards the unauthenticated methods against password guessing and email flooding.
+func RateLimitInterceptor(limiter *RateLimiter, methods map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !methods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		if ok, retryAfter := limiter.Allow(info.FullMethod + "|" + clientAddress(ctx)); !ok {
+			return nil, withDetails(status.New(codes.ResourceExhausted, "too many requests, retry later"), &errdetails.RetryInfo{
+				RetryDelay: durationpb.New(retryAfter),
+			})
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// clientAddress returns the address of the caller. The calls through the gateway come from the loopback,
+// their client is the last x-forwarded-for entry which the gateway appends, the earlier entries are set by the client.
+func clientAddress(ctx context.Context) string {
+	var address string
+	if caller, ok := peer.FromContext(ctx); ok && caller.Addr != nil {
+		address = caller.Addr.String()
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			address = host
+		}
+	}
+
+	if ip := net.ParseIP(address); ip == nil || ip.IsLoopback() {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if forwarded := md.Get("x-forwarded-for"); len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			address = strings.TrimSpace(entries[len(entries)-1])
+		}
+	}
+
+	return address
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("call %d: expected the call to be allowed", i)
+		}
+	}
+	ok, retryAfter := limiter.Allow("a")
+	if ok || retryAfter != time.Minute {
+		t.Errorf("expected the third call to wait a minute, got %v %v", ok, retryAfter)
+	}
+	if ok, _ := limiter.Allow("b"); !ok {
+		t.Error("expected the keys to be limited apart")
+	}
+
+	now = now.Add(time.Minute)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Error("expected the next window to allow the call")
+	}
+	if _, ok := limiter.windows["b"]; ok {
+		t.Error("expected the ended window to be dropped")
+	}
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	const limited = "/test/Limited"
+	interceptor := RateLimitInterceptor(NewRateLimiter(1, time.Minute), map[string]bool{limited: true})
+	handler := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+	call := func(method, forwardedFor string) error {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}})
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", forwardedFor))
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	if err := call(limited, "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	// the entries set by the client do not change the client
+	if err := call(limited, "10.0.0.9, 10.0.0.1"); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected the client to be limited, got %v", err)
+	}
+	if err := call(limited, "10.0.0.2"); err != nil {
+		t.Errorf("expected another client to be allowed, got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := call("/test/Open", "10.0.0.1"); err != nil {
+			t.Errorf("expected the other methods to be open, got %v", err)
+		}
+	}
+}
+
+func TestClientAddress(t *testing.T) {
+	remote := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 168, 1, 7), Port: 5000}})
+	remote = metadata.NewIncomingContext(remote, metadata.Pairs("x-forwarded-for", "10.0.0.1"))
+	// a direct grpc caller cannot pick its address
+	if address := clientAddress(remote); address != "192.168.1.7" {
+		t.Errorf("expected the peer address, got %s", address)
+	}
+}
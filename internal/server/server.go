@@ -7,23 +7,37 @@ import (
 	gatewayfile "github.com/black-06/grpc-gateway-file"
 	authx "github.com/emrgen/authbase/x"
 	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/apitoken"
+	"github.com/emrgen/unpost/internal/auth"
+	"github.com/emrgen/unpost/internal/cache"
 	"github.com/emrgen/unpost/internal/config"
+	"github.com/emrgen/unpost/internal/health"
+	"github.com/emrgen/unpost/internal/metrics"
+	"github.com/emrgen/unpost/internal/migrate"
 	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/outbox"
+	"github.com/emrgen/unpost/internal/preview"
+	"github.com/emrgen/unpost/internal/related"
+	"github.com/emrgen/unpost/internal/render"
 	"github.com/emrgen/unpost/internal/service"
 	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/tracing"
 	"github.com/gobuffalo/packr"
+	"github.com/google/uuid"
 	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpcvalidator "github.com/grpc-ecosystem/go-grpc-middleware/validator"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/rs/cors"
 	"github.com/sirupsen/logrus"
-	"github.com/supabase-community/auth-go"
-	"github.com/supabase-community/auth-go/types"
 	"golang.org/x/sys/unix"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/protobuf/encoding/protojson"
+	"gorm.io/gorm"
+	"math"
 	"net"
 	"net/http"
 	"os"
@@ -32,16 +46,33 @@ import (
 	"time"
 )
 
-// Start starts the grpc and http servers
-func Start(grpcPort, httpPort string) error {
+// Start starts the grpc and http servers, the admin server exposes the metrics
+func Start(grpcPort, httpPort, adminPort string) error {
 	var err error
 
 	grpcPort = ":" + grpcPort
 	httpPort = ":" + httpPort
+	adminPort = ":" + adminPort
 
 	cfg := config.LoadConfig()
 	rdb := config.GetDb(cfg)
 
+	// the traces follow a request from the gateway through grpc into the database and the downstream services
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingConfig)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logrus.Errorf("error flushing traces: %v", err)
+		}
+	}()
+	if err = rdb.Use(tracing.GormPlugin{}); err != nil {
+		return err
+	}
+
 	gl, err := net.Listen("tcp", grpcPort)
 	if err != nil {
 		return err
@@ -52,19 +83,23 @@ func Start(grpcPort, httpPort string) error {
 		return err
 	}
 
-	authConfig, err := authx.ConfigFromEnv()
+	al, err := net.Listen("tcp", adminPort)
 	if err != nil {
 		return err
 	}
 
-	// authClient provides the auth service
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(grpcmiddleware.ChainUnaryServer(
-			grpcvalidator.UnaryServerInterceptor(),
-			VerifyTokenInterceptor(cfg.SupabaseConfig.JwtSecret),
-			UnaryGrpcRequestTimeInterceptor(),
-		)),
-	)
+	sqlDB, err := rdb.DB()
+	if err != nil {
+		return err
+	}
+	if err = metrics.RegisterDB(sqlDB, cfg.DbConfig.Type); err != nil {
+		return err
+	}
+
+	authConfig, err := authx.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
 
 	// connect the rest gateway to the grpc server
 	mux := runtime.NewServeMux(
@@ -78,45 +113,150 @@ func Start(grpcPort, httpPort string) error {
 				},
 			},
 		}),
+		// server streams are rendered as server-sent events when the client accepts them
+		runtime.WithMarshalerOption(eventStreamContentType, &eventStreamMarshaler{
+			Marshaler: &runtime.JSONPb{
+				MarshalOptions: protojson.MarshalOptions{
+					EmitUnpopulated: true,
+				},
+			},
+		}),
 		gatewayfile.WithHTTPBodyMarshaler(),
+		// the request id is accepted from and returned to the http clients
+		runtime.WithIncomingHeaderMatcher(gatewayIncomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(gatewayOutgoingHeaderMatcher),
+		runtime.WithMiddlewares(metrics.GatewayMiddleware),
 	)
 
 	opts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithUnaryInterceptor(UnaryRequestTimeInterceptor()),
+		tracing.DialOption(),
 	}
 	endpoint := "localhost" + grpcPort
 
-	authClient := auth.New(cfg.SupabaseConfig.ProjectRef, cfg.SupabaseConfig.ApiKey)
+	// the invalidation bus keeps the caches of all the replicas in sync
+	invalidationBus, err := newInvalidationBus(cfg, rdb)
+	if err != nil {
+		return err
+	}
+	defer invalidationBus.Close()
 
-	// update the admin role on app start up if not set
-	client := authClient.WithToken(cfg.SupabaseConfig.ApiKey)
-	user, err := client.AdminGetUser(types.AdminGetUserRequest{UserID: cfg.AdminUserID})
+	// the schema is migrated with `unstak db migrate up` before the new version is rolled out
+	migrator, err := migrate.New(rdb)
 	if err != nil {
-		logrus.Error(err)
+		return err
 	}
-	if _, ok := user.AppMetadata["role"]; !ok {
-		_, err := client.AdminUpdateUser(types.AdminUpdateUserRequest{
-			UserID: cfg.AdminUserID,
-			AppMetadata: map[string]interface{}{
-				"role": model.UserRoleOwner,
-			},
-		})
-		if err != nil {
+	if err = migrator.Check(context.Background()); err != nil {
+		return err
+	}
+
+	unpostStore := store.NewGormStore(rdb, invalidationBus)
+
+	authProvider := newAuthProvider(cfg, unpostStore)
+	accountService := service.NewAccountService(unpostStore, authProvider, newSocialLogin(cfg, unpostStore, authProvider))
+	// the owner is created by the owner setup, the admin user of the older deployments is carried over
+	if cfg.AdminUserID != uuid.Nil {
+		if err = accountService.ImportOwner(context.Background(), cfg.AdminUserID); err != nil {
 			return err
 		}
 	}
 
-	unpostStore := store.NewGormStore(rdb)
-	err = unpostStore.Migrate()
-	if err != nil {
-		return err
+	// the calls are authenticated with a jwt token of the auth provider or with an api token of a space
+	apiTokens := apitoken.NewAuthenticator(unpostStore)
+	// the unauthenticated account methods are limited per client against password guessing
+	authLimit := cfg.AuthConfig.RateLimit
+	if authLimit <= 0 {
+		authLimit = math.MaxInt
+	}
+	authLimiter := NewRateLimiter(authLimit, time.Minute)
+	grpcServer := grpc.NewServer(
+		tracing.ServerOption(),
+		grpc.UnaryInterceptor(grpcmiddleware.ChainUnaryServer(
+			RequestIDInterceptor(),
+			metrics.UnaryServerInterceptor(),
+			AccessLogInterceptor(),
+			RecoveryInterceptor(),
+			ErrorInterceptor(),
+			RateLimitInterceptor(authLimiter, publicMethods),
+			grpcvalidator.UnaryServerInterceptor(),
+			VerifyTokenInterceptor(cfg.AuthConfig.JwtSecret, apiTokens),
+		)),
+		grpc.StreamInterceptor(grpcmiddleware.ChainStreamServer(
+			RequestIDStreamInterceptor(),
+			metrics.StreamServerInterceptor(),
+			AccessLogStreamInterceptor(),
+			RecoveryStreamInterceptor(),
+			ErrorStreamInterceptor(),
+			grpcvalidator.StreamServerInterceptor(),
+			VerifyTokenStreamInterceptor(cfg.AuthConfig.JwtSecret, apiTokens),
+		)),
+	)
+
+	// published posts and rendered post versions are cached in memory when the cache is enabled
+	var postCache *cache.ObjectCache[model.Post]
+	var renderedCache *cache.ObjectCache[render.Rendered]
+	if cfg.CacheConfig.Enabled {
+		postCache = cache.NewObjectCache[model.Post](cfg.CacheConfig.Size, cfg.CacheConfig.TTL)
+		unsubscribe := cache.Subscribe(invalidationBus, postCache)
+		defer unsubscribe()
+
+		if err = metrics.RegisterCache("post", postCache.Stats); err != nil {
+			return err
+		}
+
+		// the rendered posts are keyed by version, they are never stale and need no invalidation
+		renderedCache = cache.NewObjectCache[render.Rendered](cfg.CacheConfig.Size, cfg.CacheConfig.TTL)
+		if err = metrics.RegisterCache("rendered_post", renderedCache.Stats); err != nil {
+			return err
+		}
+	}
+
+	// the dispatcher delivers the outbox events to the registered webhooks
+	webhookSender := outbox.NewSender(nil)
+	dispatcher := outbox.NewDispatcher(unpostStore, webhookSender)
+	dispatcher.Start(context.Background())
+	defer dispatcher.Stop()
+
+	// the related posts are recomputed in the background, the reads only query the relations table
+	relatedJob := related.NewJob(unpostStore)
+	relatedJob.Start(context.Background())
+	defer relatedJob.Stop()
+
+	// the readiness checks cover the dependencies without which the requests fail
+	checker := health.NewChecker(5 * time.Second)
+	checker.Add("database", sqlDB.PingContext)
+	checker.Add("migrations", migrator.Check)
+	if cfg.DocumentConfig.Address != "" {
+		documentConn, err := grpc.NewClient(cfg.DocumentConfig.Address,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			tracing.DialOption(),
+		)
+		if err != nil {
+			return err
+		}
+		defer documentConn.Close()
+		checker.Add("document", health.GRPCCheck(documentConn))
 	}
 
+	// the grpc health service mirrors the readiness checks
+	healthServer := grpchealth.NewServer()
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	defer stopHealth()
+	go checker.Watch(healthCtx, healthServer, 10*time.Second)
+
+	feedService := service.NewFeedService(unpostStore)
+
 	// Register the grpc server
-	v1.RegisterAccountServiceServer(grpcServer, service.NewAccountService(unpostStore, authClient))
-	v1.RegisterPostServiceServer(grpcServer, service.NewPostService(authConfig, unpostStore))
-	//v1.RegisterTagServiceServer(grpcServer, service.NewTagService(unpostStore))
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	v1.RegisterAccountServiceServer(grpcServer, accountService)
+	v1.RegisterPostServiceServer(grpcServer, service.NewPostService(authConfig, unpostStore, postCache, renderedCache, preview.NewSigner(cfg.AuthConfig.JwtSecret)))
+	v1.RegisterWebhookServiceServer(grpcServer, service.NewWebhookService(unpostStore, webhookSender))
+	v1.RegisterFeedServiceServer(grpcServer, feedService)
+	v1.RegisterSpaceServiceServer(grpcServer, service.NewSpaceService(unpostStore, nil))
+	v1.RegisterPlatformTagServiceServer(grpcServer, service.NewPlatformTagService(unpostStore))
+	v1.RegisterTagServiceServer(grpcServer, service.NewTagService(unpostStore))
+	v1.RegisterTokenServiceServer(grpcServer, service.NewTokenService(unpostStore))
 	//v1.RegisterCourseServiceServer(grpcServer, service.NewCourseService(authConfig, unpostStore))
 	//v1.RegisterPageServiceServer(grpcServer, service.NewPageService(authConfig, unpostStore))
 
@@ -136,72 +276,205 @@ func Start(grpcPort, httpPort string) error {
 	if err = v1.RegisterPageServiceHandlerFromEndpoint(context.TODO(), mux, endpoint, opts); err != nil {
 		return err
 	}
+	if err = v1.RegisterWebhookServiceHandlerFromEndpoint(context.TODO(), mux, endpoint, opts); err != nil {
+		return err
+	}
+	if err = v1.RegisterFeedServiceHandlerFromEndpoint(context.TODO(), mux, endpoint, opts); err != nil {
+		return err
+	}
+	if err = v1.RegisterSpaceServiceHandlerFromEndpoint(context.TODO(), mux, endpoint, opts); err != nil {
+		return err
+	}
+	if err = v1.RegisterPlatformTagServiceHandlerFromEndpoint(context.TODO(), mux, endpoint, opts); err != nil {
+		return err
+	}
+	if err = v1.RegisterTokenServiceHandlerFromEndpoint(context.TODO(), mux, endpoint, opts); err != nil {
+		return err
+	}
 
 	apiMux := http.NewServeMux()
 	openapiDocs := packr.NewBox("../../docs/v1")
 	docsPath := "/v1/docs/"
 	apiMux.Handle(docsPath, http.StripPrefix(docsPath, http.FileServer(openapiDocs)))
-	apiMux.Handle("/", mux)
+	apiMux.Handle("/healthz", checker.LivenessHandler())
+	apiMux.Handle("/readyz", checker.ReadinessHandler())
+	// the server streams are also served over websockets for the clients that cannot read SSE
+	apiMux.Handle("/", websocketHandler(mux))
 
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"}, // All origins are allowed
 		AllowedMethods:   []string{"GET", "POST", "DELETE", "PUT", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{"X-Request-Id"},
 		AllowCredentials: true,
 	})
 
 	restServer := &http.Server{
 		Addr:    httpPort,
-		Handler: c.Handler(apiMux),
+		Handler: tracing.HTTPHandler(c.Handler(apiMux), "gateway"),
+	}
+
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/metrics", metrics.Handler())
+	adminMux.Handle("/healthz", checker.LivenessHandler())
+	adminMux.Handle("/readyz", checker.ReadinessHandler())
+	adminServer := &http.Server{
+		Addr:    adminPort,
+		Handler: adminMux,
 	}
 
 	// make sure to wait for the servers to stop before exiting
 	var wg sync.WaitGroup
+	// a server that fails to serve stops the others
+	serveErrs := make(chan error, 3)
 
+	// Start the admin server
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		logrus.Info("starting admin server on: ", adminPort)
+		if err := adminServer.Serve(al); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErrs <- fmt.Errorf("admin server: %w", err)
+		}
+		logrus.Infof("admin server stopped")
+	}()
+
+	// Start the rest gateway
 	wg.Add(1)
-	// Start the grpc server
 	go func() {
 		defer wg.Done()
 		logrus.Info("starting rest gateway on: ", httpPort)
 		logrus.Info("click on the following link to view the API documentation: http://localhost", httpPort, "/v1/docs/")
-		if err := restServer.Serve(rl); err != nil {
-			if !errors.Is(err, http.ErrServerClosed) {
-				logrus.Errorf("error starting rest gateway: %v", err)
-			}
+		if err := restServer.Serve(rl); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErrs <- fmt.Errorf("rest gateway: %w", err)
 		}
 		logrus.Infof("rest gateway stopped")
 	}()
 
-	// Start the rest gateway
+	// Start the grpc server
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		logrus.Info("starting grpc server on: ", grpcPort)
 		if err := grpcServer.Serve(gl); err != nil {
-			logrus.Infof("grpc failed to start: %v", err)
+			serveErrs <- fmt.Errorf("grpc server: %w", err)
 		}
 		logrus.Infof("grpc server stopped")
 	}()
 
-	time.Sleep(1 * time.Second)
 	logrus.Infof("Press Ctrl+C to stop the server")
 
 	// listen for interrupt signal to gracefully shut down the server
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, unix.SIGTERM, unix.SIGINT, unix.SIGTSTP)
-	<-sigs
-	// clean Ctrl+C output
-	fmt.Println()
+	select {
+	case <-sigs:
+		// clean Ctrl+C output
+		fmt.Println()
+	case err = <-serveErrs:
+		logrus.Errorf("stopping the servers: %v", err)
+	}
 
-	grpcServer.Stop()
-	err = restServer.Shutdown(context.Background())
-	if err != nil {
-		logrus.Errorf("error stopping rest gateway: %v", err)
+	shutdown(cfg.ServerConfig.ShutdownTimeout, checker, healthServer, feedService, grpcServer, restServer, adminServer)
+
+	wg.Wait()
+
+	// the deferred calls stop the background workers after the servers are drained
+	return err
+}
+
+// shutdown stops accepting new requests and drains the in-flight ones until the timeout,
+// the remaining calls are cancelled once the timeout is reached
+func shutdown(
+	timeout time.Duration,
+	checker *health.Checker,
+	healthServer *grpchealth.Server,
+	feedService *service.FeedService,
+	grpcServer *grpc.Server,
+	servers ...*http.Server,
+) {
+	logrus.Infof("draining the in-flight requests, timeout %s", timeout)
+
+	// report not ready first so that the load balancers stop routing to this instance
+	checker.Shutdown()
+	healthServer.Shutdown()
+	// the change feed streams never end on their own, the clients resume from their last token
+	feedService.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				logrus.Errorf("error stopping http server %s: %v", server.Addr, err)
+				_ = server.Close()
+			}
+		}(server)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logrus.Warnf("shutdown timeout reached, cancelling the remaining grpc calls")
+		grpcServer.Stop()
 	}
 
 	wg.Wait()
+}
+
+// newInvalidationBus creates the cache invalidation bus for the database type,
+// postgres replicas share LISTEN/NOTIFY while sqlite runs as a single process
+func newInvalidationBus(cfg *config.Config, rdb *gorm.DB) (cache.InvalidationBus, error) {
+	if cfg.DbConfig.Type != "postgres" {
+		return cache.NewLocalBus(), nil
+	}
+
+	sqlDB, err := rdb.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	bus := cache.NewPostgresBus(sqlDB, cfg.DbConfig.ConnectionString)
+	bus.Start(context.Background())
+
+	return bus, nil
+}
+
+// newAuthProvider creates the auth provider selected by the config,
+// the local provider keeps the accounts in the database and needs no external service
+func newAuthProvider(cfg *config.Config, unpostStore *store.GormStore) auth.Provider {
+	if cfg.AuthConfig.Provider == config.AuthProviderLocal {
+		// there is no mailer yet, the password reset and verification tokens are logged
+		return auth.NewLocalProvider(unpostStore, auth.LogNotifier{}, cfg.AuthConfig.JwtSecret, cfg.AuthConfig.AccessTokenTTL, cfg.AuthConfig.RefreshTokenTTL)
+	}
+
+	return auth.NewSupabaseProvider(cfg.SupabaseConfig.ProjectRef, cfg.SupabaseConfig.ApiKey, tracing.HTTPClient())
+}
+
+// newSocialLogin creates the social login of the identity providers of the config, nil when there are none.
+// The config allows the identity providers with the local provider only, it signs the tokens of the linked accounts.
+func newSocialLogin(cfg *config.Config, unpostStore *store.GormStore, authProvider auth.Provider) *auth.SocialLogin {
+	linker, ok := authProvider.(auth.IdentityLinker)
+	if !ok || len(cfg.AuthConfig.OIDCProviders) == 0 {
+		return nil
+	}
+
+	providers := make([]*auth.OIDCProvider, 0, len(cfg.AuthConfig.OIDCProviders))
+	for _, provider := range cfg.AuthConfig.OIDCProviders {
+		providers = append(providers, auth.NewOIDCProvider(provider, tracing.HTTPClient()))
+	}
 
-	return nil
+	return auth.NewSocialLogin(unpostStore, linker, providers)
 }
 
 func createMasterSpace() {}
@@ -1,7 +1,8 @@
 package server
 
 import (
-	"github.com/emrgen/unpost/internal/model"
+	"context"
+	"github.com/emrgen/unpost/internal/migrate"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"os"
@@ -29,10 +30,13 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 
-	err = model.Migrate(db)
+	migrator, err := migrate.New(db)
 	if err != nil {
 		panic(err)
 	}
+	if _, err = migrator.Up(context.Background(), 0); err != nil {
+		panic(err)
+	}
 
 	//objectStore = objectstore.NewFileObjectStore(testPath+"objects/", testPath+"thumbnails/")
 
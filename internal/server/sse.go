@@ -0,0 +1,39 @@
+package server
+
+import (
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+const eventStreamContentType = "text/event-stream"
+
+var _ runtime.Delimited = (*eventStreamMarshaler)(nil)
+
+// eventStreamMarshaler renders the gateway responses as server-sent events.
+// The gateway writes every message of a server stream followed by the delimiter and flushes it,
+// so each message becomes a "data:" event on the client.
+type eventStreamMarshaler struct {
+	runtime.Marshaler
+}
+
+func (m *eventStreamMarshaler) ContentType(v interface{}) string {
+	return eventStreamContentType
+}
+
+func (m *eventStreamMarshaler) Marshal(v interface{}) ([]byte, error) {
+	data, err := m.Marshaler.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	event := make([]byte, 0, len(data)+7)
+	event = append(event, "data: "...)
+	event = append(event, data...)
+	event = append(event, '\n')
+
+	return event, nil
+}
+
+// Delimiter ends the event with a blank line
+func (m *eventStreamMarshaler) Delimiter() []byte {
+	return []byte("\n")
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmc/grpc-websocket-proxy/wsproxy"
+)
+
+const websocketPingInterval = 30 * time.Second
+
+// websocketHandler serves the server streams of the gateway over websockets, every message is sent as a json text frame.
+// The other requests go to the gateway unchanged.
+func websocketHandler(h http.Handler) http.Handler {
+	return wsproxy.WebsocketProxy(h,
+		wsproxy.WithLogger(logrus.StandardLogger()),
+		wsproxy.WithPingControl(websocketPingInterval),
+		wsproxy.WithForwardedHeaders(websocketForwardedHeader),
+		wsproxy.WithRequestMutator(websocketRequest),
+	)
+}
+
+// websocketForwardedHeader keeps the request id of the client
+func websocketForwardedHeader(header string) bool {
+	return strings.EqualFold(header, requestIDHeader)
+}
+
+// websocketRequest authenticates the proxied request with the Authorization header or the "Bearer, <token>" subprotocol of the browsers,
+// the proxy also reads a token cookie that any page could send along and it is dropped
+func websocketRequest(incoming *http.Request, outgoing *http.Request) *http.Request {
+	outgoing.Header.Del("Authorization")
+	if authorization := incoming.Header.Get("Authorization"); authorization != "" {
+		outgoing.Header.Set("Authorization", authorization)
+	} else if token, ok := strings.CutPrefix(incoming.Header.Get("Sec-WebSocket-Protocol"), "Bearer,"); ok {
+		outgoing.Header.Set("Authorization", "Bearer "+strings.TrimSpace(token))
+	}
+
+	return outgoing
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebsocketHandler(t *testing.T) {
+	// the stream writes the authorization it received then a change per line like the gateway
+	stream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "{\"authorization\":%q}\n", r.Header.Get("Authorization"))
+		fmt.Fprintf(w, "{\"result\":{\"id\":\"1\"}}\n")
+	})
+	server := httptest.NewServer(websocketHandler(stream))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/feed/content"
+
+	tests := []struct {
+		name          string
+		header        http.Header
+		authorization string
+	}{
+		{"header", http.Header{"Authorization": {"Bearer header"}}, "Bearer header"},
+		{"subprotocol", http.Header{"Sec-Websocket-Protocol": {"Bearer, protocol"}}, "Bearer protocol"},
+		{"cookie", http.Header{"Cookie": {"token=cookie"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, _, err := websocket.DefaultDialer.Dial(url, tt.header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			messages := make([]string, 2)
+			for i := range messages {
+				_, message, err := conn.ReadMessage()
+				if err != nil {
+					t.Fatal(err)
+				}
+				messages[i] = string(message)
+			}
+			if want := fmt.Sprintf("{\"authorization\":%q}", tt.authorization); messages[0] != want {
+				t.Errorf("expected %s, got %s", want, messages[0])
+			}
+			if messages[1] != `{"result":{"id":"1"}}` {
+				t.Errorf("expected the change, got %s", messages[1])
+			}
+		})
+	}
+
+	// the plain requests reach the gateway
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected the gateway response, got %d", res.StatusCode)
+	}
+}
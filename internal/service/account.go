@@ -3,19 +3,28 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
+	"sync"
+	"time"
+
 	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/auth"
+	"github.com/emrgen/unpost/internal/model"
 	"github.com/emrgen/unpost/internal/store"
 	"github.com/emrgen/unpost/internal/x"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"github.com/supabase-community/auth-go"
-	"github.com/supabase-community/auth-go/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// NewAccountService creates a new auth service
-func NewAccountService(store store.UnstakStore, authClient auth.Client) *AccountService {
+// NewAccountService creates a new auth service, a nil social login turns the social logins off
+func NewAccountService(store store.UnstakStore, provider auth.Provider, social *auth.SocialLogin) *AccountService {
 	return &AccountService{
-		store:      store,
-		authClient: authClient,
+		store:    store,
+		provider: provider,
+		social:   social,
 	}
 }
 
@@ -23,89 +32,552 @@ var (
 	_ v1.AccountServiceServer = new(AccountService)
 )
 
+// AccountService manages the accounts through the auth provider, the users table mirrors the accounts
+// of the provider so that they can be listed and filtered by role.
 type AccountService struct {
-	store      store.UnstakStore
-	authClient auth.Client
+	store    store.UnstakStore
+	provider auth.Provider
+	social   *auth.SocialLogin
+	// ownersMu serializes the owner setup and the changes of the owners of this process so that the last owner is kept,
+	// the lock of the store serializes them across the replicas
+	ownersMu sync.Mutex
 	v1.UnimplementedAccountServiceServer
 }
 
-func (a *AccountService) CreateAccount(ctx context.Context, req *v1.CreateAccountRequest) (*v1.CreateAccountResponse, error) {
-	signup, err := a.authClient.Signup(types.SignupRequest{
-		Email:         req.Email,
-		Password:      req.Password,
-		SecurityEmbed: types.SecurityEmbed{},
+// CheckOwnerSetup reports whether an owner exists, the clients run the owner setup until it does
+func (a *AccountService) CheckOwnerSetup(ctx context.Context, request *v1.CheckOwnerSetupRequest) (*v1.CheckOwnerSetupResponse, error) {
+	setUp, err := a.ownerSetUp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.CheckOwnerSetupResponse{
+		IsSetup: setUp,
+	}, nil
+}
+
+// CreateOwner creates the first account with the owner role, the call is open until an owner exists
+func (a *AccountService) CreateOwner(ctx context.Context, request *v1.CreateOwnerRequest) (*v1.CreateOwnerResponse, error) {
+	a.ownersMu.Lock()
+	defer a.ownersMu.Unlock()
+
+	var row *model.User
+	err := a.store.LockOwners(ctx, func(ctx context.Context) error {
+		setUp, err := a.ownerSetUp(ctx)
+		if err != nil {
+			return err
+		}
+		if setUp {
+			return status.Error(codes.FailedPrecondition, "owner is already set up")
+		}
+
+		user, err := a.provider.Signup(ctx, request.GetEmail(), request.GetPassword(), request.GetUsername())
+		if err != nil {
+			return authStatusError(err)
+		}
+		row, err = a.setUpOwner(ctx, user)
+		if err != nil {
+			// the account is removed so that the owner setup can be retried with the same email
+			if err := a.provider.DeleteUser(context.WithoutCancel(ctx), uuid.MustParse(user.ID)); err != nil {
+				logrus.Errorf("error removing the account %s of a failed owner setup: %v", user.ID, err)
+			}
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.CreateOwnerResponse{
+		User: userToProto(row),
+	}, nil
+}
+
+// setUpOwner gives the owner role to the new account and mirrors it in the users table
+func (a *AccountService) setUpOwner(ctx context.Context, user *auth.User) (*model.User, error) {
+	if err := a.provider.SetRole(ctx, uuid.MustParse(user.ID), model.UserRoleOwner); err != nil {
+		return nil, authStatusError(err)
+	}
+	user.Role = model.UserRoleOwner
+
+	return a.syncUser(ctx, user)
+}
+
+// ImportOwner gives the owner role to an existing account of the auth provider unless an owner is set up.
+// It carries the owner of the deployments created before the owner setup over.
+func (a *AccountService) ImportOwner(ctx context.Context, userID uuid.UUID) error {
+	a.ownersMu.Lock()
+	defer a.ownersMu.Unlock()
+
+	return a.store.LockOwners(ctx, func(ctx context.Context) error {
+		setUp, err := a.ownerSetUp(ctx)
+		if err != nil || setUp {
+			return err
+		}
+
+		user, err := a.provider.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.Role != model.UserRoleOwner {
+			if err := a.provider.SetRole(ctx, userID, model.UserRoleOwner); err != nil {
+				return err
+			}
+			user.Role = model.UserRoleOwner
+		}
+
+		_, err = a.syncUser(ctx, user)
+		return err
 	})
+}
+
+func (a *AccountService) CreateAccount(ctx context.Context, req *v1.CreateAccountRequest) (*v1.CreateAccountResponse, error) {
+	user, err := a.provider.Signup(ctx, req.GetEmail(), req.GetPassword(), req.GetName())
+	if err != nil {
+		return nil, authStatusError(err)
+	}
+
+	row, err := a.syncUser(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
 	return &v1.CreateAccountResponse{
-		Account: &v1.Account{
-			Id: signup.ID.String(),
-		},
+		Account: userToProto(row),
 	}, nil
 }
 
 func (a *AccountService) Logout(ctx context.Context, request *v1.LogoutRequest) (*v1.LogoutResponse, error) {
 	token, _ := x.TokenFromContext(ctx)
-	logrus.Printf("token: %s", token)
-	err := a.authClient.WithToken(token).Logout()
-	if err != nil {
-		return nil, err
+	if err := a.provider.Logout(ctx, token); err != nil {
+		return nil, authStatusError(err)
 	}
 
 	return &v1.LogoutResponse{}, nil
 }
 
 func (a *AccountService) LoginUsingPassword(ctx context.Context, req *v1.LoginRequest) (*v1.LoginResponse, error) {
-	email := req.GetEmail()
-	password := req.GetPassword()
+	session, err := a.provider.Login(ctx, req.GetEmail(), req.GetPassword())
+	if err != nil {
+		return nil, authStatusError(err)
+	}
+	// the accounts get a role before they can use the api
+	if userRoleToProto(session.User.Role) == nil {
+		return nil, status.Error(codes.PermissionDenied, "role not found in token")
+	}
 
-	token, err := a.authClient.Token(types.TokenRequest{
-		GrantType: "password",
-		Email:     email,
-		Password:  password,
-	})
+	row, err := a.syncUser(ctx, session.User)
 	if err != nil {
 		return nil, err
 	}
 
-	user := token.User
-	var userRole string
-	if role, ok := token.User.AppMetadata["role"]; ok {
-		userRole = role.(string)
-	} else {
-		return nil, errors.New("role not found in token")
+	return &v1.LoginResponse{
+		Token:   authTokenToProto(session),
+		Account: userToProto(row),
+	}, nil
+}
+
+// RefreshToken exchanges a refresh token for new tokens carrying the current role of the account
+func (a *AccountService) RefreshToken(ctx context.Context, request *v1.RefreshTokenRequest) (*v1.RefreshTokenResponse, error) {
+	session, err := a.provider.Refresh(ctx, request.GetRefreshToken())
+	if err != nil {
+		return nil, authStatusError(err)
 	}
 
-	var role *v1.UserRole
-	switch userRole {
-	case "viewer":
-		role = v1.UserRole_Viewer.Enum()
-	case "author":
-		role = v1.UserRole_Author.Enum()
-	case "admin":
-		role = v1.UserRole_Admin.Enum()
-	case "owner":
-		role = v1.UserRole_Owner.Enum()
+	row, err := a.syncUser(ctx, session.User)
+	if err != nil {
+		return nil, err
 	}
 
-	if role == nil {
-		return nil, errors.New("role not found in token")
+	return &v1.RefreshTokenResponse{
+		Token:   authTokenToProto(session),
+		Account: userToProto(row),
+	}, nil
+}
+
+// RequestPasswordReset sends a reset token to the email, the response is the same for the unknown emails
+func (a *AccountService) RequestPasswordReset(ctx context.Context, request *v1.RequestPasswordResetRequest) (*v1.RequestPasswordResetResponse, error) {
+	if err := a.provider.RequestPasswordReset(ctx, request.GetEmail()); err != nil {
+		return nil, authStatusError(err)
 	}
 
-	return &v1.LoginResponse{
-		Token: &v1.AuthToken{
-			AccessToken:  token.AccessToken,
-			RefreshToken: token.RefreshToken,
-			TokenType:    token.TokenType,
-			ExpiresIn:    int32(token.ExpiresIn),
-			ExpiresAt:    int32(token.ExpiresAt),
-		},
-		Account: &v1.Account{
-			Id:    user.ID.String(),
-			Email: user.Email,
-			Role:  *role,
-		},
+	return &v1.RequestPasswordResetResponse{}, nil
+}
+
+func (a *AccountService) ConfirmPasswordReset(ctx context.Context, request *v1.ConfirmPasswordResetRequest) (*v1.ConfirmPasswordResetResponse, error) {
+	if err := a.provider.ConfirmPasswordReset(ctx, request.GetEmail(), request.GetToken(), request.GetPassword()); err != nil {
+		return nil, authStatusError(err)
+	}
+
+	return &v1.ConfirmPasswordResetResponse{}, nil
+}
+
+func (a *AccountService) VerifyEmail(ctx context.Context, request *v1.VerifyEmailRequest) (*v1.VerifyEmailResponse, error) {
+	if err := a.provider.VerifyEmail(ctx, request.GetEmail(), request.GetToken()); err != nil {
+		return nil, authStatusError(err)
+	}
+
+	return &v1.VerifyEmailResponse{}, nil
+}
+
+// ChangePassword replaces the password of the caller, the local provider ends the sessions of the caller
+func (a *AccountService) ChangePassword(ctx context.Context, request *v1.ChangePasswordRequest) (*v1.ChangePasswordResponse, error) {
+	userID, err := parseID("user_id", callerID(ctx))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "caller is not authenticated")
+	}
+
+	if err := a.provider.ChangePassword(ctx, userID, request.GetCurrentPassword(), request.GetNewPassword()); err != nil {
+		return nil, authStatusError(err)
+	}
+
+	return &v1.ChangePasswordResponse{}, nil
+}
+
+// StartOAuthLogin returns the page of the identity provider the user signs in at
+func (a *AccountService) StartOAuthLogin(ctx context.Context, request *v1.StartOAuthLoginRequest) (*v1.StartOAuthLoginResponse, error) {
+	if a.social == nil {
+		return nil, status.Error(codes.FailedPrecondition, "social login is not configured")
+	}
+
+	authorizationURL, state, err := a.social.Start(ctx, request.GetProvider())
+	if err != nil {
+		return nil, authStatusError(err)
+	}
+
+	return &v1.StartOAuthLoginResponse{
+		AuthorizationUrl: authorizationURL,
+		State:            state,
 	}, nil
 }
+
+// CompleteOAuthLogin logs the user of the identity provider in, the first login creates or links the account
+func (a *AccountService) CompleteOAuthLogin(ctx context.Context, request *v1.CompleteOAuthLoginRequest) (*v1.CompleteOAuthLoginResponse, error) {
+	if a.social == nil {
+		return nil, status.Error(codes.FailedPrecondition, "social login is not configured")
+	}
+
+	session, err := a.social.Complete(ctx, request.GetProvider(), request.GetState(), request.GetCode())
+	if err != nil {
+		return nil, authStatusError(err)
+	}
+
+	row, err := a.syncUser(ctx, session.User)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.CompleteOAuthLoginResponse{
+		Token:   authTokenToProto(session),
+		Account: userToProto(row),
+	}, nil
+}
+
+// ListAccounts lists the accounts, oldest first
+func (a *AccountService) ListAccounts(ctx context.Context, request *v1.ListAccountsRequest) (*v1.ListAccountsResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	filter := &store.UserFilter{IncludeDeactivated: request.GetIncludeDeactivated()}
+	for _, role := range request.GetRoles() {
+		filter.Roles = append(filter.Roles, userRoleFromProto(role))
+	}
+
+	page, perPage := pagination(request.GetPage(), request.GetPerPage())
+	users, err := a.store.ListUsers(ctx, filter, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]*v1.Account, 0, len(users))
+	for _, user := range users {
+		accounts = append(accounts, userToProto(user))
+	}
+
+	return &v1.ListAccountsResponse{
+		Accounts: accounts,
+	}, nil
+}
+
+// UpdateAccountRole changes the role of an account, the new role is carried by the next tokens of the account
+func (a *AccountService) UpdateAccountRole(ctx context.Context, request *v1.UpdateAccountRoleRequest) (*v1.UpdateAccountRoleResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	userID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	role := userRoleFromProto(request.GetRole())
+
+	a.ownersMu.Lock()
+	defer a.ownersMu.Unlock()
+
+	var user *model.User
+	err = a.store.LockOwners(ctx, func(ctx context.Context) error {
+		user, err = a.store.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if (user.Role == model.UserRoleOwner || role == model.UserRoleOwner) && !isOwner(ctx) {
+			return status.Error(codes.PermissionDenied, "only an owner can grant or revoke the owner role")
+		}
+		if user.Role == role {
+			return nil
+		}
+		if user.Role == model.UserRoleOwner && user.Active() {
+			if err := a.keepLastOwner(ctx, "demote"); err != nil {
+				return err
+			}
+		}
+
+		if err := a.provider.SetRole(ctx, userID, role); err != nil {
+			return authStatusError(err)
+		}
+		user.Role = role
+
+		return a.store.UpdateUser(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.UpdateAccountRoleResponse{
+		Account: userToProto(user),
+	}, nil
+}
+
+// DeactivateAccount stops an account from logging in, the access tokens already issued expire on their own
+func (a *AccountService) DeactivateAccount(ctx context.Context, request *v1.DeactivateAccountRequest) (*v1.DeactivateAccountResponse, error) {
+	user, err := a.setActive(ctx, request.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.DeactivateAccountResponse{
+		Account: userToProto(user),
+	}, nil
+}
+
+// ReactivateAccount lets a deactivated account log in again
+func (a *AccountService) ReactivateAccount(ctx context.Context, request *v1.ReactivateAccountRequest) (*v1.ReactivateAccountResponse, error) {
+	user, err := a.setActive(ctx, request.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.ReactivateAccountResponse{
+		Account: userToProto(user),
+	}, nil
+}
+
+// setActive deactivates or reactivates an account, the deactivation revokes its api tokens, only the owners change the owners
+func (a *AccountService) setActive(ctx context.Context, id string, active bool) (*model.User, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	userID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	a.ownersMu.Lock()
+	defer a.ownersMu.Unlock()
+
+	var user *model.User
+	err = a.store.LockOwners(ctx, func(ctx context.Context) error {
+		user, err = a.store.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.Role == model.UserRoleOwner && !isOwner(ctx) {
+			return status.Error(codes.PermissionDenied, "only an owner can change an owner")
+		}
+		if user.Active() == active {
+			return nil
+		}
+		if !active && user.Role == model.UserRoleOwner {
+			if err := a.keepLastOwner(ctx, "deactivate"); err != nil {
+				return err
+			}
+		}
+
+		if err := a.provider.SetActive(ctx, userID, active); err != nil {
+			return authStatusError(err)
+		}
+		user.DeactivatedAt = nil
+		if !active {
+			now := time.Now()
+			user.DeactivatedAt = &now
+			// the provider ends the sessions, the api tokens of the account are revoked here,
+			// a demotion needs no revocation as the tokens act with the current role of their creator
+			if err := a.store.RevokeApiTokens(ctx, userID, now); err != nil {
+				return err
+			}
+		}
+
+		return a.store.UpdateUser(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// keepLastOwner fails when the change would leave the platform without an active owner
+func (a *AccountService) keepLastOwner(ctx context.Context, action string) error {
+	owners, err := a.store.CountUsers(ctx, &store.UserFilter{Roles: []model.UserRole{model.UserRoleOwner}})
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return status.Errorf(codes.FailedPrecondition, "cannot %s the last owner", action)
+	}
+
+	return nil
+}
+
+// ownerSetUp returns true once an owner exists, a deactivated owner still counts
+func (a *AccountService) ownerSetUp(ctx context.Context) (bool, error) {
+	owners, err := a.store.CountUsers(ctx, &store.UserFilter{
+		Roles:              []model.UserRole{model.UserRoleOwner},
+		IncludeDeactivated: true,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return owners > 0, nil
+}
+
+// syncUser mirrors the account of the auth provider in the users table and returns the row
+func (a *AccountService) syncUser(ctx context.Context, user *auth.User) (*model.User, error) {
+	role := user.Role
+	if role == "" {
+		role = model.UserRoleViewer
+	}
+
+	row, err := a.store.GetUser(ctx, uuid.MustParse(user.ID))
+	if errors.Is(err, store.ErrNotFound) {
+		name := user.Name
+		if name == "" {
+			name, _, _ = strings.Cut(user.Email, "@")
+		}
+		row = &model.User{ID: user.ID, Username: name, Email: user.Email, Role: role}
+		if user.Deactivated {
+			now := time.Now()
+			row.DeactivatedAt = &now
+		}
+		return row, a.store.CreateUser(ctx, row)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name := user.Name
+	if name == "" {
+		name = row.Username
+	}
+	if row.Username == name && row.Email == user.Email && row.Role == role && row.Active() != user.Deactivated {
+		return row, nil
+	}
+
+	row.Username = name
+	row.Email = user.Email
+	row.Role = role
+	if !user.Deactivated {
+		row.DeactivatedAt = nil
+	} else if row.Active() {
+		now := time.Now()
+		row.DeactivatedAt = &now
+	}
+
+	return row, a.store.UpdateUser(ctx, row)
+}
+
+// authStatusError maps the errors of the auth provider to the grpc codes
+func authStatusError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials), errors.Is(err, auth.ErrInvalidRefreshToken):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, auth.ErrInvalidAuthorization):
+		// the reason is logged, the client starts the login over
+		logrus.Warnf("social login rejected: %v", err)
+		return status.Error(codes.Unauthenticated, auth.ErrInvalidAuthorization.Error())
+	case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrInvalidOIDCState):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, auth.ErrIdentityEmailMissing), errors.Is(err, auth.ErrIdentityNotLinked):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, auth.ErrUnknownOIDCProvider):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, auth.ErrUserDeactivated):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, auth.ErrUserExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, auth.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return err
+	}
+}
+
+func authTokenToProto(session *auth.Session) *v1.AuthToken {
+	return &v1.AuthToken{
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		TokenType:    session.TokenType,
+		ExpiresIn:    int32(session.ExpiresIn),
+		ExpiresAt:    int32(session.ExpiresAt),
+	}
+}
+
+func userToProto(user *model.User) *v1.Account {
+	account := &v1.Account{
+		Id:            user.ID,
+		Email:         user.Email,
+		Name:          user.Username,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		CreatedAt:     timestamppb.New(user.CreatedAt),
+		UpdatedAt:     timestamppb.New(user.UpdatedAt),
+	}
+	if role := userRoleToProto(user.Role); role != nil {
+		account.Role = *role
+	}
+	if user.DeactivatedAt != nil {
+		account.DeactivatedAt = timestamppb.New(*user.DeactivatedAt)
+	}
+
+	return account
+}
+
+func userRoleToProto(role model.UserRole) *v1.UserRole {
+	switch role {
+	case model.UserRoleViewer:
+		return v1.UserRole_Viewer.Enum()
+	case model.UserRoleAuthor:
+		return v1.UserRole_Author.Enum()
+	case model.UserRoleAdmin:
+		return v1.UserRole_Admin.Enum()
+	case model.UserRoleOwner:
+		return v1.UserRole_Owner.Enum()
+	default:
+		return nil
+	}
+}
+
+func userRoleFromProto(role v1.UserRole) model.UserRole {
+	switch role {
+	case v1.UserRole_Author:
+		return model.UserRoleAuthor
+	case v1.UserRole_Admin:
+		return model.UserRoleAdmin
+	case v1.UserRole_Owner:
+		return model.UserRoleOwner
+	default:
+		return model.UserRoleViewer
+	}
+}
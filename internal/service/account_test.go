@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/apitoken"
+	"github.com/emrgen/unpost/internal/auth"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store/storetest"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKeepLastOwner(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+	service := NewAccountService(s, auth.NewLocalProvider(s, auth.LogNotifier{}, "secret", 15*time.Minute, time.Hour), nil)
+
+	res, err := service.CreateOwner(ctx, &v1.CreateOwnerRequest{Email: "owner@example.com", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownerID := res.GetUser().GetId()
+	owner := asCaller(ownerID, model.UserRoleOwner)
+
+	_, err = service.CreateOwner(ctx, &v1.CreateOwnerRequest{Email: "other@example.com", Password: "password"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected the owner to be set up once, got %v", err)
+	}
+
+	_, err = service.UpdateAccountRole(owner, &v1.UpdateAccountRoleRequest{Id: ownerID, Role: v1.UserRole_Admin})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected the last owner to be kept, got %v", err)
+	}
+	_, err = service.DeactivateAccount(owner, &v1.DeactivateAccountRequest{Id: ownerID})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected the last owner to stay active, got %v", err)
+	}
+
+	// a second owner lets the first one step down
+	account, err := service.CreateAccount(ctx, &v1.CreateAccountRequest{Email: "jane@example.com", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.UpdateAccountRole(owner, &v1.UpdateAccountRoleRequest{Id: account.GetAccount().GetId(), Role: v1.UserRole_Owner}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.DeactivateAccount(owner, &v1.DeactivateAccountRequest{Id: ownerID}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.UpdateAccountRole(owner, &v1.UpdateAccountRoleRequest{Id: account.GetAccount().GetId(), Role: v1.UserRole_Admin})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected the last active owner to be kept, got %v", err)
+	}
+}
+
+func TestDeactivateAccountRevokesApiTokens(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+	service := NewAccountService(s, auth.NewLocalProvider(s, auth.LogNotifier{}, "secret", 15*time.Minute, time.Hour), nil)
+	authenticator := apitoken.NewAuthenticator(s)
+
+	account, err := service.CreateAccount(ctx, &v1.CreateAccountRequest{Email: "jane@example.com", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminID := account.GetAccount().GetId()
+	owner := asCaller(uuid.NewString(), model.UserRoleOwner)
+	if _, err := service.UpdateAccountRole(owner, &v1.UpdateAccountRoleRequest{Id: adminID, Role: v1.UserRole_Admin}); err != nil {
+		t.Fatal(err)
+	}
+
+	id := uuid.New()
+	secret, hash, err := apitoken.Generate(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := &model.ApiToken{ID: id.String(), SpaceID: uuid.NewString(), Name: "ci", SecretHash: hash, Scopes: apitoken.ScopeAdminTiers, CreatedByID: adminID}
+	if err := s.CreateApiToken(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	if _, role, err := authenticator.Authenticate(ctx, secret); err != nil || role != model.UserRoleAdmin {
+		t.Fatalf("expected the token to act as an admin, got %s %v", role, err)
+	}
+
+	if _, err := service.DeactivateAccount(owner, &v1.DeactivateAccountRequest{Id: adminID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := authenticator.Authenticate(ctx, secret); err != apitoken.ErrInvalidToken {
+		t.Errorf("expected the token of the deactivated admin to be rejected, got %v", err)
+	}
+
+	// the reactivation does not bring the revoked tokens back
+	if _, err := service.ReactivateAccount(owner, &v1.ReactivateAccountRequest{Id: adminID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := authenticator.Authenticate(ctx, secret); err != apitoken.ErrInvalidToken {
+		t.Errorf("expected the revoked token to stay rejected, got %v", err)
+	}
+}
+
+// failingRoleProvider is a local provider whose role changes fail
+type failingRoleProvider struct {
+	*auth.LocalProvider
+}
+
+func (failingRoleProvider) SetRole(ctx context.Context, userID uuid.UUID, role model.UserRole) error {
+	return errors.New("provider unavailable")
+}
+
+func TestCreateOwnerRemovesFailedSignup(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+	provider := auth.NewLocalProvider(s, auth.LogNotifier{}, "secret", 15*time.Minute, time.Hour)
+	request := &v1.CreateOwnerRequest{Email: "owner@example.com", Password: "password"}
+
+	if _, err := NewAccountService(s, failingRoleProvider{provider}, nil).CreateOwner(ctx, request); err == nil {
+		t.Fatal("expected the owner setup to fail")
+	}
+	// the retry signs the same email up again
+	res, err := NewAccountService(s, provider, nil).CreateOwner(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetUser().GetRole() != v1.UserRole_Owner {
+		t.Errorf("expected an owner, got %s", res.GetUser().GetRole())
+	}
+}
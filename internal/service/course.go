@@ -7,6 +7,7 @@ import (
 	v1 "github.com/emrgen/unpost/apis/v1"
 	"github.com/emrgen/unpost/internal/model"
 	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/tracing"
 	"github.com/google/uuid"
 )
 
@@ -38,7 +39,7 @@ func (c *CourseService) CreateCourse(ctx context.Context, request *v1.CreateCour
 		return nil, err
 	}
 
-	res, err := c.docClient.CreateDocument(c.cfg.IntoContext(), &docv1.CreateDocumentRequest{
+	res, err := c.docClient.CreateDocument(tracing.WithSpan(c.cfg.IntoContext(), ctx), &docv1.CreateDocumentRequest{
 		ProjectId: poolID.String(),
 	})
 	if err != nil {
@@ -52,7 +53,14 @@ func (c *CourseService) CreateCourse(ctx context.Context, request *v1.CreateCour
 		Status:      model.PostStatusDraft,
 	}
 
-	if err := c.store.CreateCourse(ctx, course); err != nil {
+	err = c.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		if err := tx.CreateCourse(ctx, course); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventCourseCreated, course.ID, course.SpaceID, newCourseEventData(course))
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -64,12 +72,17 @@ func (c *CourseService) CreateCourse(ctx context.Context, request *v1.CreateCour
 }
 
 func (c *CourseService) GetCourse(ctx context.Context, request *v1.GetCourseRequest) (*v1.GetCourseResponse, error) {
-	course, err := c.store.GetCourse(ctx, uuid.MustParse(request.GetId()))
+	courseID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	course, err := c.store.GetCourse(ctx, courseID)
 	if err != nil {
 		return nil, err
 	}
 
-	res, err := c.docClient.GetDocument(c.cfg.IntoContext(), &docv1.GetDocumentRequest{
+	res, err := c.docClient.GetDocument(tracing.WithSpan(c.cfg.IntoContext(), ctx), &docv1.GetDocumentRequest{
 		DocumentId: course.DocumentID,
 	})
 	if err != nil {
@@ -123,8 +136,23 @@ func (c *CourseService) UpdateCourse(ctx context.Context, request *v1.UpdateCour
 }
 
 func (c *CourseService) DeleteCourse(ctx context.Context, request *v1.DeleteCourseRequest) (*v1.DeleteCourseResponse, error) {
-	courseID := uuid.MustParse(request.GetId())
-	if err := c.store.DeleteCourse(ctx, courseID); err != nil {
+	courseID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	err = c.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		course, err := tx.GetCourse(ctx, courseID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.DeleteCourse(ctx, courseID); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventCourseDeleted, course.ID, course.SpaceID, deletedEventData{ID: course.ID})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -132,10 +160,16 @@ func (c *CourseService) DeleteCourse(ctx context.Context, request *v1.DeleteCour
 }
 
 func (c *CourseService) AddCourseTag(ctx context.Context, request *v1.AddCourseTagRequest) (*v1.AddCourseTagResponse, error) {
-	courseID := uuid.MustParse(request.GetCourseId())
-	tagID := uuid.MustParse(request.GetTagId())
+	courseID, err := parseID("course_id", request.GetCourseId())
+	if err != nil {
+		return nil, err
+	}
+	tagID, err := parseID("tag_id", request.GetTagId())
+	if err != nil {
+		return nil, err
+	}
 
-	err := c.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+	err = c.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
 		course, err := tx.GetCourse(ctx, courseID)
 		if err != nil {
 			return err
@@ -153,7 +187,7 @@ func (c *CourseService) AddCourseTag(ctx context.Context, request *v1.AddCourseT
 			return err
 		}
 
-		return nil
+		return publishEvent(ctx, tx, model.EventCourseUpdated, course.ID, course.SpaceID, newCourseEventData(course))
 	})
 	if err != nil {
 		return nil, err
@@ -163,10 +197,16 @@ func (c *CourseService) AddCourseTag(ctx context.Context, request *v1.AddCourseT
 }
 
 func (c *CourseService) RemoveCourseTag(ctx context.Context, request *v1.RemoveCourseTagRequest) (*v1.RemoveCourseTagResponse, error) {
-	courseID := uuid.MustParse(request.GetCourseId())
-	tagID := uuid.MustParse(request.GetTagId())
+	courseID, err := parseID("course_id", request.GetCourseId())
+	if err != nil {
+		return nil, err
+	}
+	tagID, err := parseID("tag_id", request.GetTagId())
+	if err != nil {
+		return nil, err
+	}
 
-	err := c.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+	err = c.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
 		course, err := tx.GetCourse(ctx, courseID)
 		if err != nil {
 			return err
@@ -189,7 +229,7 @@ func (c *CourseService) RemoveCourseTag(ctx context.Context, request *v1.RemoveC
 			return err
 		}
 
-		return nil
+		return publishEvent(ctx, tx, model.EventCourseUpdated, course.ID, course.SpaceID, newCourseEventData(course))
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+)
+
+// publishEvent appends a domain event to the outbox, it must be called within the transaction of the change
+func publishEvent(ctx context.Context, tx store.UnstakStore, eventType model.EventType, aggregateID, spaceID string, data any) error {
+	event, err := model.NewOutboxEvent(eventType, aggregateID, spaceID, data)
+	if err != nil {
+		return err
+	}
+
+	return tx.CreateOutboxEvent(ctx, event)
+}
+
+type postEventData struct {
+	ID      string           `json:"id"`
+	SpaceID string           `json:"space_id"`
+	SlugID  string           `json:"slug_id"`
+	Slug    string           `json:"slug"`
+	Title   string           `json:"title"`
+	Status  model.PostStatus `json:"status"`
+	Version int64            `json:"version"`
+	Tags    []string         `json:"tags"`
+}
+
+func newPostEventData(post *model.Post) postEventData {
+	tags := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	return postEventData{
+		ID:      post.ID,
+		SpaceID: post.SpaceID,
+		SlugID:  post.SlugID,
+		Slug:    post.Slug,
+		Title:   post.Title,
+		Status:  post.Status,
+		Version: post.Version,
+		Tags:    tags,
+	}
+}
+
+type courseEventData struct {
+	ID          string           `json:"id"`
+	DocumentID  string           `json:"document_id"`
+	SpaceID     string           `json:"space_id"`
+	CreatedByID string           `json:"created_by_id"`
+	Status      model.PostStatus `json:"status"`
+}
+
+func newCourseEventData(course *model.Course) courseEventData {
+	return courseEventData{
+		ID:          course.ID,
+		DocumentID:  course.DocumentID,
+		SpaceID:     course.SpaceID,
+		CreatedByID: course.CreatedByID,
+		Status:      course.Status,
+	}
+}
+
+type pageEventData struct {
+	ID          string           `json:"id"`
+	CourseID    string           `json:"course_id"`
+	SpaceID     string           `json:"space_id"`
+	CreatedByID string           `json:"created_by_id"`
+	Status      model.PostStatus `json:"status"`
+}
+
+func newPageEventData(page *model.Page) pageEventData {
+	return pageEventData{
+		ID:          page.ID,
+		CourseID:    page.CourseID,
+		SpaceID:     page.SpaceID,
+		CreatedByID: page.CreatedByID,
+		Status:      page.Status,
+	}
+}
+
+type tierEventData struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	SpaceID     string `json:"space_id"`
+	CreatedByID string `json:"created_by_id"`
+}
+
+type tierMemberEventData struct {
+	ID     string `json:"id"`
+	TierID string `json:"tier_id"`
+	UserID string `json:"user_id"`
+}
+
+// deletedEventData is the payload of the deletion events
+type deletedEventData struct {
+	ID string `json:"id"`
+}
+
+// reviewEventData is the payload of the review events, the notifiers reach the reviewers and the author with it
+type reviewEventData struct {
+	ID          string                    `json:"id"`
+	PostID      string                    `json:"post_id"`
+	SpaceID     string                    `json:"space_id"`
+	Title       string                    `json:"title"`
+	AuthorID    string                    `json:"author_id"`
+	RequestedBy string                    `json:"requested_by"`
+	ReviewerID  string                    `json:"reviewer_id,omitempty"`
+	Status      model.ReviewRequestStatus `json:"status"`
+	Notes       string                    `json:"notes,omitempty"`
+}
+
+func newReviewEventData(review *model.ReviewRequest, post *model.Post) reviewEventData {
+	return reviewEventData{
+		ID:          review.ID,
+		PostID:      post.ID,
+		SpaceID:     post.SpaceID,
+		Title:       post.Title,
+		AuthorID:    post.AuthorID,
+		RequestedBy: review.RequestedBy,
+		ReviewerID:  review.ReviewerID,
+		Status:      review.Status,
+		Notes:       review.Notes,
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/feed"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	feedPollInterval      = time.Second
+	feedHeartbeatInterval = 15 * time.Second
+)
+
+// contentEventPrefixes are the event types streamed by the content feed
+var contentEventPrefixes = []string{"post.", "page.", "course."}
+
+// NewFeedService creates a new feed service
+func NewFeedService(store store.UnstakStore) *FeedService {
+	return &FeedService{
+		store:   store,
+		closing: make(chan struct{}),
+	}
+}
+
+var _ v1.FeedServiceServer = new(FeedService)
+
+// FeedService streams the content changes from the outbox
+type FeedService struct {
+	store     store.UnstakStore
+	closing   chan struct{}
+	closeOnce sync.Once
+	v1.UnimplementedFeedServiceServer
+}
+
+// Close ends the open streams so that the server can drain, the clients resume from their last token
+func (f *FeedService) Close() {
+	f.closeOnce.Do(func() {
+		close(f.closing)
+	})
+}
+
+// WatchContent streams the content changes after the resume token.
+// A heartbeat carrying the latest resume token is sent when the feed is idle, so that a client
+// filtering rare events resumes from a recent position. Only the admins watch every space at once.
+func (f *FeedService) WatchContent(request *v1.WatchContentRequest, stream grpc.ServerStreamingServer[v1.ContentChange]) error {
+	ctx := stream.Context()
+	if request.GetSpaceId() == "" {
+		if err := requireAdmin(ctx); err != nil {
+			return err
+		}
+	}
+
+	var cursor uint64
+	var err error
+	if request.GetResumeToken() != "" {
+		cursor, err = feed.DecodeToken(request.GetResumeToken())
+		if err != nil {
+			return store.NewValidationError("resume_token", err.Error())
+		}
+	} else {
+		cursor, err = f.store.LatestEventSeq(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	reader := feed.NewReader(f.store)
+	lastSent := time.Now()
+	for {
+		events, next, err := reader.Next(ctx, cursor)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if !matchContentEvent(request, event) {
+				continue
+			}
+
+			if err := stream.Send(contentChangeToProto(event)); err != nil {
+				return err
+			}
+			lastSent = time.Now()
+		}
+		cursor = next
+
+		// keep reading while there is a backlog
+		if len(events) == reader.BatchSize() {
+			continue
+		}
+
+		if time.Since(lastSent) >= feedHeartbeatInterval {
+			err := stream.Send(&v1.ContentChange{
+				ResumeToken: feed.EncodeToken(cursor),
+				Heartbeat:   true,
+				CreatedAt:   timestamppb.Now(),
+			})
+			if err != nil {
+				return err
+			}
+			lastSent = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-f.closing:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-time.After(feedPollInterval):
+		}
+	}
+}
+
+func matchContentEvent(request *v1.WatchContentRequest, event *model.OutboxEvent) bool {
+	if request.SpaceId != nil && event.SpaceID != request.GetSpaceId() {
+		return false
+	}
+
+	if len(request.GetEventTypes()) > 0 {
+		for _, eventType := range request.GetEventTypes() {
+			if string(event.Type) == eventType {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, prefix := range contentEventPrefixes {
+		if strings.HasPrefix(string(event.Type), prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contentChangeToProto(event *model.OutboxEvent) *v1.ContentChange {
+	return &v1.ContentChange{
+		Id:          event.ID,
+		EventType:   string(event.Type),
+		AggregateId: event.AggregateID,
+		SpaceId:     event.SpaceID,
+		Payload:     event.Payload,
+		ResumeToken: feed.EncodeToken(event.Seq),
+		CreatedAt:   timestamppb.New(event.CreatedAt),
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/google/uuid"
+)
+
+// parseID parses a uuid field of a request, a malformed value is reported as a field violation
+func parseID(field, value string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, store.NewValidationError(field, "must be a valid uuid")
+	}
+
+	return id, nil
+}
+
+// parseIDs parses a repeated uuid field of a request
+func parseIDs(field string, values []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, value := range values {
+		id, err := parseID(field, value)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
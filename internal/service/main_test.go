@@ -1,6 +1,7 @@
 package service
 
 import (
+	"flag"
 	"os"
 	"testing"
 
@@ -8,6 +9,12 @@ import (
 )
 
 func TestMain(m *testing.M) {
+	// the store backed tests run on sqlite, -short runs them without the docker services
+	flag.Parse()
+	if testing.Short() {
+		os.Exit(m.Run())
+	}
+
 	purge, err := tester.SetupDocker()
 	if err != nil {
 		panic(err)
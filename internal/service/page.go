@@ -40,7 +40,14 @@ func (p *PageService) CreatePage(ctx context.Context, request *v1.CreatePageRequ
 		CreatedByID: userID.String(),
 	}
 
-	if err := p.store.CreatePage(ctx, page); err != nil {
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		if err := tx.CreatePage(ctx, page); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventPageCreated, page.ID, page.SpaceID, newPageEventData(page))
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -52,7 +59,10 @@ func (p *PageService) CreatePage(ctx context.Context, request *v1.CreatePageRequ
 }
 
 func (p *PageService) GetPage(ctx context.Context, request *v1.GetPageRequest) (*v1.GetPageResponse, error) {
-	pageID := uuid.MustParse(request.Id)
+	pageID, err := parseID("id", request.Id)
+	if err != nil {
+		return nil, err
+	}
 	page, err := p.store.GetPage(ctx, pageID)
 	if err != nil {
 		return nil, err
@@ -82,8 +92,23 @@ func (p *PageService) UpdatePage(ctx context.Context, request *v1.UpdatePageRequ
 }
 
 func (p *PageService) DeletePage(ctx context.Context, request *v1.DeletePageRequest) (*v1.DeletePageResponse, error) {
-	pageID := uuid.MustParse(request.Id)
-	if err := p.store.DeletePage(ctx, pageID); err != nil {
+	pageID, err := parseID("id", request.Id)
+	if err != nil {
+		return nil, err
+	}
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		page, err := tx.GetPage(ctx, pageID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.DeletePage(ctx, pageID); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventPageDeleted, page.ID, page.SpaceID, deletedEventData{ID: page.ID})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -91,10 +116,16 @@ func (p *PageService) DeletePage(ctx context.Context, request *v1.DeletePageRequ
 }
 
 func (p *PageService) AddPageTag(ctx context.Context, request *v1.AddPageTagRequest) (*v1.AddPageTagResponse, error) {
-	pageID := uuid.MustParse(request.GetPageId())
-	tagID := uuid.MustParse(request.GetTagId())
+	pageID, err := parseID("page_id", request.GetPageId())
+	if err != nil {
+		return nil, err
+	}
+	tagID, err := parseID("tag_id", request.GetTagId())
+	if err != nil {
+		return nil, err
+	}
 
-	err := p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
 		post, err := tx.GetPost(ctx, pageID)
 		if err != nil {
 			return err
@@ -122,10 +153,16 @@ func (p *PageService) AddPageTag(ctx context.Context, request *v1.AddPageTagRequ
 }
 
 func (p *PageService) RemovePageTag(ctx context.Context, request *v1.RemovePageTagRequest) (*v1.RemovePageTagResponse, error) {
-	pageID := uuid.MustParse(request.GetPageId())
-	tagID := uuid.MustParse(request.GetTagId())
+	pageID, err := parseID("page_id", request.GetPageId())
+	if err != nil {
+		return nil, err
+	}
+	tagID, err := parseID("tag_id", request.GetTagId())
+	if err != nil {
+		return nil, err
+	}
 
-	err := p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
 		post, err := tx.GetPost(ctx, pageID)
 		if err != nil {
 			return err
@@ -0,0 +1,23 @@
+package service
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pagination converts the zero based page and the page size of a request into the store arguments
+func pagination(page, perPage int32) (uint64, uint64) {
+	if page < 0 {
+		page = 0
+	}
+
+	if perPage <= 0 {
+		perPage = defaultPageSize
+	}
+
+	if perPage > maxPageSize {
+		perPage = maxPageSize
+	}
+
+	return uint64(page), uint64(perPage)
+}
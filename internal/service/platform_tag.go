@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/suggest"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// corpusTTL is how long the learned tag profiles are reused before they are rebuilt from the posts
+	corpusTTL = 10 * time.Minute
+	// corpusDocuments caps the tagged posts the profiles are learned from
+	corpusDocuments       = 5000
+	defaultSuggestLimit   = 5
+	maxSuggestLimit       = 20
+	platformTagsBatchSize = 500
+)
+
+// NewPlatformTagService creates a new platform tag service
+func NewPlatformTagService(store store.UnstakStore) *PlatformTagService {
+	return &PlatformTagService{
+		store: store,
+	}
+}
+
+var _ v1.PlatformTagServiceServer = new(PlatformTagService)
+
+// PlatformTagService is the service that curates the platform wide taxonomy and suggests it for the posts
+type PlatformTagService struct {
+	store store.UnstakStore
+	v1.UnimplementedPlatformTagServiceServer
+
+	mu       sync.Mutex
+	corpus   *suggest.Corpus
+	loadedAt time.Time
+}
+
+func (p *PlatformTagService) CreatePlatformTag(ctx context.Context, request *v1.CreatePlatformTagRequest) (*v1.CreatePlatformTagResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tag := &model.PlatformTag{
+		ID:           uuid.New().String(),
+		Name:         strings.TrimSpace(request.GetName()),
+		Description:  request.GetDescription(),
+		Status:       model.PlatformTagApproved,
+		ProposedByID: callerID(ctx),
+		ReviewedByID: callerID(ctx),
+		ReviewedAt:   &now,
+	}
+	if err := p.store.CreatePlatformTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	p.invalidate()
+
+	return &v1.CreatePlatformTagResponse{
+		Tag: platformTagToProto(tag),
+	}, nil
+}
+
+func (p *PlatformTagService) ProposePlatformTag(ctx context.Context, request *v1.ProposePlatformTagRequest) (*v1.ProposePlatformTagResponse, error) {
+	tag := &model.PlatformTag{
+		ID:           uuid.New().String(),
+		Name:         strings.TrimSpace(request.GetName()),
+		Description:  request.GetDescription(),
+		Status:       model.PlatformTagProposed,
+		ProposedByID: callerID(ctx),
+	}
+	if err := p.store.CreatePlatformTag(ctx, tag); err != nil {
+		return nil, err
+	}
+
+	return &v1.ProposePlatformTagResponse{
+		Tag: platformTagToProto(tag),
+	}, nil
+}
+
+func (p *PlatformTagService) GetPlatformTag(ctx context.Context, request *v1.GetPlatformTagRequest) (*v1.GetPlatformTagResponse, error) {
+	tagID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := p.store.GetPlatformTag(ctx, tagID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.GetPlatformTagResponse{
+		Tag: platformTagToProto(tag),
+	}, nil
+}
+
+func (p *PlatformTagService) ListPlatformTags(ctx context.Context, request *v1.ListPlatformTagsRequest) (*v1.ListPlatformTagsResponse, error) {
+	filter := &store.PlatformTagFilter{}
+	if request.Status != nil {
+		tagStatus := platformTagStatusFromProto(request.GetStatus())
+		filter.Status = &tagStatus
+	}
+
+	page, perPage := pagination(request.GetPage(), request.GetPerPage())
+	tags, err := p.store.ListPlatformTags(ctx, filter, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	tagProtos := make([]*v1.PlatformTag, 0, len(tags))
+	for _, tag := range tags {
+		tagProtos = append(tagProtos, platformTagToProto(tag))
+	}
+
+	return &v1.ListPlatformTagsResponse{
+		Tags: tagProtos,
+	}, nil
+}
+
+func (p *PlatformTagService) UpdatePlatformTag(ctx context.Context, request *v1.UpdatePlatformTagRequest) (*v1.UpdatePlatformTagResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	tagID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := p.store.GetPlatformTag(ctx, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Name != nil {
+		tag.Name = strings.TrimSpace(request.GetName())
+		if tag.Name == "" {
+			return nil, store.NewValidationError("name", "must not be empty")
+		}
+	}
+	if request.Description != nil {
+		tag.Description = request.GetDescription()
+	}
+
+	if err := p.store.UpdatePlatformTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	p.invalidate()
+
+	return &v1.UpdatePlatformTagResponse{
+		Tag: platformTagToProto(tag),
+	}, nil
+}
+
+func (p *PlatformTagService) DeletePlatformTag(ctx context.Context, request *v1.DeletePlatformTagRequest) (*v1.DeletePlatformTagResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	tagID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.store.DeletePlatformTag(ctx, tagID); err != nil {
+		return nil, err
+	}
+	p.invalidate()
+
+	return &v1.DeletePlatformTagResponse{
+		Id: tagID.String(),
+	}, nil
+}
+
+func (p *PlatformTagService) ReviewPlatformTag(ctx context.Context, request *v1.ReviewPlatformTagRequest) (*v1.ReviewPlatformTagResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	tagID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := p.store.GetPlatformTag(ctx, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.Status != model.PlatformTagProposed {
+		return nil, status.Errorf(codes.FailedPrecondition, "platform tag %s is already %s", tag.ID, tag.Status)
+	}
+
+	now := time.Now()
+	tag.Status = model.PlatformTagRejected
+	if request.GetApprove() {
+		tag.Status = model.PlatformTagApproved
+	}
+	tag.ReviewedByID = callerID(ctx)
+	tag.ReviewedAt = &now
+
+	if err := p.store.UpdatePlatformTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	p.invalidate()
+
+	return &v1.ReviewPlatformTagResponse{
+		Tag: platformTagToProto(tag),
+	}, nil
+}
+
+func (p *PlatformTagService) MapTag(ctx context.Context, request *v1.MapTagRequest) (*v1.MapTagResponse, error) {
+	tagID, err := parseID("tag_id", request.GetTagId())
+	if err != nil {
+		return nil, err
+	}
+	tag, err := p.store.GetTag(ctx, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSpace(ctx, tag.SpaceID); err != nil {
+		return nil, err
+	}
+
+	var platformTagID *uuid.UUID
+	if request.GetPlatformTagId() != "" {
+		id, err := parseID("platform_tag_id", request.GetPlatformTagId())
+		if err != nil {
+			return nil, err
+		}
+		platformTag, err := p.store.GetPlatformTag(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if platformTag.Status != model.PlatformTagApproved {
+			return nil, status.Errorf(codes.FailedPrecondition, "platform tag %s is not approved", platformTag.ID)
+		}
+		platformTagID = &id
+	}
+
+	if err := p.store.SetTagPlatformTag(ctx, tagID, platformTagID); err != nil {
+		return nil, err
+	}
+	p.invalidate()
+
+	tag, err = p.store.GetTag(ctx, tagID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.MapTagResponse{
+		Tag: tagToProto(tag),
+	}, nil
+}
+
+func (p *PlatformTagService) SuggestPlatformTags(ctx context.Context, request *v1.SuggestPlatformTagsRequest) (*v1.SuggestPlatformTagsResponse, error) {
+	postID, err := parseID("post_id", request.GetPostId())
+	if err != nil {
+		return nil, err
+	}
+	limit := int(request.GetLimit())
+	if limit <= 0 {
+		limit = defaultSuggestLimit
+	}
+	if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
+
+	post, err := p.store.GetPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSpace(ctx, post.SpaceID); err != nil {
+		return nil, err
+	}
+	corpus, err := p.loadCorpus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the platform tags the post already has through its space tags are not suggested again
+	mapped := make(map[string]bool)
+	for _, tag := range post.Tags {
+		if tag.PlatformTagID != nil {
+			mapped[*tag.PlatformTagID] = true
+		}
+	}
+
+	suggestions := make([]*v1.PlatformTagSuggestion, 0, limit)
+	for _, suggestion := range corpus.Suggest(suggest.Terms(post.Title, post.Content), limit+len(mapped)) {
+		if len(suggestions) == limit {
+			break
+		}
+		if mapped[suggestion.ID] {
+			continue
+		}
+
+		tag, err := p.store.GetPlatformTag(ctx, uuid.MustParse(suggestion.ID))
+		if errors.Is(err, store.ErrNotFound) {
+			// deleted since the corpus was loaded
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tag.Status != model.PlatformTagApproved {
+			continue
+		}
+
+		suggestions = append(suggestions, &v1.PlatformTagSuggestion{
+			Tag:   platformTagToProto(tag),
+			Score: suggestion.Score,
+		})
+	}
+
+	return &v1.SuggestPlatformTagsResponse{
+		Suggestions: suggestions,
+	}, nil
+}
+
+// loadCorpus returns the profiles of the approved platform tags, they are rebuilt when they are older than corpusTTL
+func (p *PlatformTagService) loadCorpus(ctx context.Context) (*suggest.Corpus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.corpus != nil && time.Since(p.loadedAt) < corpusTTL {
+		return p.corpus, nil
+	}
+
+	approved := model.PlatformTagApproved
+	filter := &store.PlatformTagFilter{Status: &approved}
+	corpus := suggest.NewCorpus()
+	for page := uint64(0); ; page++ {
+		tags, err := p.store.ListPlatformTags(ctx, filter, page, platformTagsBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			corpus.AddTag(tag.ID, tag.Name)
+		}
+		if len(tags) < platformTagsBatchSize {
+			break
+		}
+	}
+
+	documents, err := p.store.ListPlatformTagDocuments(ctx, corpusDocuments)
+	if err != nil {
+		return nil, err
+	}
+	for _, document := range documents {
+		corpus.Add(document.PlatformTagID, suggest.Terms(document.Title, document.Content))
+	}
+
+	p.corpus = corpus
+	p.loadedAt = time.Now()
+
+	return corpus, nil
+}
+
+// invalidate drops the learned profiles after the taxonomy or the mappings changed
+func (p *PlatformTagService) invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.corpus = nil
+}
+
+func platformTagToProto(tag *model.PlatformTag) *v1.PlatformTag {
+	tagProto := &v1.PlatformTag{
+		Id:           tag.ID,
+		Name:         tag.Name,
+		Description:  tag.Description,
+		Status:       platformTagStatusToProto(tag.Status),
+		ProposedById: tag.ProposedByID,
+		ReviewedById: tag.ReviewedByID,
+		CreatedAt:    timestamppb.New(tag.CreatedAt),
+		UpdatedAt:    timestamppb.New(tag.UpdatedAt),
+	}
+	if tag.ReviewedAt != nil {
+		tagProto.ReviewedAt = timestamppb.New(*tag.ReviewedAt)
+	}
+
+	return tagProto
+}
+
+func platformTagStatusToProto(tagStatus model.PlatformTagStatus) v1.PlatformTagStatus {
+	switch tagStatus {
+	case model.PlatformTagProposed:
+		return v1.PlatformTagStatus_PLATFORM_TAG_PROPOSED
+	case model.PlatformTagRejected:
+		return v1.PlatformTagStatus_PLATFORM_TAG_REJECTED
+	default:
+		return v1.PlatformTagStatus_PLATFORM_TAG_APPROVED
+	}
+}
+
+func platformTagStatusFromProto(tagStatus v1.PlatformTagStatus) model.PlatformTagStatus {
+	switch tagStatus {
+	case v1.PlatformTagStatus_PLATFORM_TAG_PROPOSED:
+		return model.PlatformTagProposed
+	case v1.PlatformTagStatus_PLATFORM_TAG_REJECTED:
+		return model.PlatformTagRejected
+	default:
+		return model.PlatformTagApproved
+	}
+}
@@ -2,11 +2,21 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/emrgen/authbase"
 	authx "github.com/emrgen/authbase/x"
 	docv1 "github.com/emrgen/document/apis/v1"
 	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/cache"
 	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/preview"
+	"github.com/emrgen/unpost/internal/related"
+	"github.com/emrgen/unpost/internal/render"
 	"github.com/emrgen/unpost/internal/store"
 	"github.com/emrgen/unpost/internal/x"
 	"github.com/google/uuid"
@@ -15,10 +25,15 @@ import (
 )
 
 // NewPostService creates a new post service
-func NewPostService(cfg *authx.AuthbaseConfig, store store.UnstakStore) *PostService {
+// The cache holds the published posts and the rendered holds the rendered post versions, nil caches disable caching
+// The previews signer signs the tokens of the preview links.
+func NewPostService(cfg *authx.AuthbaseConfig, store store.UnstakStore, cache *cache.ObjectCache[model.Post], rendered *cache.ObjectCache[render.Rendered], previews *preview.Signer) *PostService {
 	return &PostService{
-		cfg:   cfg,
-		store: store,
+		cfg:      cfg,
+		store:    store,
+		cache:    cache,
+		rendered: rendered,
+		previews: previews,
 	}
 }
 
@@ -28,6 +43,9 @@ var _ v1.PostServiceServer = new(PostService)
 type PostService struct {
 	cfg        *authx.AuthbaseConfig
 	store      store.UnstakStore
+	cache      *cache.ObjectCache[model.Post]
+	rendered   *cache.ObjectCache[render.Rendered]
+	previews   *preview.Signer
 	docClient  docv1.DocumentServiceClient
 	authClient authbase.Client
 	v1.UnimplementedPostServiceServer
@@ -37,7 +55,7 @@ func (p *PostService) CreatePost(ctx context.Context, req *v1.CreatePostRequest)
 	var postID uuid.UUID
 	var err error
 	if req.PostId != nil {
-		postID, err = uuid.Parse(req.GetPostId())
+		postID, err = parseID("post_id", req.GetPostId())
 		if err != nil {
 			return nil, err
 		}
@@ -46,18 +64,26 @@ func (p *PostService) CreatePost(ctx context.Context, req *v1.CreatePostRequest)
 	}
 
 	post := &model.Post{
-		ID:      postID.String(),
-		Title:   req.GetTitle(),
-		Summary: req.GetSummary(),
-		Content: req.GetContent(),
-		Slug:    req.GetSlug(),
-		SlugID:  x.RandomString(12),
-		Status:  model.PostStatusDraft,
-		Tags:    nil,
-		Version: 0,
-	}
-
-	err = p.store.CreatePost(ctx, post)
+		ID:       postID.String(),
+		SpaceID:  req.GetSpaceId(),
+		Title:    req.GetTitle(),
+		Summary:  req.GetSummary(),
+		Content:  req.GetContent(),
+		Slug:     req.GetSlug(),
+		SlugID:   x.RandomString(12),
+		Status:   model.PostStatusDraft,
+		Tags:     nil,
+		Version:  0,
+		AuthorID: callerID(ctx),
+	}
+
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		if err := tx.CreatePost(ctx, post); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventPostCreated, post.ID, post.SpaceID, newPostEventData(post))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -77,24 +103,168 @@ func (p *PostService) GetPost(ctx context.Context, request *v1.GetPostRequest) (
 	var post *model.Post
 	var err error
 
-	postID, err := uuid.Parse(request.GetId())
-	if err != nil {
-		post, err = p.store.GetPostBySlugID(ctx, request.GetId())
+	postID, err := parseID("id", request.GetId())
+	if request.GetPreviewToken() != "" {
+		post, err = p.previewPost(ctx, request.GetId(), request.GetPreviewToken())
+	} else if err != nil {
+		post, err = p.getPostBySlugID(ctx, request.GetId())
 	} else {
-		post, err = p.store.GetPost(ctx, postID)
+		post, err = p.cache.GetOrLoad(ctx, cache.PostKey(postID.String()), func(ctx context.Context) (*model.Post, bool, error) {
+			post, err := p.store.GetPost(ctx, postID)
+			if err != nil {
+				return nil, false, err
+			}
+
+			return post, post.Status == model.PostStatusPublished, nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSpace(ctx, post.SpaceID); err != nil {
+		return nil, err
+	}
+
+	postProto := postToProto(post)
+	if request.GetRender() {
+		rendered, err := p.renderPost(ctx, post)
+		if err != nil {
+			return nil, err
+		}
+		postProto.RenderedHtml = rendered.HTML
+	}
+
+	return &v1.GetPostResponse{
+		Post: postProto,
+	}, nil
+}
+
+// GetPostBySlag retrieves a post by the slug id
+func (p *PostService) GetPostBySlag(ctx context.Context, request *v1.GetPostBySlagRequest) (*v1.GetPostBySlagResponse, error) {
+	post, err := p.getPostBySlugID(ctx, request.GetSlag())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSpace(ctx, post.SpaceID); err != nil {
+		return nil, err
+	}
+
+	return &v1.GetPostBySlagResponse{
+		Post: postToProto(post),
+	}, nil
+}
 
+// RenderPost returns the sanitized html of the post content with its table of contents
+func (p *PostService) RenderPost(ctx context.Context, request *v1.RenderPostRequest) (*v1.RenderPostResponse, error) {
+	postID, err := parseID("post_id", request.GetPostId())
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := p.store.GetPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSpace(ctx, post.SpaceID); err != nil {
+		return nil, err
 	}
+
+	rendered, err := p.renderPost(ctx, post)
 	if err != nil {
 		return nil, err
 	}
-	
+
+	response := &v1.RenderPostResponse{
+		PostId:  post.ID,
+		Version: post.Version,
+		Html:    rendered.HTML,
+	}
+	for _, heading := range rendered.TOC {
+		response.Toc = append(response.Toc, &v1.TocEntry{
+			Level: int32(heading.Level),
+			Id:    heading.ID,
+			Text:  heading.Text,
+		})
+	}
+
+	return response, nil
+}
+
+// ListRelatedPosts returns the published posts related to the post, they are precomputed by the related posts job
+func (p *PostService) ListRelatedPosts(ctx context.Context, request *v1.ListRelatedPostsRequest) (*v1.ListRelatedPostsResponse, error) {
+	postID, err := parseID("post_id", request.GetPostId())
+	if err != nil {
+		return nil, err
+	}
+	limit := int(request.GetLimit())
+	if limit <= 0 || limit > related.MaxRelations {
+		limit = related.MaxRelations
+	}
+
+	posts, err := p.store.ListRelatedPosts(ctx, postID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	postProtos := make([]*v1.Post, 0, len(posts))
+	for _, post := range posts {
+		// the related posts share the space of the post
+		if err := requireSpace(ctx, post.SpaceID); err != nil {
+			return nil, err
+		}
+		postProtos = append(postProtos, postToProto(post))
+	}
+
+	return &v1.ListRelatedPostsResponse{
+		Posts: postProtos,
+	}, nil
+}
+
+// renderPost renders the content of the post, the result is cached by post version
+func (p *PostService) renderPost(ctx context.Context, post *model.Post) (*render.Rendered, error) {
+	return p.rendered.GetOrLoad(ctx, cache.RenderedPostKey(post.ID, post.Version), func(ctx context.Context) (*render.Rendered, bool, error) {
+		rendered, err := render.Render(post.Content)
+		return rendered, err == nil, err
+	})
+}
+
+// getPostBySlugID reads the post through the cache, only the published posts are cached
+func (p *PostService) getPostBySlugID(ctx context.Context, slugID string) (*model.Post, error) {
+	return p.cache.GetOrLoad(ctx, cache.PostSlugKey(slugID), func(ctx context.Context) (*model.Post, bool, error) {
+		post, err := p.store.GetPostBySlugID(ctx, slugID)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return post, post.Status == model.PostStatusPublished, nil
+	})
+}
+
+// evictPost removes the post from the local cache, it must be called after the post is changed.
+// The other replicas evict the post when the store invalidation reaches them.
+func (p *PostService) evictPost(post *model.Post) {
+	p.cache.Delete(cache.PostKey(post.ID), cache.PostSlugKey(post.SlugID))
+}
+
+func postToProto(post *model.Post) *v1.Post {
 	postProto := &v1.Post{
-		Id:      post.ID,
-		Title:   post.Title,
-		Content: post.Content,
-		Tags:    make([]*v1.Tag, 0),
-		Version: 1,
-		Status:  postStatusToProto(post.Status),
+		Id:        post.ID,
+		Title:     post.Title,
+		Content:   post.Content,
+		SlugId:    post.SlugID,
+		SpaceId:   post.SpaceID,
+		Tags:      make([]*v1.Tag, 0),
+		Version:   post.Version,
+		Status:    postStatusToProto(post.Status),
+		AuthorId:  post.AuthorID,
+		CreatedAt: timestamppb.New(post.CreatedAt),
+		UpdatedAt: timestamppb.New(post.UpdatedAt),
+	}
+	if post.TierID != nil {
+		postProto.TierId = *post.TierID
+	}
+	if post.PublishedAt != nil {
+		postProto.PublishedAt = timestamppb.New(*post.PublishedAt)
 	}
 
 	for _, tag := range post.Tags {
@@ -104,30 +274,50 @@ func (p *PostService) GetPost(ctx context.Context, request *v1.GetPostRequest) (
 		})
 	}
 
-	return &v1.GetPostResponse{
-		Post: postProto,
-	}, nil
+	return postProto
 }
 
 // ListPost retrieves a list of posts within a space
 func (p *PostService) ListPost(ctx context.Context, request *v1.ListPostRequest) (*v1.ListPostResponse, error) {
-	posts, err := p.store.ListPosts(ctx, &store.PostFiler{})
+	filter := &store.PostFiler{}
+	if request.SpaceId != nil {
+		spaceID, err := parseID("space_id", request.GetSpaceId())
+		if err != nil {
+			return nil, err
+		}
+		filter.SpaceID = &spaceID
+	}
+	if request.Status != nil {
+		postStatus := postStatusFromProto(request.GetStatus())
+		filter.Status = &postStatus
+	}
+	// the posts of several authors cannot be matched by the owner filter
+	if len(request.GetAuthors()) == 1 {
+		ownerID, err := parseID("authors", request.GetAuthors()[0].GetId())
+		if err != nil {
+			return nil, err
+		}
+		filter.OwnerID = &ownerID
+	} else if len(request.GetAuthors()) > 1 {
+		return nil, store.NewValidationError("authors", "at most one author is supported")
+	}
+	for _, tag := range request.GetTags() {
+		tagID, err := parseID("tags", tag.GetId())
+		if err != nil {
+			return nil, err
+		}
+		filter.TagIDs = append(filter.TagIDs, tagID.String())
+	}
+
+	page, perPage := pagination(request.GetPage(), request.GetPerPage())
+	posts, err := p.store.ListPosts(ctx, filter, page, perPage)
 	if err != nil {
 		return nil, err
 	}
 
-	postProtos := make([]*v1.Post, 0)
+	postProtos := make([]*v1.Post, 0, len(posts))
 	for _, post := range posts {
-		postProto := &v1.Post{
-			Id:        post.ID,
-			Title:     post.Title,
-			Content:   post.Content,
-			SlugId:    post.SlugID,
-			CreatedAt: timestamppb.New(post.CreatedAt),
-			UpdatedAt: timestamppb.New(post.UpdatedAt),
-		}
-
-		postProtos = append(postProtos, postProto)
+		postProtos = append(postProtos, postToProto(post))
 	}
 
 	return &v1.ListPostResponse{
@@ -136,15 +326,26 @@ func (p *PostService) ListPost(ctx context.Context, request *v1.ListPostRequest)
 }
 
 func (p *PostService) UpdatePost(ctx context.Context, req *v1.UpdatePostRequest) (*v1.UpdatePostResponse, error) {
-	postID, err := uuid.Parse(req.GetPostId())
+	postID, err := parseID("post_id", req.GetPostId())
 	if err != nil {
 		return nil, err
 	}
-	err = p.store.Transaction(ctx, func(ctx context.Context, store store.UnstakStore) error {
-		post, err := store.GetPost(ctx, postID)
+	var post *model.Post
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		post, err = tx.GetPost(ctx, postID)
 		if err != nil {
 			return err
 		}
+		if err := requirePostAuthor(ctx, post); err != nil {
+			return err
+		}
+
+		// the version guards against overwriting a concurrent update, zero skips the check
+		if req.GetVersion() != 0 && req.GetVersion() != post.Version {
+			return store.ErrConflict
+		}
+		post.Version++
+		revised := (req.Title != nil && req.GetTitle() != post.Title) || (req.Content != nil && req.GetContent() != post.Content)
 
 		if req.Title != nil {
 			post.Title = req.GetTitle()
@@ -158,11 +359,22 @@ func (p *PostService) UpdatePost(ctx context.Context, req *v1.UpdatePostRequest)
 			post.Slug = req.GetSlug()
 		}
 
-		return store.UpdatePost(ctx, post)
+		if revised {
+			if err := reviseReviewedPost(ctx, tx, post); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.UpdatePost(ctx, post); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventPostUpdated, post.ID, post.SpaceID, newPostEventData(post))
 	})
 	if err != nil {
 		return nil, err
 	}
+	p.evictPost(post)
 
 	return &v1.UpdatePostResponse{
 		Post: &v1.Post{
@@ -172,28 +384,63 @@ func (p *PostService) UpdatePost(ctx context.Context, req *v1.UpdatePostRequest)
 }
 
 func (p *PostService) DeletePost(ctx context.Context, request *v1.DeletePostRequest) (*v1.DeletePostResponse, error) {
-	err := p.store.DeletePost(ctx, uuid.MustParse(request.GetId()))
+	postID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	var post *model.Post
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		var err error
+		post, err = tx.GetPost(ctx, postID)
+		if err != nil {
+			return err
+		}
+		if err := requirePostAuthor(ctx, post); err != nil {
+			return err
+		}
+
+		if err := tx.DeletePost(ctx, postID); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventPostDeleted, post.ID, post.SpaceID, deletedEventData{ID: post.ID})
+	})
 	if err != nil {
 		return nil, err
 	}
+	p.evictPost(post)
 
 	return &v1.DeletePostResponse{}, nil
 }
 
 func (p *PostService) AddPostTag(ctx context.Context, request *v1.AddPostTagRequest) (*v1.AddPostTagResponse, error) {
-	postID := uuid.MustParse(request.GetPostId())
-	tagID := uuid.MustParse(request.GetTagId())
+	postID, err := parseID("post_id", request.GetPostId())
+	if err != nil {
+		return nil, err
+	}
+	tagID, err := parseID("tag_id", request.GetTagId())
+	if err != nil {
+		return nil, err
+	}
 
-	err := p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
-		post, err := tx.GetPost(ctx, postID)
+	var post *model.Post
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		var err error
+		post, err = tx.GetPost(ctx, postID)
 		if err != nil {
 			return err
 		}
+		if err := requirePostAuthor(ctx, post); err != nil {
+			return err
+		}
 
 		tag, err := tx.GetTag(ctx, tagID)
 		if err != nil {
 			return err
 		}
+		if tag.SpaceID != post.SpaceID {
+			return store.NewValidationError("tag_id", "tag belongs to another space")
+		}
 
 		post.Tags = append(post.Tags, tag)
 
@@ -202,11 +449,12 @@ func (p *PostService) AddPostTag(ctx context.Context, request *v1.AddPostTagRequ
 			return err
 		}
 
-		return nil
+		return publishEvent(ctx, tx, model.EventPostUpdated, post.ID, post.SpaceID, newPostEventData(post))
 	})
 	if err != nil {
 		return nil, err
 	}
+	p.evictPost(post)
 
 	return &v1.AddPostTagResponse{
 		Post: &v1.Post{
@@ -217,18 +465,33 @@ func (p *PostService) AddPostTag(ctx context.Context, request *v1.AddPostTagRequ
 
 func (p *PostService) RemovePostTag(ctx context.Context, request *v1.RemovePostTagRequest) (*v1.RemovePostTagResponse, error) {
 	tags := make([]*v1.Tag, 0)
-	err := p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
-		postID := uuid.MustParse(request.GetPostId())
-		tagID := uuid.MustParse(request.GetTagId())
-		post, err := tx.GetPost(ctx, postID)
+	var post *model.Post
+	postID, err := parseID("post_id", request.GetPostId())
+	if err != nil {
+		return nil, err
+	}
+	tagID, err := parseID("tag_id", request.GetTagId())
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		var err error
+		post, err = tx.GetPost(ctx, postID)
 		if err != nil {
 			return err
 		}
+		if err := requirePostAuthor(ctx, post); err != nil {
+			return err
+		}
 
 		tag, err := tx.GetTag(ctx, tagID)
 		if err != nil {
 			return err
 		}
+		if tag.SpaceID != post.SpaceID {
+			return store.NewValidationError("tag_id", "tag belongs to another space")
+		}
 
 		for i, t := range post.Tags {
 			if t.ID == tag.ID {
@@ -250,11 +513,12 @@ func (p *PostService) RemovePostTag(ctx context.Context, request *v1.RemovePostT
 			})
 		}
 
-		return nil
+		return publishEvent(ctx, tx, model.EventPostUpdated, post.ID, post.SpaceID, newPostEventData(post))
 	})
 	if err != nil {
 		return nil, err
 	}
+	p.evictPost(post)
 
 	return &v1.RemovePostTagResponse{
 		Post: &v1.Post{
@@ -271,13 +535,13 @@ func (p *PostService) UpdatePostReaction(ctx context.Context, request *v1.Update
 	if err != nil {
 		return nil, err
 	}
-	postID, err := uuid.Parse(request.GetPostId())
+	postID, err := parseID("post_id", request.GetPostId())
 	if err != nil {
 		return nil, err
 	}
 
 	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
-		_, err := tx.GetPost(ctx, uuid.MustParse(request.GetPostId()))
+		_, err := tx.GetPost(ctx, postID)
 		if err != nil {
 			return err
 		}
@@ -309,24 +573,41 @@ func (p *PostService) UpdatePostReaction(ctx context.Context, request *v1.Update
 }
 
 func (p *PostService) UpdatePostStatus(ctx context.Context, request *v1.UpdatePostStatusRequest) (*v1.UpdatePostStatusResponse, error) {
-	postID := uuid.MustParse(request.GetPostId())
-	err := p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
-		post, err := tx.GetPost(ctx, postID)
+	postID, err := parseID("post_id", request.GetPostId())
+	if err != nil {
+		return nil, err
+	}
+	var post *model.Post
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		var err error
+		post, err = tx.GetPost(ctx, postID)
 		if err != nil {
 			return err
 		}
+		if err := requireSpace(ctx, post.SpaceID); err != nil {
+			return err
+		}
 
-		post.Status = postStatusFromProto(request.GetStatus())
+		previous := post.Status
+		if err := transitionPost(ctx, tx, post, postStatusFromProto(request.GetStatus()), request.GetNotes()); err != nil {
+			return err
+		}
 		err = tx.UpdatePost(ctx, post)
 		if err != nil {
 			return err
 		}
 
-		return nil
+		eventType := model.EventPostUpdated
+		if post.Status == model.PostStatusPublished && previous != model.PostStatusPublished {
+			eventType = model.EventPostPublished
+		}
+
+		return publishEvent(ctx, tx, eventType, post.ID, post.SpaceID, newPostEventData(post))
 	})
 	if err != nil {
 		return nil, err
 	}
+	p.evictPost(post)
 
 	return &v1.UpdatePostStatusResponse{
 		Post: &v1.Post{
@@ -337,6 +618,10 @@ func (p *PostService) UpdatePostStatus(ctx context.Context, request *v1.UpdatePo
 
 func postStatusFromProto(status v1.PostStatus) model.PostStatus {
 	switch status {
+	case v1.PostStatus_IN_REVIEW:
+		return model.PostStatusInReview
+	case v1.PostStatus_APPROVED:
+		return model.PostStatusApproved
 	case v1.PostStatus_PUBLISHED:
 		return model.PostStatusPublished
 	case v1.PostStatus_ARCHIVED:
@@ -348,6 +633,10 @@ func postStatusFromProto(status v1.PostStatus) model.PostStatus {
 
 func postStatusToProto(status model.PostStatus) v1.PostStatus {
 	switch status {
+	case model.PostStatusInReview:
+		return v1.PostStatus_IN_REVIEW
+	case model.PostStatusApproved:
+		return v1.PostStatus_APPROVED
 	case model.PostStatusPublished:
 		return v1.PostStatus_PUBLISHED
 	case model.PostStatusArchived:
@@ -356,3 +645,227 @@ func postStatusToProto(status model.PostStatus) v1.PostStatus {
 		return v1.PostStatus_DRAFT
 	}
 }
+
+// ImportPost creates a post from a markdown document, the front matter tags missing in the space are created
+func (p *PostService) ImportPost(ctx context.Context, request *v1.ImportPostRequest) (*v1.ImportPostResponse, error) {
+	spaceID, err := parseID("space_id", request.GetSpaceId())
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := render.ParseMarkdown([]byte(request.GetContent()))
+	if err != nil {
+		return nil, store.NewValidationError("content", err.Error())
+	}
+	meta := doc.FrontMatter
+	if strings.TrimSpace(meta.Title) == "" {
+		return nil, store.NewValidationError("content", "the front matter title is required")
+	}
+	status, err := parsePostStatus(meta.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	post := &model.Post{
+		ID:          uuid.NewString(),
+		SpaceID:     spaceID.String(),
+		Slug:        meta.Slug,
+		SlugID:      x.RandomString(12),
+		Title:       meta.Title,
+		Summary:     meta.Summary,
+		Content:     doc.Body,
+		Status:      status,
+		PublishedAt: meta.Date,
+		AuthorID:    callerID(ctx),
+	}
+	// only the admins import the posts of another author
+	if meta.Author != "" && meta.Author != post.AuthorID {
+		if err := requireAdmin(ctx); err != nil {
+			return nil, err
+		}
+		authorID, err := parseID("author", meta.Author)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.store.GetUser(ctx, authorID); err != nil {
+			return nil, err
+		}
+		post.AuthorID = authorID.String()
+	}
+	if request.GetConflictPolicy() == v1.ImportConflictPolicy_OVERWRITE {
+		return nil, store.NewValidationError("conflict_policy", "an imported post is skipped or renamed, it does not overwrite a post")
+	}
+	// only the admins import a post past the review
+	if post.Status != model.PostStatusDraft {
+		if err := authorizeTransition(ctx, post, model.PostStatusDraft, post.Status); err != nil {
+			return nil, err
+		}
+	}
+	if post.Slug == "" {
+		post.Slug = render.Slugify(meta.Title)
+	}
+	if post.Status == model.PostStatusPublished && post.PublishedAt == nil {
+		now := time.Now()
+		post.PublishedAt = &now
+	}
+
+	var created []*model.Tag
+	var skipped bool
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		// a post with the same slug is the same post imported again
+		existing, err := tx.GetPostBySlug(ctx, spaceID, post.Slug)
+		if err == nil && request.GetConflictPolicy() == v1.ImportConflictPolicy_SKIP {
+			post, skipped = existing, true
+			return nil
+		}
+		if err == nil {
+			if post.Slug, err = freePostSlug(ctx, tx, spaceID, post.Slug); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+
+		var tags []*model.Tag
+		tags, created, err = resolveTags(ctx, tx, spaceID, meta.Tags)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.CreatePost(ctx, post); err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			if err := tx.UpdatePostTags(ctx, uuid.MustParse(post.ID), tags); err != nil {
+				return err
+			}
+			post.Tags = tags
+		}
+
+		if err := publishEvent(ctx, tx, model.EventPostCreated, post.ID, post.SpaceID, newPostEventData(post)); err != nil {
+			return err
+		}
+		if post.Status == model.PostStatusPublished {
+			return publishEvent(ctx, tx, model.EventPostPublished, post.ID, post.SpaceID, newPostEventData(post))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &v1.ImportPostResponse{Post: postToProto(post), Skipped: skipped}
+	for _, tag := range created {
+		response.CreatedTags = append(response.CreatedTags, &v1.Tag{Id: tag.ID, Name: tag.Name})
+	}
+
+	return response, nil
+}
+
+// maxSlugAttempts bounds the search of a free slug
+const maxSlugAttempts = 100
+
+// freePostSlug returns the first of slug-2, slug-3... that no post of the space has
+func freePostSlug(ctx context.Context, tx store.UnstakStore, spaceID uuid.UUID, slug string) (string, error) {
+	for n := 2; n < maxSlugAttempts; n++ {
+		candidate := slug + "-" + strconv.Itoa(n)
+		_, err := tx.GetPostBySlug(ctx, spaceID, candidate)
+		if errors.Is(err, store.ErrNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("%w: no free slug for %q", store.ErrAlreadyExists, slug)
+}
+
+// ExportPost returns the post as markdown with its front matter or as sanitized html
+func (p *PostService) ExportPost(ctx context.Context, request *v1.ExportPostRequest) (*v1.ExportPostResponse, error) {
+	postID, err := parseID("post_id", request.GetPostId())
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := p.store.GetPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSpace(ctx, post.SpaceID); err != nil {
+		return nil, err
+	}
+
+	if request.GetFormat() == v1.PostFormat_HTML {
+		rendered, err := p.renderPost(ctx, post)
+		if err != nil {
+			return nil, err
+		}
+
+		return &v1.ExportPostResponse{Content: rendered.HTML, ContentType: "text/html; charset=utf-8"}, nil
+	}
+
+	doc := &render.Document{
+		FrontMatter: render.FrontMatter{
+			Title:   post.Title,
+			Slug:    post.Slug,
+			Summary: post.Summary,
+			Status:  string(post.Status),
+			Date:    post.PublishedAt,
+		},
+		Body: post.Content,
+	}
+	for _, tag := range post.Tags {
+		doc.FrontMatter.Tags = append(doc.FrontMatter.Tags, tag.Name)
+	}
+
+	markdown, err := doc.Markdown()
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.ExportPostResponse{Content: string(markdown), ContentType: "text/markdown; charset=utf-8"}, nil
+}
+
+// resolveTags looks up the named tags of the space, the missing tags are created and returned separately
+func resolveTags(ctx context.Context, tx store.UnstakStore, spaceID uuid.UUID, names []string) ([]*model.Tag, []*model.Tag, error) {
+	var tags, created []*model.Tag
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		tag, err := tx.GetTagByName(ctx, spaceID, name)
+		if errors.Is(err, store.ErrNotFound) {
+			tag = &model.Tag{ID: uuid.NewString(), SpaceID: spaceID.String(), Name: name}
+			if err := tx.CreateTag(ctx, tag); err != nil {
+				return nil, nil, err
+			}
+			created = append(created, tag)
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, created, nil
+}
+
+// parsePostStatus parses the status of a front matter, an empty status is a draft
+func parsePostStatus(status string) (model.PostStatus, error) {
+	switch model.PostStatus(strings.ToLower(strings.TrimSpace(status))) {
+	case "", model.PostStatusDraft:
+		return model.PostStatusDraft, nil
+	case model.PostStatusPublished:
+		return model.PostStatusPublished, nil
+	case model.PostStatusArchived:
+		return model.PostStatusArchived, nil
+	default:
+		return "", store.NewValidationError("content", "unknown front matter status "+status)
+	}
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/status"
+)
+
+// errBatchAborted rolls back an atomic batch after one of its posts failed
+var errBatchAborted = errors.New("batch aborted")
+
+// batchRolledBack is the error of the posts of an atomic batch rolled back because another post failed
+const batchRolledBack = "rolled back, another post of the batch failed"
+
+// BatchGetPosts returns the posts with a result for each requested id
+func (p *PostService) BatchGetPosts(ctx context.Context, request *v1.BatchGetPostsRequest) (*v1.BatchGetPostsResponse, error) {
+	items, results := batchItems(request.GetPostIds())
+
+	postIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		postIDs = append(postIDs, item.postID)
+	}
+	posts, err := p.store.GetPosts(ctx, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]*model.Post, len(posts))
+	for _, post := range posts {
+		found[post.ID] = post
+	}
+
+	for _, item := range items {
+		post, ok := found[item.postID.String()]
+		if !ok {
+			item.result.Error = (&store.NotFoundError{Resource: "post", ID: item.postID.String()}).Error()
+			continue
+		}
+		if err := requireSpace(ctx, post.SpaceID); err != nil {
+			item.result.Error = status.Convert(err).Message()
+			continue
+		}
+		item.result.Post = postToProto(post)
+	}
+
+	return &v1.BatchGetPostsResponse{
+		Results: results,
+	}, nil
+}
+
+// BatchUpdatePosts changes the status, the tags, the tier or the author of the posts in a single transaction.
+// The authors change their own posts, only the admins move the posts to another tier or author.
+func (p *PostService) BatchUpdatePosts(ctx context.Context, request *v1.BatchUpdatePostsRequest) (*v1.BatchUpdatePostsResponse, error) {
+	if request.TierId != nil || request.AuthorId != nil {
+		if err := requireAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	addTagIDs, err := parseIDs("add_tag_ids", request.GetAddTagIds())
+	if err != nil {
+		return nil, err
+	}
+	removeTagIDs, err := parseIDs("remove_tag_ids", request.GetRemoveTagIds())
+	if err != nil {
+		return nil, err
+	}
+	var tierID *uuid.UUID
+	if request.GetTierId() != "" {
+		id, err := parseID("tier_id", request.GetTierId())
+		if err != nil {
+			return nil, err
+		}
+		tierID = &id
+	}
+	var authorID uuid.UUID
+	if request.AuthorId != nil {
+		authorID, err = parseID("author_id", request.GetAuthorId())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var addTags []*model.Tag
+	var tier *model.Tier
+	results, err := p.runBatch(ctx, request.GetPostIds(), request.GetAtomic(), func(ctx context.Context, tx store.UnstakStore) error {
+		// the tags and the tier are shared by the posts, they are loaded once
+		addTags = nil
+		for _, tagID := range addTagIDs {
+			tag, err := tx.GetTag(ctx, tagID)
+			if err != nil {
+				return err
+			}
+			addTags = append(addTags, tag)
+		}
+		if tierID != nil {
+			tier, err = tx.GetTier(ctx, *tierID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(ctx context.Context, tx store.UnstakStore, postID uuid.UUID) (*model.Post, error) {
+		post, err := tx.GetPost(ctx, postID)
+		if err != nil {
+			return nil, err
+		}
+		if err := requirePostAuthor(ctx, post); err != nil {
+			return nil, err
+		}
+		previous := post.Status
+
+		if request.Status != nil {
+			if err := transitionPost(ctx, tx, post, postStatusFromProto(request.GetStatus()), ""); err != nil {
+				return nil, err
+			}
+		}
+		if request.TierId != nil {
+			post.TierID = nil
+			if tier != nil {
+				if tier.SpaceID != post.SpaceID {
+					return nil, store.NewValidationError("tier_id", "tier belongs to another space")
+				}
+				post.TierID = &tier.ID
+			}
+		}
+		if request.AuthorId != nil {
+			// a missing author fails the posts one by one like a missing post
+			if _, err := tx.GetUser(ctx, authorID); err != nil {
+				return nil, err
+			}
+			post.AuthorID = authorID.String()
+		}
+
+		tagsChanged := len(addTags) > 0 || len(removeTagIDs) > 0
+		if tagsChanged {
+			post.Tags, err = changeTags(post, addTags, removeTagIDs)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		post.Version++
+		if err := tx.UpdatePost(ctx, post); err != nil {
+			return nil, err
+		}
+		if tagsChanged {
+			if err := tx.UpdatePostTags(ctx, postID, post.Tags); err != nil {
+				return nil, err
+			}
+		}
+
+		eventType := model.EventPostUpdated
+		if post.Status == model.PostStatusPublished && previous != model.PostStatusPublished {
+			eventType = model.EventPostPublished
+		}
+		if err := publishEvent(ctx, tx, eventType, post.ID, post.SpaceID, newPostEventData(post)); err != nil {
+			return nil, err
+		}
+
+		return post, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.BatchUpdatePostsResponse{
+		Results: results,
+	}, nil
+}
+
+// BatchDeletePosts deletes the posts in a single transaction, the authors delete only their own posts
+func (p *PostService) BatchDeletePosts(ctx context.Context, request *v1.BatchDeletePostsRequest) (*v1.BatchDeletePostsResponse, error) {
+	results, err := p.runBatch(ctx, request.GetPostIds(), request.GetAtomic(), nil, func(ctx context.Context, tx store.UnstakStore, postID uuid.UUID) (*model.Post, error) {
+		post, err := tx.GetPost(ctx, postID)
+		if err != nil {
+			return nil, err
+		}
+		if err := requirePostAuthor(ctx, post); err != nil {
+			return nil, err
+		}
+
+		if err := tx.DeletePost(ctx, postID); err != nil {
+			return nil, err
+		}
+		if err := publishEvent(ctx, tx, model.EventPostDeleted, post.ID, post.SpaceID, deletedEventData{ID: post.ID}); err != nil {
+			return nil, err
+		}
+
+		return post, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the deleted posts are not returned
+	for _, result := range results {
+		result.Post = nil
+	}
+
+	return &v1.BatchDeletePostsResponse{
+		Results: results,
+	}, nil
+}
+
+// batchItem is a valid post id of a batch and its result
+type batchItem struct {
+	postID uuid.UUID
+	result *v1.BatchPostResult
+}
+
+// runBatch applies fn to each post in a single transaction, each post runs in a savepoint.
+// A post failing on its own input is reported in its result, the other errors fail the whole batch.
+func (p *PostService) runBatch(
+	ctx context.Context,
+	ids []string,
+	atomic bool,
+	prepare func(ctx context.Context, tx store.UnstakStore) error,
+	fn func(ctx context.Context, tx store.UnstakStore, postID uuid.UUID) (*model.Post, error),
+) ([]*v1.BatchPostResult, error) {
+	items, results := batchItems(ids)
+
+	var changed []*model.Post
+	err := p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		changed = nil
+		if prepare != nil {
+			if err := prepare(ctx, tx); err != nil {
+				return err
+			}
+		}
+
+		failed := false
+		for _, item := range items {
+			var post *model.Post
+			err := tx.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+				var err error
+				post, err = fn(ctx, tx, item.postID)
+				return err
+			})
+			if err != nil {
+				message, ok := batchFailure(err)
+				if !ok {
+					return err
+				}
+				item.result.Error = message
+				failed = true
+				continue
+			}
+
+			item.result.Post = postToProto(post)
+			changed = append(changed, post)
+		}
+
+		if atomic && failed {
+			return errBatchAborted
+		}
+
+		return nil
+	})
+	if errors.Is(err, errBatchAborted) {
+		for _, item := range items {
+			if item.result.Error == "" {
+				item.result.Post = nil
+				item.result.Error = batchRolledBack
+			}
+		}
+
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, post := range changed {
+		p.evictPost(post)
+	}
+
+	return results, nil
+}
+
+// batchItems parses the ids of a batch, the duplicates are dropped.
+// The results follow the request order, the results of the malformed ids carry their error.
+func batchItems(ids []string) ([]batchItem, []*v1.BatchPostResult) {
+	var items []batchItem
+	results := make([]*v1.BatchPostResult, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		result := &v1.BatchPostResult{PostId: id}
+		results = append(results, result)
+
+		postID, err := uuid.Parse(id)
+		if err != nil {
+			result.Error = "invalid post id"
+			continue
+		}
+		items = append(items, batchItem{postID: postID, result: result})
+	}
+
+	return items, results
+}
+
+// batchFailure returns the message of an error failing a single post, false when the error must fail the whole batch
+func batchFailure(err error) (string, bool) {
+	if st, ok := status.FromError(err); ok {
+		return st.Message(), true
+	}
+
+	switch {
+	case errors.Is(err, store.ErrNotFound),
+		errors.Is(err, store.ErrInvalidArgument),
+		errors.Is(err, store.ErrConflict),
+		errors.Is(err, store.ErrAlreadyExists),
+		errors.Is(err, store.ErrForeignKeyViolation):
+		return err.Error(), true
+	default:
+		return "", false
+	}
+}
+
+// changeTags returns the tags of the post without the removed tags and with the added ones
+func changeTags(post *model.Post, add []*model.Tag, remove []uuid.UUID) ([]*model.Tag, error) {
+	removed := make(map[string]bool, len(remove))
+	for _, tagID := range remove {
+		removed[tagID.String()] = true
+	}
+
+	tags := make([]*model.Tag, 0, len(post.Tags)+len(add))
+	present := make(map[string]bool, len(post.Tags))
+	for _, tag := range post.Tags {
+		if !removed[tag.ID] {
+			tags = append(tags, tag)
+			present[tag.ID] = true
+		}
+	}
+	for _, tag := range add {
+		if tag.SpaceID != post.SpaceID {
+			return nil, store.NewValidationError("add_tag_ids", "tag "+tag.ID+" belongs to another space")
+		}
+		if !present[tag.ID] && !removed[tag.ID] {
+			tags = append(tags, tag)
+			present[tag.ID] = true
+		}
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/cache"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/render"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/store/storetest"
+	"github.com/emrgen/unpost/internal/x"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestPostService(t *testing.T) (*PostService, *store.GormStore) {
+	s := storetest.NewStore(t)
+	posts := cache.NewObjectCache[model.Post](100, time.Minute)
+	rendered := cache.NewObjectCache[render.Rendered](100, time.Minute)
+
+	return NewPostService(nil, s, posts, rendered, nil), s
+}
+
+// asCaller returns a context of a caller with the role
+func asCaller(userID string, role model.UserRole) context.Context {
+	return x.ContextWithRole(x.ContextWithUserID(context.Background(), userID), string(role))
+}
+
+func createTestPost(t *testing.T, s store.UnstakStore, spaceID, authorID string, postStatus model.PostStatus) *model.Post {
+	post := &model.Post{
+		ID:       uuid.NewString(),
+		SpaceID:  spaceID,
+		SlugID:   uuid.NewString(),
+		Title:    "post",
+		Content:  "content",
+		Status:   postStatus,
+		AuthorID: authorID,
+	}
+	if err := s.CreatePost(context.Background(), post); err != nil {
+		t.Fatal(err)
+	}
+
+	return post
+}
+
+func createTestTag(t *testing.T, s store.UnstakStore, spaceID, name string, parentID *string) *model.Tag {
+	tag := &model.Tag{ID: uuid.NewString(), SpaceID: spaceID, Name: name, ParentID: parentID}
+	if err := s.CreateTag(context.Background(), tag); err != nil {
+		t.Fatal(err)
+	}
+
+	return tag
+}
+
+func getTestPost(t *testing.T, s store.UnstakStore, postID string) *model.Post {
+	post, err := s.GetPost(context.Background(), uuid.MustParse(postID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return post
+}
+
+func TestBatchUpdatePosts(t *testing.T) {
+	service, s := newTestPostService(t)
+	ctx := asCaller(uuid.NewString(), model.UserRoleAdmin)
+	spaceID := uuid.NewString()
+	tag := createTestTag(t, s, spaceID, "go", nil)
+	first := createTestPost(t, s, spaceID, "", model.PostStatusDraft)
+	second := createTestPost(t, s, spaceID, "", model.PostStatusDraft)
+	missing := uuid.NewString()
+
+	res, err := service.BatchUpdatePosts(ctx, &v1.BatchUpdatePostsRequest{
+		PostIds:   []string{first.ID, "invalid", missing, second.ID, first.ID},
+		AddTagIds: []string{tag.ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the duplicates are dropped, the results follow the request order
+	results := res.GetResults()
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if results[0].GetPostId() != first.ID || results[0].GetError() != "" || results[0].GetPost() == nil {
+		t.Errorf("expected the first post to be updated, got %v", results[0])
+	}
+	if results[1].GetError() != "invalid post id" {
+		t.Errorf("expected the malformed id to fail, got %v", results[1])
+	}
+	if results[2].GetError() == "" {
+		t.Errorf("expected the missing post to fail, got %v", results[2])
+	}
+	if results[3].GetPostId() != second.ID || results[3].GetError() != "" {
+		t.Errorf("expected the second post to be updated, got %v", results[3])
+	}
+	for _, post := range []*model.Post{first, second} {
+		if tags := getTestPost(t, s, post.ID).Tags; len(tags) != 1 || tags[0].ID != tag.ID {
+			t.Errorf("expected the post %s to be tagged, got %v", post.ID, tags)
+		}
+	}
+}
+
+func TestBatchUpdatePostsSavepoint(t *testing.T) {
+	service, s := newTestPostService(t)
+	ctx := asCaller(uuid.NewString(), model.UserRoleAdmin)
+	spaceID := uuid.NewString()
+	tier := &model.Tier{ID: uuid.NewString(), SpaceID: spaceID, Name: "gold", CreatedByID: uuid.NewString()}
+	if err := s.CreateTier(context.Background(), tier); err != nil {
+		t.Fatal(err)
+	}
+	post := createTestPost(t, s, spaceID, "", model.PostStatusDraft)
+	// the tier of another space fails the post after its review request is written
+	other := createTestPost(t, s, uuid.NewString(), "", model.PostStatusDraft)
+
+	inReview := v1.PostStatus_IN_REVIEW
+	res, err := service.BatchUpdatePosts(ctx, &v1.BatchUpdatePostsRequest{
+		PostIds: []string{post.ID, other.ID},
+		Status:  &inReview,
+		TierId:  &tier.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetResults()[0].GetError() != "" || res.GetResults()[1].GetError() == "" {
+		t.Fatalf("expected only the post of the other space to fail, got %v", res.GetResults())
+	}
+
+	updated := getTestPost(t, s, post.ID)
+	if updated.Status != model.PostStatusInReview || updated.TierID == nil || *updated.TierID != tier.ID {
+		t.Errorf("expected the post to be in review behind the tier, got %s %v", updated.Status, updated.TierID)
+	}
+	// the savepoint of the failed post rolled its review request back
+	if getTestPost(t, s, other.ID).Status != model.PostStatusDraft {
+		t.Errorf("expected the failed post to stay a draft")
+	}
+	if _, err := s.GetPendingReviewRequest(context.Background(), uuid.MustParse(other.ID)); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected the review request of the failed post to be rolled back, got %v", err)
+	}
+}
+
+func TestBatchUpdatePostsAtomic(t *testing.T) {
+	service, s := newTestPostService(t)
+	ctx := asCaller(uuid.NewString(), model.UserRoleAdmin)
+	spaceID := uuid.NewString()
+	tag := createTestTag(t, s, spaceID, "go", nil)
+	post := createTestPost(t, s, spaceID, "", model.PostStatusDraft)
+	other := createTestPost(t, s, uuid.NewString(), "", model.PostStatusDraft)
+
+	res, err := service.BatchUpdatePosts(ctx, &v1.BatchUpdatePostsRequest{
+		PostIds:   []string{post.ID, other.ID},
+		AddTagIds: []string{tag.ID},
+		Atomic:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := res.GetResults()
+	if results[0].GetError() != batchRolledBack || results[0].GetPost() != nil {
+		t.Errorf("expected the valid post to be rolled back, got %v", results[0])
+	}
+	if results[1].GetError() == "" || results[1].GetError() == batchRolledBack {
+		t.Errorf("expected the post of the other space to carry its own error, got %v", results[1])
+	}
+	if tags := getTestPost(t, s, post.ID).Tags; len(tags) != 0 {
+		t.Errorf("expected the rolled back post to have no tags, got %v", tags)
+	}
+}
+
+func TestBatchPostsAuthor(t *testing.T) {
+	service, s := newTestPostService(t)
+	authorID := uuid.NewString()
+	ctx := asCaller(authorID, model.UserRoleAuthor)
+	spaceID := uuid.NewString()
+	own := createTestPost(t, s, spaceID, authorID, model.PostStatusDraft)
+	others := createTestPost(t, s, spaceID, uuid.NewString(), model.PostStatusDraft)
+
+	// only the admins move the posts to another author or tier
+	newAuthorID := uuid.NewString()
+	_, err := service.BatchUpdatePosts(ctx, &v1.BatchUpdatePostsRequest{PostIds: []string{own.ID}, AuthorId: &newAuthorID})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected the author change to be denied, got %v", err)
+	}
+	tierID := ""
+	_, err = service.BatchUpdatePosts(ctx, &v1.BatchUpdatePostsRequest{PostIds: []string{own.ID}, TierId: &tierID})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected the tier change to be denied, got %v", err)
+	}
+
+	res, err := service.BatchDeletePosts(ctx, &v1.BatchDeletePostsRequest{PostIds: []string{own.ID, others.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetResults()[0].GetError() != "" || res.GetResults()[1].GetError() == "" {
+		t.Fatalf("expected only the own post to be deleted, got %v", res.GetResults())
+	}
+	if _, err := s.GetPost(context.Background(), uuid.MustParse(own.ID)); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected the own post to be deleted, got %v", err)
+	}
+	getTestPost(t, s, others.ID)
+
+	// a viewer changes no post
+	res, err = service.BatchDeletePosts(asCaller(uuid.NewString(), model.UserRoleViewer), &v1.BatchDeletePostsRequest{PostIds: []string{others.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetResults()[0].GetError() == "" {
+		t.Errorf("expected the viewer to be denied, got %v", res.GetResults())
+	}
+}
+
+func TestBatchUpdatePostsAuthor(t *testing.T) {
+	service, s := newTestPostService(t)
+	ctx := asCaller(uuid.NewString(), model.UserRoleAdmin)
+	post := createTestPost(t, s, uuid.NewString(), "", model.PostStatusDraft)
+	author := &model.User{ID: uuid.NewString(), Username: "jane", Email: "jane@example.com", Role: model.UserRoleAuthor}
+	if err := s.CreateUser(context.Background(), author); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := uuid.NewString()
+	res, err := service.BatchUpdatePosts(ctx, &v1.BatchUpdatePostsRequest{PostIds: []string{post.ID}, AuthorId: &missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetResults()[0].GetError() == "" || getTestPost(t, s, post.ID).AuthorID != "" {
+		t.Errorf("expected the missing author to fail the post, got %v", res.GetResults())
+	}
+
+	res, err = service.BatchUpdatePosts(ctx, &v1.BatchUpdatePostsRequest{PostIds: []string{post.ID}, AuthorId: &author.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetResults()[0].GetError() != "" || getTestPost(t, s, post.ID).AuthorID != author.ID {
+		t.Errorf("expected the post to move to the author, got %v", res.GetResults())
+	}
+}
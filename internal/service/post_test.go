@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestImportPost(t *testing.T) {
+	service, s := newTestPostService(t)
+	adminID := uuid.NewString()
+	admin := asCaller(adminID, model.UserRoleAdmin)
+	spaceID := uuid.NewString()
+	author := &model.User{ID: uuid.NewString(), Username: "jane", Email: "jane@example.com", Role: model.UserRoleAuthor}
+	if err := s.CreateUser(context.Background(), author); err != nil {
+		t.Fatal(err)
+	}
+	content := "---\ntitle: Hello\nslug: hello\nauthor: " + author.ID + "\n---\nbody"
+
+	res, err := service.ImportPost(admin, &v1.ImportPostRequest{SpaceId: spaceID, Content: content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := getTestPost(t, s, res.GetPost().GetId())
+	if res.GetSkipped() || imported.AuthorID != author.ID {
+		t.Errorf("expected the post to be imported for the author, got skipped %v and author %q", res.GetSkipped(), imported.AuthorID)
+	}
+
+	// the import run again skips the post
+	res, err = service.ImportPost(admin, &v1.ImportPostRequest{SpaceId: spaceID, Content: content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.GetSkipped() || res.GetPost().GetId() != imported.ID {
+		t.Errorf("expected the existing post to be returned, got %v", res.GetPost())
+	}
+
+	res, err = service.ImportPost(admin, &v1.ImportPostRequest{SpaceId: spaceID, Content: content, ConflictPolicy: v1.ImportConflictPolicy_RENAME})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetSkipped() || getTestPost(t, s, res.GetPost().GetId()).Slug != "hello-2" {
+		t.Errorf("expected a renamed copy, got %v", res.GetPost())
+	}
+
+	_, err = service.ImportPost(admin, &v1.ImportPostRequest{SpaceId: spaceID, Content: content, ConflictPolicy: v1.ImportConflictPolicy_OVERWRITE})
+	if !errors.Is(err, store.ErrInvalidArgument) {
+		t.Errorf("expected the overwrite to be refused, got %v", err)
+	}
+
+	// only the admins import the posts of another author
+	_, err = service.ImportPost(asCaller(uuid.NewString(), model.UserRoleAuthor), &v1.ImportPostRequest{SpaceId: spaceID, Content: content})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected the author to be denied, got %v", err)
+	}
+}
+
+func TestPostAuthor(t *testing.T) {
+	service, s := newTestPostService(t)
+	authorID := uuid.NewString()
+	spaceID := uuid.NewString()
+	post := createTestPost(t, s, spaceID, authorID, model.PostStatusDraft)
+	tag := createTestTag(t, s, spaceID, "go", nil)
+	title := "title"
+
+	calls := map[string]func(ctx context.Context) error{
+		"update post": func(ctx context.Context) error {
+			_, err := service.UpdatePost(ctx, &v1.UpdatePostRequest{PostId: post.ID, Title: &title})
+			return err
+		},
+		"add post tag": func(ctx context.Context) error {
+			_, err := service.AddPostTag(ctx, &v1.AddPostTagRequest{PostId: post.ID, TagId: tag.ID})
+			return err
+		},
+		"remove post tag": func(ctx context.Context) error {
+			_, err := service.RemovePostTag(ctx, &v1.RemovePostTagRequest{PostId: post.ID, TagId: tag.ID})
+			return err
+		},
+		"delete post": func(ctx context.Context) error {
+			_, err := service.DeletePost(ctx, &v1.DeletePostRequest{Id: post.ID})
+			return err
+		},
+	}
+
+	// the single calls agree with the batches, only the author or an admin changes a post
+	for name, call := range calls {
+		for caller, ctx := range map[string]context.Context{
+			"viewer":         asCaller(uuid.NewString(), model.UserRoleViewer),
+			"another author": asCaller(uuid.NewString(), model.UserRoleAuthor),
+		} {
+			if err := call(ctx); status.Code(err) != codes.PermissionDenied {
+				t.Errorf("%s by %s: expected permission denied, got %v", name, caller, err)
+			}
+		}
+	}
+
+	author := asCaller(authorID, model.UserRoleAuthor)
+	for _, name := range []string{"update post", "add post tag", "remove post tag", "delete post"} {
+		if err := calls[name](author); err != nil {
+			t.Errorf("%s by the author: %v", name, err)
+		}
+	}
+}
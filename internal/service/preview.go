@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/preview"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// defaultPreviewTTL is the lifetime of a preview link created without one
+const defaultPreviewTTL = 7 * 24 * time.Hour
+
+// CreatePreviewLink shares the post with the readers without an account until the link expires
+func (p *PostService) CreatePreviewLink(ctx context.Context, request *v1.CreatePreviewLinkRequest) (*v1.CreatePreviewLinkResponse, error) {
+	postID, err := parseID("post_id", request.GetPostId())
+	if err != nil {
+		return nil, err
+	}
+	ttl := defaultPreviewTTL
+	if request.GetTtlSeconds() > 0 {
+		ttl = time.Duration(request.GetTtlSeconds()) * time.Second
+	}
+
+	post, err := p.store.GetPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requirePostAuthor(ctx, post); err != nil {
+		return nil, err
+	}
+
+	link := &model.PreviewLink{
+		ID:          uuid.NewString(),
+		PostID:      post.ID,
+		SpaceID:     post.SpaceID,
+		CreatedByID: callerID(ctx),
+		ExpiresAt:   time.Now().Add(ttl).Truncate(time.Second),
+	}
+	token, err := p.previews.Sign(preview.Claims{LinkID: link.ID, PostID: link.PostID, ExpiresAt: link.ExpiresAt})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.store.CreatePreviewLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	linkProto := previewLinkToProto(link)
+	linkProto.Token = token
+
+	return &v1.CreatePreviewLinkResponse{
+		Link: linkProto,
+	}, nil
+}
+
+// ListPreviewLinks returns the preview links of the post, newest first
+func (p *PostService) ListPreviewLinks(ctx context.Context, request *v1.ListPreviewLinksRequest) (*v1.ListPreviewLinksResponse, error) {
+	postID, err := parseID("post_id", request.GetPostId())
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := p.store.GetPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requirePostAuthor(ctx, post); err != nil {
+		return nil, err
+	}
+
+	links, err := p.store.ListPreviewLinks(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	linkProtos := make([]*v1.PreviewLink, 0, len(links))
+	for _, link := range links {
+		linkProtos = append(linkProtos, previewLinkToProto(link))
+	}
+
+	return &v1.ListPreviewLinksResponse{
+		Links: linkProtos,
+	}, nil
+}
+
+// RevokePreviewLink closes the preview link before it expires, revoking a revoked link is a no-op
+func (p *PostService) RevokePreviewLink(ctx context.Context, request *v1.RevokePreviewLinkRequest) (*v1.RevokePreviewLinkResponse, error) {
+	linkID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	var link *model.PreviewLink
+	err = p.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		link, err = tx.GetPreviewLink(ctx, linkID)
+		if err != nil {
+			return err
+		}
+		post, err := tx.GetPost(ctx, uuid.MustParse(link.PostID))
+		if err != nil {
+			return err
+		}
+		if err := requirePostAuthor(ctx, post); err != nil {
+			return err
+		}
+		if link.RevokedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		link.RevokedAt = &now
+		link.RevokedByID = callerID(ctx)
+
+		return tx.UpdatePreviewLink(ctx, link)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.RevokePreviewLinkResponse{
+		Link: previewLinkToProto(link),
+	}, nil
+}
+
+// ListPreviewAccesses returns the reads of the post through the preview link, newest first
+func (p *PostService) ListPreviewAccesses(ctx context.Context, request *v1.ListPreviewAccessesRequest) (*v1.ListPreviewAccessesResponse, error) {
+	linkID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := p.store.GetPreviewLink(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	post, err := p.store.GetPost(ctx, uuid.MustParse(link.PostID))
+	if err != nil {
+		return nil, err
+	}
+	if err := requirePostAuthor(ctx, post); err != nil {
+		return nil, err
+	}
+
+	page, perPage := pagination(request.GetPage(), request.GetPerPage())
+	accesses, err := p.store.ListPreviewAccesses(ctx, linkID, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	accessProtos := make([]*v1.PreviewAccess, 0, len(accesses))
+	for _, access := range accesses {
+		accessProtos = append(accessProtos, &v1.PreviewAccess{
+			Id:            access.ID,
+			PreviewLinkId: access.PreviewLinkID,
+			UserId:        access.UserID,
+			RemoteAddr:    access.RemoteAddr,
+			UserAgent:     access.UserAgent,
+			AccessedAt:    timestamppb.New(access.AccessedAt),
+		})
+	}
+
+	return &v1.ListPreviewAccessesResponse{
+		Accesses: accessProtos,
+	}, nil
+}
+
+// previewPost returns the post opened by the preview token, whatever its status.
+// The id is the id or the slug id of the post, the read is recorded in the audit of the link.
+func (p *PostService) previewPost(ctx context.Context, id, token string) (*model.Post, error) {
+	claims, err := p.previews.Verify(token)
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	linkID, err := uuid.Parse(claims.LinkID)
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, preview.ErrInvalidToken.Error())
+	}
+
+	link, err := p.store.GetPreviewLink(ctx, linkID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, status.Error(codes.PermissionDenied, preview.ErrInvalidToken.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !link.Active(time.Now()) || link.PostID != claims.PostID {
+		return nil, status.Error(codes.PermissionDenied, "preview link is revoked or expired")
+	}
+
+	post, err := p.store.GetPost(ctx, uuid.MustParse(link.PostID))
+	if err != nil {
+		return nil, err
+	}
+	if id != post.ID && id != post.SlugID {
+		return nil, status.Error(codes.PermissionDenied, "preview link does not open this post")
+	}
+
+	remoteAddr, userAgent := requestClient(ctx)
+	err = p.store.CreatePreviewAccess(ctx, &model.PreviewAccess{
+		ID:            uuid.NewString(),
+		PreviewLinkID: link.ID,
+		PostID:        post.ID,
+		UserID:        callerID(ctx),
+		RemoteAddr:    remoteAddr,
+		UserAgent:     userAgent,
+		AccessedAt:    time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// requestClient returns the address and the user agent of the caller, the gateway forwards those of the http client
+func requestClient(ctx context.Context) (string, string) {
+	var remoteAddr, userAgent string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if forwarded := md.Get("x-forwarded-for"); len(forwarded) > 0 {
+		remoteAddr, _, _ = strings.Cut(forwarded[0], ",")
+		remoteAddr = strings.TrimSpace(remoteAddr)
+	} else if caller, ok := peer.FromContext(ctx); ok && caller.Addr != nil {
+		remoteAddr = caller.Addr.String()
+		if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+			remoteAddr = host
+		}
+	}
+	if agents := md.Get("grpcgateway-user-agent"); len(agents) > 0 {
+		userAgent = agents[0]
+	} else if agents := md.Get("user-agent"); len(agents) > 0 {
+		userAgent = agents[0]
+	}
+
+	return remoteAddr, userAgent
+}
+
+func previewLinkToProto(link *model.PreviewLink) *v1.PreviewLink {
+	linkProto := &v1.PreviewLink{
+		Id:          link.ID,
+		PostId:      link.PostID,
+		CreatedById: link.CreatedByID,
+		ExpiresAt:   timestamppb.New(link.ExpiresAt),
+		CreatedAt:   timestamppb.New(link.CreatedAt),
+		RevokedById: link.RevokedByID,
+	}
+	if link.RevokedAt != nil {
+		linkProto.RevokedAt = timestamppb.New(*link.RevokedAt)
+	}
+
+	return linkProto
+}
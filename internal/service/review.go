@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// authorTransitions are the status changes open to the authors, the admins review the posts and may change any status.
+// An author submits a draft for review and publishes it once an admin approved it.
+var authorTransitions = map[model.PostStatus][]model.PostStatus{
+	model.PostStatusDraft:     {model.PostStatusInReview, model.PostStatusArchived},
+	model.PostStatusInReview:  {model.PostStatusDraft},
+	model.PostStatusApproved:  {model.PostStatusPublished, model.PostStatusDraft},
+	model.PostStatusPublished: {model.PostStatusDraft, model.PostStatusArchived},
+	model.PostStatusArchived:  {model.PostStatusDraft},
+}
+
+// ListPendingReviews returns the posts waiting for an admin, oldest submission first
+func (p *PostService) ListPendingReviews(ctx context.Context, request *v1.ListPendingReviewsRequest) (*v1.ListPendingReviewsResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	pending := model.ReviewRequestPending
+	filter := &store.ReviewRequestFilter{Status: &pending}
+	if request.SpaceId != nil {
+		spaceID, err := parseID("space_id", request.GetSpaceId())
+		if err != nil {
+			return nil, err
+		}
+		filter.SpaceID = &spaceID
+	}
+
+	page, perPage := pagination(request.GetPage(), request.GetPerPage())
+	reviews, err := p.store.ListReviewRequests(ctx, filter, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	postIDs := make([]uuid.UUID, 0, len(reviews))
+	for _, review := range reviews {
+		postIDs = append(postIDs, uuid.MustParse(review.PostID))
+	}
+	posts, err := p.store.GetPosts(ctx, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	postProtos := make(map[string]*v1.Post, len(posts))
+	for _, post := range posts {
+		postProtos[post.ID] = postToProto(post)
+	}
+
+	reviewProtos := make([]*v1.ReviewRequest, 0, len(reviews))
+	for _, review := range reviews {
+		reviewProto := reviewRequestToProto(review)
+		reviewProto.Post = postProtos[review.PostID]
+		reviewProtos = append(reviewProtos, reviewProto)
+	}
+
+	return &v1.ListPendingReviewsResponse{
+		Reviews: reviewProtos,
+	}, nil
+}
+
+// transitionPost moves the post to the next status of the editorial workflow, the caller saves the post.
+// Submitting a post opens a review request, leaving the review decides it, the reviewers and the author
+// are notified through the outbox.
+func transitionPost(ctx context.Context, tx store.UnstakStore, post *model.Post, next model.PostStatus, notes string) error {
+	previous := post.Status
+	if previous == next {
+		return nil
+	}
+	if err := authorizeTransition(ctx, post, previous, next); err != nil {
+		return err
+	}
+
+	post.Status = next
+	if next == model.PostStatusPublished && post.PublishedAt == nil {
+		now := time.Now()
+		post.PublishedAt = &now
+	}
+
+	if previous == model.PostStatusInReview {
+		if err := decideReview(ctx, tx, post, next, notes); err != nil {
+			return err
+		}
+	}
+
+	if next == model.PostStatusInReview {
+		review := &model.ReviewRequest{
+			ID:          uuid.NewString(),
+			PostID:      post.ID,
+			SpaceID:     post.SpaceID,
+			RequestedBy: callerID(ctx),
+			Status:      model.ReviewRequestPending,
+		}
+		if err := tx.CreateReviewRequest(ctx, review); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventReviewRequested, post.ID, post.SpaceID, newReviewEventData(review, post))
+	}
+
+	return nil
+}
+
+// reviseReviewedPost sends a post back when an author changes the reviewed text, so that only the text an admin read
+// is published. A post in review or approved goes back to draft, the pending review is withdrawn and the approval
+// is dropped. A published post is unpublished and submitted for review again.
+func reviseReviewedPost(ctx context.Context, tx store.UnstakStore, post *model.Post) error {
+	if isAdmin(ctx) {
+		return nil
+	}
+
+	switch post.Status {
+	case model.PostStatusInReview, model.PostStatusApproved:
+		return transitionPost(ctx, tx, post, model.PostStatusDraft, "")
+	case model.PostStatusPublished:
+		if err := transitionPost(ctx, tx, post, model.PostStatusDraft, ""); err != nil {
+			return err
+		}
+		return transitionPost(ctx, tx, post, model.PostStatusInReview, "")
+	default:
+		return nil
+	}
+}
+
+// authorizeTransition fails unless the caller may move the post from the previous to the next status
+func authorizeTransition(ctx context.Context, post *model.Post, previous, next model.PostStatus) error {
+	if err := requirePostAuthor(ctx, post); err != nil {
+		return err
+	}
+	if isAdmin(ctx) {
+		return nil
+	}
+	if !slices.Contains(authorTransitions[previous], next) {
+		if next == model.PostStatusPublished || next == model.PostStatusApproved {
+			return status.Errorf(codes.PermissionDenied, "post must be approved by an admin before it is published")
+		}
+		return status.Errorf(codes.FailedPrecondition, "post cannot move from %s to %s", previous, next)
+	}
+
+	return nil
+}
+
+// decideReview closes the pending review request of a post leaving the review.
+// An admin approves or rejects the post, an author moving it back to draft withdraws the request.
+func decideReview(ctx context.Context, tx store.UnstakStore, post *model.Post, next model.PostStatus, notes string) error {
+	review, err := tx.GetPendingReviewRequest(ctx, uuid.MustParse(post.ID))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	review.DecidedAt = &now
+	review.Notes = notes
+
+	var eventType model.EventType
+	switch {
+	case !isAdmin(ctx):
+		review.Status = model.ReviewRequestWithdrawn
+	case next == model.PostStatusApproved || next == model.PostStatusPublished:
+		review.Status = model.ReviewRequestApproved
+		review.ReviewerID = callerID(ctx)
+		eventType = model.EventReviewApproved
+	default:
+		review.Status = model.ReviewRequestRejected
+		review.ReviewerID = callerID(ctx)
+		eventType = model.EventReviewRejected
+	}
+
+	if err := tx.UpdateReviewRequest(ctx, review); err != nil {
+		return err
+	}
+	if eventType == "" {
+		return nil
+	}
+
+	return publishEvent(ctx, tx, eventType, post.ID, post.SpaceID, newReviewEventData(review, post))
+}
+
+func reviewRequestToProto(review *model.ReviewRequest) *v1.ReviewRequest {
+	reviewProto := &v1.ReviewRequest{
+		Id:          review.ID,
+		PostId:      review.PostID,
+		SpaceId:     review.SpaceID,
+		RequestedBy: review.RequestedBy,
+		Status:      reviewRequestStatusToProto(review.Status),
+		ReviewerId:  review.ReviewerID,
+		Notes:       review.Notes,
+		CreatedAt:   timestamppb.New(review.CreatedAt),
+	}
+	if review.DecidedAt != nil {
+		reviewProto.DecidedAt = timestamppb.New(*review.DecidedAt)
+	}
+
+	return reviewProto
+}
+
+func reviewRequestStatusToProto(reviewStatus model.ReviewRequestStatus) v1.ReviewRequestStatus {
+	switch reviewStatus {
+	case model.ReviewRequestApproved:
+		return v1.ReviewRequestStatus_REVIEW_REQUEST_APPROVED
+	case model.ReviewRequestRejected:
+		return v1.ReviewRequestStatus_REVIEW_REQUEST_REJECTED
+	case model.ReviewRequestWithdrawn:
+		return v1.ReviewRequestStatus_REVIEW_REQUEST_WITHDRAWN
+	default:
+		return v1.ReviewRequestStatus_REVIEW_REQUEST_PENDING
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthorizeTransition(t *testing.T) {
+	authorID := uuid.NewString()
+	post := &model.Post{ID: uuid.NewString(), AuthorID: authorID}
+
+	tests := []struct {
+		name     string
+		role     model.UserRole
+		callerID string
+		previous model.PostStatus
+		next     model.PostStatus
+		want     codes.Code
+	}{
+		{"author submits a draft", model.UserRoleAuthor, authorID, model.PostStatusDraft, model.PostStatusInReview, codes.OK},
+		{"author archives a draft", model.UserRoleAuthor, authorID, model.PostStatusDraft, model.PostStatusArchived, codes.OK},
+		{"author withdraws a review", model.UserRoleAuthor, authorID, model.PostStatusInReview, model.PostStatusDraft, codes.OK},
+		{"author publishes an approved post", model.UserRoleAuthor, authorID, model.PostStatusApproved, model.PostStatusPublished, codes.OK},
+		{"author unpublishes a post", model.UserRoleAuthor, authorID, model.PostStatusPublished, model.PostStatusDraft, codes.OK},
+		{"author restores an archived post", model.UserRoleAuthor, authorID, model.PostStatusArchived, model.PostStatusDraft, codes.OK},
+		{"author publishes a draft", model.UserRoleAuthor, authorID, model.PostStatusDraft, model.PostStatusPublished, codes.PermissionDenied},
+		{"author approves a review", model.UserRoleAuthor, authorID, model.PostStatusInReview, model.PostStatusApproved, codes.PermissionDenied},
+		{"author publishes a review", model.UserRoleAuthor, authorID, model.PostStatusInReview, model.PostStatusPublished, codes.PermissionDenied},
+		{"author archives a review", model.UserRoleAuthor, authorID, model.PostStatusInReview, model.PostStatusArchived, codes.FailedPrecondition},
+		{"author publishes an archived post", model.UserRoleAuthor, authorID, model.PostStatusArchived, model.PostStatusPublished, codes.PermissionDenied},
+		{"another author submits the post", model.UserRoleAuthor, uuid.NewString(), model.PostStatusDraft, model.PostStatusInReview, codes.PermissionDenied},
+		{"viewer submits the post", model.UserRoleViewer, authorID, model.PostStatusDraft, model.PostStatusInReview, codes.PermissionDenied},
+		{"admin approves a review", model.UserRoleAdmin, uuid.NewString(), model.PostStatusInReview, model.PostStatusApproved, codes.OK},
+		{"admin publishes a draft", model.UserRoleAdmin, uuid.NewString(), model.PostStatusDraft, model.PostStatusPublished, codes.OK},
+		{"owner archives a review", model.UserRoleOwner, uuid.NewString(), model.PostStatusInReview, model.PostStatusArchived, codes.OK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := authorizeTransition(asCaller(test.callerID, test.role), post, test.previous, test.next)
+			if got := status.Code(err); got != test.want {
+				t.Errorf("expected %s, got %s (%v)", test.want, got, err)
+			}
+		})
+	}
+}
+
+func TestTransitionPostDecidesReview(t *testing.T) {
+	authorID := uuid.NewString()
+	adminID := uuid.NewString()
+	author := asCaller(authorID, model.UserRoleAuthor)
+	admin := asCaller(adminID, model.UserRoleAdmin)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		next     model.PostStatus
+		want     model.ReviewRequestStatus
+		reviewer string
+	}{
+		{"author withdraws", author, model.PostStatusDraft, model.ReviewRequestWithdrawn, ""},
+		{"admin approves", admin, model.PostStatusApproved, model.ReviewRequestApproved, adminID},
+		{"admin publishes", admin, model.PostStatusPublished, model.ReviewRequestApproved, adminID},
+		{"admin rejects", admin, model.PostStatusDraft, model.ReviewRequestRejected, adminID},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, s := newTestPostService(t)
+			post := createTestPost(t, s, uuid.NewString(), authorID, model.PostStatusDraft)
+
+			submit(t, s, author, post)
+			if err := transitionPost(test.ctx, s, post, test.next, "notes"); err != nil {
+				t.Fatal(err)
+			}
+			if post.Status != test.next {
+				t.Errorf("expected the post to be %s, got %s", test.next, post.Status)
+			}
+
+			review := onlyReviewRequest(t, s, post)
+			if review.Status != test.want || review.ReviewerID != test.reviewer || review.DecidedAt == nil {
+				t.Errorf("expected the review to be %s by %q, got %s by %q", test.want, test.reviewer, review.Status, review.ReviewerID)
+			}
+		})
+	}
+}
+
+func TestUpdatePostRevisesReviewedPost(t *testing.T) {
+	authorID := uuid.NewString()
+	author := asCaller(authorID, model.UserRoleAuthor)
+	admin := asCaller(uuid.NewString(), model.UserRoleAdmin)
+	title := "revised"
+	slug := "revised"
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		status  model.PostStatus
+		request *v1.UpdatePostRequest
+		want    model.PostStatus
+	}{
+		{"author revises a review", author, model.PostStatusInReview, &v1.UpdatePostRequest{Title: &title}, model.PostStatusDraft},
+		{"author revises an approved post", author, model.PostStatusApproved, &v1.UpdatePostRequest{Title: &title}, model.PostStatusDraft},
+		{"author renames the slug of an approved post", author, model.PostStatusApproved, &v1.UpdatePostRequest{Slug: &slug}, model.PostStatusApproved},
+		{"author revises a published post", author, model.PostStatusPublished, &v1.UpdatePostRequest{Title: &title}, model.PostStatusInReview},
+		{"author renames the slug of a published post", author, model.PostStatusPublished, &v1.UpdatePostRequest{Slug: &slug}, model.PostStatusPublished},
+		{"author revises a draft", author, model.PostStatusDraft, &v1.UpdatePostRequest{Title: &title}, model.PostStatusDraft},
+		{"admin revises an approved post", admin, model.PostStatusApproved, &v1.UpdatePostRequest{Title: &title}, model.PostStatusApproved},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, s := newTestPostService(t)
+			post := createTestPost(t, s, uuid.NewString(), authorID, model.PostStatusDraft)
+			submit(t, s, author, post)
+			if test.status != model.PostStatusInReview {
+				if err := transitionPost(admin, s, post, test.status, ""); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.UpdatePost(context.Background(), post); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			test.request.PostId = post.ID
+			if _, err := service.UpdatePost(test.ctx, test.request); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := getTestPost(t, s, post.ID).Status; got != test.want {
+				t.Errorf("expected the post to be %s, got %s", test.want, got)
+			}
+			if test.status == model.PostStatusInReview && test.want == model.PostStatusDraft {
+				if review := onlyReviewRequest(t, s, post); review.Status != model.ReviewRequestWithdrawn {
+					t.Errorf("expected the review to be withdrawn, got %s", review.Status)
+				}
+			}
+			// the revised text of a published post waits for a new review
+			if test.status == model.PostStatusPublished && test.want == model.PostStatusInReview {
+				if _, err := s.GetPendingReviewRequest(context.Background(), uuid.MustParse(post.ID)); err != nil {
+					t.Errorf("expected a pending review of the revision, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+// submit sends the post to review as the author
+func submit(t *testing.T, s store.UnstakStore, author context.Context, post *model.Post) {
+	if err := transitionPost(author, s, post, model.PostStatusInReview, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdatePost(context.Background(), post); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// onlyReviewRequest returns the single review request of the post
+func onlyReviewRequest(t *testing.T, s store.UnstakStore, post *model.Post) *model.ReviewRequest {
+	spaceID := uuid.MustParse(post.SpaceID)
+	reviews, err := s.ListReviewRequests(context.Background(), &store.ReviewRequestFilter{SpaceID: &spaceID}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviews) != 1 {
+		t.Fatalf("expected a single review request, got %d", len(reviews))
+	}
+
+	return reviews[0]
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/x"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// requireAdmin fails unless the caller is an admin or the owner of the platform
+func requireAdmin(ctx context.Context) error {
+	if !isAdmin(ctx) {
+		return status.Error(codes.PermissionDenied, "admin role required")
+	}
+
+	return nil
+}
+
+// requireAuthor fails unless the caller may write posts, the admins are authors too
+func requireAuthor(ctx context.Context) error {
+	role, _ := x.RoleFromContext(ctx)
+	switch model.UserRole(role) {
+	case model.UserRoleAuthor, model.UserRoleAdmin, model.UserRoleOwner:
+		return nil
+	default:
+		return status.Error(codes.PermissionDenied, "author role required")
+	}
+}
+
+// requirePostAuthor fails unless the caller is an admin or the author of the post, in the space of its api token
+func requirePostAuthor(ctx context.Context, post *model.Post) error {
+	if err := requireSpace(ctx, post.SpaceID); err != nil {
+		return err
+	}
+	if isAdmin(ctx) {
+		return nil
+	}
+	if err := requireAuthor(ctx); err != nil {
+		return err
+	}
+
+	// the posts created before the authors were recorded have none
+	if post.AuthorID != "" && post.AuthorID != callerID(ctx) {
+		return status.Error(codes.PermissionDenied, "only the author of the post or an admin can change it")
+	}
+
+	return nil
+}
+
+// requireSpace fails when the caller uses an api token of another space than the space of the resource
+func requireSpace(ctx context.Context, spaceID string) error {
+	tokenSpace, ok := x.TokenSpaceFromContext(ctx)
+	if ok && tokenSpace != spaceID {
+		return status.Error(codes.PermissionDenied, "api token is limited to space "+tokenSpace)
+	}
+
+	return nil
+}
+
+// isAdmin returns true if the caller is an admin or the owner of the platform
+func isAdmin(ctx context.Context) bool {
+	role, _ := x.RoleFromContext(ctx)
+	switch model.UserRole(role) {
+	case model.UserRoleAdmin, model.UserRoleOwner:
+		return true
+	default:
+		return false
+	}
+}
+
+// isOwner returns true if the caller is an owner of the platform
+func isOwner(ctx context.Context) bool {
+	role, _ := x.RoleFromContext(ctx)
+	return model.UserRole(role) == model.UserRoleOwner
+}
+
+// callerID returns the id of the authenticated caller, empty when the call is not authenticated
+func callerID(ctx context.Context) string {
+	userID, _ := x.UserIDFromContext(ctx)
+	return userID
+}
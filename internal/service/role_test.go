@@ -0,0 +1,93 @@
+package service
+
+import (
+	"testing"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/x"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRequireSpace(t *testing.T) {
+	service, s := newTestPostService(t)
+	tags := NewTagService(s)
+	authorID := uuid.NewString()
+	spaceID := uuid.NewString()
+	// an admin token of a space reaches only that space
+	ctx := x.ContextWithTokenSpace(asCaller(authorID, model.UserRoleAdmin), spaceID)
+
+	post := createTestPost(t, s, spaceID, authorID, model.PostStatusDraft)
+	other := createTestPost(t, s, uuid.NewString(), authorID, model.PostStatusDraft)
+	tag := createTestTag(t, s, spaceID, "go", nil)
+	otherTag := createTestTag(t, s, other.SpaceID, "go", nil)
+
+	if _, err := service.GetPost(ctx, &v1.GetPostRequest{Id: post.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tags.GetTag(ctx, &v1.GetTagRequest{Id: tag.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	title := "title"
+	for name, call := range map[string]func() error{
+		"get post": func() error {
+			_, err := service.GetPost(ctx, &v1.GetPostRequest{Id: other.ID})
+			return err
+		},
+		"update post": func() error {
+			_, err := service.UpdatePost(ctx, &v1.UpdatePostRequest{PostId: other.ID, Title: &title})
+			return err
+		},
+		"delete post": func() error {
+			_, err := service.DeletePost(ctx, &v1.DeletePostRequest{Id: other.ID})
+			return err
+		},
+		"tag post": func() error {
+			_, err := service.AddPostTag(ctx, &v1.AddPostTagRequest{PostId: other.ID, TagId: otherTag.ID})
+			return err
+		},
+		"update post status": func() error {
+			_, err := service.UpdatePostStatus(ctx, &v1.UpdatePostStatusRequest{PostId: other.ID, Status: v1.PostStatus_DRAFT})
+			return err
+		},
+		"create preview link": func() error {
+			_, err := service.CreatePreviewLink(ctx, &v1.CreatePreviewLinkRequest{PostId: other.ID})
+			return err
+		},
+		"update tag": func() error {
+			_, err := tags.UpdateTag(ctx, &v1.UpdateTagRequest{Id: otherTag.ID, Name: &title})
+			return err
+		},
+		"delete tag": func() error {
+			_, err := tags.DeleteTag(ctx, &v1.DeleteTagRequest{Id: otherTag.ID})
+			return err
+		},
+		"merge tags": func() error {
+			_, err := tags.MergeTags(ctx, &v1.MergeTagsRequest{TargetId: otherTag.ID, SourceIds: []string{uuid.NewString()}})
+			return err
+		},
+	} {
+		if err := call(); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: expected the other space to be denied, got %v", name, err)
+		}
+	}
+
+	res, err := service.BatchGetPosts(ctx, &v1.BatchGetPostsRequest{PostIds: []string{post.ID, other.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetResults()[0].GetPost() == nil || res.GetResults()[1].GetPost() != nil || res.GetResults()[1].GetError() == "" {
+		t.Errorf("expected only the post of the space to be returned, got %v", res.GetResults())
+	}
+	batch, err := service.BatchDeletePosts(ctx, &v1.BatchDeletePostsRequest{PostIds: []string{other.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.GetResults()[0].GetError() == "" {
+		t.Errorf("expected the post of the other space to be kept, got %v", batch.GetResults())
+	}
+	getTestPost(t, s, other.ID)
+}
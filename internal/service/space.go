@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/archive"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// archiveChunkSize is the size of the archive chunks streamed to the clients
+const archiveChunkSize = 64 * 1024
+
+// NewSpaceService creates a new space service, a nil file store leaves the files out of the archives
+func NewSpaceService(store store.UnstakStore, files archive.FileStore) *SpaceService {
+	return &SpaceService{
+		store: store,
+		files: files,
+	}
+}
+
+var _ v1.SpaceServiceServer = new(SpaceService)
+
+// SpaceService exports and imports the content of a space, only the admins move whole spaces
+type SpaceService struct {
+	store store.UnstakStore
+	files archive.FileStore
+	v1.UnimplementedSpaceServiceServer
+}
+
+// ExportSpace streams the archive of the space while it is written
+func (s *SpaceService) ExportSpace(request *v1.ExportSpaceRequest, stream grpc.ServerStreamingServer[v1.ArchiveChunk]) error {
+	if err := requireAdmin(stream.Context()); err != nil {
+		return err
+	}
+
+	spaceID, err := parseID("space_id", request.GetSpaceId())
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriterSize(&chunkWriter{stream: stream}, archiveChunkSize)
+	result, err := archive.NewExporter(s.store, s.files).Export(stream.Context(), spaceID, w)
+	if err != nil {
+		return err
+	}
+	if len(result.SkippedFiles) > 0 {
+		logrus.Warnf("export of space %s skipped %d files", spaceID, len(result.SkippedFiles))
+	}
+
+	return w.Flush()
+}
+
+// ImportSpace reads the archive from the stream while it is received
+func (s *SpaceService) ImportSpace(stream grpc.ClientStreamingServer[v1.ImportSpaceRequest, v1.ImportSpaceResponse]) error {
+	if err := requireAdmin(stream.Context()); err != nil {
+		return err
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if first.GetOptions() == nil {
+		return store.NewValidationError("options", "the first message must carry the import options")
+	}
+
+	spaceID, err := parseID("options.space_id", first.GetOptions().GetSpaceId())
+	if err != nil {
+		return err
+	}
+
+	importer := archive.NewImporter(s.store, s.files, archive.ImportOptions{
+		SpaceID:  spaceID,
+		Conflict: conflictPolicyFromProto(first.GetOptions().GetConflictPolicy()),
+	})
+	result, err := importer.Import(stream.Context(), &chunkReader{stream: stream, buf: first.GetData()})
+	if errors.Is(err, archive.ErrInvalidArchive) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(importResultToProto(result))
+}
+
+func conflictPolicyFromProto(policy v1.ImportConflictPolicy) archive.ConflictPolicy {
+	switch policy {
+	case v1.ImportConflictPolicy_OVERWRITE:
+		return archive.ConflictOverwrite
+	case v1.ImportConflictPolicy_RENAME:
+		return archive.ConflictRename
+	default:
+		return archive.ConflictSkip
+	}
+}
+
+func importResultToProto(result *archive.ImportResult) *v1.ImportSpaceResponse {
+	response := &v1.ImportSpaceResponse{Warnings: result.Warnings}
+	for _, section := range archive.Sections {
+		counts, ok := result.Sections[section]
+		if !ok {
+			continue
+		}
+
+		response.Sections = append(response.Sections, &v1.ImportSectionSummary{
+			Section:     section,
+			Created:     int32(counts.Created),
+			Skipped:     int32(counts.Skipped),
+			Overwritten: int32(counts.Overwritten),
+			Renamed:     int32(counts.Renamed),
+		})
+	}
+
+	return response
+}
+
+// chunkWriter sends each write as an archive chunk
+type chunkWriter struct {
+	stream grpc.ServerStreamingServer[v1.ArchiveChunk]
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	// the message is serialized by Send, so the buffer can be reused after it returns
+	if err := w.stream.Send(&v1.ArchiveChunk{Data: p}); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// chunkReader reads the archive chunks of the import stream
+type chunkReader struct {
+	stream grpc.ClientStreamingServer[v1.ImportSpaceRequest, v1.ImportSpaceResponse]
+	buf    []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		request, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = request.GetData()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
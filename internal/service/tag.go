@@ -2,12 +2,18 @@ package service
 
 import (
 	"context"
+	"slices"
+	"strings"
+
 	v1 "github.com/emrgen/unpost/apis/v1"
 	"github.com/emrgen/unpost/internal/model"
 	"github.com/emrgen/unpost/internal/store"
 	"github.com/google/uuid"
 )
 
+// maxTagDepth is the most levels of the tag tree, it also bounds the walks of a tree broken by a cycle
+const maxTagDepth = 64
+
 // NewTagService creates a new tag service
 func NewTagService(store store.UnstakStore) *TagService {
 	return &TagService{
@@ -25,83 +31,350 @@ type TagService struct {
 
 func (t *TagService) CreateTag(ctx context.Context, request *v1.CreateTagRequest) (*v1.CreateTagResponse, error) {
 	tag := &model.Tag{
-		ID:      uuid.New().String(),
-		Name:    request.GetName(),
-		SpaceID: request.GetSpaceId(),
+		ID:          uuid.New().String(),
+		Name:        strings.TrimSpace(request.GetName()),
+		SpaceID:     request.GetSpaceId(),
+		Description: request.GetDescription(),
+		Color:       request.GetColor(),
+	}
+	if tag.Name == "" {
+		return nil, store.NewValidationError("name", "must not be empty")
 	}
 
-	err := t.store.CreateTag(ctx, tag)
+	err := t.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		if request.GetParentId() != "" {
+			if err := setTagParent(ctx, tx, tag, request.GetParentId()); err != nil {
+				return err
+			}
+		}
+
+		return tx.CreateTag(ctx, tag)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &v1.CreateTagResponse{
-		Tag: &v1.Tag{
-			Id:   tag.ID,
-			Name: request.GetName(),
-		},
+		Tag: tagToProto(tag),
 	}, nil
 }
 
 func (t *TagService) GetTag(ctx context.Context, request *v1.GetTagRequest) (*v1.GetTagResponse, error) {
-	tag, err := t.store.GetTag(ctx, uuid.MustParse(request.GetId()))
+	tagID, err := parseID("id", request.GetId())
 	if err != nil {
 		return nil, err
 	}
 
+	tag, err := t.store.GetTag(ctx, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSpace(ctx, tag.SpaceID); err != nil {
+		return nil, err
+	}
+
 	return &v1.GetTagResponse{
-		Tag: &v1.Tag{
-			Id:      tag.ID,
-			Name:    tag.Name,
-			SpaceId: tag.SpaceID,
-		},
+		Tag: tagToProto(tag),
 	}, nil
 }
 
 func (t *TagService) ListTag(ctx context.Context, request *v1.ListTagRequest) (*v1.ListTagResponse, error) {
-	spaceID := uuid.MustParse(request.GetSpaceId())
-	tags, err := t.store.ListTags(ctx, spaceID, 0, 100)
+	spaceID, err := parseID("space_id", request.GetSpaceId())
+	if err != nil {
+		return nil, err
+	}
+
+	page, perPage := pagination(request.GetPage(), request.GetPerPage())
+	tags, err := t.store.ListTags(ctx, spaceID, &store.TagFilter{Prefix: strings.TrimSpace(request.GetPrefix())}, page, perPage)
 	if err != nil {
 		return nil, err
 	}
 
-	var tagResponses []*v1.Tag
+	tagIDs := make([]string, 0, len(tags))
 	for _, tag := range tags {
-		tagResponses = append(tagResponses, &v1.Tag{
-			Id:   tag.ID,
-			Name: tag.Name,
-		})
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	counts, err := t.store.CountTagPosts(ctx, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	tagProtos := make([]*v1.Tag, 0, len(tags))
+	for _, tag := range tags {
+		tagProto := tagToProto(tag)
+		tagProto.PostCount = counts[tag.ID]
+		tagProtos = append(tagProtos, tagProto)
 	}
 
 	return &v1.ListTagResponse{
-		Tags: tagResponses,
+		Tags: tagProtos,
 	}, nil
 }
 
 func (t *TagService) UpdateTag(ctx context.Context, request *v1.UpdateTagRequest) (*v1.UpdateTagResponse, error) {
-	tag := &model.Tag{
-		ID:   request.GetId(),
-		Name: request.GetName(),
+	tagID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
 	}
 
-	err := t.store.UpdateTag(ctx, tag)
+	var tag *model.Tag
+	err = t.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		// the tag is loaded first, so the fields left out of the request keep their values
+		tag, err = tx.GetTag(ctx, tagID)
+		if err != nil {
+			return err
+		}
+		if err := requireSpace(ctx, tag.SpaceID); err != nil {
+			return err
+		}
+
+		if request.Name != nil {
+			tag.Name = strings.TrimSpace(request.GetName())
+			if tag.Name == "" {
+				return store.NewValidationError("name", "must not be empty")
+			}
+		}
+		if request.Description != nil {
+			tag.Description = request.GetDescription()
+		}
+		if request.Color != nil {
+			tag.Color = request.GetColor()
+		}
+		if request.ParentId != nil {
+			tag.ParentID = nil
+			if request.GetParentId() != "" {
+				if err := setTagParent(ctx, tx, tag, request.GetParentId()); err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.UpdateTag(ctx, tag)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &v1.UpdateTagResponse{
-		Tag: &v1.Tag{
-			Id:   tag.ID,
-			Name: tag.Name,
-		},
+		Tag: tagToProto(tag),
 	}, nil
 }
 
 func (t *TagService) DeleteTag(ctx context.Context, request *v1.DeleteTagRequest) (*v1.DeleteTagResponse, error) {
-	err := t.store.DeleteTag(ctx, uuid.MustParse(request.GetId()))
+	tagID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		tag, err := tx.GetTag(ctx, tagID)
+		if err != nil {
+			return err
+		}
+		if err := requireSpace(ctx, tag.SpaceID); err != nil {
+			return err
+		}
+
+		return tx.DeleteTag(ctx, tagID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.DeleteTagResponse{
+		Id: tagID.String(),
+	}, nil
+}
+
+func (t *TagService) MergeTags(ctx context.Context, request *v1.MergeTagsRequest) (*v1.MergeTagsResponse, error) {
+	targetID, err := parseID("target_id", request.GetTargetId())
+	if err != nil {
+		return nil, err
+	}
+
+	sourceIDs := make([]uuid.UUID, 0, len(request.GetSourceIds()))
+	seen := make(map[uuid.UUID]bool)
+	for _, id := range request.GetSourceIds() {
+		sourceID, err := parseID("source_ids", id)
+		if err != nil {
+			return nil, err
+		}
+		if sourceID == targetID {
+			return nil, store.NewValidationError("source_ids", "must not contain the target tag")
+		}
+		if !seen[sourceID] {
+			seen[sourceID] = true
+			sourceIDs = append(sourceIDs, sourceID)
+		}
+	}
+
+	var target *model.Tag
+	err = t.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		target, err = tx.GetTag(ctx, targetID)
+		if err != nil {
+			return err
+		}
+		// the sources must share the space of the target
+		if err := requireSpace(ctx, target.SpaceID); err != nil {
+			return err
+		}
+
+		sources := make(map[string]*model.Tag, len(sourceIDs))
+		for _, sourceID := range sourceIDs {
+			source, err := tx.GetTag(ctx, sourceID)
+			if err != nil {
+				return err
+			}
+			if source.SpaceID != target.SpaceID {
+				return store.NewValidationError("source_ids", "tag "+source.ID+" belongs to another space")
+			}
+			sources[source.ID] = source
+
+			// the target keeps a platform tag of the sources when it has none
+			if target.PlatformTagID == nil {
+				target.PlatformTagID = source.PlatformTagID
+			}
+		}
+
+		// a target nested under a source moves up to the closest ancestor that is not merged
+		for depth := 0; target.ParentID != nil && sources[*target.ParentID] != nil; depth++ {
+			if depth == maxTagDepth {
+				return store.NewValidationError("target_id", "tag hierarchy is too deep")
+			}
+			target.ParentID = sources[*target.ParentID].ParentID
+		}
+
+		// the children of the sources move under the target
+		ancestors, err := tagAncestors(ctx, tx, target, "target_id")
+		if err != nil {
+			return err
+		}
+		merged := []string{target.ID}
+		for id := range sources {
+			merged = append(merged, id)
+		}
+		height, err := tagHeight(ctx, tx, merged)
+		if err != nil {
+			return err
+		}
+		if len(ancestors)+height > maxTagDepth {
+			return store.NewValidationError("target_id", "tag hierarchy is too deep")
+		}
+
+		if err := tx.UpdateTag(ctx, target); err != nil {
+			return err
+		}
+
+		return tx.MergeTags(ctx, targetID, sourceIDs)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &v1.DeleteTagResponse{}, nil
+	return &v1.MergeTagsResponse{
+		Tag: tagToProto(target),
+	}, nil
+}
+
+// setTagParent nests the tag under the parent, the parent must belong to the same space and must not be a descendant of the tag.
+// The subtree of the tag moves with it, so its height counts in the depth of the tree.
+func setTagParent(ctx context.Context, tx store.UnstakStore, tag *model.Tag, parentID string) error {
+	id, err := parseID("parent_id", parentID)
+	if err != nil {
+		return err
+	}
+
+	parent, err := tx.GetTag(ctx, id)
+	if err != nil {
+		return err
+	}
+	if parent.SpaceID != tag.SpaceID {
+		return store.NewValidationError("parent_id", "parent tag belongs to another space")
+	}
+
+	ancestors, err := tagAncestors(ctx, tx, parent, "parent_id")
+	if err != nil {
+		return err
+	}
+	if slices.Contains(ancestors, tag.ID) {
+		return store.NewValidationError("parent_id", "tag cannot be nested under itself or its descendants")
+	}
+
+	height, err := tagHeight(ctx, tx, []string{tag.ID})
+	if err != nil {
+		return err
+	}
+	if len(ancestors)+1+height > maxTagDepth {
+		return store.NewValidationError("parent_id", "tag hierarchy is too deep")
+	}
+
+	tag.ParentID = &parent.ID
+
+	return nil
+}
+
+// tagAncestors returns the ids of the tag and its ancestors up to the root
+func tagAncestors(ctx context.Context, tx store.UnstakStore, tag *model.Tag, field string) ([]string, error) {
+	ids := []string{tag.ID}
+	for ancestor := tag; ancestor.ParentID != nil; {
+		if len(ids) == maxTagDepth {
+			return nil, store.NewValidationError(field, "tag hierarchy is too deep")
+		}
+
+		var err error
+		ancestor, err = tx.GetTag(ctx, uuid.MustParse(*ancestor.ParentID))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, ancestor.ID)
+	}
+
+	return ids, nil
+}
+
+// tagHeight returns the levels of the tags below the roots taken as a single tag, the walk stops past maxTagDepth
+func tagHeight(ctx context.Context, tx store.UnstakStore, roots []string) (int, error) {
+	merged := make(map[string]bool, len(roots))
+	for _, id := range roots {
+		merged[id] = true
+	}
+
+	height := 0
+	for level := roots; height <= maxTagDepth; height++ {
+		children, err := tx.ListChildTags(ctx, level)
+		if err != nil {
+			return 0, err
+		}
+
+		next := make([]string, 0, len(children))
+		for _, child := range children {
+			if !merged[child.ID] {
+				next = append(next, child.ID)
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		level = next
+	}
+
+	return height, nil
+}
+
+func tagToProto(tag *model.Tag) *v1.Tag {
+	tagProto := &v1.Tag{
+		Id:          tag.ID,
+		SpaceId:     tag.SpaceID,
+		Name:        tag.Name,
+		Description: tag.Description,
+		Color:       tag.Color,
+	}
+	if tag.ParentID != nil {
+		tagProto.ParentId = *tag.ParentID
+	}
+	if tag.PlatformTagID != nil {
+		tagProto.PlatformTagId = *tag.PlatformTagID
+	}
+
+	return tagProto
 }
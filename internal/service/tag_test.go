@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/store/storetest"
+	"github.com/google/uuid"
+)
+
+// createTagChain creates a chain of nested tags under the parent and returns them from the top down
+func createTagChain(t *testing.T, s store.UnstakStore, spaceID, name string, parentID *string, levels int) []*model.Tag {
+	chain := make([]*model.Tag, 0, levels)
+	for i := 0; i < levels; i++ {
+		tag := createTestTag(t, s, spaceID, name+strconv.Itoa(i), parentID)
+		chain = append(chain, tag)
+		parentID = &tag.ID
+	}
+
+	return chain
+}
+
+func TestMergeTags(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+	service := NewTagService(s)
+	spaceID := uuid.NewString()
+
+	root := createTestTag(t, s, spaceID, "root", nil)
+	source := createTestTag(t, s, spaceID, "golang", &root.ID)
+	// the target nested under a source moves up to the root
+	target := createTestTag(t, s, spaceID, "go", &source.ID)
+	other := createTestTag(t, s, spaceID, "gopher", nil)
+	child := createTestTag(t, s, spaceID, "generics", &other.ID)
+
+	tagged := createTestPost(t, s, spaceID, "", model.PostStatusDraft)
+	both := createTestPost(t, s, spaceID, "", model.PostStatusDraft)
+	if err := s.UpdatePostTags(ctx, uuid.MustParse(tagged.ID), []*model.Tag{source, other}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdatePostTags(ctx, uuid.MustParse(both.ID), []*model.Tag{source, target}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := service.MergeTags(ctx, &v1.MergeTagsRequest{TargetId: target.ID, SourceIds: []string{source.ID, other.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetTag().GetParentId() != root.ID {
+		t.Errorf("expected the target to move up to the root, got parent %q", res.GetTag().GetParentId())
+	}
+
+	// the posts of the sources are re-pointed to the target, once per post
+	for _, post := range []*model.Post{tagged, both} {
+		if tags := getTestPost(t, s, post.ID).Tags; len(tags) != 1 || tags[0].ID != target.ID {
+			t.Errorf("expected the post %s to carry only the target, got %v", post.ID, tags)
+		}
+	}
+	for _, id := range []string{source.ID, other.ID} {
+		if _, err := s.GetTag(ctx, uuid.MustParse(id)); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("expected the source %s to be deleted, got %v", id, err)
+		}
+	}
+	moved, err := s.GetTag(ctx, uuid.MustParse(child.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.ParentID == nil || *moved.ParentID != target.ID {
+		t.Errorf("expected the child of a source to move under the target, got %v", moved.ParentID)
+	}
+
+	another := createTestTag(t, s, uuid.NewString(), "go", nil)
+	_, err = service.MergeTags(ctx, &v1.MergeTagsRequest{TargetId: target.ID, SourceIds: []string{another.ID}})
+	if !errors.Is(err, store.ErrInvalidArgument) {
+		t.Errorf("expected the tag of another space to be refused, got %v", err)
+	}
+}
+
+func TestMergeTagsDepth(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+	service := NewTagService(s)
+	spaceID := uuid.NewString()
+
+	chain := createTagChain(t, s, spaceID, "chain", nil, maxTagDepth-4)
+	target := chain[len(chain)-1]
+	// the five levels below the source would end one level too deep under the target
+	source := createTestTag(t, s, spaceID, "source", nil)
+	createTagChain(t, s, spaceID, "subtree", &source.ID, 5)
+
+	_, err := service.MergeTags(ctx, &v1.MergeTagsRequest{TargetId: target.ID, SourceIds: []string{source.ID}})
+	if !errors.Is(err, store.ErrInvalidArgument) {
+		t.Fatalf("expected the merge to be too deep, got %v", err)
+	}
+
+	_, err = service.MergeTags(ctx, &v1.MergeTagsRequest{TargetId: chain[len(chain)-2].ID, SourceIds: []string{source.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetTagParent(t *testing.T) {
+	ctx := context.Background()
+	s := storetest.NewStore(t)
+	service := NewTagService(s)
+	spaceID := uuid.NewString()
+
+	tree := createTagChain(t, s, spaceID, "tree", nil, 3)
+	chain := createTagChain(t, s, spaceID, "chain", nil, maxTagDepth-1)
+
+	tests := []struct {
+		name     string
+		tag      *model.Tag
+		parentID string
+		valid    bool
+	}{
+		{"under itself", tree[0], tree[0].ID, false},
+		{"under a descendant", tree[0], tree[2].ID, false},
+		{"under another space", tree[0], createTestTag(t, s, uuid.NewString(), "other", nil).ID, false},
+		// the subtree of the tag ends one level too deep
+		{"too deep with its subtree", tree[0], chain[len(chain)-2].ID, false},
+		{"the deepest subtree", tree[0], chain[len(chain)-3].ID, true},
+		{"a leaf at the deepest level", tree[2], chain[len(chain)-1].ID, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parentID := test.parentID
+			_, err := service.UpdateTag(ctx, &v1.UpdateTagRequest{Id: test.tag.ID, ParentId: &parentID})
+			if test.valid && err != nil {
+				t.Fatal(err)
+			}
+			if !test.valid && !errors.Is(err, store.ErrInvalidArgument) {
+				t.Errorf("expected the parent to be refused, got %v", err)
+			}
+		})
+	}
+}
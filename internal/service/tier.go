@@ -6,6 +6,7 @@ import (
 	v1 "github.com/emrgen/unpost/apis/v1"
 	"github.com/emrgen/unpost/internal/model"
 	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/x"
 	"github.com/google/uuid"
 )
 
@@ -30,11 +31,25 @@ func (s *TierService) CreateTier(ctx context.Context, request *v1.CreateTierRequ
 	}
 
 	tier := &model.Tier{
+		ID:          uuid.New().String(),
 		Name:        request.GetName(),
 		CreatedByID: userID.String(),
 	}
+	// a tier created with an api token belongs to the space of the token
+	tier.SpaceID, _ = x.TokenSpaceFromContext(ctx)
 
-	err = s.store.CreateTier(ctx, tier)
+	err = s.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		if err := tx.CreateTier(ctx, tier); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventTierCreated, tier.ID, tier.SpaceID, tierEventData{
+			ID:          tier.ID,
+			Name:        tier.Name,
+			SpaceID:     tier.SpaceID,
+			CreatedByID: tier.CreatedByID,
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -60,9 +75,27 @@ func (s *TierService) UpdateTier(ctx context.Context, request *v1.UpdateTierRequ
 }
 
 func (s *TierService) DeleteTier(ctx context.Context, request *v1.DeleteTierRequest) (*v1.DeleteTierResponse, error) {
-	subID := uuid.MustParse(request.GetId())
+	subID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
 
-	if err := s.store.DeleteTier(ctx, subID); err != nil {
+	err = s.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		tier, err := tx.GetTier(ctx, subID)
+		if err != nil {
+			return err
+		}
+		if err := requireSpace(ctx, tier.SpaceID); err != nil {
+			return err
+		}
+
+		if err := tx.DeleteTier(ctx, subID); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventTierDeleted, tier.ID, tier.SpaceID, deletedEventData{ID: tier.ID})
+	})
+	if err != nil {
 		return nil, err
 	}
 
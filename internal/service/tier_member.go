@@ -21,12 +21,38 @@ type TierMemberService struct {
 }
 
 func (s *TierMemberService) CreateTierMember(ctx context.Context, request *v1.CreateTierMemberRequest) (*v1.CreateTierMemberResponse, error) {
-	userID := uuid.MustParse(request.GetUserId())
-	tierID := uuid.MustParse(request.GetTierId())
+	userID, err := parseID("user_id", request.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	tierID, err := parseID("tier_id", request.GetTierId())
+	if err != nil {
+		return nil, err
+	}
 
-	err := s.store.AddTierMember(ctx, &model.TierMember{
+	member := &model.TierMember{
+		ID:     uuid.New().String(),
 		UserID: userID.String(),
 		TierID: tierID.String(),
+	}
+	err = s.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		tier, err := tx.GetTier(ctx, tierID)
+		if err != nil {
+			return err
+		}
+		if err := requireSpace(ctx, tier.SpaceID); err != nil {
+			return err
+		}
+
+		if err := tx.AddTierMember(ctx, member); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventTierMemberCreated, member.ID, "", tierMemberEventData{
+			ID:     member.ID,
+			TierID: member.TierID,
+			UserID: member.UserID,
+		})
 	})
 	if err != nil {
 		return nil, err
@@ -36,12 +62,18 @@ func (s *TierMemberService) CreateTierMember(ctx context.Context, request *v1.Cr
 }
 
 func (s *TierMemberService) GetTierMember(ctx context.Context, request *v1.GetTierMemberRequest) (*v1.GetTierMemberResponse, error) {
-	subMemberID := uuid.MustParse(request.GetId())
+	subMemberID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
 
 	tierMember, err := s.store.GetTierMember(ctx, subMemberID)
 	if err != nil {
 		return nil, err
 	}
+	if err := requireSpace(ctx, memberSpaceID(tierMember)); err != nil {
+		return nil, err
+	}
 
 	return &v1.GetTierMemberResponse{
 		Member: &v1.TierMember{
@@ -57,7 +89,18 @@ func (s *TierMemberService) GetTierMember(ctx context.Context, request *v1.GetTi
 }
 
 func (s *TierMemberService) ListTierMember(ctx context.Context, request *v1.ListTierMemberRequest) (*v1.ListTierMemberResponse, error) {
-	subID := uuid.MustParse(request.GetTierId())
+	subID, err := parseID("tier_id", request.GetTierId())
+	if err != nil {
+		return nil, err
+	}
+
+	tier, err := s.store.GetTier(ctx, subID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSpace(ctx, tier.SpaceID); err != nil {
+		return nil, err
+	}
 
 	tierMembers, err := s.store.ListTierMembers(ctx, subID)
 	if err != nil {
@@ -87,11 +130,38 @@ func (s *TierMemberService) UpdateTierMember(ctx context.Context, request *v1.Up
 }
 
 func (s *TierMemberService) DeleteTierMember(ctx context.Context, request *v1.DeleteTierMemberRequest) (*v1.DeleteTierMemberResponse, error) {
-	subID := uuid.MustParse(request.GetId())
+	subID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
 
-	if err := s.store.RemoveTierMember(ctx, subID); err != nil {
+	err = s.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		member, err := tx.GetTierMember(ctx, subID)
+		if err != nil {
+			return err
+		}
+		if err := requireSpace(ctx, memberSpaceID(member)); err != nil {
+			return err
+		}
+
+		if err := tx.RemoveTierMember(ctx, subID); err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, tx, model.EventTierMemberDeleted, subID.String(), "", deletedEventData{ID: subID.String()})
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &v1.DeleteTierMemberResponse{}, nil
 }
+
+// memberSpaceID returns the space of the tier of the member, empty when the tier is deleted
+func memberSpaceID(member *model.TierMember) string {
+	if member.Tier == nil {
+		return ""
+	}
+
+	return member.Tier.SpaceID
+}
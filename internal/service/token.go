@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/apitoken"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewTokenService creates a new token service
+func NewTokenService(store store.UnstakStore) *TokenService {
+	return &TokenService{
+		store: store,
+	}
+}
+
+var _ v1.TokenServiceServer = new(TokenService)
+
+// TokenService manages the scoped api tokens of the spaces
+type TokenService struct {
+	store store.UnstakStore
+	v1.UnimplementedTokenServiceServer
+}
+
+func (t *TokenService) CreateApiToken(ctx context.Context, request *v1.CreateApiTokenRequest) (*v1.CreateApiTokenResponse, error) {
+	if err := requireAuthor(ctx); err != nil {
+		return nil, err
+	}
+	spaceID, err := parseID("space_id", request.GetSpaceId())
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make([]string, 0, len(request.GetScopes()))
+	for _, scope := range request.GetScopes() {
+		if !apitoken.ValidScope(scope) {
+			return nil, store.NewValidationError("scopes", "unknown scope "+scope+", expected one of "+strings.Join(apitoken.Scopes, ", "))
+		}
+		if apitoken.AdminScope(scope) && !isAdmin(ctx) {
+			return nil, status.Errorf(codes.PermissionDenied, "only the admins grant the %s scope", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	name := strings.TrimSpace(request.GetName())
+	if name == "" {
+		return nil, store.NewValidationError("name", "must not be empty")
+	}
+
+	id := uuid.New()
+	secret, secretHash, err := apitoken.Generate(id)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &model.ApiToken{
+		ID:          id.String(),
+		SpaceID:     spaceID.String(),
+		Name:        name,
+		SecretHash:  secretHash,
+		Scopes:      strings.Join(scopes, ","),
+		CreatedByID: callerID(ctx),
+	}
+	if request.GetTtlSeconds() > 0 {
+		expiresAt := time.Now().Add(time.Duration(request.GetTtlSeconds()) * time.Second)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := t.store.CreateApiToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	return &v1.CreateApiTokenResponse{
+		Token:  apiTokenToProto(token),
+		Secret: secret,
+	}, nil
+}
+
+func (t *TokenService) ListApiTokens(ctx context.Context, request *v1.ListApiTokensRequest) (*v1.ListApiTokensResponse, error) {
+	filter := &store.ApiTokenFilter{}
+	if request.SpaceId != nil {
+		spaceID, err := parseID("space_id", request.GetSpaceId())
+		if err != nil {
+			return nil, err
+		}
+		filter.SpaceID = &spaceID
+	}
+	// the admins see every token, the other callers their own
+	if !isAdmin(ctx) {
+		userID, err := parseID("user_id", callerID(ctx))
+		if err != nil {
+			return nil, err
+		}
+		filter.CreatedByID = &userID
+	}
+
+	tokens, err := t.store.ListApiTokens(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenProtos := make([]*v1.ApiToken, 0, len(tokens))
+	for _, token := range tokens {
+		tokenProtos = append(tokenProtos, apiTokenToProto(token))
+	}
+
+	return &v1.ListApiTokensResponse{
+		Tokens: tokenProtos,
+	}, nil
+}
+
+func (t *TokenService) RevokeApiToken(ctx context.Context, request *v1.RevokeApiTokenRequest) (*v1.RevokeApiTokenResponse, error) {
+	tokenID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	var token *model.ApiToken
+	err = t.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		token, err = tx.GetApiToken(ctx, tokenID)
+		if err != nil {
+			return err
+		}
+		if token.CreatedByID != callerID(ctx) && !isAdmin(ctx) {
+			return status.Error(codes.PermissionDenied, "only the creator of the token or an admin can revoke it")
+		}
+		if token.RevokedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		token.RevokedAt = &now
+
+		return tx.UpdateApiToken(ctx, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.RevokeApiTokenResponse{
+		Token: apiTokenToProto(token),
+	}, nil
+}
+
+func apiTokenToProto(token *model.ApiToken) *v1.ApiToken {
+	tokenProto := &v1.ApiToken{
+		Id:          token.ID,
+		SpaceId:     token.SpaceID,
+		Name:        token.Name,
+		Scopes:      token.ScopeList(),
+		CreatedById: token.CreatedByID,
+		CreatedAt:   timestamppb.New(token.CreatedAt),
+	}
+	if token.ExpiresAt != nil {
+		tokenProto.ExpiresAt = timestamppb.New(*token.ExpiresAt)
+	}
+	if token.LastUsedAt != nil {
+		tokenProto.LastUsedAt = timestamppb.New(*token.LastUsedAt)
+	}
+	if token.RevokedAt != nil {
+		tokenProto.RevokedAt = timestamppb.New(*token.RevokedAt)
+	}
+
+	return tokenProto
+}
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	v1 "github.com/emrgen/unpost/apis/v1"
+	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/outbox"
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/emrgen/unpost/internal/x"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const webhookSecretLength = 32
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(store store.UnstakStore, sender *outbox.Sender) *WebhookService {
+	return &WebhookService{
+		store:  store,
+		sender: sender,
+	}
+}
+
+var _ v1.WebhookServiceServer = new(WebhookService)
+
+// WebhookService is the service that lets the integrators subscribe to the domain events.
+// The webhooks receive the events of every space, only the admins manage them.
+type WebhookService struct {
+	store  store.UnstakStore
+	sender *outbox.Sender
+	v1.UnimplementedWebhookServiceServer
+}
+
+func (w *WebhookService) CreateWebhook(ctx context.Context, request *v1.CreateWebhookRequest) (*v1.CreateWebhookResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := validateWebhookURL(ctx, request.GetUrl()); err != nil {
+		return nil, err
+	}
+	userID, _ := x.UserIDFromContext(ctx)
+
+	webhook := &model.Webhook{
+		ID:          uuid.New().String(),
+		URL:         request.GetUrl(),
+		Secret:      x.RandomString(webhookSecretLength),
+		EventTypes:  strings.Join(request.GetEventTypes(), ","),
+		Active:      true,
+		CreatedByID: userID,
+	}
+
+	if err := w.store.CreateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return &v1.CreateWebhookResponse{
+		Webhook: webhookToProto(webhook, true),
+	}, nil
+}
+
+func (w *WebhookService) ListWebhooks(ctx context.Context, request *v1.ListWebhooksRequest) (*v1.ListWebhooksResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	page, perPage := pagination(request.GetPage(), request.GetPerPage())
+	webhooks, err := w.store.ListWebhooks(ctx, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookProtos := make([]*v1.Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		webhookProtos = append(webhookProtos, webhookToProto(webhook, false))
+	}
+
+	return &v1.ListWebhooksResponse{
+		Webhooks: webhookProtos,
+	}, nil
+}
+
+func (w *WebhookService) UpdateWebhook(ctx context.Context, request *v1.UpdateWebhookRequest) (*v1.UpdateWebhookResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	webhookID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if request.Url != nil {
+		if err := validateWebhookURL(ctx, request.GetUrl()); err != nil {
+			return nil, err
+		}
+	}
+
+	var webhook *model.Webhook
+	err = w.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		var err error
+		webhook, err = tx.GetWebhook(ctx, webhookID)
+		if err != nil {
+			return err
+		}
+
+		if request.Url != nil {
+			webhook.URL = request.GetUrl()
+		}
+
+		if request.EventTypes != nil {
+			webhook.EventTypes = strings.Join(request.GetEventTypes(), ",")
+		}
+
+		if request.Active != nil {
+			webhook.Active = request.GetActive()
+		}
+
+		return tx.UpdateWebhook(ctx, webhook)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.UpdateWebhookResponse{
+		Webhook: webhookToProto(webhook, false),
+	}, nil
+}
+
+func (w *WebhookService) DeleteWebhook(ctx context.Context, request *v1.DeleteWebhookRequest) (*v1.DeleteWebhookResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	webhookID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.store.DeleteWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	return &v1.DeleteWebhookResponse{
+		Id: request.GetId(),
+	}, nil
+}
+
+// TestWebhook sends a webhook.test event to the webhook, the event is not stored in the outbox
+func (w *WebhookService) TestWebhook(ctx context.Context, request *v1.TestWebhookRequest) (*v1.TestWebhookResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	webhookID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := w.store.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := model.NewOutboxEvent(model.EventWebhookTest, webhook.ID, "", map[string]string{"webhook_id": webhook.ID})
+	if err != nil {
+		return nil, err
+	}
+	event.CreatedAt = time.Now()
+
+	code, err := w.sender.Send(ctx, webhook, event, uuid.New().String())
+	if err != nil {
+		return &v1.TestWebhookResponse{
+			Success:      false,
+			ResponseCode: int32(code),
+			Error:        err.Error(),
+		}, nil
+	}
+
+	return &v1.TestWebhookResponse{
+		Success:      true,
+		ResponseCode: int32(code),
+	}, nil
+}
+
+// RotateWebhookSecret replaces the secret used to sign the deliveries
+func (w *WebhookService) RotateWebhookSecret(ctx context.Context, request *v1.RotateWebhookSecretRequest) (*v1.RotateWebhookSecretResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	webhookID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	var webhook *model.Webhook
+	err = w.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		var err error
+		webhook, err = tx.GetWebhook(ctx, webhookID)
+		if err != nil {
+			return err
+		}
+
+		webhook.Secret = x.RandomString(webhookSecretLength)
+
+		return tx.UpdateWebhook(ctx, webhook)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.RotateWebhookSecretResponse{
+		Webhook: webhookToProto(webhook, true),
+	}, nil
+}
+
+func (w *WebhookService) ListWebhookDeliveries(ctx context.Context, request *v1.ListWebhookDeliveriesRequest) (*v1.ListWebhookDeliveriesResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	filter := &store.WebhookDeliveryFilter{}
+	if request.WebhookId != nil {
+		webhookID, err := parseID("webhook_id", request.GetWebhookId())
+		if err != nil {
+			return nil, err
+		}
+		filter.WebhookID = &webhookID
+	}
+	if request.Status != nil {
+		status := webhookDeliveryStatusFromProto(request.GetStatus())
+		filter.Status = &status
+	}
+
+	page, perPage := pagination(request.GetPage(), request.GetPerPage())
+	deliveries, err := w.store.ListWebhookDeliveries(ctx, filter, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveryProtos := make([]*v1.WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveryProtos = append(deliveryProtos, webhookDeliveryToProto(delivery))
+	}
+
+	return &v1.ListWebhookDeliveriesResponse{
+		Deliveries: deliveryProtos,
+	}, nil
+}
+
+// RetryWebhookDelivery moves a delivery from the dead-letter queue back to the pending queue
+func (w *WebhookService) RetryWebhookDelivery(ctx context.Context, request *v1.RetryWebhookDeliveryRequest) (*v1.RetryWebhookDeliveryResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	deliveryID, err := parseID("id", request.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	var delivery *model.WebhookDelivery
+	err = w.store.Transaction(ctx, func(ctx context.Context, tx store.UnstakStore) error {
+		var err error
+		delivery, err = tx.GetWebhookDelivery(ctx, deliveryID)
+		if err != nil {
+			return err
+		}
+		if delivery.Status == model.WebhookDeliveryCancelled {
+			return status.Error(codes.FailedPrecondition, "the webhook of the delivery is deleted")
+		}
+
+		delivery.Status = model.WebhookDeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = time.Now()
+
+		return tx.UpdateWebhookDelivery(ctx, delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.RetryWebhookDeliveryResponse{
+		Delivery: webhookDeliveryToProto(delivery),
+	}, nil
+}
+
+// validateWebhookURL refuses the urls that point into the private network of the server
+func validateWebhookURL(ctx context.Context, rawURL string) error {
+	if err := outbox.ValidateURL(ctx, rawURL); err != nil {
+		return store.NewValidationError("url", err.Error())
+	}
+
+	return nil
+}
+
+// webhookToProto converts the webhook, the secret is only exposed when withSecret is set
+func webhookToProto(webhook *model.Webhook, withSecret bool) *v1.Webhook {
+	webhookProto := &v1.Webhook{
+		Id:          webhook.ID,
+		Url:         webhook.URL,
+		EventTypes:  make([]string, 0),
+		Active:      webhook.Active,
+		CreatedById: webhook.CreatedByID,
+		CreatedAt:   timestamppb.New(webhook.CreatedAt),
+		UpdatedAt:   timestamppb.New(webhook.UpdatedAt),
+	}
+
+	if webhook.EventTypes != "" {
+		webhookProto.EventTypes = strings.Split(webhook.EventTypes, ",")
+	}
+
+	if withSecret {
+		webhookProto.Secret = webhook.Secret
+	}
+
+	return webhookProto
+}
+
+func webhookDeliveryToProto(delivery *model.WebhookDelivery) *v1.WebhookDelivery {
+	deliveryProto := &v1.WebhookDelivery{
+		Id:            delivery.ID,
+		WebhookId:     delivery.WebhookID,
+		EventId:       delivery.EventID,
+		Status:        webhookDeliveryStatusToProto(delivery.Status),
+		Attempts:      int32(delivery.Attempts),
+		ResponseCode:  int32(delivery.ResponseCode),
+		LastError:     delivery.LastError,
+		NextAttemptAt: timestamppb.New(delivery.NextAttemptAt),
+		CreatedAt:     timestamppb.New(delivery.CreatedAt),
+		UpdatedAt:     timestamppb.New(delivery.UpdatedAt),
+	}
+
+	if delivery.Event != nil {
+		deliveryProto.EventType = string(delivery.Event.Type)
+	}
+
+	return deliveryProto
+}
+
+func webhookDeliveryStatusFromProto(status v1.WebhookDeliveryStatus) model.WebhookDeliveryStatus {
+	switch status {
+	case v1.WebhookDeliveryStatus_DELIVERED:
+		return model.WebhookDeliveryDelivered
+	case v1.WebhookDeliveryStatus_FAILED:
+		return model.WebhookDeliveryFailed
+	case v1.WebhookDeliveryStatus_CANCELLED:
+		return model.WebhookDeliveryCancelled
+	default:
+		return model.WebhookDeliveryPending
+	}
+}
+
+func webhookDeliveryStatusToProto(status model.WebhookDeliveryStatus) v1.WebhookDeliveryStatus {
+	switch status {
+	case model.WebhookDeliveryDelivered:
+		return v1.WebhookDeliveryStatus_DELIVERED
+	case model.WebhookDeliveryFailed:
+		return v1.WebhookDeliveryStatus_FAILED
+	case model.WebhookDeliveryCancelled:
+		return v1.WebhookDeliveryStatus_CANCELLED
+	default:
+		return v1.WebhookDeliveryStatus_PENDING
+	}
+}
@@ -1,7 +1,125 @@
 package store
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
 
 var (
 	ErrMasterSpaceAlreadyExists = errors.New("master space already exists")
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrAlreadyExists is returned when a unique constraint is violated
+	ErrAlreadyExists = errors.New("record already exists")
+	// ErrForeignKeyViolation is returned when a referenced record does not exist or is still referenced
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	// ErrConflict is returned when the record was modified concurrently
+	ErrConflict = errors.New("record was modified concurrently")
+	// ErrInvalidArgument is returned when the input fails the validation
+	ErrInvalidArgument = errors.New("invalid argument")
 )
+
+// postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// NotFoundError is returned when a record of a resource type does not exist, it matches ErrNotFound
+type NotFoundError struct {
+	Resource string
+	ID       string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", e.Resource, e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// FieldViolation describes a single invalid field of the input
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ValidationError is returned when one or more fields of the input are invalid, it matches ErrInvalidArgument
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+// NewValidationError creates a ValidationError for a single field
+func NewValidationError(field, description string) *ValidationError {
+	return &ValidationError{
+		Violations: []FieldViolation{{Field: field, Description: description}},
+	}
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Field+": "+violation.Description)
+	}
+
+	return "invalid argument: " + strings.Join(messages, ", ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
+
+// translateError converts the gorm and driver errors into the store errors, other errors are returned as is
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%w: %v", ErrAlreadyExists, err)
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return fmt.Errorf("%w: %v", ErrForeignKeyViolation, err)
+	}
+
+	// the dialect errors are only translated by gorm when TranslateError is enabled
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return fmt.Errorf("%w: %s", ErrAlreadyExists, pgErr.ConstraintName)
+		case pgForeignKeyViolation:
+			return fmt.Errorf("%w: %s", ErrForeignKeyViolation, pgErr.ConstraintName)
+		case pgSerializationFailure, pgDeadlockDetected:
+			return fmt.Errorf("%w: %s", ErrConflict, pgErr.Message)
+		}
+	}
+
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "UNIQUE constraint failed"):
+		return fmt.Errorf("%w: %v", ErrAlreadyExists, err)
+	case strings.Contains(message, "FOREIGN KEY constraint failed"):
+		return fmt.Errorf("%w: %v", ErrForeignKeyViolation, err)
+	}
+
+	return err
+}
+
+// recordError translates the error of a single record lookup, a missing record becomes a NotFoundError
+func recordError(err error, resource, id string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &NotFoundError{Resource: resource, ID: id}
+	}
+
+	return translateError(err)
+}
@@ -2,15 +2,24 @@ package store
 
 import (
 	"context"
+	"fmt"
+	"github.com/emrgen/unpost/internal/cache"
+	"github.com/emrgen/unpost/internal/migrate"
 	"github.com/emrgen/unpost/internal/model"
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
 )
 
 // NewGormStore creates a new GormStore.
-func NewGormStore(db *gorm.DB) *GormStore {
+// The writes publish the invalidated cache keys on the bus, a nil bus disables the invalidation.
+func NewGormStore(db *gorm.DB, bus cache.InvalidationBus) *GormStore {
 	return &GormStore{
-		db: db,
+		db:  db,
+		bus: bus,
 	}
 }
 
@@ -19,17 +28,55 @@ var (
 )
 
 type GormStore struct {
-	db *gorm.DB
+	db  *gorm.DB
+	bus cache.InvalidationBus
+	// pending collects the invalidated keys within a transaction, they are published after the commit
+	pending *[]string
+}
+
+// invalidate publishes the cache keys, within a transaction the keys are deferred until the commit
+func (g *GormStore) invalidate(ctx context.Context, keys ...string) {
+	if g.bus == nil {
+		return
+	}
+
+	if g.pending != nil {
+		*g.pending = append(*g.pending, keys...)
+		return
+	}
+
+	if err := g.bus.Publish(ctx, keys...); err != nil {
+		logrus.Errorf("error publishing cache invalidation: %v", err)
+	}
+}
+
+// invalidatePost publishes the cache keys of a post
+func (g *GormStore) invalidatePost(ctx context.Context, postID uuid.UUID) {
+	if g.bus == nil {
+		return
+	}
+
+	keys := []string{cache.PostKey(postID.String())}
+
+	var slugIDs []string
+	if err := g.conn(ctx).Unscoped().Model(&model.Post{}).Where("id = ?", postID.String()).Pluck("slug_id", &slugIDs).Error; err != nil {
+		logrus.Errorf("error loading post slug for cache invalidation: %v", err)
+	}
+	for _, slugID := range slugIDs {
+		keys = append(keys, cache.PostSlugKey(slugID))
+	}
+
+	g.invalidate(ctx, keys...)
 }
 
 func (g *GormStore) CreatePost(ctx context.Context, post *model.Post) error {
-	return g.db.Create(post).Error
+	return translateError(g.conn(ctx).Create(post).Error)
 }
 
 func (g *GormStore) GetPost(ctx context.Context, id uuid.UUID) (*model.Post, error) {
 	var post model.Post
-	if err := g.db.Where("id = ?", id.String()).Preload("Tags").First(&post).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("id = ?", id.String()).Preload("Tags").First(&post).Error; err != nil {
+		return nil, recordError(err, "post", id.String())
 	}
 
 	return &post, nil
@@ -37,102 +84,592 @@ func (g *GormStore) GetPost(ctx context.Context, id uuid.UUID) (*model.Post, err
 
 func (g *GormStore) GetPostBySlugID(ctx context.Context, id string) (*model.Post, error) {
 	var post model.Post
-	if err := g.db.Where("slug_id = ?", id).Preload("Tags").First(&post).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("slug_id = ?", id).Preload("Tags").First(&post).Error; err != nil {
+		return nil, recordError(err, "post", id)
 	}
 
 	return &post, nil
 }
 
-func (g *GormStore) ListPosts(ctx context.Context, filer *PostFiler) ([]*model.Post, error) {
+func (g *GormStore) GetPostBySlug(ctx context.Context, spaceID uuid.UUID, slug string) (*model.Post, error) {
+	var post model.Post
+	if err := g.conn(ctx).Where("space_id = ? AND slug = ?", spaceID.String(), slug).Preload("Tags").First(&post).Error; err != nil {
+		return nil, recordError(err, "post", slug)
+	}
+
+	return &post, nil
+}
+
+func (g *GormStore) GetPosts(ctx context.Context, ids []uuid.UUID) ([]*model.Post, error) {
+	var posts []*model.Post
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	postIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		postIDs = append(postIDs, id.String())
+	}
+	if err := g.conn(ctx).Preload("Tags").Where("id IN ?", postIDs).Find(&posts).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return posts, nil
+}
+
+func (g *GormStore) ListPosts(ctx context.Context, filer *PostFiler, pageNumber, pageSize uint64) ([]*model.Post, error) {
 	var posts []*model.Post
-	if err := g.db.Where("").Find(&posts).Error; err != nil {
-		return nil, err
+	query := g.conn(ctx).Preload("Tags").Order("created_at DESC")
+	if filer != nil {
+		if filer.SpaceID != nil {
+			query = query.Where("space_id = ?", filer.SpaceID.String())
+		}
+		if filer.TierID != nil {
+			query = query.Where("tier_id = ?", filer.TierID.String())
+		}
+		if filer.OwnerID != nil {
+			query = query.Where("author_id = ?", filer.OwnerID.String())
+		}
+		if filer.Status != nil {
+			query = query.Where("status = ?", *filer.Status)
+		}
+		if len(filer.TagIDs) > 0 {
+			tagged := g.conn(ctx).Table("post_tags").Select("post_id").Where("tag_id IN ?", filer.TagIDs)
+			query = query.Where("id IN (?)", tagged)
+		}
+	}
+	if err := query.Limit(int(pageSize)).Offset(int(pageNumber * pageSize)).Find(&posts).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return posts, nil
 }
 
 func (g *GormStore) UpdatePostTags(ctx context.Context, postID uuid.UUID, tags []*model.Tag) error {
-	return g.db.Model(&model.Post{ID: postID.String()}).Association("Tags").Replace(tags)
+	if err := g.conn(ctx).Model(&model.Post{ID: postID.String()}).Association("Tags").Replace(tags); err != nil {
+		return translateError(err)
+	}
+
+	g.invalidatePost(ctx, postID)
+
+	return nil
 }
 
 func (g *GormStore) ListPostByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Post, error) {
 	var posts []*model.Post
 
-	if err := g.db.Find(&posts).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Find(&posts).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return posts, nil
 }
 
 func (g *GormStore) UpdatePost(ctx context.Context, post *model.Post) error {
-	return g.db.Save(post).Error
+	if err := g.conn(ctx).Save(post).Error; err != nil {
+		return translateError(err)
+	}
+
+	g.invalidate(ctx, cache.PostKey(post.ID), cache.PostSlugKey(post.SlugID))
+
+	return nil
 }
 
 func (g *GormStore) DeletePost(ctx context.Context, id uuid.UUID) error {
 	post := &model.Post{
 		ID: id.String(),
 	}
-	return g.db.Delete(post).Error
+	if err := g.conn(ctx).Delete(post).Error; err != nil {
+		return translateError(err)
+	}
+
+	g.invalidatePost(ctx, id)
+
+	return nil
 }
 
 func (g *GormStore) ListPostBySpace(ctx context.Context, spaceID uuid.UUID, status *model.PostStatus) ([]*model.Post, error) {
 	var posts []*model.Post
 	if status != nil {
-		if err := g.db.Where("space_id = ? AND status = ?", spaceID.String(), status).Find(&posts).Error; err != nil {
-			return nil, err
+		if err := g.conn(ctx).Where("space_id = ? AND status = ?", spaceID.String(), status).Find(&posts).Error; err != nil {
+			return nil, translateError(err)
 		}
 
 		return posts, nil
 	}
 
-	if err := g.db.Where("space_id = ?", spaceID.String()).Find(&posts).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("space_id = ?", spaceID.String()).Find(&posts).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return posts, nil
 }
 
 func (g *GormStore) CreatePlatformTag(ctx context.Context, tag *model.PlatformTag) error {
-	return g.db.Create(tag).Error
+	return translateError(g.conn(ctx).Create(tag).Error)
 }
 
 func (g *GormStore) GetPlatformTag(ctx context.Context, id uuid.UUID) (*model.PlatformTag, error) {
 	var tag model.PlatformTag
-	if err := g.db.Where("id = ?", id.String()).First(&tag).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&tag).Error; err != nil {
+		return nil, recordError(err, "platform_tag", id.String())
 	}
 
 	return &tag, nil
 }
 
-func (g *GormStore) ListPlatformTags(ctx context.Context, pageNumber, pageSize uint64) ([]*model.PlatformTag, error) {
+func (g *GormStore) ListPlatformTags(ctx context.Context, filter *PlatformTagFilter, pageNumber, pageSize uint64) ([]*model.PlatformTag, error) {
 	var tags []*model.PlatformTag
-	if err := g.db.Limit(int(pageSize)).Offset(int(pageNumber * pageSize)).Find(&tags).Error; err != nil {
-		return nil, err
+	query := g.conn(ctx).Order("name")
+	if filter != nil && filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if err := query.Limit(int(pageSize)).Offset(int(pageNumber * pageSize)).Find(&tags).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return tags, nil
 }
 
 func (g *GormStore) UpdatePlatformTag(ctx context.Context, tag *model.PlatformTag) error {
-	return g.db.Save(tag).Error
+	return translateError(g.conn(ctx).Save(tag).Error)
 }
 
 func (g *GormStore) DeletePlatformTag(ctx context.Context, id uuid.UUID) error {
-	return g.db.Delete(&model.PlatformTag{ID: id.String()}).Error
+	// the platform tags are soft deleted, the foreign key does not clear the mappings
+	err := g.conn(ctx).Model(&model.Tag{}).Where("platform_tag_id = ?", id.String()).Update("platform_tag_id", nil).Error
+	if err != nil {
+		return translateError(err)
+	}
+
+	return translateError(g.conn(ctx).Delete(&model.PlatformTag{ID: id.String()}).Error)
+}
+
+func (g *GormStore) SetTagPlatformTag(ctx context.Context, tagID uuid.UUID, platformTagID *uuid.UUID) error {
+	var value *string
+	if platformTagID != nil {
+		id := platformTagID.String()
+		value = &id
+	}
+
+	res := g.conn(ctx).Model(&model.Tag{}).Where("id = ?", tagID.String()).Update("platform_tag_id", value)
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return &NotFoundError{Resource: "tag", ID: tagID.String()}
+	}
+
+	return nil
+}
+
+func (g *GormStore) ListPlatformTagDocuments(ctx context.Context, limit int) ([]*PlatformTagDocument, error) {
+	var documents []*PlatformTagDocument
+	err := g.conn(ctx).Model(&model.Post{}).
+		Select("platform_tags.id AS platform_tag_id, posts.title, posts.content").
+		Joins("JOIN post_tags ON post_tags.post_id = posts.id").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Joins("JOIN platform_tags ON platform_tags.id = tags.platform_tag_id AND platform_tags.deleted_at IS NULL").
+		Where("platform_tags.status = ?", model.PlatformTagApproved).
+		Order("posts.updated_at DESC").
+		Limit(limit).
+		Scan(&documents).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return documents, nil
+}
+
+func (g *GormStore) ListRelationSpaceIDs(ctx context.Context) ([]string, error) {
+	var spaceIDs []string
+	err := g.conn(ctx).Model(&model.Post{}).Distinct("space_id").
+		Where("status = ? AND space_id IS NOT NULL AND space_id <> ''", model.PostStatusPublished).
+		Pluck("space_id", &spaceIDs).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return spaceIDs, nil
+}
+
+func (g *GormStore) ListRelationCandidates(ctx context.Context, spaceID uuid.UUID, limit int) ([]*model.Post, error) {
+	var posts []*model.Post
+	err := g.conn(ctx).Preload("Tags").
+		Where("space_id = ? AND status = ?", spaceID.String(), model.PostStatusPublished).
+		Order("published_at DESC").
+		Limit(limit).
+		Find(&posts).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return posts, nil
+}
+
+func (g *GormStore) ListPostReactors(ctx context.Context, postIDs []string) (map[string][]string, error) {
+	reactors := make(map[string][]string)
+	if len(postIDs) == 0 {
+		return reactors, nil
+	}
+
+	var rows []struct {
+		PostID string
+		UserID string
+	}
+	err := g.conn(ctx).Model(&model.Reaction{}).
+		Distinct("post_id", "user_id").
+		Where("post_id IN ? AND state = ?", postIDs, true).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	for _, row := range rows {
+		reactors[row.PostID] = append(reactors[row.PostID], row.UserID)
+	}
+
+	return reactors, nil
+}
+
+func (g *GormStore) ReplacePostRelations(ctx context.Context, spaceID uuid.UUID, relations []*model.PostRelation) error {
+	if err := g.conn(ctx).Where("space_id = ?", spaceID.String()).Delete(&model.PostRelation{}).Error; err != nil {
+		return translateError(err)
+	}
+	if len(relations) == 0 {
+		return nil
+	}
+
+	return translateError(g.conn(ctx).CreateInBatches(relations, 500).Error)
+}
+
+func (g *GormStore) ListRelatedPosts(ctx context.Context, postID uuid.UUID, limit int) ([]*model.Post, error) {
+	var posts []*model.Post
+	// a related post unpublished since the relations were computed is left out
+	err := g.conn(ctx).Preload("Tags").
+		Joins("JOIN post_relations ON post_relations.related_post_id = posts.id").
+		Where("post_relations.post_id = ? AND posts.status = ?", postID.String(), model.PostStatusPublished).
+		Order("post_relations.score DESC").
+		Limit(limit).
+		Find(&posts).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return posts, nil
+}
+
+func (g *GormStore) CreateReviewRequest(ctx context.Context, request *model.ReviewRequest) error {
+	return translateError(g.conn(ctx).Create(request).Error)
+}
+
+func (g *GormStore) GetPendingReviewRequest(ctx context.Context, postID uuid.UUID) (*model.ReviewRequest, error) {
+	var request model.ReviewRequest
+	err := g.conn(ctx).
+		Where("post_id = ? AND status = ?", postID.String(), model.ReviewRequestPending).
+		Order("created_at DESC").
+		First(&request).Error
+	if err != nil {
+		return nil, recordError(err, "review_request", postID.String())
+	}
+
+	return &request, nil
+}
+
+func (g *GormStore) UpdateReviewRequest(ctx context.Context, request *model.ReviewRequest) error {
+	return translateError(g.conn(ctx).Save(request).Error)
+}
+
+func (g *GormStore) ListReviewRequests(ctx context.Context, filter *ReviewRequestFilter, pageNumber, pageSize uint64) ([]*model.ReviewRequest, error) {
+	var requests []*model.ReviewRequest
+	// the requests of the deleted posts are left out
+	query := g.conn(ctx).
+		Joins("JOIN posts ON posts.id = review_requests.post_id AND posts.deleted_at IS NULL").
+		Order("review_requests.created_at")
+	if filter != nil {
+		if filter.SpaceID != nil {
+			query = query.Where("review_requests.space_id = ?", filter.SpaceID.String())
+		}
+		if filter.Status != nil {
+			query = query.Where("review_requests.status = ?", *filter.Status)
+		}
+	}
+	if err := query.Limit(int(pageSize)).Offset(int(pageNumber * pageSize)).Find(&requests).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return requests, nil
+}
+
+func (g *GormStore) CreatePreviewLink(ctx context.Context, link *model.PreviewLink) error {
+	return translateError(g.conn(ctx).Create(link).Error)
+}
+
+func (g *GormStore) GetPreviewLink(ctx context.Context, id uuid.UUID) (*model.PreviewLink, error) {
+	var link model.PreviewLink
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&link).Error; err != nil {
+		return nil, recordError(err, "preview_link", id.String())
+	}
+
+	return &link, nil
+}
+
+func (g *GormStore) ListPreviewLinks(ctx context.Context, postID uuid.UUID) ([]*model.PreviewLink, error) {
+	var links []*model.PreviewLink
+	if err := g.conn(ctx).Where("post_id = ?", postID.String()).Order("created_at DESC").Find(&links).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return links, nil
+}
+
+func (g *GormStore) UpdatePreviewLink(ctx context.Context, link *model.PreviewLink) error {
+	return translateError(g.conn(ctx).Save(link).Error)
+}
+
+func (g *GormStore) CreatePreviewAccess(ctx context.Context, access *model.PreviewAccess) error {
+	return translateError(g.conn(ctx).Create(access).Error)
+}
+
+func (g *GormStore) ListPreviewAccesses(ctx context.Context, linkID uuid.UUID, pageNumber, pageSize uint64) ([]*model.PreviewAccess, error) {
+	var accesses []*model.PreviewAccess
+	err := g.conn(ctx).
+		Where("preview_link_id = ?", linkID.String()).
+		Order("accessed_at DESC").
+		Limit(int(pageSize)).
+		Offset(int(pageNumber * pageSize)).
+		Find(&accesses).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return accesses, nil
+}
+
+func (g *GormStore) CreateApiToken(ctx context.Context, token *model.ApiToken) error {
+	return translateError(g.conn(ctx).Create(token).Error)
+}
+
+func (g *GormStore) GetApiToken(ctx context.Context, id uuid.UUID) (*model.ApiToken, error) {
+	var token model.ApiToken
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&token).Error; err != nil {
+		return nil, recordError(err, "api_token", id.String())
+	}
+
+	return &token, nil
+}
+
+func (g *GormStore) ListApiTokens(ctx context.Context, filter *ApiTokenFilter) ([]*model.ApiToken, error) {
+	var tokens []*model.ApiToken
+	query := g.conn(ctx).Order("created_at DESC")
+	if filter != nil {
+		if filter.SpaceID != nil {
+			query = query.Where("space_id = ?", filter.SpaceID.String())
+		}
+		if filter.CreatedByID != nil {
+			query = query.Where("created_by_id = ?", filter.CreatedByID.String())
+		}
+	}
+	if err := query.Find(&tokens).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return tokens, nil
+}
+
+func (g *GormStore) UpdateApiToken(ctx context.Context, token *model.ApiToken) error {
+	return translateError(g.conn(ctx).Save(token).Error)
+}
+
+func (g *GormStore) TouchApiToken(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	// only the column is written, a concurrent revocation is not overwritten
+	err := g.conn(ctx).Model(&model.ApiToken{}).Where("id = ?", id.String()).Update("last_used_at", usedAt).Error
+	return translateError(err)
+}
+
+func (g *GormStore) RevokeApiTokens(ctx context.Context, createdByID uuid.UUID, revokedAt time.Time) error {
+	err := g.conn(ctx).Model(&model.ApiToken{}).
+		Where("created_by_id = ? AND revoked_at IS NULL", createdByID.String()).
+		Update("revoked_at", revokedAt).Error
+	return translateError(err)
+}
+
+func (g *GormStore) CreateUser(ctx context.Context, user *model.User) error {
+	return translateError(g.conn(ctx).Create(user).Error)
+}
+
+func (g *GormStore) GetUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	var user model.User
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&user).Error; err != nil {
+		return nil, recordError(err, "user", id.String())
+	}
+
+	return &user, nil
+}
+
+func (g *GormStore) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	var user model.User
+	if err := g.conn(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, recordError(err, "user", email)
+	}
+
+	return &user, nil
+}
+
+func (g *GormStore) UpdateUser(ctx context.Context, user *model.User) error {
+	return translateError(g.conn(ctx).Save(user).Error)
+}
+
+func (g *GormStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	err := g.conn(ctx).Unscoped().Where("id = ?", id.String()).Delete(&model.User{}).Error
+	return translateError(err)
+}
+
+func (g *GormStore) ListUsers(ctx context.Context, filter *UserFilter, pageNumber, pageSize uint64) ([]*model.User, error) {
+	var users []*model.User
+	query := userQuery(g.conn(ctx), filter).Order("created_at, id")
+	if err := query.Limit(int(pageSize)).Offset(int(pageNumber * pageSize)).Find(&users).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return users, nil
+}
+
+func (g *GormStore) CountUsers(ctx context.Context, filter *UserFilter) (int64, error) {
+	var count int64
+	if err := userQuery(g.conn(ctx), filter).Model(&model.User{}).Count(&count).Error; err != nil {
+		return 0, translateError(err)
+	}
+
+	return count, nil
+}
+
+// ownersLock names the advisory lock of the owner changes
+const ownersLock = "unpost.owners"
+
+// LockOwners holds a session advisory lock in postgres, the owner changes call the auth provider which writes
+// through its own connections, so the lock cannot be bound to a transaction. Sqlite serves a single process,
+// the callers serialize the owner changes in memory.
+func (g *GormStore) LockOwners(ctx context.Context, fn func(ctx context.Context) error) error {
+	if g.db.Dialector.Name() != "postgres" {
+		return fn(ctx)
+	}
+
+	return g.conn(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(hashtext(?))", ownersLock).Error; err != nil {
+			return translateError(err)
+		}
+		// the lock is released on the same connection even when the call is cancelled
+		defer conn.WithContext(context.WithoutCancel(ctx)).Exec("SELECT pg_advisory_unlock(hashtext(?))", ownersLock)
+
+		return fn(ctx)
+	})
+}
+
+func userQuery(query *gorm.DB, filter *UserFilter) *gorm.DB {
+	if filter == nil || !filter.IncludeDeactivated {
+		query = query.Where("deactivated_at IS NULL")
+	}
+	if filter != nil && len(filter.Roles) > 0 {
+		query = query.Where("role IN ?", filter.Roles)
+	}
+
+	return query
+}
+
+func (g *GormStore) CreateAuthSession(ctx context.Context, session *model.AuthSession) error {
+	return translateError(g.conn(ctx).Create(session).Error)
+}
+
+func (g *GormStore) GetAuthSession(ctx context.Context, id uuid.UUID) (*model.AuthSession, error) {
+	var session model.AuthSession
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&session).Error; err != nil {
+		return nil, recordError(err, "auth_session", id.String())
+	}
+
+	return &session, nil
+}
+
+func (g *GormStore) UpdateAuthSession(ctx context.Context, session *model.AuthSession) error {
+	return translateError(g.conn(ctx).Save(session).Error)
+}
+
+func (g *GormStore) RotateAuthSession(ctx context.Context, id uuid.UUID, previousHash, nextHash string, expiresAt time.Time) error {
+	// the previous hash in the condition lets a single one of the concurrent refreshes win
+	res := g.conn(ctx).Model(&model.AuthSession{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", id.String(), previousHash).
+		Updates(map[string]any{"refresh_token_hash": nextHash, "expires_at": expiresAt})
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return &NotFoundError{Resource: "auth_session", ID: id.String()}
+	}
+
+	return nil
+}
+
+func (g *GormStore) RevokeAuthSessions(ctx context.Context, userID uuid.UUID, revokedAt time.Time) error {
+	err := g.conn(ctx).Model(&model.AuthSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID.String()).
+		Update("revoked_at", revokedAt).Error
+	return translateError(err)
+}
+
+func (g *GormStore) CreateVerificationToken(ctx context.Context, token *model.VerificationToken) error {
+	return translateError(g.conn(ctx).Create(token).Error)
+}
+
+func (g *GormStore) GetVerificationToken(ctx context.Context, id uuid.UUID) (*model.VerificationToken, error) {
+	var token model.VerificationToken
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&token).Error; err != nil {
+		return nil, recordError(err, "verification_token", id.String())
+	}
+
+	return &token, nil
+}
+
+func (g *GormStore) UpdateVerificationToken(ctx context.Context, token *model.VerificationToken) error {
+	return translateError(g.conn(ctx).Save(token).Error)
+}
+
+func (g *GormStore) CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	return translateError(g.conn(ctx).Create(identity).Error)
+}
+
+func (g *GormStore) GetUserIdentity(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	if err := g.conn(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, recordError(err, "user_identity", provider+"/"+subject)
+	}
+
+	return &identity, nil
+}
+
+func (g *GormStore) CreateOIDCLogin(ctx context.Context, login *model.OIDCLogin) error {
+	return translateError(g.conn(ctx).Create(login).Error)
+}
+
+func (g *GormStore) GetOIDCLogin(ctx context.Context, id uuid.UUID) (*model.OIDCLogin, error) {
+	var login model.OIDCLogin
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&login).Error; err != nil {
+		return nil, recordError(err, "oidc_login", id.String())
+	}
+
+	return &login, nil
+}
+
+func (g *GormStore) UpdateOIDCLogin(ctx context.Context, login *model.OIDCLogin) error {
+	return translateError(g.conn(ctx).Save(login).Error)
 }
 
 func (g *GormStore) CreateCourse(ctx context.Context, course *model.Course) error {
-	return g.db.Create(course).Error
+	return translateError(g.conn(ctx).Create(course).Error)
 }
 
 func (g *GormStore) GetCourse(ctx context.Context, id uuid.UUID) (*model.Course, error) {
 	var course model.Course
-	if err := g.db.Where("id = ?", id.String()).First(&course).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&course).Error; err != nil {
+		return nil, recordError(err, "course", id.String())
 	}
 
 	return &course, nil
@@ -140,54 +677,54 @@ func (g *GormStore) GetCourse(ctx context.Context, id uuid.UUID) (*model.Course,
 
 func (g *GormStore) ListCourses(ctx context.Context, spaceID uuid.UUID) ([]*model.Course, error) {
 	var courses []*model.Course
-	if err := g.db.Where("space_id = ?", spaceID.String()).Find(&courses).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("space_id = ?", spaceID.String()).Find(&courses).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return courses, nil
 }
 
 func (g *GormStore) UpdateCourse(ctx context.Context, course *model.Course) error {
-	return g.db.Save(course).Error
+	return translateError(g.conn(ctx).Save(course).Error)
 }
 
 func (g *GormStore) DeleteCourse(ctx context.Context, id uuid.UUID) error {
 	course := &model.Course{
 		ID: id.String(),
 	}
-	return g.db.Delete(course).Error
+	return translateError(g.conn(ctx).Delete(course).Error)
 }
 
 func (g *GormStore) UpdateCourseTags(ctx context.Context, courseID uuid.UUID, tags []*model.Tag) error {
-	return g.db.Model(&model.Course{ID: courseID.String()}).Association("Tags").Replace(tags)
+	return translateError(g.conn(ctx).Model(&model.Course{ID: courseID.String()}).Association("Tags").Replace(tags))
 }
 
 func (g *GormStore) CreatePage(ctx context.Context, page *model.Page) error {
-	return g.db.Create(page).Error
+	return translateError(g.conn(ctx).Create(page).Error)
 }
 
 func (g *GormStore) GetPage(ctx context.Context, id uuid.UUID) (*model.Page, error) {
 	var page model.Page
-	if err := g.db.Where("id = ?", id.String()).First(&page).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&page).Error; err != nil {
+		return nil, recordError(err, "page", id.String())
 	}
 
 	return &page, nil
 }
 
 func (g *GormStore) UpdatePage(ctx context.Context, page *model.Page) error {
-	return g.db.Save(page).Error
+	return translateError(g.conn(ctx).Save(page).Error)
 }
 
 func (g *GormStore) DeletePage(ctx context.Context, id uuid.UUID) error {
 	page := &model.Page{
 		ID: id.String(),
 	}
-	return g.db.Delete(page).Error
+	return translateError(g.conn(ctx).Delete(page).Error)
 }
 
 func (g *GormStore) UpdatePageTags(ctx context.Context, pageID uuid.UUID, tags []*model.Tag) error {
-	return g.db.Model(&model.Page{ID: pageID.String()}).Association("Tags").Replace(tags)
+	return translateError(g.conn(ctx).Model(&model.Page{ID: pageID.String()}).Association("Tags").Replace(tags))
 }
 
 // -----------------------
@@ -195,7 +732,7 @@ func (g *GormStore) UpdatePageTags(ctx context.Context, pageID uuid.UUID, tags [
 // -----------------------
 
 func (g *GormStore) UpdatePostReaction(ctx context.Context, userID, postID uuid.UUID, reaction *model.Reaction) error {
-	return g.db.Create(reaction).Error
+	return translateError(g.conn(ctx).Create(reaction).Error)
 }
 
 func (g *GormStore) AddMember(ctx context.Context, spaceID, userID uuid.UUID, permission uint64) error {
@@ -204,13 +741,13 @@ func (g *GormStore) AddMember(ctx context.Context, spaceID, userID uuid.UUID, pe
 		UserID: userID.String(),
 	}
 
-	return g.db.Create(member).Error
+	return translateError(g.conn(ctx).Create(member).Error)
 }
 
 func (g *GormStore) GetMember(ctx context.Context, spaceID, userID uuid.UUID) (*model.TierMember, error) {
 	var member model.TierMember
-	if err := g.db.Where("space_id = ? AND user_id = ?", spaceID.String(), userID.String()).First(&member).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("space_id = ? AND user_id = ?", spaceID.String(), userID.String()).First(&member).Error; err != nil {
+		return nil, recordError(err, "member", userID.String())
 	}
 
 	return &member, nil
@@ -219,15 +756,15 @@ func (g *GormStore) GetMember(ctx context.Context, spaceID, userID uuid.UUID) (*
 
 func (g *GormStore) ListMembers(ctx context.Context, spaceID uuid.UUID) ([]*uuid.UUID, error) {
 	var members []*model.TierMember
-	if err := g.db.Where("space_id = ?", spaceID.String()).Find(&members).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("space_id = ?", spaceID.String()).Find(&members).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	var memberIDs []*uuid.UUID
 	for _, member := range members {
 		id, err := uuid.Parse(member.UserID)
 		if err != nil {
-			return nil, err
+			return nil, translateError(err)
 		}
 		memberIDs = append(memberIDs, &id)
 	}
@@ -237,7 +774,7 @@ func (g *GormStore) ListMembers(ctx context.Context, spaceID uuid.UUID) ([]*uuid
 }
 
 func (g *GormStore) UpdateMember(ctx context.Context, member *model.TierMember) error {
-	return g.db.Save(member).Error
+	return translateError(g.conn(ctx).Save(member).Error)
 }
 
 func (g *GormStore) RemoveMember(ctx context.Context, spaceID, userID uuid.UUID) error {
@@ -245,7 +782,7 @@ func (g *GormStore) RemoveMember(ctx context.Context, spaceID, userID uuid.UUID)
 		TierID: spaceID.String(),
 		UserID: userID.String(),
 	}
-	return g.db.Delete(member).Error
+	return translateError(g.conn(ctx).Delete(member).Error)
 }
 
 // -----------------------
@@ -253,48 +790,161 @@ func (g *GormStore) RemoveMember(ctx context.Context, spaceID, userID uuid.UUID)
 // -----------------------
 
 func (g *GormStore) CreateTag(ctx context.Context, tag *model.Tag) error {
-	return g.db.Create(tag).Error
+	return translateError(g.conn(ctx).Create(tag).Error)
 }
 
 func (g *GormStore) GetTag(ctx context.Context, id uuid.UUID) (*model.Tag, error) {
 	var tag model.Tag
-	if err := g.db.Where("id = ?", id.String()).First(&tag).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&tag).Error; err != nil {
+		return nil, recordError(err, "tag", id.String())
 	}
 
 	return &tag, nil
 }
 
-func (g *GormStore) ListTags(ctx context.Context, spaceID uuid.UUID, pageNumber, pageSize uint64) ([]*model.Tag, error) {
+func (g *GormStore) ListChildTags(ctx context.Context, parentIDs []string) ([]*model.Tag, error) {
 	var tags []*model.Tag
-	if err := g.db.Where("space_id = ?", spaceID.String()).Limit(int(pageSize)).Offset(int(pageNumber * pageSize)).Find(&tags).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("parent_id IN ?", parentIDs).Find(&tags).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return tags, nil
 }
 
+func (g *GormStore) ListTags(ctx context.Context, spaceID uuid.UUID, filter *TagFilter, pageNumber, pageSize uint64) ([]*model.Tag, error) {
+	var tags []*model.Tag
+	query := g.conn(ctx).Where("space_id = ?", spaceID.String()).Order("name")
+	if filter != nil && filter.Prefix != "" {
+		query = query.Where(`LOWER(name) LIKE ? ESCAPE '\'`, likeEscaper.Replace(strings.ToLower(filter.Prefix))+"%")
+	}
+	if err := query.Limit(int(pageSize)).Offset(int(pageNumber * pageSize)).Find(&tags).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return tags, nil
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (g *GormStore) CountTagPosts(ctx context.Context, tagIDs []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(tagIDs))
+	if len(tagIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		TagID string
+		Count int64
+	}
+	err := g.conn(ctx).Table("post_tags").
+		Select("post_tags.tag_id, COUNT(*) AS count").
+		Joins("JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL").
+		Where("post_tags.tag_id IN ?", tagIDs).
+		Group("post_tags.tag_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
+	}
+
+	return counts, nil
+}
+
 func (g *GormStore) UpdateTag(ctx context.Context, tag *model.Tag) error {
-	return g.db.Save(tag).Error
+	if err := g.conn(ctx).Save(tag).Error; err != nil {
+		return translateError(err)
+	}
+
+	// the cached posts carry the tag names
+	return g.invalidateTagPosts(ctx, tag.ID)
 }
 
 func (g *GormStore) DeleteTag(ctx context.Context, id uuid.UUID) error {
-	return g.db.Delete(&model.Tag{ID: id.String()}).Error
+	tag, err := g.GetTag(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = g.conn(ctx).Model(&model.Tag{}).Where("parent_id = ?", tag.ID).Update("parent_id", tag.ParentID).Error
+	if err != nil {
+		return translateError(err)
+	}
+	if err := g.invalidateTagPosts(ctx, tag.ID); err != nil {
+		return err
+	}
+
+	return translateError(g.conn(ctx).Delete(&model.Tag{ID: id.String()}).Error)
+}
+
+func (g *GormStore) MergeTags(ctx context.Context, targetID uuid.UUID, sourceIDs []uuid.UUID) error {
+	sources := make([]string, 0, len(sourceIDs))
+	for _, id := range sourceIDs {
+		sources = append(sources, id.String())
+	}
+	target := targetID.String()
+
+	// the posts and the courses tagged with both a source and the target keep a single association
+	for _, table := range []struct{ name, owner string }{{"post_tags", "post_id"}, {"course_tags", "course_id"}} {
+		err := g.conn(ctx).Exec(
+			fmt.Sprintf("INSERT INTO %[1]s (%[2]s, tag_id) SELECT DISTINCT %[2]s, ? FROM %[1]s WHERE tag_id IN ? AND %[2]s NOT IN (SELECT %[2]s FROM %[1]s WHERE tag_id = ?)", table.name, table.owner),
+			target, sources, target,
+		).Error
+		if err != nil {
+			return translateError(err)
+		}
+	}
+
+	// the keys are collected before the source associations are deleted
+	if err := g.invalidateTagPosts(ctx, append([]string{target}, sources...)...); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"post_tags", "course_tags"} {
+		if err := g.conn(ctx).Exec("DELETE FROM "+table+" WHERE tag_id IN ?", sources).Error; err != nil {
+			return translateError(err)
+		}
+	}
+
+	err := g.conn(ctx).Model(&model.Tag{}).Where("parent_id IN ? AND id <> ?", sources, target).Update("parent_id", target).Error
+	if err != nil {
+		return translateError(err)
+	}
+
+	return translateError(g.conn(ctx).Where("id IN ?", sources).Delete(&model.Tag{}).Error)
+}
+
+// invalidateTagPosts publishes the cache keys of the posts tagged with the tags
+func (g *GormStore) invalidateTagPosts(ctx context.Context, tagIDs ...string) error {
+	if g.bus == nil {
+		return nil
+	}
+
+	var postIDs []string
+	if err := g.conn(ctx).Table("post_tags").Distinct("post_id").Where("tag_id IN ?", tagIDs).Pluck("post_id", &postIDs).Error; err != nil {
+		return translateError(err)
+	}
+	for _, postID := range postIDs {
+		g.invalidatePost(ctx, uuid.MustParse(postID))
+	}
+
+	return nil
 }
 
 func (g *GormStore) UpdateTierMember(ctx context.Context, member *model.TierMember) error {
-	//TODO implement me
-	panic("implement me")
+	return translateError(g.conn(ctx).Save(member).Error)
 }
 
 func (g *GormStore) CreateTier(ctx context.Context, space *model.Tier) error {
-	return g.db.Create(space).Error
+	return translateError(g.conn(ctx).Create(space).Error)
 }
 
 func (g *GormStore) GetTier(ctx context.Context, id uuid.UUID) (*model.Tier, error) {
 	var space model.Tier
-	if err := g.db.Where("id = ?", id.String()).First(&space).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&space).Error; err != nil {
+		return nil, recordError(err, "tier", id.String())
 	}
 
 	return &space, nil
@@ -302,41 +952,41 @@ func (g *GormStore) GetTier(ctx context.Context, id uuid.UUID) (*model.Tier, err
 
 func (g *GormStore) ListTiers(ctx context.Context, userID uuid.UUID) ([]*model.Tier, error) {
 	var spaces []*model.Tier
-	if err := g.db.Where("created_by_id = ?", userID.String()).Find(&spaces).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("created_by_id = ?", userID.String()).Find(&spaces).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return spaces, nil
 }
 
 func (g *GormStore) UpdateTier(ctx context.Context, space *model.Tier) error {
-	return g.db.Save(space).Error
+	return translateError(g.conn(ctx).Save(space).Error)
 }
 
 func (g *GormStore) DeleteTier(ctx context.Context, id uuid.UUID) error {
 	post := &model.Tier{
 		ID: id.String(),
 	}
-	return g.db.Delete(post).Error
+	return translateError(g.conn(ctx).Delete(post).Error)
 }
 
 func (g *GormStore) GetDefaultTier(ctx context.Context, userID uuid.UUID) (*model.Tier, error) {
 	var space model.Tier
-	if err := g.db.Where("created_by_id = ? AND user_default = true", userID.String()).First(&space).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("created_by_id = ? AND user_default = true", userID.String()).First(&space).Error; err != nil {
+		return nil, recordError(err, "default_tier", userID.String())
 	}
 
 	return &space, nil
 }
 
 func (g *GormStore) AddTierMember(ctx context.Context, member *model.TierMember) error {
-	return g.db.Create(member).Error
+	return translateError(g.conn(ctx).Create(member).Error)
 }
 
 func (g *GormStore) GetTierMember(ctx context.Context, subMemberID uuid.UUID) (*model.TierMember, error) {
 	var member model.TierMember
-	if err := g.db.Where("id = ?", subMemberID.String()).Preload("Tier").First(&member).Error; err != nil {
-		return nil, err
+	if err := g.conn(ctx).Where("id = ?", subMemberID.String()).Preload("Tier").First(&member).Error; err != nil {
+		return nil, recordError(err, "tier_member", subMemberID.String())
 	}
 
 	return &member, nil
@@ -353,12 +1003,257 @@ func (g *GormStore) RemoveTierMember(ctx context.Context, subMemberID uuid.UUID)
 	panic("implement me")
 }
 
+// -----------------------
+// SpaceStore
+// -----------------------
+
+// scanInBatches reads the rows of the query in primary key order and calls fn with each batch
+func scanInBatches[T any](query *gorm.DB, batchSize int, fn func([]*T) error) error {
+	var batch []*T
+	err := query.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+
+	return translateError(err)
+}
+
+func (g *GormStore) ScanSpaceTags(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(tags []*model.Tag) error) error {
+	return scanInBatches(g.conn(ctx).Where("space_id = ?", spaceID.String()), batchSize, fn)
+}
+
+func (g *GormStore) ScanSpaceTiers(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(tiers []*model.Tier) error) error {
+	return scanInBatches(g.conn(ctx).Where("space_id = ?", spaceID.String()), batchSize, fn)
+}
+
+func (g *GormStore) ScanSpaceTierMembers(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(members []*model.TierMember) error) error {
+	tiers := g.conn(ctx).Model(&model.Tier{}).Select("id").Where("space_id = ?", spaceID.String())
+	return scanInBatches(g.conn(ctx).Where("tier_id IN (?)", tiers), batchSize, fn)
+}
+
+func (g *GormStore) ScanSpaceCourses(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(courses []*model.Course) error) error {
+	return scanInBatches(g.conn(ctx).Preload("Tags").Where("space_id = ?", spaceID.String()), batchSize, fn)
+}
+
+func (g *GormStore) ScanSpacePages(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(pages []*model.Page) error) error {
+	return scanInBatches(g.conn(ctx).Where("space_id = ?", spaceID.String()), batchSize, fn)
+}
+
+func (g *GormStore) ScanSpacePosts(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(posts []*model.Post) error) error {
+	return scanInBatches(g.conn(ctx).Preload("Tags").Where("space_id = ?", spaceID.String()), batchSize, fn)
+}
+
+func (g *GormStore) GetTagByName(ctx context.Context, spaceID uuid.UUID, name string) (*model.Tag, error) {
+	var tag model.Tag
+	if err := g.conn(ctx).Where("space_id = ? AND name = ?", spaceID.String(), name).First(&tag).Error; err != nil {
+		return nil, recordError(err, "tag", name)
+	}
+
+	return &tag, nil
+}
+
+func (g *GormStore) GetTierByName(ctx context.Context, name string) (*model.Tier, error) {
+	var tier model.Tier
+	if err := g.conn(ctx).Where("name = ?", name).First(&tier).Error; err != nil {
+		return nil, recordError(err, "tier", name)
+	}
+
+	return &tier, nil
+}
+
+// -----------------------
+// OutboxStore
+// -----------------------
+
+func (g *GormStore) CreateOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	return translateError(g.conn(ctx).Create(event).Error)
+}
+
+func (g *GormStore) ListUndispatchedEvents(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+	if err := g.locked(ctx).Where("dispatched_at IS NULL").Order("seq").Limit(limit).Find(&events).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return events, nil
+}
+
+func (g *GormStore) MarkEventsDispatched(ctx context.Context, ids []string, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return translateError(g.conn(ctx).Model(&model.OutboxEvent{}).Where("id IN ?", ids).Update("dispatched_at", at).Error)
+}
+
+func (g *GormStore) ListEventsAfter(ctx context.Context, afterSeq uint64, limit int) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+	if err := g.conn(ctx).Where("seq > ?", afterSeq).Order("seq").Limit(limit).Find(&events).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return events, nil
+}
+
+func (g *GormStore) LatestEventSeq(ctx context.Context) (uint64, error) {
+	var seq uint64
+	if err := g.conn(ctx).Model(&model.OutboxEvent{}).Select("COALESCE(MAX(seq), 0)").Scan(&seq).Error; err != nil {
+		return 0, translateError(err)
+	}
+
+	return seq, nil
+}
+
+// -----------------------
+// WebhookStore
+// -----------------------
+
+func (g *GormStore) CreateWebhook(ctx context.Context, webhook *model.Webhook) error {
+	return translateError(g.conn(ctx).Create(webhook).Error)
+}
+
+func (g *GormStore) GetWebhook(ctx context.Context, id uuid.UUID) (*model.Webhook, error) {
+	var webhook model.Webhook
+	if err := g.conn(ctx).Where("id = ?", id.String()).First(&webhook).Error; err != nil {
+		return nil, recordError(err, "webhook", id.String())
+	}
+
+	return &webhook, nil
+}
+
+func (g *GormStore) ListWebhooks(ctx context.Context, pageNumber, pageSize uint64) ([]*model.Webhook, error) {
+	var webhooks []*model.Webhook
+	if err := g.conn(ctx).Order("created_at").Limit(int(pageSize)).Offset(int(pageNumber * pageSize)).Find(&webhooks).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return webhooks, nil
+}
+
+func (g *GormStore) ListActiveWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	var webhooks []*model.Webhook
+	if err := g.conn(ctx).Where("active = ?", true).Find(&webhooks).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return webhooks, nil
+}
+
+func (g *GormStore) UpdateWebhook(ctx context.Context, webhook *model.Webhook) error {
+	return translateError(g.conn(ctx).Save(webhook).Error)
+}
+
+// DeleteWebhook soft deletes the webhook, the deliveries are kept for the history so the pending ones are cancelled
+func (g *GormStore) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	err := g.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.WebhookDelivery{}).
+			Where("webhook_id = ? AND status = ?", id.String(), model.WebhookDeliveryPending).
+			Update("status", model.WebhookDeliveryCancelled).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&model.Webhook{ID: id.String()}).Error
+	})
+	return translateError(err)
+}
+
+func (g *GormStore) CreateWebhookDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return translateError(g.conn(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(deliveries).Error)
+}
+
+func (g *GormStore) GetWebhookDelivery(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := g.conn(ctx).Where("id = ?", id.String()).Preload("Event").First(&delivery).Error; err != nil {
+		return nil, recordError(err, "webhook_delivery", id.String())
+	}
+
+	return &delivery, nil
+}
+
+func (g *GormStore) ListDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	err := g.locked(ctx).
+		Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Preload("Event").
+		Preload("Webhook").
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return deliveries, nil
+}
+
+func (g *GormStore) ListWebhookDeliveries(ctx context.Context, filter *WebhookDeliveryFilter, pageNumber, pageSize uint64) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	db := g.conn(ctx).Preload("Event")
+	if filter != nil && filter.WebhookID != nil {
+		db = db.Where("webhook_id = ?", filter.WebhookID.String())
+	}
+	if filter != nil && filter.Status != nil {
+		db = db.Where("status = ?", *filter.Status)
+	}
+
+	if err := db.Order("created_at DESC").Limit(int(pageSize)).Offset(int(pageNumber * pageSize)).Find(&deliveries).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return deliveries, nil
+}
+
+func (g *GormStore) UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return translateError(g.conn(ctx).Omit(clause.Associations).Save(delivery).Error)
+}
+
+// locked locks the selected rows on postgres and skips the rows locked by other replicas,
+// sqlite serializes the writers so it needs no row locks
+func (g *GormStore) locked(ctx context.Context) *gorm.DB {
+	if g.db.Dialector.Name() == "postgres" {
+		return g.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+	}
+
+	return g.conn(ctx)
+}
+
+// conn binds the connection to the context of the call, the context cancels the query and carries the trace
+func (g *GormStore) conn(ctx context.Context) *gorm.DB {
+	return g.db.WithContext(ctx)
+}
+
 func (g *GormStore) Transaction(ctx context.Context, f func(ctx context.Context, store UnstakStore) error) error {
-	return g.db.Transaction(func(tx *gorm.DB) error {
-		return f(ctx, NewGormStore(tx))
+	// nested transactions share the pending keys of the outermost one
+	pending := g.pending
+	if pending == nil {
+		pending = new([]string)
+	}
+
+	err := g.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		return f(ctx, &GormStore{db: tx, bus: g.bus, pending: pending})
 	})
+	if err != nil {
+		return translateError(err)
+	}
+
+	if g.pending == nil && len(*pending) > 0 {
+		g.invalidate(ctx, *pending...)
+	}
+
+	return nil
 }
 
+// Migrate applies the pending versioned migrations
 func (g *GormStore) Migrate() error {
-	return model.Migrate(g.db)
+	migrator, err := migrate.New(g.db)
+	if err != nil {
+		return err
+	}
+
+	_, err = migrator.Up(context.Background(), 0)
+	return err
 }
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"github.com/google/uuid"
+	"time"
 
 	"github.com/emrgen/unpost/internal/model"
 )
@@ -15,6 +16,18 @@ type UnstakStore interface {
 	PageStore
 	TagStore
 	PlatformTagStore
+	OutboxStore
+	WebhookStore
+	SpaceStore
+	PostRelationStore
+	ReviewRequestStore
+	PreviewLinkStore
+	ApiTokenStore
+	UserStore
+	AuthSessionStore
+	VerificationTokenStore
+	UserIdentityStore
+	OIDCLoginStore
 	Transaction(ctx context.Context, f func(ctx context.Context, store UnstakStore) error) error
 	Migrate() error
 }
@@ -47,11 +60,16 @@ type TierMemberStore interface {
 	RemoveTierMember(ctx context.Context, subMemberID uuid.UUID) error
 }
 
+// PostFiler narrows the listed posts, the nil and empty fields match every post
 type PostFiler struct {
+	SpaceID *uuid.UUID
 	TierID  *uuid.UUID
+	// OwnerID matches the posts of an author
 	OwnerID *uuid.UUID
 	UserID  *uuid.UUID
 	Status  *model.PostStatus
+	// TagIDs matches the posts tagged with any of the tags
+	TagIDs []string
 }
 
 type PostStore interface {
@@ -61,8 +79,12 @@ type PostStore interface {
 	GetPost(ctx context.Context, id uuid.UUID) (*model.Post, error)
 	// GetPostBySlugID retries the post by slug id.
 	GetPostBySlugID(ctx context.Context, id string) (*model.Post, error)
-	// ListPosts retrieves a list of tinyposts by space ID.
-	ListPosts(ctx context.Context, filer *PostFiler) ([]*model.Post, error)
+	// GetPostBySlug retrieves the post of a space by slug.
+	GetPostBySlug(ctx context.Context, spaceID uuid.UUID, slug string) (*model.Post, error)
+	// GetPosts retrieves the posts by ID with their tags, the missing posts are left out.
+	GetPosts(ctx context.Context, ids []uuid.UUID) ([]*model.Post, error)
+	// ListPosts retrieves a page of the posts matching the filter, latest first.
+	ListPosts(ctx context.Context, filer *PostFiler, pageNumber, pageSize uint64) ([]*model.Post, error)
 	// UpdatePost updates a post.
 	UpdatePost(ctx context.Context, doc *model.Post) error
 	// DeletePost deletes a post by ID.
@@ -73,6 +95,144 @@ type PostStore interface {
 	UpdatePostTags(ctx context.Context, postID uuid.UUID, tags []*model.Tag) error
 }
 
+// PostRelationStore keeps the precomputed related posts
+type PostRelationStore interface {
+	// ListRelationSpaceIDs retrieves the spaces that have published posts.
+	ListRelationSpaceIDs(ctx context.Context) ([]string, error)
+	// ListRelationCandidates retrieves the latest published posts of a space and their tags.
+	ListRelationCandidates(ctx context.Context, spaceID uuid.UUID, limit int) ([]*model.Post, error)
+	// ListPostReactors retrieves the users holding a reaction on each post.
+	ListPostReactors(ctx context.Context, postIDs []string) (map[string][]string, error)
+	// ReplacePostRelations replaces the relations of the posts of a space.
+	ReplacePostRelations(ctx context.Context, spaceID uuid.UUID, relations []*model.PostRelation) error
+	// ListRelatedPosts retrieves the published posts related to a post, best first.
+	ListRelatedPosts(ctx context.Context, postID uuid.UUID, limit int) ([]*model.Post, error)
+}
+
+// ReviewRequestFilter selects the review requests, the nil fields match every request
+type ReviewRequestFilter struct {
+	SpaceID *uuid.UUID
+	Status  *model.ReviewRequestStatus
+}
+
+// ReviewRequestStore keeps the submissions of the posts to the editors
+type ReviewRequestStore interface {
+	// CreateReviewRequest creates a new review request.
+	CreateReviewRequest(ctx context.Context, request *model.ReviewRequest) error
+	// GetPendingReviewRequest retrieves the pending review request of a post.
+	GetPendingReviewRequest(ctx context.Context, postID uuid.UUID) (*model.ReviewRequest, error)
+	// UpdateReviewRequest updates a review request.
+	UpdateReviewRequest(ctx context.Context, request *model.ReviewRequest) error
+	// ListReviewRequests retrieves the review requests of the existing posts, oldest first.
+	ListReviewRequests(ctx context.Context, filter *ReviewRequestFilter, pageNumber, pageSize uint64) ([]*model.ReviewRequest, error)
+}
+
+// PreviewLinkStore keeps the preview links of the posts and the audit of their reads
+type PreviewLinkStore interface {
+	// CreatePreviewLink creates a new preview link.
+	CreatePreviewLink(ctx context.Context, link *model.PreviewLink) error
+	// GetPreviewLink retrieves a preview link by ID.
+	GetPreviewLink(ctx context.Context, id uuid.UUID) (*model.PreviewLink, error)
+	// ListPreviewLinks retrieves the preview links of a post, newest first.
+	ListPreviewLinks(ctx context.Context, postID uuid.UUID) ([]*model.PreviewLink, error)
+	// UpdatePreviewLink updates a preview link.
+	UpdatePreviewLink(ctx context.Context, link *model.PreviewLink) error
+	// CreatePreviewAccess records a read through a preview link.
+	CreatePreviewAccess(ctx context.Context, access *model.PreviewAccess) error
+	// ListPreviewAccesses retrieves the reads through a preview link, newest first.
+	ListPreviewAccesses(ctx context.Context, linkID uuid.UUID, pageNumber, pageSize uint64) ([]*model.PreviewAccess, error)
+}
+
+// ApiTokenFilter selects the api tokens, the nil fields match every token
+type ApiTokenFilter struct {
+	SpaceID     *uuid.UUID
+	CreatedByID *uuid.UUID
+}
+
+// ApiTokenStore keeps the api tokens of the spaces
+type ApiTokenStore interface {
+	// CreateApiToken creates a new api token.
+	CreateApiToken(ctx context.Context, token *model.ApiToken) error
+	// GetApiToken retrieves an api token by ID.
+	GetApiToken(ctx context.Context, id uuid.UUID) (*model.ApiToken, error)
+	// ListApiTokens retrieves the api tokens, newest first.
+	ListApiTokens(ctx context.Context, filter *ApiTokenFilter) ([]*model.ApiToken, error)
+	// UpdateApiToken updates an api token.
+	UpdateApiToken(ctx context.Context, token *model.ApiToken) error
+	// TouchApiToken records the last use of an api token.
+	TouchApiToken(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+	// RevokeApiTokens revokes the active api tokens created by a user.
+	RevokeApiTokens(ctx context.Context, createdByID uuid.UUID, revokedAt time.Time) error
+}
+
+// UserFilter selects the users, the deactivated users are left out unless included
+type UserFilter struct {
+	Roles              []model.UserRole
+	IncludeDeactivated bool
+}
+
+// UserStore keeps the accounts
+type UserStore interface {
+	// CreateUser creates a new user.
+	CreateUser(ctx context.Context, user *model.User) error
+	// GetUser retrieves a user by ID.
+	GetUser(ctx context.Context, id uuid.UUID) (*model.User, error)
+	// GetUserByEmail retrieves a user by email.
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	// UpdateUser updates a user.
+	UpdateUser(ctx context.Context, user *model.User) error
+	// DeleteUser deletes a user for good, its email is free again.
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	// ListUsers retrieves the users, oldest first.
+	ListUsers(ctx context.Context, filter *UserFilter, pageNumber, pageSize uint64) ([]*model.User, error)
+	// CountUsers counts the users.
+	CountUsers(ctx context.Context, filter *UserFilter) (int64, error)
+	// LockOwners runs fn while holding the lock on the owner changes, the lock is shared by the replicas.
+	LockOwners(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// AuthSessionStore keeps the sessions of the local auth provider
+type AuthSessionStore interface {
+	// CreateAuthSession creates a new session.
+	CreateAuthSession(ctx context.Context, session *model.AuthSession) error
+	// GetAuthSession retrieves a session by ID.
+	GetAuthSession(ctx context.Context, id uuid.UUID) (*model.AuthSession, error)
+	// UpdateAuthSession updates a session.
+	UpdateAuthSession(ctx context.Context, session *model.AuthSession) error
+	// RotateAuthSession replaces the refresh token hash of an active session still holding the previous hash, it returns ErrNotFound otherwise.
+	RotateAuthSession(ctx context.Context, id uuid.UUID, previousHash, nextHash string, expiresAt time.Time) error
+	// RevokeAuthSessions revokes the active sessions of a user.
+	RevokeAuthSessions(ctx context.Context, userID uuid.UUID, revokedAt time.Time) error
+}
+
+// VerificationTokenStore keeps the one-time tokens of the local auth provider
+type VerificationTokenStore interface {
+	// CreateVerificationToken creates a new verification token.
+	CreateVerificationToken(ctx context.Context, token *model.VerificationToken) error
+	// GetVerificationToken retrieves a verification token by ID.
+	GetVerificationToken(ctx context.Context, id uuid.UUID) (*model.VerificationToken, error)
+	// UpdateVerificationToken updates a verification token.
+	UpdateVerificationToken(ctx context.Context, token *model.VerificationToken) error
+}
+
+// UserIdentityStore keeps the identities of the external OIDC providers linked to the users
+type UserIdentityStore interface {
+	// CreateUserIdentity links an identity to a user.
+	CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error
+	// GetUserIdentity retrieves the identity of a subject of a provider.
+	GetUserIdentity(ctx context.Context, provider, subject string) (*model.UserIdentity, error)
+}
+
+// OIDCLoginStore keeps the pending social logins
+type OIDCLoginStore interface {
+	// CreateOIDCLogin creates a new login.
+	CreateOIDCLogin(ctx context.Context, login *model.OIDCLogin) error
+	// GetOIDCLogin retrieves a login by ID.
+	GetOIDCLogin(ctx context.Context, id uuid.UUID) (*model.OIDCLogin, error)
+	// UpdateOIDCLogin updates a login.
+	UpdateOIDCLogin(ctx context.Context, login *model.OIDCLogin) error
+}
+
 type CourseStore interface {
 	// CreateCourse creates a new course.
 	CreateCourse(ctx context.Context, course *model.Course) error
@@ -101,17 +261,41 @@ type PageStore interface {
 	UpdatePageTags(ctx context.Context, pageID uuid.UUID, tags []*model.Tag) error
 }
 
+// TagFilter narrows the listed tags, the empty fields match every tag
+type TagFilter struct {
+	// Prefix matches the tags whose name starts with it, ignoring the case
+	Prefix string
+}
+
 type TagStore interface {
 	// CreateTag creates a new tag.
 	CreateTag(ctx context.Context, tag *model.Tag) error
 	// GetTag retrieves a tag by ID.
 	GetTag(ctx context.Context, id uuid.UUID) (*model.Tag, error)
-	// ListTags retrieves a list of tags by space ID.
-	ListTags(ctx context.Context, spaceID uuid.UUID, pageNumber, pageSize uint64) ([]*model.Tag, error)
+	// ListChildTags retrieves the direct children of the tags.
+	ListChildTags(ctx context.Context, parentIDs []string) ([]*model.Tag, error)
+	// ListTags retrieves a page of the tags of a space matching the filter, ordered by name.
+	ListTags(ctx context.Context, spaceID uuid.UUID, filter *TagFilter, pageNumber, pageSize uint64) ([]*model.Tag, error)
+	// CountTagPosts counts the posts of each tag, the tags without posts are left out.
+	CountTagPosts(ctx context.Context, tagIDs []string) (map[string]int64, error)
 	// UpdateTag updates a tag.
 	UpdateTag(ctx context.Context, tag *model.Tag) error
-	// DeleteTag deletes a tag by ID.
+	// DeleteTag deletes a tag by ID, its children move up to its parent.
 	DeleteTag(ctx context.Context, id uuid.UUID) error
+	// MergeTags moves the posts, the courses and the children of the source tags to the target tag and deletes the sources.
+	MergeTags(ctx context.Context, targetID uuid.UUID, sourceIDs []uuid.UUID) error
+}
+
+// PlatformTagFilter narrows the listed platform tags, nil fields match every tag
+type PlatformTagFilter struct {
+	Status *model.PlatformTagStatus
+}
+
+// PlatformTagDocument is the text of a post tagged with a platform tag through a space tag
+type PlatformTagDocument struct {
+	PlatformTagID string
+	Title         string
+	Content       string
 }
 
 type PlatformTagStore interface {
@@ -119,10 +303,79 @@ type PlatformTagStore interface {
 	CreatePlatformTag(ctx context.Context, tag *model.PlatformTag) error
 	// GetPlatformTag retrieves a platform tag by ID.
 	GetPlatformTag(ctx context.Context, id uuid.UUID) (*model.PlatformTag, error)
-	// ListPlatformTags retrieves a list of platform tags by space ID.
-	ListPlatformTags(ctx context.Context, pageNumber, pageSize uint64) ([]*model.PlatformTag, error)
+	// ListPlatformTags retrieves a page of the platform tags matching the filter, ordered by name.
+	ListPlatformTags(ctx context.Context, filter *PlatformTagFilter, pageNumber, pageSize uint64) ([]*model.PlatformTag, error)
 	// UpdatePlatformTag updates a platform tag.
 	UpdatePlatformTag(ctx context.Context, tag *model.PlatformTag) error
-	// DeletePlatformTag deletes a platform tag by ID.
+	// DeletePlatformTag deletes a platform tag by ID and removes the mappings of the space tags onto it.
 	DeletePlatformTag(ctx context.Context, id uuid.UUID) error
+	// SetTagPlatformTag maps a space tag onto a platform tag, a nil platform tag removes the mapping.
+	SetTagPlatformTag(ctx context.Context, tagID uuid.UUID, platformTagID *uuid.UUID) error
+	// ListPlatformTagDocuments retrieves the latest posts tagged through a space tag mapped onto an approved platform tag.
+	ListPlatformTagDocuments(ctx context.Context, limit int) ([]*PlatformTagDocument, error)
+}
+
+type OutboxStore interface {
+	// CreateOutboxEvent appends an event to the outbox.
+	CreateOutboxEvent(ctx context.Context, event *model.OutboxEvent) error
+	// ListUndispatchedEvents retrieves the oldest events not yet fanned out to the webhooks.
+	// Within a transaction the events are locked so that a single replica dispatches them.
+	ListUndispatchedEvents(ctx context.Context, limit int) ([]*model.OutboxEvent, error)
+	// MarkEventsDispatched marks the events as fanned out to the webhooks.
+	MarkEventsDispatched(ctx context.Context, ids []string, at time.Time) error
+	// ListEventsAfter retrieves the events with a sequence greater than afterSeq in sequence order.
+	ListEventsAfter(ctx context.Context, afterSeq uint64, limit int) ([]*model.OutboxEvent, error)
+	// LatestEventSeq retrieves the sequence of the latest event, zero when the outbox is empty.
+	LatestEventSeq(ctx context.Context) (uint64, error)
+}
+
+type WebhookDeliveryFilter struct {
+	WebhookID *uuid.UUID
+	Status    *model.WebhookDeliveryStatus
+}
+
+type WebhookStore interface {
+	// CreateWebhook creates a new webhook.
+	CreateWebhook(ctx context.Context, webhook *model.Webhook) error
+	// GetWebhook retrieves a webhook by ID.
+	GetWebhook(ctx context.Context, id uuid.UUID) (*model.Webhook, error)
+	// ListWebhooks retrieves a list of webhooks.
+	ListWebhooks(ctx context.Context, pageNumber, pageSize uint64) ([]*model.Webhook, error)
+	// ListActiveWebhooks retrieves all the active webhooks.
+	ListActiveWebhooks(ctx context.Context) ([]*model.Webhook, error)
+	// UpdateWebhook updates a webhook.
+	UpdateWebhook(ctx context.Context, webhook *model.Webhook) error
+	// DeleteWebhook deletes a webhook by ID and cancels its pending deliveries.
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	// CreateWebhookDeliveries creates the deliveries, the existing ones are skipped.
+	CreateWebhookDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error
+	// GetWebhookDelivery retrieves a delivery by ID.
+	GetWebhookDelivery(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error)
+	// ListDueWebhookDeliveries retrieves the pending deliveries due at the given time with their event and webhook.
+	// Within a transaction the deliveries are locked so that a single replica sends them.
+	ListDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]*model.WebhookDelivery, error)
+	// ListWebhookDeliveries retrieves a list of deliveries, the failed ones form the dead-letter queue.
+	ListWebhookDeliveries(ctx context.Context, filter *WebhookDeliveryFilter, pageNumber, pageSize uint64) ([]*model.WebhookDelivery, error)
+	// UpdateWebhookDelivery updates a delivery.
+	UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+}
+
+// SpaceStore reads the whole content of a space in batches, it backs the export of a space.
+type SpaceStore interface {
+	// ScanSpaceTags calls fn with the tags of the space in batches.
+	ScanSpaceTags(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(tags []*model.Tag) error) error
+	// ScanSpaceTiers calls fn with the tiers of the space in batches.
+	ScanSpaceTiers(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(tiers []*model.Tier) error) error
+	// ScanSpaceTierMembers calls fn with the members of the tiers of the space in batches.
+	ScanSpaceTierMembers(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(members []*model.TierMember) error) error
+	// ScanSpaceCourses calls fn with the courses of the space and their tags in batches.
+	ScanSpaceCourses(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(courses []*model.Course) error) error
+	// ScanSpacePages calls fn with the pages of the space in batches.
+	ScanSpacePages(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(pages []*model.Page) error) error
+	// ScanSpacePosts calls fn with the posts of the space and their tags in batches.
+	ScanSpacePosts(ctx context.Context, spaceID uuid.UUID, batchSize int, fn func(posts []*model.Post) error) error
+	// GetTagByName retrieves a tag of the space by name.
+	GetTagByName(ctx context.Context, spaceID uuid.UUID, name string) (*model.Tag, error)
+	// GetTierByName retrieves a tier by name, the tier names are unique across the spaces.
+	GetTierByName(ctx context.Context, name string) (*model.Tier, error)
 }
@@ -0,0 +1,28 @@
+// Package storetest creates the stores of the tests.
+package storetest
+
+import (
+	"testing"
+
+	"github.com/emrgen/unpost/internal/store"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// NewStore returns a migrated store on a private in-memory sqlite database, the errors are translated like in the server
+func NewStore(t testing.TB) *store.GormStore {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+uuid.NewString()+"?mode=memory&cache=shared"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := store.NewGormStore(db, nil)
+	if err := s.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
@@ -0,0 +1,206 @@
+package suggest
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// titleWeight is how much more a title term counts than a content term
+const titleWeight = 3
+
+var (
+	markupPattern = regexp.MustCompile(`<[^>]*>|&[a-z]+;|!?\[|\]\([^)]*\)`)
+	termPattern   = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+	stopWords = map[string]bool{}
+)
+
+func init() {
+	for _, word := range strings.Fields(`a about above after again all also am an and any are as at be because been
+		before being below between both but by can could did do does doing down during each few for from further
+		had has have having he her here hers him his how i if in into is it its itself just me more most my no nor
+		not of off on once only or other our ours out over own same she should so some such than that the their
+		theirs them then there these they this those through to too under until up very was we were what when
+		where which while who whom why will with would you your yours`) {
+		stopWords[word] = true
+	}
+}
+
+// Terms counts the terms of a text, the markup and the stop words are left out
+func Terms(title, content string) map[string]float64 {
+	terms := make(map[string]float64)
+	add := func(text string, weight float64) {
+		text = markupPattern.ReplaceAllString(strings.ToLower(text), " ")
+		for _, term := range termPattern.FindAllString(text, -1) {
+			if len([]rune(term)) < 2 || stopWords[term] {
+				continue
+			}
+			terms[term] += weight
+		}
+	}
+	add(title, titleWeight)
+	add(content, 1)
+
+	return terms
+}
+
+// Suggestion is a profile of the corpus recommended for a text
+type Suggestion struct {
+	// ID is the tag or the document of the profile
+	ID    string
+	Score float64
+}
+
+// Corpus holds the term profiles of the tags, learned from the texts tagged with them,
+// or of the documents themselves. The profiles are compared to a text by the cosine similarity of their tf-idf vectors.
+type Corpus struct {
+	profiles map[string]map[string]float64
+
+	// idf and vectors are computed on the first comparison, Add resets them.
+	// The comparisons are safe for a concurrent use, the additions are not.
+	mu      sync.Mutex
+	idf     map[string]float64
+	vectors map[string]map[string]float64
+}
+
+// NewCorpus creates an empty corpus
+func NewCorpus() *Corpus {
+	return &Corpus{profiles: make(map[string]map[string]float64)}
+}
+
+// AddTag registers a tag, its name is part of its profile, so a tag without tagged texts can be suggested
+func (c *Corpus) AddTag(tagID, name string) {
+	c.Add(tagID, Terms(name, ""))
+}
+
+// Add adds the terms of a text to the profile of a tag or a document
+func (c *Corpus) Add(id string, terms map[string]float64) {
+	profile, ok := c.profiles[id]
+	if !ok {
+		profile = make(map[string]float64)
+		c.profiles[id] = profile
+	}
+	for term, count := range terms {
+		profile[term] += count
+	}
+	c.idf, c.vectors = nil, nil
+}
+
+// Len returns the number of the profiles in the corpus
+func (c *Corpus) Len() int {
+	return len(c.profiles)
+}
+
+// Suggest returns the profiles most similar to the terms, best first, at most limit of them
+func (c *Corpus) Suggest(terms map[string]float64, limit int) []Suggestion {
+	if len(terms) == 0 || len(c.profiles) == 0 {
+		return nil
+	}
+	c.prepare()
+
+	return c.rank(weigh(terms, c.weight), "", limit)
+}
+
+// Similar returns the profiles most similar to the profile of id, best first, at most limit of them
+func (c *Corpus) Similar(id string, limit int) []Suggestion {
+	if _, ok := c.profiles[id]; !ok {
+		return nil
+	}
+	c.prepare()
+
+	return c.rank(c.vectors[id], id, limit)
+}
+
+// prepare computes the inverse document frequencies and the tf-idf vectors of the profiles
+func (c *Corpus) prepare() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.vectors != nil {
+		return
+	}
+
+	// a term found in every profile does not tell them apart
+	df := make(map[string]int)
+	for _, profile := range c.profiles {
+		for term := range profile {
+			df[term]++
+		}
+	}
+	c.idf = make(map[string]float64, len(df))
+	for term, count := range df {
+		c.idf[term] = math.Log(1 + float64(len(c.profiles))/float64(1+count))
+	}
+
+	c.vectors = make(map[string]map[string]float64, len(c.profiles))
+	for id, profile := range c.profiles {
+		c.vectors[id] = weigh(profile, c.weight)
+	}
+}
+
+// weight returns the inverse document frequency of a term, an unknown term is the rarest
+func (c *Corpus) weight(term string) float64 {
+	if idf, ok := c.idf[term]; ok {
+		return idf
+	}
+
+	return math.Log(1 + float64(len(c.profiles)))
+}
+
+func (c *Corpus) rank(query map[string]float64, skip string, limit int) []Suggestion {
+	var suggestions []Suggestion
+	for id, vector := range c.vectors {
+		if id == skip {
+			continue
+		}
+		score := cosine(query, vector)
+		if score > 0 {
+			suggestions = append(suggestions, Suggestion{ID: id, Score: score})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].ID < suggestions[j].ID
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions
+}
+
+// weigh returns the tf-idf vector of the terms, the term frequencies are dampened by a log
+func weigh(terms map[string]float64, idf func(string) float64) map[string]float64 {
+	vector := make(map[string]float64, len(terms))
+	for term, count := range terms {
+		vector[term] = (1 + math.Log(count)) * idf(term)
+	}
+
+	return vector
+}
+
+func cosine(a, b map[string]float64) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	var dot, normA, normB float64
+	for term, weight := range a {
+		dot += weight * b[term]
+		normA += weight * weight
+	}
+	for _, weight := range b {
+		normB += weight * weight
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / math.Sqrt(normA*normB)
+}
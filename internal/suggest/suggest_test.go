@@ -0,0 +1,62 @@
+package suggest
+
+import "testing"
+
+func TestTerms(t *testing.T) {
+	terms := Terms("Go Generics", "<p>The generics of <b>Go</b> and the [docs](https://go.dev).</p>")
+
+	if terms["go"] != titleWeight+1 || terms["generics"] != titleWeight+1 {
+		t.Errorf("unexpected term counts %v", terms)
+	}
+	for _, left := range []string{"the", "of", "and", "p", "b", "https", "dev"} {
+		if _, ok := terms[left]; ok {
+			t.Errorf("term %q should be left out: %v", left, terms)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	corpus := NewCorpus()
+	corpus.AddTag("golang", "Golang")
+	corpus.AddTag("databases", "Databases")
+	corpus.AddTag("kubernetes", "Kubernetes")
+
+	corpus.Add("golang", Terms("Concurrency in Go", "goroutines and channels make go concurrency simple"))
+	corpus.Add("golang", Terms("Go modules", "go modules replace gopath"))
+	corpus.Add("databases", Terms("Postgres indexes", "a btree index speeds up postgres queries"))
+
+	suggestions := corpus.Suggest(Terms("Channels and goroutines", "a worker pool with go channels"), 2)
+	if len(suggestions) == 0 || suggestions[0].ID != "golang" {
+		t.Fatalf("expected golang first, got %+v", suggestions)
+	}
+	for _, suggestion := range suggestions {
+		if suggestion.ID == "kubernetes" {
+			t.Errorf("unrelated tag suggested %+v", suggestions)
+		}
+	}
+
+	// a tag without tagged content is found by its name
+	suggestions = corpus.Suggest(Terms("Running on Kubernetes", "deployments and pods"), 1)
+	if len(suggestions) != 1 || suggestions[0].ID != "kubernetes" {
+		t.Errorf("expected kubernetes, got %+v", suggestions)
+	}
+
+	if suggestions := corpus.Suggest(Terms("", ""), 5); suggestions != nil {
+		t.Errorf("expected no suggestion for an empty text, got %+v", suggestions)
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	corpus := NewCorpus()
+	corpus.Add("channels", Terms("Go channels", "goroutines talk over channels"))
+	corpus.Add("goroutines", Terms("Goroutines", "goroutines are cheap threads"))
+	corpus.Add("postgres", Terms("Postgres", "postgres indexes"))
+
+	similar := corpus.Similar("channels", 0)
+	if len(similar) != 1 || similar[0].ID != "goroutines" {
+		t.Errorf("expected goroutines only, got %+v", similar)
+	}
+	if similar := corpus.Similar("unknown", 0); similar != nil {
+		t.Errorf("expected nothing for an unknown profile, got %+v", similar)
+	}
+}
@@ -1,9 +1,10 @@
 package tester
 
 import (
+	"context"
 	"os"
 
-	"github.com/emrgen/unpost/internal/model"
+	"github.com/emrgen/unpost/internal/migrate"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -26,15 +27,18 @@ func Setup() {
 		panic(err)
 	}
 
-	db, err = gorm.Open(sqlite.Open(testPath+"db/unpost.db"), &gorm.Config{})
+	db, err = gorm.Open(sqlite.Open(testPath+"db/unpost.db"), &gorm.Config{TranslateError: true})
 	if err != nil {
 		panic(err)
 	}
 
-	err = model.Migrate(db)
+	migrator, err := migrate.New(db)
 	if err != nil {
 		panic(err)
 	}
+	if _, err = migrator.Up(context.Background(), 0); err != nil {
+		panic(err)
+	}
 }
 
 func TestDB() *gorm.DB {
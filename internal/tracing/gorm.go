@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+var _ gorm.Plugin = GormPlugin{}
+
+// GormPlugin records a span for every gorm operation made with a traced context.
+// The operations without a parent span, e.g. the polling of the background jobs, are not traced.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("tracing:before_create", startSpan("create")),
+		callback.Create().After("gorm:create").Register("tracing:after_create", endSpan),
+		callback.Query().Before("gorm:query").Register("tracing:before_query", startSpan("query")),
+		callback.Query().After("gorm:query").Register("tracing:after_query", endSpan),
+		callback.Update().Before("gorm:update").Register("tracing:before_update", startSpan("update")),
+		callback.Update().After("gorm:update").Register("tracing:after_update", endSpan),
+		callback.Delete().Before("gorm:delete").Register("tracing:before_delete", startSpan("delete")),
+		callback.Delete().After("gorm:delete").Register("tracing:after_delete", endSpan),
+		callback.Row().Before("gorm:row").Register("tracing:before_row", startSpan("row")),
+		callback.Row().After("gorm:row").Register("tracing:after_row", endSpan),
+		callback.Raw().Before("gorm:raw").Register("tracing:before_raw", startSpan("raw")),
+		callback.Raw().After("gorm:raw").Register("tracing:after_raw", endSpan),
+	)
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return
+		}
+
+		ctx, span := Tracer().Start(ctx, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient))
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBSystemKey.String(db.Dialector.Name()),
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type record struct {
+	ID   uint
+	Name string
+}
+
+func TestGormPluginTracesChildSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	db, err := gorm.Open(sqlite.Open("file:"+uuid.NewString()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&record{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the operations without a parent span are not traced
+	if err := db.Create(&record{Name: "untraced"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := len(recorder.Ended()); got != 0 {
+		t.Fatalf("spans without parent = %d, want 0", got)
+	}
+
+	ctx, parent := Tracer().Start(context.Background(), "request")
+	var records []record
+	if err := db.WithContext(ctx).Find(&records).Error; err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	var query sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "gorm.query" {
+			query = span
+		}
+	}
+	if query == nil {
+		t.Fatal("gorm.query span is not recorded")
+	}
+	if query.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("gorm.query parent = %s, want %s", query.Parent().SpanID(), parent.SpanContext().SpanID())
+	}
+}
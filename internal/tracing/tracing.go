@@ -0,0 +1,147 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/emrgen/unpost/internal/config"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const (
+	serviceName = "unstak"
+	tracerName  = "github.com/emrgen/unpost"
+)
+
+// ShutdownFunc flushes the pending spans and stops the exporter
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup installs the global tracer provider for the exporter selected in the config.
+// The trace context propagator is installed even when the tracing is disabled, so that the
+// incoming trace ids are still passed on to the downstream services.
+func Setup(ctx context.Context, cfg config.TracingConfig) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, closer, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+
+		return err
+	}, nil
+}
+
+// newExporter creates the span exporter, none disables the tracing and returns a nil exporter
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.Exporter {
+	case "", "none":
+		return nil, nil, nil
+	case "stdout":
+		exporter, err := stdouttrace.New()
+		return exporter, nil, err
+	case "file":
+		if err := os.MkdirAll(filepath.Dir(cfg.FilePath), os.ModePerm); err != nil {
+			return nil, nil, err
+		}
+
+		file, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			_ = file.Close()
+			return nil, nil, err
+		}
+
+		return exporter, file, nil
+	case "otlp":
+		// the standard OTEL_EXPORTER_OTLP_* variables configure the tls and the headers
+		var opts []otlptracegrpc.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+
+		exporter, err := otlptracegrpc.New(ctx, opts...)
+		return exporter, nil, err
+	default:
+		return nil, nil, fmt.Errorf("unknown tracing exporter: %s", cfg.Exporter)
+	}
+}
+
+// Tracer returns the tracer of the service
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// ServerOption traces the incoming gRPC calls and continues the trace of the caller
+func ServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
+
+// DialOption traces the outgoing gRPC calls and propagates the trace context to the called service,
+// it is used for the gateway and the document and authbase clients
+func DialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
+
+// HTTPHandler traces the incoming http requests and continues the trace of the caller,
+// the spans are named by the http method to keep the number of the span names bounded
+func HTTPHandler(handler http.Handler, operation string) http.Handler {
+	return otelhttp.NewHandler(handler, operation, otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+		return operation + " " + r.Method
+	}))
+}
+
+// HTTPClient returns a http client that traces the outgoing requests
+func HTTPClient() http.Client {
+	return http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+}
+
+// WithSpan carries the span of src over to dst, it keeps the calls made with a detached context
+// such as the authbase service context in the trace of the request
+func WithSpan(dst, src context.Context) context.Context {
+	return trace.ContextWithSpan(dst, trace.SpanFromContext(src))
+}
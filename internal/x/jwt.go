@@ -18,3 +18,24 @@ func ContextWithToken(ctx context.Context, token string) context.Context {
 func TokenFromContext(ctx context.Context) (string, bool) {
 	return ctx.Value("token").(string), true
 }
+
+func ContextWithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, "role", role)
+}
+
+// RoleFromContext returns the user role of the token, the tokens without a role have none
+func RoleFromContext(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value("role").(string)
+	return role, ok && role != ""
+}
+
+// ContextWithTokenSpace limits the call to the space of the api token it is made with
+func ContextWithTokenSpace(ctx context.Context, spaceID string) context.Context {
+	return context.WithValue(ctx, "tokenSpace", spaceID)
+}
+
+// TokenSpaceFromContext returns the space of the api token, the calls made with a login have none
+func TokenSpaceFromContext(ctx context.Context) (string, bool) {
+	spaceID, ok := ctx.Value("tokenSpace").(string)
+	return spaceID, ok
+}
@@ -2,6 +2,7 @@ package x
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 )
 
@@ -13,3 +14,9 @@ func RandomString(n int) string {
 
 	return hex.EncodeToString(b)[:n]
 }
+
+// HashSecret returns the stored hash of a token secret, the secrets are random so a plain hash is enough
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,12 @@
+package x
+
+import "context"
+
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, "requestID", requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value("requestID").(string)
+	return requestID, ok
+}